@@ -8,7 +8,9 @@ import (
 
 	"github.com/aiq/aiq/internal/cli"
 	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/db"
 	"github.com/aiq/aiq/internal/prompt"
+	"github.com/aiq/aiq/internal/secret"
 	"github.com/aiq/aiq/internal/source"
 	"github.com/aiq/aiq/internal/sql"
 	"github.com/aiq/aiq/internal/ui"
@@ -24,6 +26,9 @@ func main() {
 		}
 	}
 
+	// Keep the MySQL driver from printing the connection errors that automatic reconnects handle
+	db.FilterDriverLogs()
+
 	// Ensure directory structure exists (needed for prompt initialization)
 	if err := config.EnsureDirectoryStructure(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: Failed to create config directory structure: %v\n", err)
@@ -35,9 +40,29 @@ func main() {
 	_, err := prompt.NewLoader()
 	if err != nil {
 		// Log warning but don't fail - prompts will use fallback defaults
-		fmt.Printf("Warning: Failed to initialize prompts: %v. Using default prompts.\n", err)
+		// Stderr, so the output of ask and the JSON-RPC stream of mcp stay clean
+		fmt.Fprintf(os.Stderr, "Warning: Failed to initialize prompts: %v. Using default prompts.\n", err)
+	}
+
+	// Non-interactive one-shot request: aiq ask [flags] "question"
+	if len(os.Args) > 1 && os.Args[1] == "ask" {
+		os.Exit(cli.RunAsk(os.Args[2:]))
+	}
+
+	// Local HTTP API: aiq serve [--listen host:port]
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(cli.RunServe(os.Args[2:]))
 	}
 
+	// MCP server over stdio: aiq mcp [--source name]
+	if len(os.Args) > 1 && os.Args[1] == "mcp" {
+		os.Exit(cli.RunMCP(os.Args[2:]))
+	}
+
+	// Ask for the secrets passphrase interactively when no key file or env var provides it
+	// Only the interactive modes below prompt: ask, serve and mcp use stdin for their own input.
+	secret.PromptPassphrase = ui.ShowPassword
+
 	// Parse database connection arguments first (before flag.Parse to avoid conflicts)
 	dbArgs, err := cli.ParseDatabaseArgs()
 	if err != nil {
@@ -78,14 +103,7 @@ func main() {
 		}
 
 		// Create source with auto-generated name
-		newSource := &source.Source{
-			Type:     dbArgs.Engine,
-			Host:     dbArgs.Host,
-			Port:     dbArgs.Port,
-			Database: dbArgs.Database,
-			Username: dbArgs.Username,
-			Password: dbArgs.Password,
-		}
+		newSource := dbArgs.NewSource()
 
 		// Check if source already exists before creating
 		existingName, err := source.FindExistingSourceByConnection(dbArgs.Host, dbArgs.Port, dbArgs.Username)
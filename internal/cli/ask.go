@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aiq/aiq/internal/secret"
+	"github.com/aiq/aiq/internal/sql"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// Exit codes of the ask and serve commands
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// RunAsk runs `aiq ask [flags] "question"` non-interactively and returns the process exit code
+// The question may also be piped on stdin. Flags must come before the question.
+// With --output-format stream-json, stdin instead carries JSON lines answering confirmations and,
+// when no question is given, the user requests of each turn.
+func RunAsk(args []string) int {
+	fs := flag.NewFlagSet("ask", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	sourceName := fs.String("source", "", "Name of the data source to query (omit for free mode)")
+	database := fs.String("database", "", "Database to use instead of the source's default")
+	fs.StringVar(database, "D", "", "Shorthand for --database")
+	format := fs.String("format", sql.FormatText, "Output format: text, json, csv or stream-json")
+	fs.StringVar(format, "output-format", sql.FormatText, "Alias for --format")
+	profile := fs.String("profile", "", "LLM profile to use instead of the one configured for chat")
+	confirm := fs.String("confirm", "", "Policy for operations that need confirmation: deny (read-only SQL only), allow-low-risk (also CREATE TABLE), or prompt (stream-json only, the default there)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: aiq ask [--source name] [--database db] [--format text|json|csv|stream-json] [--confirm deny|allow-low-risk|prompt] [--profile name] \"question\"")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Runs one request without prompting and prints the answer and query results to stdout.")
+		fmt.Fprintln(os.Stderr, "Exit status is 0 on success, 1 if the request failed or an operation was denied, 2 on usage errors.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	outputFormat := strings.ToLower(*format)
+	switch outputFormat {
+	case sql.FormatText, sql.FormatJSON, sql.FormatCSV, sql.FormatStreamJSON:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q (use text, json, csv or stream-json)\n", *format)
+		return exitUsage
+	}
+	streaming := outputFormat == sql.FormatStreamJSON
+
+	policy := sql.PolicyDeny
+	if streaming {
+		policy = sql.PolicyPrompt
+	}
+	if *confirm != "" {
+		var err error
+		policy, err = sql.ParseConfirmPolicy(*confirm)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		if policy == sql.PolicyPrompt && !streaming {
+			fmt.Fprintln(os.Stderr, "Error: --confirm prompt requires --output-format stream-json")
+			return exitUsage
+		}
+	}
+
+	question := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if question == "-" {
+		question = ""
+	}
+	// In stream-json mode stdin carries JSON lines, so a missing question is read from there per turn
+	if question == "" && !streaming {
+		// Read the question from stdin when it is piped in
+		if !isTerminal(os.Stdin) {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to read question from stdin: %v\n", err)
+				return exitError
+			}
+			question = strings.TrimSpace(string(data))
+		}
+	}
+	if question == "" && !streaming {
+		fs.Usage()
+		return exitUsage
+	}
+
+	// A locked secrets file may only be unlocked by prompting when a user is at the terminal;
+	// otherwise the passphrase must come from the key file or the environment
+	if !streaming && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		secret.PromptPassphrase = ui.ShowPassword
+	}
+
+	err := sql.RunAsk(sql.AskOptions{
+		SourceName: *sourceName,
+		Database:   *database,
+		Question:   question,
+		Format:     outputFormat,
+		Policy:     policy,
+		Profile:    *profile,
+	}, os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
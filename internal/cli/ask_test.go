@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"os"
+	"testing"
+)
+
+// TestRunAsk_ExitCodes tests the exit codes of usage errors, which are reported before anything runs
+func TestRunAsk_ExitCodes(t *testing.T) {
+	// Quiet the usage output and make sure no question can be read from stdin
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdin, stderr := os.Stdin, os.Stderr
+	os.Stdin, os.Stderr = devNull, devNull
+	defer func() { os.Stdin, os.Stderr = stdin, stderr }()
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"help", []string{"-h"}, exitOK},
+		{"unknown flag", []string{"--verbose", "question"}, exitUsage},
+		{"unknown format", []string{"--format", "xml", "question"}, exitUsage},
+		{"unknown policy", []string{"--confirm", "always", "question"}, exitUsage},
+		{"prompt without stream-json", []string{"--confirm", "prompt", "question"}, exitUsage},
+		{"no question", []string{"--format", "json"}, exitUsage},
+		{"dash without piped question", []string{"-"}, exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RunAsk(tt.args); got != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
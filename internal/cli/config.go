@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/source"
 	"github.com/aiq/aiq/internal/ui"
 )
 
@@ -13,9 +14,13 @@ func RunConfigMenu() error {
 	for {
 		items := []ui.MenuItem{
 			{Label: "view    - View current configuration", Value: "view"},
+			{Label: "api     - Update LLM API provider (openai or anthropic)", Value: "update_provider"},
 			{Label: "url     - Update LLM API URL", Value: "update_url"},
 			{Label: "model   - Update model name", Value: "update_model"},
 			{Label: "key     - Update LLM API key", Value: "update_key"},
+			{Label: "profile - Add or update a named LLM profile", Value: "update_profile"},
+			{Label: "tasks   - Choose the profile for chat, skill matching and compression", Value: "update_tasks"},
+			{Label: "secrets - Move plaintext passwords and API key to encrypted secrets file", Value: "migrate_secrets"},
 			{Label: "back    - Back to main menu", Value: "back"},
 		}
 
@@ -29,6 +34,12 @@ func RunConfigMenu() error {
 			if err := viewConfig(); err != nil {
 				ui.ShowError(err.Error())
 			}
+		case "update_provider":
+			if err := updateProvider(); err != nil {
+				ui.ShowError(err.Error())
+			} else {
+				ui.ShowSuccess("LLM provider updated successfully!")
+			}
 		case "update_url":
 			if err := updateURL(); err != nil {
 				ui.ShowError(err.Error())
@@ -47,6 +58,22 @@ func RunConfigMenu() error {
 			} else {
 				ui.ShowSuccess("API Key updated successfully!")
 			}
+		case "update_profile":
+			if err := updateProfile(); err != nil {
+				ui.ShowError(err.Error())
+			} else {
+				ui.ShowSuccess("LLM profile saved successfully!")
+			}
+		case "update_tasks":
+			if err := updateTasks(); err != nil {
+				ui.ShowError(err.Error())
+			} else {
+				ui.ShowSuccess("Task profiles updated successfully!")
+			}
+		case "migrate_secrets":
+			if err := migrateSecrets(); err != nil {
+				ui.ShowError(err.Error())
+			}
 		case "back":
 			return nil
 		}
@@ -63,9 +90,19 @@ func viewConfig() error {
 
 	fmt.Println()
 	ui.ShowInfo("Current Configuration:")
+	fmt.Printf("  Provider: %s\n", cfg.LLM.ProviderName())
 	fmt.Printf("  LLM URL: %s\n", cfg.LLM.URL)
 	fmt.Printf("  Model: %s\n", cfg.LLM.Model)
-	fmt.Printf("  API Key: %s\n", maskAPIKey(cfg.LLM.APIKey))
+	fmt.Printf("  API Key: %s\n", describeAPIKey(&cfg.LLM))
+	for _, name := range cfg.ProfileNames()[1:] {
+		profile, _ := cfg.Profile(name)
+		fmt.Printf("  Profile %s: %s at %s\n", name, profile.Model, profile.URL)
+	}
+	if len(cfg.Profiles) > 0 {
+		for _, task := range config.Tasks {
+			fmt.Printf("  Profile for %s: %s\n", task, cfg.ProfileFor(task))
+		}
+	}
 	fmt.Println()
 
 	return nil
@@ -85,7 +122,7 @@ func updateURL() error {
 	fmt.Println("    - https://api.anthropic.com/v1")
 	fmt.Println("    - https://api.example.com/v1")
 	fmt.Println()
-	fmt.Println("  Note: The '/chat/completions' path will be added automatically.")
+	fmt.Println("  Note: The '/chat/completions' path (or '/messages' for the anthropic provider) will be added automatically.")
 	fmt.Println()
 
 	newURL, err := ui.ShowInput("Enter new LLM URL", cfg.LLM.URL)
@@ -106,6 +143,29 @@ func updateURL() error {
 	return nil
 }
 
+func updateProvider() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	provider, err := ui.ShowMenu("Select LLM API provider", config.ProviderMenuItems())
+	if err != nil {
+		return fmt.Errorf("failed to get provider: %w", err)
+	}
+	cfg.LLM.Provider = provider
+
+	if err := config.ValidatePartialLLMConfig(&cfg.LLM); err != nil {
+		return err
+	}
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	return nil
+}
+
 func updateModel() error {
 	cfg, err := config.Load()
 	if err != nil {
@@ -113,29 +173,123 @@ func updateModel() error {
 	}
 
 	fmt.Println()
-	fmt.Println("Model Name:")
-	fmt.Println("  Enter the model name to use for SQL translation.")
-	fmt.Println("  Examples:")
-	fmt.Println("    - gpt-3.5-turbo")
-	fmt.Println("    - gpt-4")
-	fmt.Println("    - claude-3-opus")
-	fmt.Println("    - deepseek-chat")
+	newModel, err := config.ChooseModel(&cfg.LLM, "")
+	if err != nil {
+		return fmt.Errorf("failed to get model name: %w", err)
+	}
+	cfg.LLM.Model = newModel
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	return nil
+}
+
+// updateProfile adds or updates a named LLM profile
+func updateProfile() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("LLM Profiles:")
+	fmt.Println("  A profile is a named model, e.g. 'fast' for skill matching and compression.")
+	fmt.Println("  Switch the chat profile in a session with /model <profile>.")
 	fmt.Println()
 
-	newModel, err := ui.ShowInput("Enter Model Name", cfg.LLM.Model)
+	name, err := ui.ShowInput("Enter profile name", "")
 	if err != nil {
-		return fmt.Errorf("failed to get model name: %w", err)
+		return fmt.Errorf("failed to get profile name: %w", err)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || name == config.DefaultProfile {
+		return fmt.Errorf("profile name cannot be empty or %q", config.DefaultProfile)
 	}
 
-	cfg.LLM.Model = strings.TrimSpace(newModel)
-	if cfg.LLM.Model == "" {
-		return fmt.Errorf("model name cannot be empty")
+	profile := cfg.Profiles[name]
+	shared, err := ui.ShowConfirm("Use the API provider, URL and key of the default profile?")
+	if err != nil {
+		return err
+	}
+	if shared {
+		profile.Provider, profile.URL = "", ""
+		profile.APIKey, profile.APIKeyEnv, profile.APIKeyCmd, profile.APIKeySecret = "", "", "", ""
+	} else {
+		if profile.Provider, err = ui.ShowMenu("Select LLM API provider", config.ProviderMenuItems()); err != nil {
+			return fmt.Errorf("failed to get provider: %w", err)
+		}
+		if profile.URL, err = ui.ShowInput("Enter LLM API URL", profile.URL); err != nil {
+			return fmt.Errorf("failed to get URL: %w", err)
+		}
+		if profile.APIKey, err = ui.ShowPassword("Enter LLM API Key"); err != nil {
+			return fmt.Errorf("failed to get API key: %w", err)
+		}
+		profile.APIKeyEnv, profile.APIKeyCmd, profile.APIKeySecret = "", "", ""
+	}
+
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]config.LLMConfig)
+	}
+	cfg.Profiles[name] = profile
+	resolved, err := cfg.Profile(name)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	if profile.Model, err = config.ChooseModel(resolved, ""); err != nil {
+		return fmt.Errorf("failed to get model name: %w", err)
 	}
+	cfg.Profiles[name] = profile
 
+	if err := config.ValidateProfiles(cfg); err != nil {
+		return err
+	}
 	if err := config.Save(cfg); err != nil {
 		return fmt.Errorf("failed to save configuration: %w", err)
 	}
+	return nil
+}
+
+// updateTasks chooses the profile used for each task
+func updateTasks() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
 
+	profiles := make([]ui.MenuItem, 0, len(cfg.Profiles)+1)
+	for _, name := range cfg.ProfileNames() {
+		llmCfg, _ := cfg.Profile(name)
+		profiles = append(profiles, ui.MenuItem{Label: fmt.Sprintf("%s (%s)", name, llmCfg.Model), Value: name})
+	}
+	descriptions := map[string]string{
+		config.TaskChat:        "chat and SQL generation",
+		config.TaskSkills:      "skill matching",
+		config.TaskCompression: "conversation compression",
+	}
+	for _, task := range config.Tasks {
+		name, err := ui.ShowMenu(fmt.Sprintf("Profile for %s (now %s)", descriptions[task], cfg.ProfileFor(task)), profiles)
+		if err != nil {
+			return err
+		}
+		if name == config.DefaultProfile {
+			name = ""
+		}
+		switch task {
+		case config.TaskChat:
+			cfg.Tasks.Chat = name
+		case config.TaskSkills:
+			cfg.Tasks.Skills = name
+		case config.TaskCompression:
+			cfg.Tasks.Compression = name
+		}
+	}
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
 	return nil
 }
 
@@ -151,11 +305,26 @@ func updateAPIKey() error {
 	}
 
 	cfg.LLM.APIKey = newKey
+	cfg.LLM.APIKeyEnv = ""
+	cfg.LLM.APIKeyCmd = ""
+	cfg.LLM.APIKeySecret = ""
 
 	if err := config.ValidatePartialLLMConfig(&cfg.LLM); err != nil {
 		return err
 	}
 
+	encrypt, err := ui.ShowConfirm("Store the API key in the encrypted secrets file instead of config.yaml?")
+	if err == nil && encrypt {
+		store, err := config.OpenSecrets()
+		if err != nil {
+			return fmt.Errorf("failed to open secrets file: %w", err)
+		}
+		cfg.MigrateAPIKey(store)
+		if err := store.Save(); err != nil {
+			return err
+		}
+	}
+
 	if err := config.Save(cfg); err != nil {
 		return fmt.Errorf("failed to save configuration: %w", err)
 	}
@@ -172,3 +341,66 @@ func maskAPIKey(key string) string {
 	}
 	return key[:4] + "..." + key[len(key)-4:]
 }
+
+// describeAPIKey shows where the API key comes from without revealing it
+func describeAPIKey(l *config.LLMConfig) string {
+	switch {
+	case l.APIKey != "":
+		return maskAPIKey(l.APIKey)
+	case l.APIKeyEnv != "":
+		return fmt.Sprintf("from environment variable %s", l.APIKeyEnv)
+	case l.APIKeyCmd != "":
+		return fmt.Sprintf("from command %q", l.APIKeyCmd)
+	case l.APIKeySecret != "":
+		return fmt.Sprintf("from secrets file (%s)", l.APIKeySecret)
+	}
+	return ""
+}
+
+// migrateSecrets moves plaintext source passwords and the LLM API key into the encrypted secrets file
+func migrateSecrets() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	sources, err := source.LoadSources()
+	if err != nil {
+		return err
+	}
+
+	plaintext := 0
+	for _, s := range sources {
+		if s.Password != "" {
+			plaintext++
+		}
+	}
+	if cfg.LLM.APIKey == "" && plaintext == 0 {
+		ui.ShowInfo("No plaintext secrets found.")
+		return nil
+	}
+
+	store, err := config.OpenSecrets()
+	if err != nil {
+		return fmt.Errorf("failed to open secrets file: %w", err)
+	}
+
+	migrated, err := source.MigratePasswords(store)
+	if err != nil {
+		return fmt.Errorf("failed to migrate source passwords: %w", err)
+	}
+	for _, name := range migrated {
+		ui.ShowSuccess(fmt.Sprintf("Moved password of source '%s' to the secrets file.", name))
+	}
+
+	if cfg.MigrateAPIKey(store) {
+		if err := store.Save(); err != nil {
+			return err
+		}
+		if err := config.Save(cfg); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+		ui.ShowSuccess("Moved LLM API key to the secrets file.")
+	}
+
+	return nil
+}
@@ -5,25 +5,20 @@ import (
 	"strings"
 
 	"github.com/aiq/aiq/internal/db"
-	"github.com/aiq/aiq/internal/source"
 )
 
 // ValidateConnection validates a database connection with the given parameters
 func ValidateConnection(args *DatabaseArgs) error {
 	// Create source temporarily for DSN generation
-	tempSource := &source.Source{
-		Type:     args.Engine,
-		Host:     args.Host,
-		Port:     args.Port,
-		Database: args.Database,
-		Username: args.Username,
-		Password: args.Password,
-	}
+	tempSource := args.NewSource()
 
-	dsn := tempSource.DSN()
+	dsn, err := tempSource.DSN()
+	if err != nil {
+		return fmt.Errorf("invalid connection settings: %w", err)
+	}
 	dbType := string(args.Engine)
 
-	conn, err := db.NewConnection(dsn, dbType)
+	conn, err := db.NewConnectionWithOptions(dsn, dbType, tempSource.ConnectOptions())
 	if err != nil {
 		// Provide clearer error messages
 		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such host") {
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aiq/aiq/internal/sql"
+)
+
+// RunMCP runs `aiq mcp [flags]`, serving the Model Context Protocol over stdio, and returns the process exit code
+func RunMCP(args []string) int {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	sourceName := fs.String("source", "", "Name of the data source to expose (omit to expose only rendering and skills)")
+	database := fs.String("database", "", "Database to use instead of the source's default")
+	fs.StringVar(database, "D", "", "Shorthand for --database")
+	policyName := fs.String("policy", "deny", "SQL to run without confirmation: deny (reads only) or allow-low-risk (reads and CREATE TABLE)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: aiq mcp [--source name] [--database db] [--policy deny|allow-low-risk]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Runs aiq as an MCP server on stdin/stdout, exposing execute_sql, the schema as resources,")
+		fmt.Fprintln(os.Stderr, "render_table/render_chart and skills as prompts. Nobody can confirm statements over MCP, so")
+		fmt.Fprintln(os.Stderr, "execute_sql runs only what the policy allows: read-only SQL by default.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	policy, err := sql.ParseConfirmPolicy(*policyName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	if policy == sql.PolicyPrompt {
+		fmt.Fprintln(os.Stderr, "Error: --policy prompt is not supported, nobody can confirm statements over MCP")
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = sql.RunMCP(ctx, sql.MCPOptions{SourceName: *sourceName, Database: *database, Policy: policy}, os.Stdin, os.Stdout)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os"
+	"testing"
+)
+
+// TestRunMCP_ExitCodes tests the exit codes of usage errors, which are reported before the server starts
+func TestRunMCP_ExitCodes(t *testing.T) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stderr := os.Stderr
+	os.Stderr = devNull
+	defer func() { os.Stderr = stderr }()
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"help", []string{"-h"}, exitOK},
+		{"unknown policy", []string{"--policy", "always"}, exitUsage},
+		{"prompt policy", []string{"--policy", "prompt"}, exitUsage},
+		{"positional argument", []string{"extra"}, exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RunMCP(tt.args); got != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
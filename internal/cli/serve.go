@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aiq/aiq/internal/sql"
+)
+
+// serveTokenEnv names the environment variable holding the API bearer token
+const serveTokenEnv = "AIQ_SERVE_TOKEN"
+
+// RunServe runs `aiq serve [flags]` and returns the process exit code
+// The server stops on Ctrl+C or SIGTERM.
+func RunServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	listen := fs.String("listen", sql.DefaultServeAddress, "Address to listen on")
+	token := fs.String("token", "", "Bearer token clients must send (default: $"+serveTokenEnv+", or a generated token)")
+	maxSessions := fs.Int("max-sessions", sql.DefaultMaxSessions, "Maximum number of sessions open at the same time")
+	idleTimeout := fs.Duration("idle-timeout", sql.DefaultSessionIdleTimeout, "Close sessions idle for this long")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: aiq serve [--listen host:port] [--token token] [--max-sessions n] [--idle-timeout duration]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Serves aiq sessions over a local HTTP API with server-sent events.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *maxSessions < 1 || *idleTimeout <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-sessions and --idle-timeout must be positive")
+		return exitUsage
+	}
+
+	if *token == "" {
+		*token = os.Getenv(serveTokenEnv)
+	}
+	if *token == "" {
+		generated, err := sql.GenerateServeToken()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		*token = generated
+		fmt.Fprintf(os.Stderr, "Generated API token (set %s or --token to choose one):\n%s\n", serveTokenEnv, *token)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := sql.RunServe(ctx, sql.ServeOptions{Listen: *listen, Token: *token, MaxSessions: *maxSessions, IdleTimeout: *idleTimeout}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -3,7 +3,9 @@ package cli
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/aiq/aiq/internal/config"
 	"github.com/aiq/aiq/internal/db"
 	"github.com/aiq/aiq/internal/source"
 	"github.com/aiq/aiq/internal/ui"
@@ -29,6 +31,7 @@ func RunSourceMenu() error {
 			{Label: "list    - List all data sources", Value: "list"},
 			{Label: "edit    - Edit a data source", Value: "edit"},
 			{Label: "remove  - Remove a data source", Value: "remove"},
+			{Label: "import  - Import sources from ~/.my.cnf and ~/.pgpass", Value: "import"},
 			{Label: "back    - Back to main menu", Value: "back"},
 		}
 
@@ -60,6 +63,10 @@ func RunSourceMenu() error {
 			} else {
 				ui.ShowSuccess("Data source removed successfully!")
 			}
+		case "import":
+			if err := importSources(); err != nil {
+				ui.ShowError(err.Error())
+			}
 		case "back":
 			return nil
 		}
@@ -129,11 +136,17 @@ func addSource() error {
 	}
 	src.Username = username
 
-	password, err := ui.ShowPassword("Enter password")
+	encryptPassword, err := promptPassword(src)
 	if err != nil {
-		return fmt.Errorf("failed to get password: %w", err)
+		return err
+	}
+
+	advanced, err := ui.ShowConfirm("Configure advanced connection options (socket, TLS, SSH tunnel, driver params)?")
+	if err == nil && advanced {
+		if err := promptConnectionOptions(src); err != nil {
+			return err
+		}
 	}
-	src.Password = password
 
 	if err := source.Validate(src); err != nil {
 		return err
@@ -143,7 +156,7 @@ func addSource() error {
 	test, err := ui.ShowConfirm("Test connection before saving?")
 	if err == nil && test {
 		ui.ShowInfo("Testing connection...")
-		if err := db.TestConnection(src.DSN(), string(src.Type)); err != nil {
+		if err := testSourceConnection(src); err != nil {
 			ui.ShowWarning(fmt.Sprintf("Connection test failed: %v", err))
 			proceed, _ := ui.ShowConfirm("Save anyway?")
 			if !proceed {
@@ -154,6 +167,12 @@ func addSource() error {
 		}
 	}
 
+	if encryptPassword {
+		if err := storePasswordSecret(src); err != nil {
+			return err
+		}
+	}
+
 	return source.AddSource(src)
 }
 
@@ -255,15 +274,9 @@ func editSource() error {
 	}
 
 	// Create updated source with current values as defaults
-	updated := &source.Source{
-		Type:     oldSource.Type,
-		Name:     oldSource.Name,
-		Host:     oldSource.Host,
-		Port:     oldSource.Port,
-		Database: oldSource.Database,
-		Username: oldSource.Username,
-		Password: oldSource.Password,
-	}
+	// Copy the whole source so settings that are only edited in sources.yaml (e.g. init_sql) are kept
+	updatedSource := *oldSource
+	updated := &updatedSource
 
 	// Prompt for all fields with current values as defaults
 	name, err := ui.ShowInput("Enter source name", oldSource.Name)
@@ -307,12 +320,18 @@ func editSource() error {
 		return fmt.Errorf("failed to get password change confirmation: %w", err)
 	}
 
+	encryptPassword := false
 	if changePassword {
-		password, err := ui.ShowPassword("Enter password")
-		if err != nil {
-			return fmt.Errorf("failed to get password: %w", err)
+		if encryptPassword, err = promptPassword(updated); err != nil {
+			return err
+		}
+	}
+
+	advanced, err := ui.ShowConfirm("Edit advanced connection options (socket, TLS, SSH tunnel, driver params)?")
+	if err == nil && advanced {
+		if err := promptConnectionOptions(updated); err != nil {
+			return err
 		}
-		updated.Password = password
 	}
 
 	if err := source.Validate(updated); err != nil {
@@ -323,7 +342,7 @@ func editSource() error {
 	test, err := ui.ShowConfirm("Test connection before saving?")
 	if err == nil && test {
 		ui.ShowInfo("Testing connection...")
-		if err := db.TestConnection(updated.DSN(), string(updated.Type)); err != nil {
+		if err := testSourceConnection(updated); err != nil {
 			ui.ShowWarning(fmt.Sprintf("Connection test failed: %v", err))
 			proceed, _ := ui.ShowConfirm("Save anyway?")
 			if !proceed {
@@ -334,5 +353,287 @@ func editSource() error {
 		}
 	}
 
+	if encryptPassword {
+		if err := storePasswordSecret(updated); err != nil {
+			return err
+		}
+	}
+
 	return source.UpdateSource(selected, updated)
 }
+
+// testSourceConnection tests a source's connection including its TLS settings and init statements
+func testSourceConnection(src *source.Source) error {
+	dsn, err := src.DSN()
+	if err != nil {
+		return err
+	}
+	return db.TestConnectionWithOptions(dsn, string(src.Type), src.ConnectOptions())
+}
+
+// clearValue is entered at a prompt to clear an existing optional value
+const clearValue = "-"
+
+// promptOptional asks for an optional value; entering "-" clears the current value
+func promptOptional(label, current string) (string, error) {
+	if current != "" {
+		label = fmt.Sprintf("%s, %s to clear", label, clearValue)
+	}
+	value, err := ui.ShowInput(label, current)
+	if err != nil {
+		return "", err
+	}
+	value = strings.TrimSpace(value)
+	if value == clearValue {
+		return "", nil
+	}
+	return value, nil
+}
+
+// promptConnectionOptions prompts for socket, TLS, SSH tunnel and driver params, using the source's current values as defaults
+func promptConnectionOptions(src *source.Source) error {
+	socket, err := promptOptional("Enter socket path (empty to use host/port)", src.Socket)
+	if err != nil {
+		return fmt.Errorf("failed to get socket path: %w", err)
+	}
+	src.Socket = socket
+
+	fmt.Println()
+	fmt.Println("Select TLS Mode:")
+	tlsItems := []ui.MenuItem{
+		{Label: "Driver default", Value: ""},
+		{Label: "disable - plain connection", Value: source.TLSModeDisable},
+		{Label: "required - encrypt, don't verify certificate", Value: source.TLSModeRequired},
+		{Label: "verify-ca - encrypt, verify certificate against CA", Value: source.TLSModeVerifyCA},
+		{Label: "verify-full - encrypt, verify CA and host name", Value: source.TLSModeVerifyFull},
+	}
+	if src.Type != source.DatabaseTypePostgreSQL {
+		tlsItems = append(tlsItems, ui.MenuItem{Label: "preferred - encrypt if the server supports it", Value: source.TLSModePreferred})
+	}
+	mode, err := ui.ShowMenu("TLS Mode", tlsItems)
+	if err != nil {
+		return fmt.Errorf("failed to select TLS mode: %w", err)
+	}
+
+	if mode == "" {
+		src.TLS = nil
+	} else {
+		current := src.TLS
+		if current == nil {
+			current = &source.TLSConfig{}
+		}
+		tlsCfg := &source.TLSConfig{Mode: mode}
+
+		if tlsCfg.CA, err = promptOptional("Enter CA certificate path (optional)", current.CA); err != nil {
+			return fmt.Errorf("failed to get CA path: %w", err)
+		}
+		if tlsCfg.Cert, err = promptOptional("Enter client certificate path (optional)", current.Cert); err != nil {
+			return fmt.Errorf("failed to get client certificate path: %w", err)
+		}
+		if tlsCfg.Cert != "" {
+			if tlsCfg.Key, err = promptOptional("Enter client key path", current.Key); err != nil {
+				return fmt.Errorf("failed to get client key path: %w", err)
+			}
+		}
+		if src.Type != source.DatabaseTypePostgreSQL {
+			if tlsCfg.ServerName, err = promptOptional("Enter TLS server name (optional, defaults to host)", current.ServerName); err != nil {
+				return fmt.Errorf("failed to get TLS server name: %w", err)
+			}
+		}
+		src.TLS = tlsCfg
+	}
+
+	if err := promptSSHOptions(src); err != nil {
+		return err
+	}
+
+	paramsStr, err := promptOptional("Enter driver params (key=value, comma separated)", source.FormatParams(src.Params))
+	if err != nil {
+		return fmt.Errorf("failed to get driver params: %w", err)
+	}
+	params, err := source.ParseParams(paramsStr)
+	if err != nil {
+		return err
+	}
+	src.Params = params
+
+	return nil
+}
+
+// promptSSHOptions prompts for an SSH bastion host to tunnel the connection through
+func promptSSHOptions(src *source.Source) error {
+	useSSH, err := ui.ShowConfirm("Connect through an SSH bastion host?")
+	if err != nil {
+		return fmt.Errorf("failed to get SSH choice: %w", err)
+	}
+	if !useSSH {
+		src.SSH = nil
+		return nil
+	}
+
+	current := src.SSH
+	if current == nil {
+		current = &source.SSHConfig{Port: 22}
+	}
+	sshCfg := &source.SSHConfig{KnownHosts: current.KnownHosts, KeepaliveInterval: current.KeepaliveInterval}
+
+	if sshCfg.Host, err = ui.ShowInput("Enter SSH host", current.Host); err != nil {
+		return fmt.Errorf("failed to get SSH host: %w", err)
+	}
+
+	portStr, err := ui.ShowInput("Enter SSH port", strconv.Itoa(current.Port))
+	if err != nil {
+		return fmt.Errorf("failed to get SSH port: %w", err)
+	}
+	if sshCfg.Port, err = strconv.Atoi(strings.TrimSpace(portStr)); err != nil {
+		return fmt.Errorf("invalid SSH port: %w", err)
+	}
+
+	if sshCfg.User, err = ui.ShowInput("Enter SSH user", current.User); err != nil {
+		return fmt.Errorf("failed to get SSH user: %w", err)
+	}
+
+	if sshCfg.KeyPath, err = promptOptional("Enter SSH private key path (empty to use ssh-agent only)", current.KeyPath); err != nil {
+		return fmt.Errorf("failed to get SSH key path: %w", err)
+	}
+	sshCfg.Agent = sshCfg.KeyPath == ""
+	if !sshCfg.Agent {
+		if sshCfg.Agent, err = ui.ShowConfirm("Also use keys from ssh-agent?"); err != nil {
+			return fmt.Errorf("failed to get SSH agent choice: %w", err)
+		}
+	}
+
+	src.SSH = sshCfg
+	return nil
+}
+
+// promptPassword asks how the source's password is provided and reads it
+// Returns true if the entered password should be moved to the secrets file when the source is saved
+func promptPassword(src *source.Source) (bool, error) {
+	fmt.Println()
+	fmt.Println("Select Password Storage:")
+	items := []ui.MenuItem{
+		{Label: "secrets  - Enter password, store it in the encrypted secrets file", Value: "secret"},
+		{Label: "env      - Read password from an environment variable", Value: "env"},
+		{Label: "cmd      - Read password from a command (e.g. pass show db/prod)", Value: "cmd"},
+		{Label: "plain    - Enter password, store it in sources.yaml", Value: "plain"},
+	}
+	choice, err := ui.ShowMenu("Password Storage", items)
+	if err != nil {
+		return false, fmt.Errorf("failed to select password storage: %w", err)
+	}
+
+	src.Password, src.PasswordEnv, src.PasswordCmd, src.PasswordSecret, src.PasswordFrom = "", "", "", "", ""
+
+	switch choice {
+	case "env":
+		defaultEnv := "MYSQL_PWD"
+		if src.Type == source.DatabaseTypePostgreSQL {
+			defaultEnv = "PGPASSWORD"
+		}
+		name, err := ui.ShowInput("Enter environment variable name", defaultEnv)
+		if err != nil {
+			return false, fmt.Errorf("failed to get environment variable: %w", err)
+		}
+		src.PasswordEnv = strings.TrimSpace(name)
+		return false, nil
+	case "cmd":
+		command, err := ui.ShowInput("Enter command that prints the password", "")
+		if err != nil {
+			return false, fmt.Errorf("failed to get password command: %w", err)
+		}
+		src.PasswordCmd = strings.TrimSpace(command)
+		return false, nil
+	default:
+		password, err := ui.ShowPassword("Enter password")
+		if err != nil {
+			return false, fmt.Errorf("failed to get password: %w", err)
+		}
+		src.Password = password
+		return choice == "secret", nil
+	}
+}
+
+// storePasswordSecret moves the source's plaintext password into the encrypted secrets file
+func storePasswordSecret(src *source.Source) error {
+	store, err := config.OpenSecrets()
+	if err != nil {
+		return fmt.Errorf("failed to open secrets file: %w", err)
+	}
+	name := src.PasswordSecretName()
+	store.Set(name, src.Password)
+	if err := store.Save(); err != nil {
+		return err
+	}
+	src.Password = ""
+	src.PasswordSecret = name
+	return nil
+}
+
+// importSources creates sources for the connections found in ~/.my.cnf and ~/.pgpass
+func importSources() error {
+	discovered, err := source.DiscoverSources()
+	if err != nil {
+		return err
+	}
+
+	// Skip connections that already have a source
+	candidates := make([]*source.Source, 0, len(discovered))
+	for _, s := range discovered {
+		existing, err := source.FindExistingSourceByConnection(s.Host, s.Port, s.Username)
+		if err != nil {
+			return err
+		}
+		if existing == "" {
+			candidates = append(candidates, s)
+		}
+	}
+
+	if len(candidates) == 0 {
+		ui.ShowInfo("No new connections found in ~/.my.cnf or ~/.pgpass.")
+		return nil
+	}
+
+	fmt.Println()
+	ui.ShowInfo("Discovered Connections:")
+	fmt.Println()
+	headers := []string{"Type", "Host", "Port", "Database", "Username", "Password"}
+	rows := make([][]string, 0, len(candidates))
+	for _, s := range candidates {
+		// Passwords are not copied: they are read from the client file when connecting
+		password := "no"
+		if s.PasswordFrom != "" {
+			password = "from ~/.my.cnf"
+			if s.PasswordFrom == source.PasswordFromPgpass {
+				password = "from ~/.pgpass"
+			}
+		}
+		host := s.Host
+		if s.Socket != "" {
+			host = s.Socket
+		}
+		rows = append(rows, []string{string(s.Type), host, strconv.Itoa(s.Port), s.Database, s.Username, password})
+	}
+	ui.PrintTable(headers, rows)
+	fmt.Println()
+
+	confirm, err := ui.ShowConfirm(fmt.Sprintf("Import %d connection(s)?", len(candidates)))
+	if err != nil || !confirm {
+		ui.ShowInfo("Import cancelled.")
+		return nil
+	}
+
+	imported := 0
+	for _, s := range candidates {
+		name, err := source.AddSourceWithAutoName(s)
+		if err != nil {
+			ui.ShowWarning(fmt.Sprintf("Failed to import %s@%s: %v", s.Username, s.Host, err))
+			continue
+		}
+		ui.ShowSuccess(fmt.Sprintf("Imported source '%s'.", name))
+		imported++
+	}
+
+	ui.ShowInfo(fmt.Sprintf("Imported %d of %d connection(s).", imported, len(candidates)))
+	return nil
+}
@@ -1,15 +1,131 @@
 package config
 
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aiq/aiq/internal/secret"
+)
+
 // Config represents the application configuration
 type Config struct {
-	LLM LLMConfig `yaml:"llm"`
+	LLM        LLMConfig         `yaml:"llm"`
+	MCPServers []MCPServerConfig `yaml:"mcp_servers,omitempty"`
+	Limits     LoopLimits        `yaml:"limits,omitempty"`
+	Pricing    map[string]Price  `yaml:"pricing,omitempty"` // By model name, for cost estimates in /usage
+
+	// Named alternatives to the llm section, e.g. a fast model for skill matching and compression
+	Profiles map[string]LLMConfig `yaml:"profiles,omitempty"`
+	Tasks    TaskProfiles         `yaml:"tasks,omitempty"` // Profile used for each task
+}
+
+// Default loop limits, used for limits that are not configured
+const (
+	DefaultMaxIterations        = 10
+	DefaultMaxDuration          = 10 * time.Minute
+	DefaultMaxConsecutiveErrors = 5
+)
+
+// DefaultMaxRetries is how often a failed LLM request is retried unless configured otherwise
+const DefaultMaxRetries = 3
+
+// LoopLimits bounds the tool-calling loop of one request
+// Zero values use the defaults; MaxTokens is unlimited unless set.
+type LoopLimits struct {
+	MaxIterations        int    `yaml:"max_iterations,omitempty"`         // LLM calls per request
+	MaxDuration          string `yaml:"max_duration,omitempty"`           // Wall-clock time per request, e.g. "5m"
+	MaxTokens            int    `yaml:"max_tokens,omitempty"`             // Tokens used by the LLM calls of a request
+	MaxConsecutiveErrors int    `yaml:"max_consecutive_errors,omitempty"` // Failed tool calls in a row
+}
+
+// WithDefaults returns the limits with unset values replaced by the defaults
+func (l LoopLimits) WithDefaults() LoopLimits {
+	if l.MaxIterations <= 0 {
+		l.MaxIterations = DefaultMaxIterations
+	}
+	if l.MaxDuration == "" {
+		l.MaxDuration = DefaultMaxDuration.String()
+	}
+	if l.MaxConsecutiveErrors <= 0 {
+		l.MaxConsecutiveErrors = DefaultMaxConsecutiveErrors
+	}
+	return l
+}
+
+// Duration returns MaxDuration, or the default if it is unset or invalid
+func (l LoopLimits) Duration() time.Duration {
+	d, err := time.ParseDuration(l.MaxDuration)
+	if err != nil || d <= 0 {
+		return DefaultMaxDuration
+	}
+	return d
+}
+
+// Price is what a model charges, in US dollars per million tokens
+type Price struct {
+	Input  float64 `yaml:"input"`  // Prompt tokens
+	Output float64 `yaml:"output"` // Completion tokens
+}
+
+// Cost returns the estimated cost in US dollars of the given token counts
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// PriceFor returns the configured price of model
+func (c *Config) PriceFor(model string) (Price, bool) {
+	price, ok := c.Pricing[model]
+	return price, ok
+}
+
+// MCPServerConfig declares an external MCP server whose tools are offered to the LLM
+// The server is started with Command and Args over stdio when a chat session starts.
+type MCPServerConfig struct {
+	Name    string            `yaml:"name"`
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"` // Values may reference environment variables as ${VAR}
+}
+
+// Environ returns the server's environment entries with ${VAR} references expanded, sorted by key
+func (m MCPServerConfig) Environ() []string {
+	keys := make([]string, 0, len(m.Env))
+	for k := range m.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, k+"="+os.ExpandEnv(m.Env[k]))
+	}
+	return env
 }
 
+// LLM API providers
+const (
+	ProviderOpenAI    = "openai"    // OpenAI-compatible chat/completions API (default)
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+)
+
 // LLMConfig represents LLM provider configuration
 type LLMConfig struct {
-	URL    string `yaml:"url"`
-	APIKey string `yaml:"api_key"`
-	Model  string `yaml:"model"`
+	Provider string `yaml:"provider,omitempty"` // API flavor: openai (default) or anthropic
+	URL      string `yaml:"url"`
+	APIKey   string `yaml:"api_key,omitempty"`
+	Model    string `yaml:"model"`
+
+	// Replies are streamed when the provider supports it; set to wait for complete replies instead
+	DisableStreaming bool `yaml:"disable_streaming,omitempty"`
+
+	// Retries of rate-limited requests and server or network errors; 0 uses the default, -1 disables retries
+	MaxRetries int `yaml:"max_retries,omitempty"`
+
+	// The API key can instead be read from the environment, a command, or the encrypted secrets file
+	APIKeyEnv    string `yaml:"api_key_env,omitempty"`
+	APIKeyCmd    string `yaml:"api_key_cmd,omitempty"`
+	APIKeySecret string `yaml:"api_key_secret,omitempty"`
 }
 
 // NewConfig creates a new empty configuration
@@ -21,5 +137,73 @@ func NewConfig() *Config {
 
 // IsEmpty checks if the configuration is empty (first run)
 func (c *Config) IsEmpty() bool {
-	return c.LLM.URL == "" || c.LLM.APIKey == "" || c.LLM.Model == ""
+	return c.LLM.URL == "" || !c.LLM.HasAPIKey() || c.LLM.Model == ""
+}
+
+// ProviderName returns the configured provider, defaulting to the OpenAI-compatible API
+func (l *LLMConfig) ProviderName() string {
+	if l.Provider == "" {
+		return ProviderOpenAI
+	}
+	return l.Provider
+}
+
+// Retries returns how often a failed LLM request is retried
+func (l *LLMConfig) Retries() int {
+	switch {
+	case l.MaxRetries == 0:
+		return DefaultMaxRetries
+	case l.MaxRetries < 0:
+		return 0
+	}
+	return l.MaxRetries
+}
+
+// HasAPIKey reports whether an API key or a reference to one is configured
+func (l *LLMConfig) HasAPIKey() bool {
+	return l.APIKey != "" || !l.apiKeyRef().IsZero()
+}
+
+func (l *LLMConfig) apiKeyRef() secret.Ref {
+	return secret.Ref{Env: l.APIKeyEnv, Cmd: l.APIKeyCmd, Name: l.APIKeySecret}
+}
+
+// ResolveAPIKey returns the API key, reading it from its env var, command or secrets file if needed
+// The resolved key is only held in memory and never written back to config.yaml
+func (l *LLMConfig) ResolveAPIKey() (string, error) {
+	key, err := secret.Resolve(l.APIKey, l.apiKeyRef(), OpenSecrets)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve LLM API key: %w", err)
+	}
+	return key, nil
+}
+
+// OpenSecrets opens the encrypted secrets file, prompting for the passphrase if needed
+func OpenSecrets() (*secret.Store, error) {
+	path, err := GetSecretsFilePath()
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err := GetSecretsKeyFilePath()
+	if err != nil {
+		return nil, err
+	}
+	return secret.Load(path, keyFile)
+}
+
+// APIKeySecretName is the secrets file entry used when the API key is migrated
+const APIKeySecretName = "llm/api_key"
+
+// MigrateAPIKey moves a plaintext API key into the secrets store; call Save afterwards
+// Returns false if there was no plaintext key to migrate
+func (c *Config) MigrateAPIKey(store *secret.Store) bool {
+	if c.LLM.APIKey == "" {
+		return false
+	}
+	store.Set(APIKeySecretName, c.LLM.APIKey)
+	c.LLM.APIKey = ""
+	c.LLM.APIKeyEnv = ""
+	c.LLM.APIKeyCmd = ""
+	c.LLM.APIKeySecret = APIKeySecretName
+	return true
 }
@@ -19,8 +19,10 @@ const (
 	BinSubdir      = "bin"
 
 	// Config files
-	ConfigFile  = "config.yaml"
-	SourcesFile = "sources.yaml"
+	ConfigFile     = "config.yaml"
+	SourcesFile    = "sources.yaml"
+	SecretsFile    = "secrets.enc"
+	SecretsKeyFile = "secrets.key"
 )
 
 // GetBaseConfigDir returns the base configuration directory path (~/.aiq)
@@ -104,6 +106,24 @@ func GetSourcesFilePath() (string, error) {
 	return filepath.Join(configDir, SourcesFile), nil
 }
 
+// GetSecretsFilePath returns the full path to the encrypted secrets file (~/.aiq/config/secrets.enc)
+func GetSecretsFilePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, SecretsFile), nil
+}
+
+// GetSecretsKeyFilePath returns the default key file that unlocks the secrets file (~/.aiq/config/secrets.key)
+func GetSecretsKeyFilePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, SecretsKeyFile), nil
+}
+
 // EnsureDirectoryStructure creates all required subdirectories if they don't exist
 func EnsureDirectoryStructure() error {
 	dirs := []struct {
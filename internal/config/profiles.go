@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultProfile names the LLM settings of the llm section
+const DefaultProfile = "default"
+
+// Tasks that can each use their own LLM profile
+const (
+	TaskChat        = "chat"        // Tool-calling loop and SQL generation
+	TaskSkills      = "skills"      // Skill matching
+	TaskCompression = "compression" // Conversation compression
+)
+
+// Tasks lists the tasks in display order
+var Tasks = []string{TaskChat, TaskSkills, TaskCompression}
+
+// TaskProfiles names the profile used for each task; empty uses the default profile
+type TaskProfiles struct {
+	Chat        string `yaml:"chat,omitempty"`
+	Skills      string `yaml:"skills,omitempty"`
+	Compression string `yaml:"compression,omitempty"`
+}
+
+// Profile returns the LLM settings of the named profile
+// A profile without a URL uses the provider, URL and API key of the llm section, so a profile can
+// be as short as a model name; a profile with its own URL needs its own API key. Profiles without
+// max_retries use the value of the llm section.
+func (c *Config) Profile(name string) (*LLMConfig, error) {
+	if name == "" || name == DefaultProfile {
+		return &c.LLM, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown LLM profile %q (available: %v)", name, c.ProfileNames())
+	}
+	if profile.URL == "" {
+		profile.Provider = c.LLM.Provider
+		profile.URL = c.LLM.URL
+		profile.APIKey = c.LLM.APIKey
+		profile.APIKeyEnv = c.LLM.APIKeyEnv
+		profile.APIKeyCmd = c.LLM.APIKeyCmd
+		profile.APIKeySecret = c.LLM.APIKeySecret
+	}
+	if profile.Model == "" {
+		profile.Model = c.LLM.Model
+	}
+	if profile.MaxRetries == 0 {
+		profile.MaxRetries = c.LLM.MaxRetries
+	}
+	return &profile, nil
+}
+
+// ProfileNames returns the default profile followed by the configured profiles in name order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles)+1)
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+// ProfileFor returns the name of the profile configured for task
+func (c *Config) ProfileFor(task string) string {
+	var name string
+	switch task {
+	case TaskChat:
+		name = c.Tasks.Chat
+	case TaskSkills:
+		name = c.Tasks.Skills
+	case TaskCompression:
+		name = c.Tasks.Compression
+	}
+	if name == "" {
+		return DefaultProfile
+	}
+	return name
+}
+
+// ValidateProfiles validates the LLM profiles and the profiles chosen for tasks
+func ValidateProfiles(config *Config) error {
+	if _, ok := config.Profiles[DefaultProfile]; ok {
+		return fmt.Errorf("%q is reserved for the llm section", DefaultProfile)
+	}
+	for _, name := range config.ProfileNames()[1:] {
+		profile, _ := config.Profile(name)
+		if err := ValidateLLMConfig(profile); err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	for _, task := range Tasks {
+		if _, err := config.Profile(config.ProfileFor(task)); err != nil {
+			return fmt.Errorf("task %s: %w", task, err)
+		}
+	}
+	return nil
+}
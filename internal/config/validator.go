@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // Validate validates the configuration
@@ -16,6 +17,59 @@ func Validate(config *Config) error {
 		return fmt.Errorf("LLM config validation failed: %w", err)
 	}
 
+	names := make(map[string]bool, len(config.MCPServers))
+	for _, server := range config.MCPServers {
+		if err := ValidateMCPServer(server); err != nil {
+			return fmt.Errorf("MCP server config validation failed: %w", err)
+		}
+		if names[server.Name] {
+			return fmt.Errorf("MCP server config validation failed: duplicate name %q", server.Name)
+		}
+		names[server.Name] = true
+	}
+
+	if err := ValidateLoopLimits(config.Limits); err != nil {
+		return fmt.Errorf("limits config validation failed: %w", err)
+	}
+
+	if err := ValidateProfiles(config); err != nil {
+		return fmt.Errorf("profiles config validation failed: %w", err)
+	}
+
+	for model, price := range config.Pricing {
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("pricing config validation failed: prices of %q must not be negative", model)
+		}
+	}
+
+	return nil
+}
+
+// ValidateLoopLimits validates the tool-calling loop limits
+func ValidateLoopLimits(limits LoopLimits) error {
+	if limits.MaxIterations < 0 || limits.MaxTokens < 0 || limits.MaxConsecutiveErrors < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if limits.MaxDuration != "" {
+		d, err := time.ParseDuration(limits.MaxDuration)
+		if err != nil {
+			return fmt.Errorf("invalid max_duration %q: %w", limits.MaxDuration, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("max_duration must be positive")
+		}
+	}
+	return nil
+}
+
+// ValidateMCPServer validates an external MCP server declaration
+func ValidateMCPServer(server MCPServerConfig) error {
+	if strings.TrimSpace(server.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(server.Command) == "" {
+		return fmt.Errorf("command is required for %q", server.Name)
+	}
 	return nil
 }
 
@@ -25,6 +79,13 @@ func ValidateLLMConfig(llm *LLMConfig) error {
 		return fmt.Errorf("LLM config is nil")
 	}
 
+	if err := validateProvider(llm.Provider); err != nil {
+		return err
+	}
+	if llm.MaxRetries < -1 {
+		return fmt.Errorf("max_retries must be -1 (no retries) or more")
+	}
+
 	// Validate URL
 	if llm.URL == "" {
 		return fmt.Errorf("LLM URL is required")
@@ -44,11 +105,11 @@ func ValidateLLMConfig(llm *LLMConfig) error {
 	}
 
 	// Validate API Key
-	if llm.APIKey == "" {
+	if !llm.HasAPIKey() {
 		return fmt.Errorf("LLM API key is required")
 	}
 
-	if strings.TrimSpace(llm.APIKey) == "" {
+	if llm.APIKey != "" && strings.TrimSpace(llm.APIKey) == "" {
 		return fmt.Errorf("LLM API key cannot be empty")
 	}
 
@@ -64,12 +125,28 @@ func ValidateLLMConfig(llm *LLMConfig) error {
 	return nil
 }
 
+// validateProvider checks that the LLM provider is supported; empty selects the default
+func validateProvider(provider string) error {
+	switch provider {
+	case "", ProviderOpenAI, ProviderAnthropic:
+		return nil
+	}
+	return fmt.Errorf("unknown LLM provider %q (use %s or %s)", provider, ProviderOpenAI, ProviderAnthropic)
+}
+
 // ValidatePartialLLMConfig validates LLM configuration allowing empty values
 func ValidatePartialLLMConfig(llm *LLMConfig) error {
 	if llm == nil {
 		return fmt.Errorf("LLM config is nil")
 	}
 
+	if err := validateProvider(llm.Provider); err != nil {
+		return err
+	}
+	if llm.MaxRetries < -1 {
+		return fmt.Errorf("max_retries must be -1 (no retries) or more")
+	}
+
 	// If URL is provided, validate it
 	if llm.URL != "" {
 		parsedURL, err := url.Parse(llm.URL)
@@ -1,11 +1,77 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
+	"github.com/aiq/aiq/internal/llm"
 	"github.com/aiq/aiq/internal/ui"
 )
 
+// ProviderMenuItems returns the menu entries for choosing an LLM API provider
+func ProviderMenuItems() []ui.MenuItem {
+	return []ui.MenuItem{
+		{Label: "openai    - OpenAI-compatible chat/completions API (OpenAI, DeepSeek, local servers, ...)", Value: ProviderOpenAI},
+		{Label: "anthropic - Anthropic Messages API", Value: ProviderAnthropic},
+	}
+}
+
+// ChooseModel asks for the model of an LLM configuration whose provider, URL and API key are set
+// When the provider's models endpoint is available its models are offered in a menu; otherwise,
+// or if the user picks "other", the name is typed in with defaultModel as the default.
+func ChooseModel(l *LLMConfig, defaultModel string) (string, error) {
+	if l.Model != "" {
+		defaultModel = l.Model
+	}
+
+	var models []string
+	if apiKey, err := l.ResolveAPIKey(); err == nil {
+		stopLoading := ui.ShowLoading("Fetching available models...")
+		models, err = llm.ListModels(context.Background(), l.ProviderName(), l.URL, apiKey)
+		stopLoading()
+		if err != nil {
+			ui.ShowWarning(fmt.Sprintf("Could not list models: %v", err))
+		}
+	}
+	if len(models) > 0 {
+		items := []ui.MenuItem{{Label: "other - Enter a model name", Value: ""}}
+		for _, model := range models {
+			label := model
+			if model == l.Model {
+				label += " (current)"
+			}
+			items = append(items, ui.MenuItem{Label: label, Value: model})
+		}
+		model, err := ui.ShowMenu("Select model", items)
+		if err != nil {
+			return "", err
+		}
+		if model != "" {
+			return model, nil
+		}
+	}
+
+	fmt.Println("Model Name:")
+	fmt.Println("  Enter the model name to use for SQL translation.")
+	fmt.Println("  Examples:")
+	fmt.Println("    - gpt-3.5-turbo")
+	fmt.Println("    - gpt-4")
+	fmt.Println("    - claude-3-opus")
+	fmt.Println("    - deepseek-chat")
+	fmt.Println()
+
+	model, err := ui.ShowInput("Enter Model Name", defaultModel)
+	if err != nil {
+		return "", err
+	}
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return "", fmt.Errorf("model name cannot be empty")
+	}
+	return model, nil
+}
+
 // RunWizard runs the first-run configuration wizard
 func RunWizard() (*Config, error) {
 	ui.ShowInfo("Welcome to AIQ! Let's set up your configuration.")
@@ -13,6 +79,14 @@ func RunWizard() (*Config, error) {
 
 	config := NewConfig()
 
+	// Get LLM API provider
+	provider, err := ui.ShowMenu("Select LLM API provider", ProviderMenuItems())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LLM provider: %w", err)
+	}
+	config.LLM.Provider = provider
+	fmt.Println()
+
 	// Get LLM URL with format hint
 	fmt.Println("LLM API URL Format:")
 	fmt.Println("  Enter the base URL of your LLM API endpoint.")
@@ -21,7 +95,7 @@ func RunWizard() (*Config, error) {
 	fmt.Println("    - https://api.anthropic.com/v1")
 	fmt.Println("    - https://api.example.com/v1")
 	fmt.Println()
-	fmt.Println("  Note: The '/chat/completions' path will be added automatically.")
+	fmt.Println("  Note: The '/chat/completions' path (or '/messages' for the anthropic provider) will be added automatically.")
 	fmt.Println()
 	
 	url, err := ui.ShowInput("Enter LLM API URL", "")
@@ -30,23 +104,6 @@ func RunWizard() (*Config, error) {
 	}
 	config.LLM.URL = url
 
-	// Get Model Name
-	fmt.Println()
-	fmt.Println("Model Name:")
-	fmt.Println("  Enter the model name to use for SQL translation.")
-	fmt.Println("  Examples:")
-	fmt.Println("    - gpt-3.5-turbo")
-	fmt.Println("    - gpt-4")
-	fmt.Println("    - claude-3-opus")
-	fmt.Println("    - deepseek-chat")
-	fmt.Println()
-	
-	model, err := ui.ShowInput("Enter Model Name", "gpt-3.5-turbo")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get model name: %w", err)
-	}
-	config.LLM.Model = model
-
 	// Get API Key
 	fmt.Println()
 	apiKey, err := ui.ShowPassword("Enter LLM API Key")
@@ -55,6 +112,14 @@ func RunWizard() (*Config, error) {
 	}
 	config.LLM.APIKey = apiKey
 
+	// Get Model Name, offering the models the provider lists
+	fmt.Println()
+	model, err := ChooseModel(&config.LLM, "gpt-3.5-turbo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model name: %w", err)
+	}
+	config.LLM.Model = model
+
 	// Validate configuration
 	if err := Validate(config); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -3,19 +3,47 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"regexp"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 )
 
 // Connection represents a database connection
 type Connection struct {
-	db *sql.DB
+	db     *sql.DB
+	dbType string
+	tunnel *sshTunnel
+
+	// errorPattern detects procedure errors returned as status rows; nil means DefaultProcedureErrorPattern
+	errorPattern *regexp.Regexp
+
+	// onReconnect is notified about automatic reconnects; reconnectDelays overrides DefaultReconnectDelays
+	onReconnect     func(ReconnectEvent)
+	reconnectDelays []time.Duration
+}
+
+var defaultProcedureErrorRe = regexp.MustCompile(DefaultProcedureErrorPattern)
+
+// ConnectOptions configures how connections are established
+type ConnectOptions struct {
+	// InitSQL statements run on every new physical connection (e.g. SET time_zone='+00:00')
+	InitSQL []string
+
+	// SSH, when set, tunnels all connections through a bastion host
+	SSH *SSHConfig
 }
 
 // NewConnection creates a new database connection
 func NewConnection(dsn string, dbType string) (*Connection, error) {
+	return NewConnectionWithOptions(dsn, dbType, ConnectOptions{})
+}
+
+// NewConnectionWithOptions creates a new database connection with the given options
+func NewConnectionWithOptions(dsn string, dbType string, opts ConnectOptions) (*Connection, error) {
 	driverName := "mysql"
 	if dbType == "postgresql" {
 		driverName = "postgres"
@@ -23,12 +51,41 @@ func NewConnection(dsn string, dbType string) (*Connection, error) {
 		// SeekDB might use MySQL driver or custom driver
 		driverName = "mysql"
 	}
-	
+
 	db, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	var tunnel *sshTunnel
+	if opts.SSH != nil || len(opts.InitSQL) > 0 {
+		// Reopen through a connector so the tunnel and init statements apply to every pooled connection
+		drv := db.Driver()
+		db.Close()
+
+		var connector driver.Connector
+		if opts.SSH != nil {
+			tunnel, err = newSSHTunnel(*opts.SSH)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open ssh tunnel: %w", err)
+			}
+			connector, err = newTunnelConnector(driverName, dsn, tunnel)
+		} else {
+			connector, err = newConnector(drv, dsn)
+		}
+		if err != nil {
+			if tunnel != nil {
+				tunnel.Close()
+			}
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+
+		if len(opts.InitSQL) > 0 {
+			connector = &initConnector{Connector: connector, initSQL: opts.InitSQL}
+		}
+		db = sql.OpenDB(connector)
+	}
+
 	// Set connection pool settings
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(5)
@@ -40,20 +97,64 @@ func NewConnection(dsn string, dbType string) (*Connection, error) {
 
 	if err := db.PingContext(ctx); err != nil {
 		db.Close()
+		if tunnel != nil {
+			tunnel.Close()
+		}
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Connection{db: db}, nil
+	return &Connection{db: db, dbType: dbType, tunnel: tunnel}, nil
+}
+
+// DatabaseType returns the database type the connection was opened with (mysql, postgresql, seekdb)
+func (c *Connection) DatabaseType() string {
+	return c.dbType
+}
+
+// Placeholder returns the bind parameter placeholder for the n-th (1-based) parameter
+func (c *Connection) Placeholder(n int) string {
+	if c.dbType == "postgresql" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
 }
 
 // Close closes the database connection
 func (c *Connection) Close() error {
+	var err error
 	if c.db != nil {
-		return c.db.Close()
+		err = c.db.Close()
+	}
+	if c.tunnel != nil {
+		if tunnelErr := c.tunnel.Close(); err == nil {
+			err = tunnelErr
+		}
+	}
+	return err
+}
+
+// SetProcedureErrorPattern sets the regular expression used to detect errors that stored
+// procedures return as a single status row. An empty pattern restores the default.
+func (c *Connection) SetProcedureErrorPattern(pattern string) error {
+	if pattern == "" {
+		c.errorPattern = nil
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid procedure error pattern: %w", err)
 	}
+	c.errorPattern = re
 	return nil
 }
 
+func (c *Connection) procedureErrorPattern() *regexp.Regexp {
+	if c.errorPattern != nil {
+		return c.errorPattern
+	}
+	return defaultProcedureErrorRe
+}
+
 // GetDB returns the underlying sql.DB instance
 func (c *Connection) GetDB() *sql.DB {
 	return c.db
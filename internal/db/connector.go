@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+)
+
+// initConnector wraps a driver connector and runs init statements on every new physical connection.
+// A SET issued through a pooled *sql.DB only affects one connection; this applies it to all of them.
+type initConnector struct {
+	driver.Connector
+	initSQL []string
+}
+
+// Connect opens a connection and runs the init statements on it
+func (c *initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range c.initSQL {
+		if err := execOnConn(ctx, conn, stmt); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("init_sql %q failed: %w", stmt, err)
+		}
+	}
+	return conn, nil
+}
+
+// dsnConnector adapts drivers that don't implement driver.DriverContext
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+// newConnector returns the driver's connector for dsn
+func newConnector(drv driver.Driver, dsn string) (driver.Connector, error) {
+	if dc, ok := drv.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return &dsnConnector{dsn: dsn, drv: drv}, nil
+}
+
+// execOnConn executes a statement without arguments directly on a driver connection
+func execOnConn(ctx context.Context, conn driver.Conn, stmt string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, stmt, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	prepared, err := conn.Prepare(stmt)
+	if err != nil {
+		return err
+	}
+	defer prepared.Close()
+
+	if execer, ok := prepared.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = prepared.Exec(nil)
+	return err
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recordingDriver is a minimal driver that records executed statements per connection
+type recordingDriver struct {
+	mu     sync.Mutex
+	execs  [][]string
+	failOn string
+	opened int
+}
+
+type recordingConn struct {
+	drv   *recordingDriver
+	index int
+}
+
+func (d *recordingDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.execs = append(d.execs, nil)
+	d.opened++
+	return &recordingConn{drv: d, index: len(d.execs) - 1}, nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.drv.mu.Lock()
+	defer c.drv.mu.Unlock()
+	if query == c.drv.failOn {
+		return nil, errors.New("unknown system variable")
+	}
+	c.drv.execs[c.index] = append(c.drv.execs[c.index], query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+// TestInitConnector tests that init statements run on every new physical connection
+func TestInitConnector(t *testing.T) {
+	t.Run("runs init statements on each connection", func(t *testing.T) {
+		drv := &recordingDriver{}
+		initSQL := []string{"SET time_zone='+00:00'", "SET sql_mode='ANSI'"}
+		connector, err := newConnector(drv, "dsn")
+		if err != nil {
+			t.Fatalf("newConnector failed: %v", err)
+		}
+		sqlDB := sql.OpenDB(&initConnector{Connector: connector, initSQL: initSQL})
+		defer sqlDB.Close()
+
+		ctx := context.Background()
+		conn1, err := sqlDB.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn failed: %v", err)
+		}
+		conn2, err := sqlDB.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn failed: %v", err)
+		}
+		conn1.Close()
+		conn2.Close()
+
+		if drv.opened != 2 {
+			t.Fatalf("Expected 2 physical connections, got %d", drv.opened)
+		}
+		for i, execs := range drv.execs {
+			if len(execs) != len(initSQL) || execs[0] != initSQL[0] || execs[1] != initSQL[1] {
+				t.Errorf("Connection %d: expected init statements %v, got %v", i, initSQL, execs)
+			}
+		}
+	})
+
+	t.Run("fails connection when an init statement fails", func(t *testing.T) {
+		drv := &recordingDriver{failOn: "SET bogus=1"}
+		connector, _ := newConnector(drv, "dsn")
+		sqlDB := sql.OpenDB(&initConnector{Connector: connector, initSQL: []string{"SET bogus=1"}})
+		defer sqlDB.Close()
+
+		err := sqlDB.PingContext(context.Background())
+		if err == nil {
+			t.Fatal("Expected ping to fail when init_sql fails")
+		}
+	})
+}
@@ -2,39 +2,259 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 )
 
+// DefaultProcedureErrorPattern matches MySQL-style error messages that stored procedures
+// return as a status row, e.g. "ERROR 11114 (HY000): The param 'provider' is empty or null"
+const DefaultProcedureErrorPattern = `^ERROR \d+ \([0-9A-Z]{5}\): `
+
+// DefaultQueryTimeout limits how long a single statement may run unless overridden with WithQueryTimeout
+const DefaultQueryTimeout = 30 * time.Second
+
+var dollarPlaceholderRe = regexp.MustCompile(`\$\d+`)
+
+// dollarQuoteRe matches the opening tag of a PostgreSQL dollar-quoted string ($$ or $tag$)
+var dollarQuoteRe = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout returns a context whose statements run with the given timeout instead of
+// DefaultQueryTimeout; a timeout <= 0 disables the limit (cancel the context to stop the statement)
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+// withStatementTimeout applies the statement timeout configured on ctx
+func withStatementTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := DefaultQueryTimeout
+	if t, ok := ctx.Value(queryTimeoutKey{}).(time.Duration); ok {
+		timeout = t
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ResultSet represents a single result set returned by a statement
+type ResultSet struct {
+	Columns      []string
+	Rows         [][]string
+	RowsAffected int64 // Rows changed by a statement without a result set, -1 if unknown
+}
+
 // QueryResult represents a query result
+// Columns and Rows mirror the first result set that has columns; ResultSets holds all of them in order,
+// with one entry per data-modifying statement of a batch
 type QueryResult struct {
-	Columns []string
-	Rows    [][]string
+	Columns      []string
+	Rows         [][]string
+	ResultSets   []ResultSet
+	RowsAffected int64 // Total rows changed by data-modifying statements, -1 if unknown
+}
+
+// ProcedureError is returned when a result set carries an error message raised by a stored procedure
+type ProcedureError struct {
+	Message string
+}
+
+func (e *ProcedureError) Error() string {
+	return e.Message
+}
+
+// ExecuteQuery executes a SQL query and returns all of its result sets
+// args are bound to the query's placeholders by the driver and never interpolated into the SQL text
+// A batch without args that contains data-modifying statements runs one statement at a time, so each
+// statement reports its own affected-row count
+// If the connection was lost, it reconnects and re-runs read-only queries once; other statements
+// return a *ConnectionLostError
+func (c *Connection) ExecuteQuery(ctx context.Context, sqlQuery string, args ...interface{}) (*QueryResult, error) {
+	var result *QueryResult
+	err := c.withReconnect(ctx, isIdempotentRead(sqlQuery), func() error {
+		var err error
+		result, err = c.executeQuery(ctx, sqlQuery, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-// ExecuteQuery executes a SQL query and returns the results
-func (c *Connection) ExecuteQuery(ctx context.Context, sqlQuery string) (*QueryResult, error) {
+// executeQuery runs a query once without reconnect handling
+func (c *Connection) executeQuery(ctx context.Context, sqlQuery string, args ...interface{}) (*QueryResult, error) {
 	// Set timeout
-	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	queryCtx, cancel := withStatementTimeout(ctx)
 	defer cancel()
 
-	rows, err := c.db.QueryContext(queryCtx, sqlQuery)
+	if len(args) > 0 && c.dbType == "postgresql" {
+		sqlQuery = rebindPlaceholders(sqlQuery)
+	}
+
+	if len(args) == 0 {
+		if statements := c.splitStatements(sqlQuery); len(statements) > 1 && hasRowlessStatement(statements) {
+			return c.executeBatch(queryCtx, statements)
+		}
+	}
+
+	// Data-modifying statements don't return rows; execute them to get the affected-row count
+	if isRowlessStatement(sqlQuery) {
+		res, err := c.db.ExecContext(queryCtx, sqlQuery, args...)
+		if err != nil {
+			return nil, fmt.Errorf("query execution failed: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			affected = -1
+		}
+		return &QueryResult{
+			Rows:         make([][]string, 0),
+			ResultSets:   []ResultSet{{Rows: make([][]string, 0), RowsAffected: affected}},
+			RowsAffected: affected,
+		}, nil
+	}
+
+	rows, err := c.db.QueryContext(queryCtx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer rows.Close()
+
+	sets, err := c.readResultSets(rows)
+	if err != nil {
+		return nil, err
+	}
+	return newQueryResult(sets), nil
+}
+
+// statementRunner is implemented by *sql.Conn and *sql.Tx
+type statementRunner interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// executeBatch runs statements one at a time on a single connection
+// On PostgreSQL the batch runs in a transaction, as a multi-statement query would, unless it
+// contains its own transaction control statements
+func (c *Connection) executeBatch(ctx context.Context, statements []string) (*QueryResult, error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer conn.Close()
+
+	var runner statementRunner = conn
+	var tx *sql.Tx
+	if c.dbType == "postgresql" && !hasTransactionControl(statements) {
+		tx, err = conn.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("query execution failed: %w", err)
+		}
+		defer tx.Rollback()
+		runner = tx
+	}
+
+	var sets []ResultSet
+	for i, statement := range statements {
+		statementSets, err := c.runStatement(ctx, runner, statement)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d of %d: %w", i+1, len(statements), err)
+		}
+		sets = append(sets, statementSets...)
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("query execution failed: %w", err)
+		}
+	}
+	return newQueryResult(sets), nil
+}
+
+// runStatement runs a single statement of a batch and returns its result sets
+func (c *Connection) runStatement(ctx context.Context, runner statementRunner, statement string) ([]ResultSet, error) {
+	if isRowlessStatement(statement) {
+		res, err := runner.ExecContext(ctx, statement)
+		if err != nil {
+			return nil, fmt.Errorf("query execution failed: %w", err)
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			affected = -1
+		}
+		return []ResultSet{{Rows: make([][]string, 0), RowsAffected: affected}}, nil
+	}
+
+	rows, err := runner.QueryContext(ctx, statement)
 	if err != nil {
 		return nil, fmt.Errorf("query execution failed: %w", err)
 	}
 	defer rows.Close()
+	return c.readResultSets(rows)
+}
+
+// readResultSets reads every result set from rows
+func (c *Connection) readResultSets(rows *sql.Rows) ([]ResultSet, error) {
+	var sets []ResultSet
+	for {
+		set, err := c.readResultSet(rows)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, *set)
 
+		// Errors raised by a procedure after earlier result sets surface through NextResultSet/Err
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return sets, nil
+}
+
+// newQueryResult builds a query result from sets
+// Columns and Rows mirror the first set with columns; RowsAffected sums the known per-statement counts
+func newQueryResult(sets []ResultSet) *QueryResult {
+	result := &QueryResult{
+		Rows:         make([][]string, 0),
+		ResultSets:   sets,
+		RowsAffected: -1,
+	}
+	for _, set := range sets {
+		if len(set.Columns) > 0 && result.Columns == nil {
+			result.Columns = set.Columns
+			result.Rows = set.Rows
+		}
+		if set.RowsAffected >= 0 {
+			if result.RowsAffected < 0 {
+				result.RowsAffected = 0
+			}
+			result.RowsAffected += set.RowsAffected
+		}
+	}
+	return result
+}
+
+// readResultSet reads the current result set from rows
+func (c *Connection) readResultSet(rows *sql.Rows) (*ResultSet, error) {
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
 
-	// Read rows
-	result := &QueryResult{
-		Columns: columns,
-		Rows:    make([][]string, 0),
+	set := &ResultSet{
+		Columns:      columns,
+		Rows:         make([][]string, 0),
+		RowsAffected: -1,
 	}
 
 	for rows.Next() {
@@ -52,69 +272,266 @@ func (c *Connection) ExecuteQuery(ctx context.Context, sqlQuery string) (*QueryR
 		// Convert values to strings
 		row := make([]string, len(columns))
 		for i, val := range values {
-			if val == nil {
-				row[i] = "NULL"
-			} else {
-				// Handle different types properly
-				switch v := val.(type) {
-				case []byte:
-					// Convert byte slice to string
-					row[i] = string(v)
-				case string:
-					row[i] = v
-				case int64:
-					row[i] = fmt.Sprintf("%d", v)
-				case float64:
-					row[i] = fmt.Sprintf("%g", v)
-				case bool:
-					if v {
-						row[i] = "true"
-					} else {
-						row[i] = "false"
+			row[i] = formatValue(val)
+		}
+
+		set.Rows = append(set.Rows, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	// Some procedures report errors as a single status row instead of raising a SQL error
+	// (e.g. SELECT 'ERROR 11114 (HY000): ...'); only such status rows are checked, not regular data
+	if len(set.Rows) == 1 && len(set.Rows[0]) == 1 {
+		if cell := set.Rows[0][0]; c.procedureErrorPattern().MatchString(cell) {
+			return nil, fmt.Errorf("query execution failed: %w", &ProcedureError{Message: cell})
+		}
+	}
+
+	return set, nil
+}
+
+// formatValue converts a scanned column value to its display string
+func formatValue(val interface{}) string {
+	if val == nil {
+		return "NULL"
+	}
+
+	// Handle different types properly
+	switch v := val.(type) {
+	case []byte:
+		// Convert byte slice to string
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return fmt.Sprintf("%d", v)
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	default:
+		// Fallback to string representation
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// isRowlessStatement reports whether sqlQuery is a data-modifying statement that returns no rows
+func isRowlessStatement(sqlQuery string) bool {
+	fields := strings.Fields(stripLeadingComments(sqlQuery))
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE":
+	default:
+		return false
+	}
+
+	// RETURNING (PostgreSQL) and OUTPUT (SQL Server) make DML return rows
+	for _, f := range fields[1:] {
+		switch strings.ToUpper(strings.TrimRight(f, ";,")) {
+		case "RETURNING", "OUTPUT":
+			return false
+		}
+	}
+	return true
+}
+
+// hasRowlessStatement reports whether any of statements is a data-modifying statement without a result set
+func hasRowlessStatement(statements []string) bool {
+	for _, statement := range statements {
+		if isRowlessStatement(statement) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTransactionControl reports whether any of statements begins or ends a transaction
+func hasTransactionControl(statements []string) bool {
+	for _, statement := range statements {
+		fields := strings.Fields(stripLeadingComments(statement))
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(strings.TrimRight(fields[0], ";")) {
+		case "BEGIN", "START", "COMMIT", "END", "ROLLBACK", "SAVEPOINT", "RELEASE":
+			return true
+		}
+	}
+	return false
+}
+
+// splitStatements splits sqlQuery at semicolons outside quotes and comments and drops empty statements
+// MySQL strings use backslash escapes and # starts a comment; PostgreSQL has dollar-quoted strings.
+func (c *Connection) splitStatements(sqlQuery string) []string {
+	mysqlSyntax := c.dbType != "postgresql"
+
+	var statements []string
+	start := 0
+	add := func(end int) {
+		if statement := strings.TrimSpace(sqlQuery[start:end]); statement != "" {
+			statements = append(statements, statement)
+		}
+		start = end + 1
+	}
+
+	for i := 0; i < len(sqlQuery); i++ {
+		ch := sqlQuery[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			for i++; i < len(sqlQuery); i++ {
+				if mysqlSyntax && sqlQuery[i] == '\\' {
+					i++
+					continue
+				}
+				if sqlQuery[i] == ch {
+					// Doubled quote is an escaped quote inside the literal
+					if i+1 < len(sqlQuery) && sqlQuery[i+1] == ch {
+						i++
+						continue
 					}
-				case time.Time:
-					row[i] = v.Format("2006-01-02 15:04:05")
-				default:
-					// Fallback to string representation
-					row[i] = fmt.Sprintf("%v", val)
+					break
 				}
 			}
-		}
-
-		// Check if row contains error information (for CALL statements and stored procedures)
-		// MySQL may return error messages in result sets, especially for stored procedures
-		// MySQL error format: "ERROR <code> (<SQLSTATE>): <message>"
-		// Example: "ERROR 11114 (HY000): The param 'provider' is empty or null"
-		for _, cell := range row {
-			if cell != "" {
-				cellUpper := strings.ToUpper(cell)
-				// Check for MySQL error format: ERROR followed by number and SQLSTATE
-				if strings.HasPrefix(cellUpper, "ERROR") {
-					// Check if it matches MySQL error pattern: ERROR <number> (<SQLSTATE>)
-					// This is a MySQL error message returned in result set
-					return nil, fmt.Errorf("query execution failed: %s", cell)
+		case ch == '-' && i+1 < len(sqlQuery) && sqlQuery[i+1] == '-', mysqlSyntax && ch == '#':
+			end := strings.IndexByte(sqlQuery[i:], '\n')
+			if end < 0 {
+				i = len(sqlQuery)
+			} else {
+				i += end
+			}
+		case ch == '/' && i+1 < len(sqlQuery) && sqlQuery[i+1] == '*':
+			end := strings.Index(sqlQuery[i+2:], "*/")
+			if end < 0 {
+				i = len(sqlQuery)
+			} else {
+				i += 2 + end + 1
+			}
+		case !mysqlSyntax && ch == '$':
+			if tag := dollarQuoteRe.FindString(sqlQuery[i:]); tag != "" {
+				end := strings.Index(sqlQuery[i+len(tag):], tag)
+				if end < 0 {
+					i = len(sqlQuery)
+				} else {
+					i += len(tag) + end + len(tag) - 1
 				}
 			}
+		case ch == ';':
+			add(i)
 		}
+	}
+	if start < len(sqlQuery) {
+		add(len(sqlQuery))
+	}
+	return statements
+}
 
-		result.Rows = append(result.Rows, row)
+// stripLeadingComments removes whitespace and leading -- or /* */ comments from sqlQuery
+func stripLeadingComments(sqlQuery string) string {
+	s := strings.TrimSpace(sqlQuery)
+	for {
+		switch {
+		case strings.HasPrefix(s, "--") || strings.HasPrefix(s, "#"):
+			idx := strings.Index(s, "\n")
+			if idx < 0 {
+				return ""
+			}
+			s = strings.TrimSpace(s[idx+1:])
+		case strings.HasPrefix(s, "/*"):
+			idx := strings.Index(s, "*/")
+			if idx < 0 {
+				return ""
+			}
+			s = strings.TrimSpace(s[idx+2:])
+		default:
+			return s
+		}
 	}
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+// rebindPlaceholders converts ? placeholders to PostgreSQL's $1, $2, ... form.
+// Queries that already use $n placeholders are returned unchanged; ? inside quotes and comments is kept.
+func rebindPlaceholders(sqlQuery string) string {
+	if !strings.Contains(sqlQuery, "?") || dollarPlaceholderRe.MatchString(sqlQuery) {
+		return sqlQuery
 	}
 
-	return result, nil
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(sqlQuery); i++ {
+		ch := sqlQuery[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := i + 1
+			for end < len(sqlQuery) {
+				if sqlQuery[end] == ch {
+					// Doubled quote is an escaped quote inside the literal
+					if end+1 < len(sqlQuery) && sqlQuery[end+1] == ch {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(sqlQuery) {
+				end = len(sqlQuery) - 1
+			}
+			b.WriteString(sqlQuery[i : end+1])
+			i = end
+		case ch == '-' && i+1 < len(sqlQuery) && sqlQuery[i+1] == '-':
+			end := strings.IndexByte(sqlQuery[i:], '\n')
+			if end < 0 {
+				b.WriteString(sqlQuery[i:])
+				return b.String()
+			}
+			b.WriteString(sqlQuery[i : i+end])
+			i += end - 1
+		case ch == '/' && i+1 < len(sqlQuery) && sqlQuery[i+1] == '*':
+			end := strings.Index(sqlQuery[i+2:], "*/")
+			if end < 0 {
+				b.WriteString(sqlQuery[i:])
+				return b.String()
+			}
+			b.WriteString(sqlQuery[i : i+2+end+2])
+			i += 2 + end + 1
+		case ch == '?':
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
 }
 
 // ExecuteNonQuery executes a non-query SQL statement (INSERT, UPDATE, DELETE, etc.)
+// A lost connection is re-established, but the statement is never re-run
 func (c *Connection) ExecuteNonQuery(ctx context.Context, sqlQuery string) (int64, error) {
-	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	queryCtx, cancel := withStatementTimeout(ctx)
 	defer cancel()
 
-	result, err := c.db.ExecContext(queryCtx, sqlQuery)
+	var result sql.Result
+	err := c.withReconnect(ctx, false, func() error {
+		var err error
+		result, err = c.db.ExecContext(queryCtx, sqlQuery)
+		if err != nil {
+			return fmt.Errorf("query execution failed: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return 0, fmt.Errorf("query execution failed: %w", err)
+		return 0, err
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -127,7 +544,12 @@ func (c *Connection) ExecuteNonQuery(ctx context.Context, sqlQuery string) (int6
 
 // TestConnection tests the database connection
 func TestConnection(dsn string, dbType string) error {
-	conn, err := NewConnection(dsn, dbType)
+	return TestConnectionWithOptions(dsn, dbType, ConnectOptions{})
+}
+
+// TestConnectionWithOptions tests the database connection with the given options (init statements must succeed)
+func TestConnectionWithOptions(dsn string, dbType string, opts ConnectOptions) error {
+	conn, err := NewConnectionWithOptions(dsn, dbType, opts)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestIsRowlessStatement tests detection of data-modifying statements executed for affected-row counts
+func TestIsRowlessStatement(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected bool
+	}{
+		{"INSERT INTO t (a) VALUES (1)", true},
+		{"  update t set a = 1", true},
+		{"-- remove old rows\nDELETE FROM t WHERE a < 0", true},
+		{"/* hint */ REPLACE INTO t VALUES (1)", true},
+		{"INSERT INTO t (a) VALUES (1) RETURNING id", false},
+		{"SELECT * FROM t", false},
+		{"CALL refresh_stats()", false},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isRowlessStatement(tt.sql); got != tt.expected {
+			t.Errorf("isRowlessStatement(%q) = %v, expected %v", tt.sql, got, tt.expected)
+		}
+	}
+}
+
+// TestProcedureErrorPattern tests the default and configured procedure error patterns
+func TestProcedureErrorPattern(t *testing.T) {
+	conn := &Connection{}
+
+	t.Run("default pattern matches MySQL status errors only", func(t *testing.T) {
+		re := conn.procedureErrorPattern()
+		if !re.MatchString("ERROR 11114 (HY000): The param 'provider' is empty or null") {
+			t.Error("Expected default pattern to match MySQL error status row")
+		}
+		if re.MatchString("Error while syncing orders") {
+			t.Error("Expected default pattern not to match regular data starting with 'Error'")
+		}
+	})
+
+	t.Run("custom pattern", func(t *testing.T) {
+		if err := conn.SetProcedureErrorPattern(`^FAILED:`); err != nil {
+			t.Fatalf("SetProcedureErrorPattern failed: %v", err)
+		}
+		if !conn.procedureErrorPattern().MatchString("FAILED: missing input") {
+			t.Error("Expected custom pattern to match")
+		}
+		if err := conn.SetProcedureErrorPattern(""); err != nil {
+			t.Fatalf("SetProcedureErrorPattern failed: %v", err)
+		}
+		if conn.procedureErrorPattern() != defaultProcedureErrorRe {
+			t.Error("Expected empty pattern to restore the default")
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		if err := conn.SetProcedureErrorPattern(`(`); err == nil {
+			t.Error("Expected error for invalid pattern")
+		}
+	})
+}
+
+// TestRebindPlaceholders tests conversion of ? placeholders to PostgreSQL $n placeholders
+func TestRebindPlaceholders(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected string
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b = ?", "SELECT * FROM t WHERE a = $1 AND b = $2"},
+		{"SELECT '?' , a FROM t WHERE b = ?", "SELECT '?' , a FROM t WHERE b = $1"},
+		{"SELECT 'it''s ?' FROM t WHERE b = ?", "SELECT 'it''s ?' FROM t WHERE b = $1"},
+		{"SELECT a -- why?\nFROM t WHERE b = ?", "SELECT a -- why?\nFROM t WHERE b = $1"},
+		{"SELECT /* ? */ a FROM t WHERE b = ?", "SELECT /* ? */ a FROM t WHERE b = $1"},
+		{"SELECT * FROM t WHERE a = $1", "SELECT * FROM t WHERE a = $1"},
+		{"SELECT 1", "SELECT 1"},
+	}
+
+	for _, tt := range tests {
+		if got := rebindPlaceholders(tt.sql); got != tt.expected {
+			t.Errorf("rebindPlaceholders(%q) = %q, expected %q", tt.sql, got, tt.expected)
+		}
+	}
+}
+
+// TestWithQueryTimeout tests overriding the statement timeout through the context
+func TestWithQueryTimeout(t *testing.T) {
+	t.Run("default timeout", func(t *testing.T) {
+		ctx, cancel := withStatementTimeout(context.Background())
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) > DefaultQueryTimeout {
+			t.Errorf("Expected a deadline within %v, got %v", DefaultQueryTimeout, deadline)
+		}
+	})
+
+	t.Run("custom timeout", func(t *testing.T) {
+		ctx, cancel := withStatementTimeout(WithQueryTimeout(context.Background(), time.Hour))
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) <= DefaultQueryTimeout {
+			t.Errorf("Expected a deadline about an hour away, got %v", deadline)
+		}
+	})
+
+	t.Run("no timeout", func(t *testing.T) {
+		ctx, cancel := withStatementTimeout(WithQueryTimeout(context.Background(), 0))
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("Expected no deadline")
+		}
+		cancel()
+		if ctx.Err() == nil {
+			t.Error("Expected the context to be cancellable")
+		}
+	})
+}
+
+// TestSplitStatements tests splitting batches at semicolons outside quotes and comments
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		dbType   string
+		sql      string
+		expected []string
+	}{
+		{"mysql", "UPDATE t SET a = 1", []string{"UPDATE t SET a = 1"}},
+		{"mysql", "UPDATE t SET a = 1; DELETE FROM t;", []string{"UPDATE t SET a = 1", "DELETE FROM t"}},
+		{"mysql", "INSERT INTO t VALUES ('a;b'); SELECT 1", []string{"INSERT INTO t VALUES ('a;b')", "SELECT 1"}},
+		{"mysql", `INSERT INTO t VALUES ('it\'s;'); SELECT 1`, []string{`INSERT INTO t VALUES ('it\'s;')`, "SELECT 1"}},
+		{"mysql", "DELETE FROM t -- old; rows\n; # done;\nSELECT 1", []string{"DELETE FROM t -- old; rows", "# done;\nSELECT 1"}},
+		{"mysql", "UPDATE t /* a; b */ SET a = 1", []string{"UPDATE t /* a; b */ SET a = 1"}},
+		{"postgresql", `INSERT INTO t VALUES ('C:\'); SELECT 1`, []string{`INSERT INTO t VALUES ('C:\')`, "SELECT 1"}},
+		{"postgresql", "DO $body$ BEGIN DELETE FROM t; END $body$; SELECT 1", []string{"DO $body$ BEGIN DELETE FROM t; END $body$", "SELECT 1"}},
+		{"postgresql", "UPDATE t SET a = $1; SELECT 1", []string{"UPDATE t SET a = $1", "SELECT 1"}},
+		{"mysql", " ; ", nil},
+	}
+
+	for _, tt := range tests {
+		conn := &Connection{dbType: tt.dbType}
+		got := conn.splitStatements(tt.sql)
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.expected) {
+			t.Errorf("splitStatements(%q) = %q, expected %q", tt.sql, got, tt.expected)
+		}
+	}
+}
+
+// TestExecuteQuery_Batch tests that a batch reports each statement's result separately
+func TestExecuteQuery_Batch(t *testing.T) {
+	drv := &flakyDriver{}
+	conn, _ := newFlakyConnection(drv)
+	defer conn.Close()
+
+	result, err := conn.ExecuteQuery(context.Background(), "UPDATE t SET a = 1; SELECT n FROM t; DELETE FROM t")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(drv.statements) != 3 {
+		t.Errorf("Expected each statement to run separately, got %q", drv.statements)
+	}
+	if len(result.ResultSets) != 3 {
+		t.Fatalf("Expected 3 result sets, got %d", len(result.ResultSets))
+	}
+	for i, expected := range []int64{1, -1, 1} {
+		if got := result.ResultSets[i].RowsAffected; got != expected {
+			t.Errorf("Expected rows affected %d for statement %d, got %d", expected, i+1, got)
+		}
+	}
+	if result.RowsAffected != 2 {
+		t.Errorf("Expected 2 rows affected in total, got %d", result.RowsAffected)
+	}
+	if len(result.Columns) != 1 || len(result.Rows) != 1 {
+		t.Errorf("Expected Columns and Rows to mirror the SELECT, got %v %v", result.Columns, result.Rows)
+	}
+}
+
+// TestNewQueryResult tests that the legacy fields mirror the first set with columns
+func TestNewQueryResult(t *testing.T) {
+	result := newQueryResult([]ResultSet{
+		{Rows: [][]string{}, RowsAffected: 3},
+		{Columns: []string{"a"}, Rows: [][]string{{"1"}}, RowsAffected: -1},
+		{Columns: []string{"b"}, Rows: [][]string{{"2"}}, RowsAffected: -1},
+	})
+	if len(result.Columns) != 1 || result.Columns[0] != "a" || result.Rows[0][0] != "1" {
+		t.Errorf("Expected the first set with columns, got %v %v", result.Columns, result.Rows)
+	}
+	if result.RowsAffected != 3 {
+		t.Errorf("Expected 3 rows affected, got %d", result.RowsAffected)
+	}
+
+	if empty := newQueryResult([]ResultSet{{Columns: []string{"a"}, Rows: [][]string{}, RowsAffected: -1}}); empty.RowsAffected != -1 {
+		t.Errorf("Expected unknown rows affected, got %d", empty.RowsAffected)
+	}
+}
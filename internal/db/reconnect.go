@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// DefaultReconnectDelays are the waits between reconnect attempts after a connection is lost
+var DefaultReconnectDelays = []time.Duration{
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// driverLogFilter forwards MySQL driver log lines except broken-connection errors
+type driverLogFilter struct {
+	next mysql.Logger
+}
+
+// Print implements mysql.Logger
+func (f driverLogFilter) Print(v ...interface{}) {
+	for _, arg := range v {
+		if err, ok := arg.(error); ok && IsConnectionError(err) {
+			return
+		}
+	}
+	f.next.Print(v...)
+}
+
+// FilterDriverLogs stops the MySQL driver from logging broken connections to stderr before returning
+// the error; they are handled and reported by the reconnect logic instead. Other driver messages are
+// still logged. The driver's logger is process-wide, so the CLI calls this once at startup.
+func FilterDriverLogs() {
+	mysql.SetLogger(driverLogFilter{next: log.New(os.Stderr, "[mysql] ", log.Ldate|log.Ltime|log.Lshortfile)})
+}
+
+// ReconnectEvent describes progress of an automatic reconnect
+type ReconnectEvent struct {
+	Attempt int           // 1-based attempt number; 0 when the connection loss is first detected
+	Err     error         // Connection error that triggered the reconnect, or the last attempt's error
+	Delay   time.Duration // Wait before this attempt
+	Done    bool          // Reconnected successfully
+	Failed  bool          // Gave up after the last attempt
+}
+
+// ConnectionLostError is returned when the connection dropped while running a statement.
+// If Reconnected is true the connection was re-established, but the statement was not retried
+// because it may have modified data.
+type ConnectionLostError struct {
+	Err         error
+	Reconnected bool
+}
+
+func (e *ConnectionLostError) Error() string {
+	if e.Reconnected {
+		return fmt.Sprintf("connection lost and re-established; statement was not retried: %v", e.Err)
+	}
+	return fmt.Sprintf("connection lost and could not be re-established: %v", e.Err)
+}
+
+func (e *ConnectionLostError) Unwrap() error {
+	return e.Err
+}
+
+// SetReconnectHandler sets a function that is notified about automatic reconnects
+func (c *Connection) SetReconnectHandler(handler func(ReconnectEvent)) {
+	c.onReconnect = handler
+}
+
+// notifyReconnect reports a reconnect event to the handler, if any
+func (c *Connection) notifyReconnect(event ReconnectEvent) {
+	if c.onReconnect != nil {
+		c.onReconnect(event)
+	}
+}
+
+// reconnect waits for the database to accept connections again.
+// The pool discards broken connections, so a successful ping means a fresh physical connection
+// was opened (running the per-connection init statements through the connector).
+func (c *Connection) reconnect(ctx context.Context, cause error) error {
+	c.notifyReconnect(ReconnectEvent{Err: cause})
+
+	delays := c.reconnectDelays
+	if delays == nil {
+		delays = DefaultReconnectDelays
+	}
+
+	lastErr := cause
+	for i, delay := range delays {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := c.db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			c.notifyReconnect(ReconnectEvent{Attempt: i + 1, Delay: delay, Done: true})
+			return nil
+		}
+		lastErr = err
+		c.notifyReconnect(ReconnectEvent{Attempt: i + 1, Delay: delay, Err: err})
+	}
+
+	c.notifyReconnect(ReconnectEvent{Attempt: len(delays), Err: lastErr, Failed: true})
+	return lastErr
+}
+
+// withReconnect runs op, and if it fails with a connection error, reconnects and
+// retries it once when retry is true (the statement is an idempotent read)
+func (c *Connection) withReconnect(ctx context.Context, retry bool, op func() error) error {
+	err := op()
+	if err == nil || !IsConnectionError(err) || ctx.Err() != nil {
+		return err
+	}
+
+	if reconnectErr := c.reconnect(ctx, err); reconnectErr != nil {
+		return &ConnectionLostError{Err: err}
+	}
+	if !retry {
+		return &ConnectionLostError{Err: err, Reconnected: true}
+	}
+	return op()
+}
+
+// IsConnectionError reports whether err means the connection to the server is broken
+// (as opposed to an error in the statement itself)
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, net.ErrClosed) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1053, // ER_SERVER_SHUTDOWN
+			2006, // CR_SERVER_GONE_ERROR
+			2013, // CR_SERVER_LOST
+			4031: // ER_CLIENT_INTERACTION_TIMEOUT
+			return true
+		}
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 (connection exception), 57P01-57P03 (admin/crash shutdown, cannot connect now)
+		return pqErr.Code.Class() == "08" ||
+			pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	}
+
+	var netErr *net.OpError
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range []string{
+		"broken pipe",
+		"connection reset",
+		"server has gone away",
+		"lost connection to",
+		"bad connection",
+		"invalid connection",
+		"connection refused",
+		"use of closed network connection",
+	} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// sideEffectFunctionRe matches calls of built-in functions that change state even inside a SELECT:
+// sequences, advisory and named locks, sleeps, notifications, settings, backend signals and large objects
+var sideEffectFunctionRe = regexp.MustCompile(`(?i)\b(nextval|setval|get_lock|release_lock|release_all_locks|pg_(try_)?advisory_\w+|sleep|pg_sleep\w*|benchmark|pg_notify|set_config|pg_terminate_backend|pg_cancel_backend|lo_\w+|dblink_exec)\s*\(|\bNEXT\s+VALUE\s+FOR\b`)
+
+// isIdempotentRead reports whether sqlQuery only reads data and can safely be re-run
+// Reads calling a side-effecting built-in function are not; user-defined functions cannot be told apart.
+func isIdempotentRead(sqlQuery string) bool {
+	s := strings.ToUpper(stripLeadingComments(sqlQuery))
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "SELECT", "WITH", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "VALUES", "TABLE":
+	default:
+		return false
+	}
+
+	// SELECT ... INTO creates a table (PostgreSQL) or writes variables/files (MySQL);
+	// data-modifying CTEs and multi-statement batches are not re-run either
+	for _, f := range fields {
+		switch strings.Trim(f, ";,()") {
+		case "INTO", "INSERT", "UPDATE", "DELETE", "MERGE", "CREATE", "DROP", "ALTER", "TRUNCATE", "CALL", "ANALYZE":
+			return false
+		}
+	}
+	if idx := strings.Index(s, ";"); idx >= 0 && strings.TrimSpace(s[idx+1:]) != "" {
+		return false
+	}
+	return !sideEffectFunctionRe.MatchString(s)
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// flakyDriver is a driver whose statements fail with a broken connection a set number of times
+type flakyDriver struct {
+	mu         sync.Mutex
+	failures   int  // Remaining statements that fail with a connection error
+	pingFails  bool // Pings fail (the server stays unreachable)
+	statements []string
+}
+
+type flakyConn struct {
+	drv *flakyDriver
+}
+
+func (d *flakyDriver) Open(name string) (driver.Conn, error) {
+	return &flakyConn{drv: d}, nil
+}
+
+// run records a statement and returns the injected connection error, if any
+func (c *flakyConn) run(query string) error {
+	c.drv.mu.Lock()
+	defer c.drv.mu.Unlock()
+	c.drv.statements = append(c.drv.statements, query)
+	if c.drv.failures > 0 {
+		c.drv.failures--
+		return fmt.Errorf("write tcp 127.0.0.1:3306: %w", syscall.EPIPE)
+	}
+	return nil
+}
+
+func (c *flakyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.run(query); err != nil {
+		return nil, err
+	}
+	return &flakyRows{}, nil
+}
+
+func (c *flakyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.run(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *flakyConn) Ping(ctx context.Context) error {
+	c.drv.mu.Lock()
+	defer c.drv.mu.Unlock()
+	if c.drv.pingFails {
+		return errors.New("dial tcp 127.0.0.1:3306: connection refused")
+	}
+	return nil
+}
+
+func (c *flakyConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *flakyConn) Close() error              { return nil }
+func (c *flakyConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+// flakyRows is a single-row result with one column
+type flakyRows struct {
+	done bool
+}
+
+func (r *flakyRows) Columns() []string { return []string{"n"} }
+func (r *flakyRows) Close() error      { return nil }
+func (r *flakyRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+// newFlakyConnection returns a connection backed by drv that reconnects without waiting
+func newFlakyConnection(drv *flakyDriver) (*Connection, *[]ReconnectEvent) {
+	connector, _ := newConnector(drv, "dsn")
+	conn := &Connection{
+		db:              sql.OpenDB(connector),
+		dbType:          "mysql",
+		reconnectDelays: []time.Duration{time.Millisecond, time.Millisecond},
+	}
+	events := &[]ReconnectEvent{}
+	conn.SetReconnectHandler(func(e ReconnectEvent) {
+		*events = append(*events, e)
+	})
+	return conn, events
+}
+
+// TestConnection_Reconnect tests transparent reconnects after a lost connection
+func TestConnection_Reconnect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("retries read-only queries once", func(t *testing.T) {
+		drv := &flakyDriver{failures: 1}
+		conn, events := newFlakyConnection(drv)
+		defer conn.Close()
+
+		result, err := conn.ExecuteQuery(ctx, "SELECT 1")
+		if err != nil {
+			t.Fatalf("Expected query to succeed after reconnect, got %v", err)
+		}
+		if len(result.Rows) != 1 || result.Rows[0][0] != "1" {
+			t.Errorf("Expected one row with value 1, got %v", result.Rows)
+		}
+		if len(drv.statements) != 2 {
+			t.Errorf("Expected the query to run twice, got %d", len(drv.statements))
+		}
+		if len(*events) != 2 || !(*events)[1].Done {
+			t.Errorf("Expected a lost event followed by a done event, got %+v", *events)
+		}
+	})
+
+	t.Run("does not retry writes", func(t *testing.T) {
+		drv := &flakyDriver{failures: 1}
+		conn, _ := newFlakyConnection(drv)
+		defer conn.Close()
+
+		_, err := conn.ExecuteQuery(ctx, "UPDATE users SET active = 1")
+		var lostErr *ConnectionLostError
+		if !errors.As(err, &lostErr) {
+			t.Fatalf("Expected ConnectionLostError, got %v", err)
+		}
+		if !lostErr.Reconnected {
+			t.Error("Expected Reconnected to be true")
+		}
+		if len(drv.statements) != 1 {
+			t.Errorf("Expected the statement to run once, got %d", len(drv.statements))
+		}
+
+		if _, err := conn.ExecuteNonQuery(ctx, "DELETE FROM users"); err != nil {
+			t.Errorf("Expected the next statement to succeed, got %v", err)
+		}
+	})
+
+	t.Run("gives up when the server stays unreachable", func(t *testing.T) {
+		drv := &flakyDriver{failures: 1, pingFails: true}
+		conn, events := newFlakyConnection(drv)
+		defer conn.Close()
+
+		_, err := conn.ExecuteNonQuery(ctx, "INSERT INTO t VALUES (1)")
+		var lostErr *ConnectionLostError
+		if !errors.As(err, &lostErr) {
+			t.Fatalf("Expected ConnectionLostError, got %v", err)
+		}
+		if lostErr.Reconnected {
+			t.Error("Expected Reconnected to be false")
+		}
+		last := (*events)[len(*events)-1]
+		if !last.Failed {
+			t.Errorf("Expected the last event to report failure, got %+v", last)
+		}
+	})
+
+	t.Run("statement errors are returned unchanged", func(t *testing.T) {
+		drv := &flakyDriver{}
+		conn, events := newFlakyConnection(drv)
+		defer conn.Close()
+
+		stmtErr := &mysql.MySQLError{Number: 1146, Message: "Table 'db.missing' doesn't exist"}
+		err := conn.withReconnect(ctx, true, func() error { return stmtErr })
+		if err != stmtErr {
+			t.Errorf("Expected the statement error, got %v", err)
+		}
+		if len(*events) != 0 {
+			t.Errorf("Expected no reconnect events, got %+v", *events)
+		}
+	})
+}
+
+// TestIsConnectionError tests classification of connection-class errors
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"bad connection", driver.ErrBadConn, true},
+		{"mysql invalid connection", mysql.ErrInvalidConn, true},
+		{"broken pipe", fmt.Errorf("query execution failed: %w", syscall.EPIPE), true},
+		{"connection reset", syscall.ECONNRESET, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"server gone away", &mysql.MySQLError{Number: 2006, Message: "MySQL server has gone away"}, true},
+		{"server shutdown", &mysql.MySQLError{Number: 1053, Message: "Server shutdown in progress"}, true},
+		{"mysql syntax error", &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}, false},
+		{"postgres admin shutdown", &pq.Error{Code: "57P01"}, true},
+		{"postgres connection failure", &pq.Error{Code: "08006"}, true},
+		{"postgres undefined table", &pq.Error{Code: "42P01"}, false},
+		{"message pattern", errors.New("lost connection to MySQL server during query"), true},
+		{"canceled", context.Canceled, false},
+		{"deadline", fmt.Errorf("query execution failed: %w", context.DeadlineExceeded), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsConnectionError(tt.err); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// logRecorder is a mysql.Logger that records printed lines
+type logRecorder struct {
+	lines []string
+}
+
+func (r *logRecorder) Print(v ...interface{}) {
+	r.lines = append(r.lines, fmt.Sprint(v...))
+}
+
+// TestDriverLogFilter tests that only broken-connection errors are dropped from the MySQL driver log
+func TestDriverLogFilter(t *testing.T) {
+	recorder := &logRecorder{}
+	filter := driverLogFilter{next: recorder}
+
+	filter.Print(mysql.ErrInvalidConn)
+	filter.Print("closing bad idle connection: ", io.EOF)
+	filter.Print(fmt.Errorf("write tcp: %w", syscall.EPIPE))
+	filter.Print(mysql.ErrMalformPkt)
+	filter.Print("could not use requested auth plugin 'x': ", "unknown")
+
+	if len(recorder.lines) != 2 {
+		t.Fatalf("Expected 2 forwarded lines, got %v", recorder.lines)
+	}
+	if recorder.lines[0] != mysql.ErrMalformPkt.Error() {
+		t.Errorf("Expected malformed packet error to be forwarded, got %q", recorder.lines[0])
+	}
+}
+
+// TestIsIdempotentRead tests detection of statements that are safe to re-run
+func TestIsIdempotentRead(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT * FROM users", true},
+		{"  -- count\nselect count(*) from orders", true},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", true},
+		{"SHOW TABLES", true},
+		{"EXPLAIN SELECT 1", true},
+		{"SELECT 1;", true},
+		{"SELECT * INTO backup FROM users", false},
+		{"WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d", false},
+		{"SELECT 1; DROP TABLE users", false},
+		{"UPDATE users SET a = 1", false},
+		{"CALL refresh()", false},
+		{"SELECT nextval('orders_id_seq')", false},
+		{"SELECT setval('orders_id_seq', 100)", false},
+		{"SELECT NEXT VALUE FOR order_seq", false},
+		{"SELECT GET_LOCK('job', 10)", false},
+		{"SELECT release_lock('job')", false},
+		{"SELECT pg_advisory_lock(42)", false},
+		{"SELECT pg_try_advisory_xact_lock(42)", false},
+		{"SELECT SLEEP(5)", false},
+		{"SELECT pg_sleep (1)", false},
+		{"SELECT pg_notify('jobs', 'done')", false},
+		{"SELECT id, sleep_minutes FROM shifts", true},
+		{"SELECT lastval()", true},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			if got := isIdempotentRead(tt.sql); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
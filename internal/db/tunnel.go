@@ -0,0 +1,326 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// DefaultSSHKeepAlive is how often an idle SSH tunnel sends keepalive requests
+const DefaultSSHKeepAlive = 30 * time.Second
+
+// SSHConfig configures an SSH tunnel through a bastion host
+type SSHConfig struct {
+	Host string
+	Port int // Defaults to 22
+	User string
+
+	// KeyFile is a private key used for public key authentication
+	KeyFile string
+	// UseAgent authenticates with the keys held by the agent at $SSH_AUTH_SOCK
+	UseAgent bool
+
+	// KnownHosts is the known_hosts file used to verify the bastion's host key (default ~/.ssh/known_hosts)
+	KnownHosts string
+
+	// KeepAlive is the interval between keepalive requests (default DefaultSSHKeepAlive)
+	KeepAlive time.Duration
+}
+
+// sshTunnel dials database connections through an SSH client.
+// A dropped SSH connection is detected by keepalives and re-established on the next dial.
+type sshTunnel struct {
+	addr         string
+	clientConfig *ssh.ClientConfig
+	keepAlive    time.Duration
+	agentConn    net.Conn // Connection to the ssh agent, nil without UseAgent
+
+	mu     sync.Mutex
+	client *ssh.Client
+	closed bool
+	done   chan struct{}
+}
+
+// newSSHTunnel connects to the bastion host and starts sending keepalives
+func newSSHTunnel(cfg SSHConfig) (*sshTunnel, error) {
+	clientConfig, agentConn, err := sshClientConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+	keepAlive := cfg.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultSSHKeepAlive
+	}
+
+	t := &sshTunnel{
+		addr:         net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		clientConfig: clientConfig,
+		keepAlive:    keepAlive,
+		agentConn:    agentConn,
+		done:         make(chan struct{}),
+	}
+
+	// Connect eagerly so authentication and host key problems surface immediately
+	if _, err := t.getClient(); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	go t.keepAliveLoop()
+	return t, nil
+}
+
+// sshClientConfig builds the SSH client configuration from cfg
+// With UseAgent, the returned agent connection must be closed by the caller.
+func sshClientConfig(cfg SSHConfig) (*ssh.ClientConfig, net.Conn, error) {
+	if cfg.Host == "" {
+		return nil, nil, fmt.Errorf("ssh host is required")
+	}
+	if cfg.User == "" {
+		return nil, nil, fmt.Errorf("ssh user is required")
+	}
+	if cfg.KeyFile == "" && !cfg.UseAgent {
+		return nil, nil, fmt.Errorf("ssh key file or agent is required")
+	}
+
+	var auth []ssh.AuthMethod
+	if cfg.KeyFile != "" {
+		keyData, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read ssh key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(keyData)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse ssh key %s: %w", cfg.KeyFile, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	knownHostsFile := cfg.KnownHosts
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to locate known_hosts: %w", err)
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+
+	var agentConn net.Conn
+	if cfg.UseAgent {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, nil, fmt.Errorf("ssh agent requested but SSH_AUTH_SOCK is not set")
+		}
+		// The agent connection stays open until the tunnel is closed; signing happens on every reconnect
+		agentConn, err = net.Dial("unix", sock)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to ssh agent: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+	}
+
+	return &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         10 * time.Second,
+	}, agentConn, nil
+}
+
+// getClient returns the current SSH client, connecting if there is none
+func (t *sshTunnel) getClient() (*ssh.Client, error) {
+	t.mu.Lock()
+	closed, client := t.closed, t.client
+	t.mu.Unlock()
+	if closed {
+		return nil, errors.New("ssh tunnel is closed")
+	}
+	if client != nil {
+		return client, nil
+	}
+
+	// Dial without the lock so a slow bastion doesn't block Close or the keepalives
+	client, err := ssh.Dial("tcp", t.addr, t.clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("ssh connection to %s failed: %w", t.addr, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		client.Close()
+		return nil, errors.New("ssh tunnel is closed")
+	}
+	if t.client != nil {
+		// A concurrent dial connected first; keep its client
+		client.Close()
+		return t.client, nil
+	}
+	t.client = client
+	return client, nil
+}
+
+// dropClient closes client if it is still the current one, so the next dial reconnects
+func (t *sshTunnel) dropClient(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client == client {
+		t.client.Close()
+		t.client = nil
+	}
+}
+
+// DialContext opens a connection to addr on the far side of the tunnel.
+// If the SSH connection has gone away, it reconnects once and retries.
+func (t *sshTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		client, err := t.getClient()
+		if err != nil {
+			return nil, err
+		}
+		conn, err := client.DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+		t.dropClient(client)
+	}
+	return nil, fmt.Errorf("ssh tunnel dial %s failed: %w", addr, lastErr)
+}
+
+// Dial implements pq.Dialer
+func (t *sshTunnel) Dial(network, addr string) (net.Conn, error) {
+	return t.DialContext(context.Background(), network, addr)
+}
+
+// DialTimeout implements pq.Dialer
+func (t *sshTunnel) DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return t.DialContext(ctx, network, addr)
+}
+
+// keepAliveLoop sends keepalive requests and drops the client when the bastion stops answering
+func (t *sshTunnel) keepAliveLoop() {
+	ticker := time.NewTicker(t.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		}
+
+		t.mu.Lock()
+		client := t.client
+		t.mu.Unlock()
+		if client == nil {
+			continue
+		}
+
+		if err := t.sendKeepAlive(client); err != nil {
+			t.dropClient(client)
+			// Reconnect right away so the next query doesn't pay for it; failures are retried on dial
+			t.getClient()
+		}
+	}
+}
+
+// sendKeepAlive sends a keepalive request and waits at most one keepalive interval for the reply
+// A bastion that stops answering without closing the connection would otherwise block the loop forever;
+// dropping the client closes it, which also ends the pending request.
+func (t *sshTunnel) sendKeepAlive(client *ssh.Client) error {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+
+	timer := time.NewTimer(t.keepAlive)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("no keepalive reply from %s within %s", t.addr, t.keepAlive)
+	case <-t.done:
+		return nil
+	}
+}
+
+// Close closes the SSH connection and the agent connection and stops the keepalives
+func (t *sshTunnel) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	close(t.done)
+	var err error
+	if t.client != nil {
+		err = t.client.Close()
+		t.client = nil
+	}
+	if t.agentConn != nil {
+		t.agentConn.Close()
+		t.agentConn = nil
+	}
+	return err
+}
+
+var mysqlTunnelSeq atomic.Uint64
+
+// newTunnelConnector returns a connector for driverName that dials through the tunnel
+func newTunnelConnector(driverName, dsn string, tunnel *sshTunnel) (driver.Connector, error) {
+	if driverName == "postgres" {
+		connector, err := pq.NewConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		connector.Dialer(tunnel)
+		return connector, nil
+	}
+
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	// The MySQL driver looks up custom dialers by network name, so each tunnel gets its own.
+	// Registrations can't be removed; a closed tunnel's dialer just returns an error.
+	network := cfg.Net
+	netName := fmt.Sprintf("aiq-ssh-%d", mysqlTunnelSeq.Add(1))
+	mysql.RegisterDialContext(netName, func(ctx context.Context, addr string) (net.Conn, error) {
+		return tunnel.DialContext(ctx, network, addr)
+	})
+	cfg.Net = netName
+	return mysql.NewConnector(cfg)
+}
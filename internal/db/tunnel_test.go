@@ -0,0 +1,431 @@
+package db
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is an in-process SSH server that forwards direct-tcpip channels
+type testSSHServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.Signer
+
+	mu     sync.Mutex
+	conns  []*ssh.ServerConn
+	silent atomic.Bool // Leave global requests such as keepalives unanswered
+}
+
+// newTestSSHServer starts an SSH server that accepts clientKey
+func newTestSSHServer(t *testing.T, clientKey ssh.PublicKey) *testSSHServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "bastion" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testSSHServer{listener: listener, config: config, hostKey: hostKey}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		nConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			conn, chans, reqs, err := ssh.NewServerConn(nConn, s.config)
+			if err != nil {
+				nConn.Close()
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+
+			go s.handleRequests(reqs)
+			for newCh := range chans {
+				if newCh.ChannelType() != "direct-tcpip" {
+					newCh.Reject(ssh.UnknownChannelType, "unsupported channel type")
+					continue
+				}
+				go forwardChannel(newCh)
+			}
+		}()
+	}
+}
+
+// handleRequests refuses global requests, or leaves them unanswered while the server is silent
+func (s *testSSHServer) handleRequests(reqs <-chan *ssh.Request) {
+	for req := range reqs {
+		if req.WantReply && !s.silent.Load() {
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// dropConnections closes all SSH connections, simulating a bastion restart or network drop
+func (s *testSSHServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testSSHServer) connectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// forwardChannel connects a direct-tcpip channel to its target address
+func forwardChannel(newCh ssh.NewChannel) {
+	// RFC 4254 7.2: host to connect, port to connect, originator address, originator port
+	payload := newCh.ExtraData()
+	if len(payload) < 4 {
+		newCh.Reject(ssh.ConnectionFailed, "bad payload")
+		return
+	}
+	hostLen := binary.BigEndian.Uint32(payload)
+	host := string(payload[4 : 4+hostLen])
+	port := binary.BigEndian.Uint32(payload[4+hostLen:])
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		newCh.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		io.Copy(ch, target)
+		ch.CloseWrite()
+	}()
+	io.Copy(target, ch)
+	target.Close()
+}
+
+// startEchoServer starts a TCP server that echoes everything it receives
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// setupTunnelTest starts an SSH server and returns a config that can reach it
+func setupTunnelTest(t *testing.T) (*testSSHServer, SSHConfig) {
+	t.Helper()
+	dir := t.TempDir()
+
+	_, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSigner, err := ssh.NewSignerFromKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestSSHServer(t, clientSigner.PublicKey())
+	host, portStr, _ := net.SplitHostPort(server.listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{server.listener.Addr().String()}, server.hostKey.PublicKey())
+	if err := os.WriteFile(knownHostsFile, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return server, SSHConfig{
+		Host:       host,
+		Port:       port,
+		User:       "bastion",
+		KeyFile:    keyFile,
+		KnownHosts: knownHostsFile,
+	}
+}
+
+// echoThrough dials addr through the tunnel and checks that data round-trips
+func echoThrough(t *testing.T, tunnel *sshTunnel, addr string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := tunnel.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		t.Fatalf("Expected dial through tunnel to succeed, got %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("Expected 'ping', got %q", buf)
+	}
+}
+
+// TestSSHTunnel tests dialing through an in-process SSH server
+func TestSSHTunnel(t *testing.T) {
+	t.Run("forwards connections", func(t *testing.T) {
+		_, cfg := setupTunnelTest(t)
+		echoAddr := startEchoServer(t)
+
+		tunnel, err := newSSHTunnel(cfg)
+		if err != nil {
+			t.Fatalf("newSSHTunnel failed: %v", err)
+		}
+		defer tunnel.Close()
+
+		echoThrough(t, tunnel, echoAddr)
+	})
+
+	t.Run("reconnects after the SSH connection drops", func(t *testing.T) {
+		server, cfg := setupTunnelTest(t)
+		echoAddr := startEchoServer(t)
+
+		tunnel, err := newSSHTunnel(cfg)
+		if err != nil {
+			t.Fatalf("newSSHTunnel failed: %v", err)
+		}
+		defer tunnel.Close()
+
+		echoThrough(t, tunnel, echoAddr)
+		server.dropConnections()
+		echoThrough(t, tunnel, echoAddr)
+
+		if got := server.connectionCount(); got != 1 {
+			t.Errorf("Expected 1 live SSH connection after reconnect, got %d", got)
+		}
+	})
+
+	t.Run("keepalive detects a dropped connection", func(t *testing.T) {
+		server, cfg := setupTunnelTest(t)
+		cfg.KeepAlive = 20 * time.Millisecond
+
+		tunnel, err := newSSHTunnel(cfg)
+		if err != nil {
+			t.Fatalf("newSSHTunnel failed: %v", err)
+		}
+		defer tunnel.Close()
+
+		server.dropConnections()
+		deadline := time.Now().Add(5 * time.Second)
+		for server.connectionCount() == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if server.connectionCount() != 1 {
+			t.Error("Expected keepalive to re-establish the SSH connection")
+		}
+	})
+
+	t.Run("keepalive drops a connection that stops answering", func(t *testing.T) {
+		server, cfg := setupTunnelTest(t)
+		cfg.KeepAlive = 20 * time.Millisecond
+
+		tunnel, err := newSSHTunnel(cfg)
+		if err != nil {
+			t.Fatalf("newSSHTunnel failed: %v", err)
+		}
+		defer tunnel.Close()
+
+		// The connection stays open but keepalives get no reply, so the tunnel must reconnect
+		server.silent.Store(true)
+		deadline := time.Now().Add(5 * time.Second)
+		for server.connectionCount() < 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if server.connectionCount() < 2 {
+			t.Error("Expected an unanswered keepalive to re-establish the SSH connection")
+		}
+	})
+
+	t.Run("rejects unknown host key", func(t *testing.T) {
+		_, cfg := setupTunnelTest(t)
+		empty := filepath.Join(t.TempDir(), "known_hosts")
+		if err := os.WriteFile(empty, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		cfg.KnownHosts = empty
+
+		if tunnel, err := newSSHTunnel(cfg); err == nil {
+			tunnel.Close()
+			t.Fatal("Expected host key verification to fail")
+		}
+	})
+
+	t.Run("requires an auth method", func(t *testing.T) {
+		_, cfg := setupTunnelTest(t)
+		cfg.KeyFile = ""
+		if _, err := newSSHTunnel(cfg); err == nil {
+			t.Fatal("Expected error without key file or agent")
+		}
+	})
+
+	t.Run("closes the agent connection", func(t *testing.T) {
+		_, cfg := setupTunnelTest(t)
+		keyData, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := ssh.ParseRawPrivateKey(keyData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyring := agent.NewKeyring()
+		if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Unix socket paths are short, so the agent lives outside t.TempDir
+		dir, err := os.MkdirTemp("", "aiq-agent")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		listener, err := net.Listen("unix", filepath.Join(dir, "agent.sock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		served := make(chan struct{})
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			agent.ServeAgent(keyring, conn)
+			close(served)
+		}()
+
+		t.Setenv("SSH_AUTH_SOCK", listener.Addr().String())
+		cfg.KeyFile = ""
+		cfg.UseAgent = true
+		tunnel, err := newSSHTunnel(cfg)
+		if err != nil {
+			t.Fatalf("newSSHTunnel failed: %v", err)
+		}
+		tunnel.Close()
+
+		select {
+		case <-served:
+		case <-time.After(5 * time.Second):
+			t.Error("Expected the agent connection to be closed with the tunnel")
+		}
+	})
+
+	t.Run("closed tunnel refuses to dial", func(t *testing.T) {
+		_, cfg := setupTunnelTest(t)
+		tunnel, err := newSSHTunnel(cfg)
+		if err != nil {
+			t.Fatalf("newSSHTunnel failed: %v", err)
+		}
+		tunnel.Close()
+		if _, err := tunnel.Dial("tcp", "127.0.0.1:1"); err == nil {
+			t.Error("Expected dial on closed tunnel to fail")
+		}
+	})
+}
+
+// TestNewTunnelConnector tests that driver connectors are wired to the tunnel
+func TestNewTunnelConnector(t *testing.T) {
+	tunnel := &sshTunnel{done: make(chan struct{})}
+
+	for _, tc := range []struct {
+		driverName string
+		dsn        string
+	}{
+		{"mysql", "user:pass@tcp(db.internal:3306)/shop"},
+		{"postgres", "host=db.internal port=5432 user=app dbname=shop sslmode=disable"},
+	} {
+		t.Run(tc.driverName, func(t *testing.T) {
+			connector, err := newTunnelConnector(tc.driverName, tc.dsn, tunnel)
+			if err != nil {
+				t.Fatalf("newTunnelConnector failed: %v", err)
+			}
+			if connector == nil {
+				t.Fatal("Expected a connector")
+			}
+		})
+	}
+
+	t.Run("invalid mysql dsn", func(t *testing.T) {
+		if _, err := newTunnelConnector("mysql", "not a dsn", tunnel); err == nil {
+			t.Error("Expected error for invalid DSN")
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aiq/aiq/internal/db"
+)
+
+// Status is the state of a background job
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// RunFunc executes a job's statement; it must stop when ctx is cancelled
+type RunFunc func(ctx context.Context) (*db.QueryResult, error)
+
+// Job is a snapshot of a query running (or finished) in the background
+type Job struct {
+	ID       int
+	SQL      string
+	Status   Status
+	Started  time.Time
+	Finished time.Time // Zero while running
+	Result   *db.QueryResult
+	Err      error
+	Attached bool // The result has been added to the conversation
+}
+
+// Elapsed returns how long the job has been running, or how long it ran once finished
+func (j Job) Elapsed() time.Duration {
+	if j.Finished.IsZero() {
+		return time.Since(j.Started)
+	}
+	return j.Finished.Sub(j.Started)
+}
+
+// job is the mutable state behind a Job snapshot
+type job struct {
+	Job
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager runs queries in the background and keeps their results until they are attached
+type Manager struct {
+	mu     sync.Mutex
+	jobs   []*job
+	nextID int
+	onDone func(Job)
+}
+
+// NewManager creates an empty job manager
+func NewManager() *Manager {
+	return &Manager{nextID: 1}
+}
+
+// SetDoneHandler sets a function that is called (from the job's goroutine) when a job finishes
+func (m *Manager) SetDoneHandler(handler func(Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onDone = handler
+}
+
+// Start runs fn in the background and returns the new job.
+// The job is not bound to ctx's deadline; it runs until it finishes or is cancelled.
+func (m *Manager) Start(ctx context.Context, sql string, fn RunFunc) Job {
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	m.mu.Lock()
+	j := &job{
+		Job: Job{
+			ID:      m.nextID,
+			SQL:     sql,
+			Status:  StatusRunning,
+			Started: time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	m.nextID++
+	m.jobs = append(m.jobs, j)
+	snapshot := j.Job
+	m.mu.Unlock()
+
+	go m.run(jobCtx, j, fn)
+	return snapshot
+}
+
+// run executes a job and records its outcome
+func (m *Manager) run(ctx context.Context, j *job, fn RunFunc) {
+	defer close(j.done)
+	result, err := fn(ctx)
+
+	m.mu.Lock()
+	j.Finished = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		j.Status = StatusCancelled
+		j.Err = context.Canceled
+	case err != nil:
+		j.Status = StatusFailed
+		j.Err = err
+	default:
+		j.Status = StatusDone
+		j.Result = result
+	}
+	j.cancel()
+	snapshot := j.Job
+	onDone := m.onDone
+	m.mu.Unlock()
+
+	if onDone != nil {
+		onDone(snapshot)
+	}
+}
+
+// List returns snapshots of all jobs in start order
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Job, len(m.jobs))
+	for i, j := range m.jobs {
+		list[i] = j.Job
+	}
+	return list
+}
+
+// Get returns a snapshot of the job with the given ID
+func (m *Manager) Get(id int) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j := m.find(id)
+	if j == nil {
+		return Job{}, fmt.Errorf("job #%d not found", id)
+	}
+	return j.Job, nil
+}
+
+// Running returns the number of jobs that have not finished
+func (m *Manager) Running() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, j := range m.jobs {
+		if j.Status == StatusRunning {
+			n++
+		}
+	}
+	return n
+}
+
+// Cancel stops a running job and waits for its statement to return
+func (m *Manager) Cancel(id int) error {
+	m.mu.Lock()
+	j := m.find(id)
+	if j == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("job #%d not found", id)
+	}
+	if j.Status != StatusRunning {
+		m.mu.Unlock()
+		return fmt.Errorf("job #%d is not running (%s)", id, j.Status)
+	}
+	j.cancel()
+	m.mu.Unlock()
+
+	<-j.done
+	return nil
+}
+
+// CancelAll stops all running jobs and waits for them to return
+func (m *Manager) CancelAll() {
+	m.mu.Lock()
+	running := make([]*job, 0)
+	for _, j := range m.jobs {
+		if j.Status == StatusRunning {
+			j.cancel()
+			running = append(running, j)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, j := range running {
+		<-j.done
+	}
+}
+
+// TakeFinished returns the finished jobs whose results have not been attached to the
+// conversation yet, and marks them as attached
+func (m *Manager) TakeFinished() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var finished []Job
+	for _, j := range m.jobs {
+		if j.Status == StatusRunning || j.Attached {
+			continue
+		}
+		j.Attached = true
+		finished = append(finished, j.Job)
+	}
+	return finished
+}
+
+// find returns the job with the given ID; the caller must hold m.mu
+func (m *Manager) find(id int) *job {
+	for _, j := range m.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aiq/aiq/internal/db"
+)
+
+// waitFor polls until the job leaves the running state
+func waitFor(t *testing.T, m *Manager, id int) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if job.Status != StatusRunning {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Job #%d did not finish", id)
+	return Job{}
+}
+
+// TestManager_Start tests running jobs in the background
+func TestManager_Start(t *testing.T) {
+	t.Run("records the result of a finished job", func(t *testing.T) {
+		m := NewManager()
+		result := &db.QueryResult{Columns: []string{"n"}, Rows: [][]string{{"1"}}, RowsAffected: -1}
+		release := make(chan struct{})
+
+		job := m.Start(context.Background(), "SELECT 1", func(ctx context.Context) (*db.QueryResult, error) {
+			<-release
+			return result, nil
+		})
+		if job.ID != 1 || job.Status != StatusRunning {
+			t.Fatalf("Expected running job #1, got #%d %s", job.ID, job.Status)
+		}
+		if m.Running() != 1 {
+			t.Errorf("Expected 1 running job, got %d", m.Running())
+		}
+
+		close(release)
+		done := waitFor(t, m, job.ID)
+		if done.Status != StatusDone || done.Result != result {
+			t.Errorf("Expected done job with result, got %s %v", done.Status, done.Result)
+		}
+		if done.Finished.IsZero() {
+			t.Error("Expected finish time to be set")
+		}
+	})
+
+	t.Run("records failures", func(t *testing.T) {
+		m := NewManager()
+		queryErr := errors.New("division by zero")
+		job := m.Start(context.Background(), "SELECT 1/0", func(ctx context.Context) (*db.QueryResult, error) {
+			return nil, queryErr
+		})
+
+		done := waitFor(t, m, job.ID)
+		if done.Status != StatusFailed || done.Err != queryErr {
+			t.Errorf("Expected failed job with error, got %s %v", done.Status, done.Err)
+		}
+	})
+
+	t.Run("outlives the starting context", func(t *testing.T) {
+		m := NewManager()
+		ctx, cancel := context.WithCancel(context.Background())
+		release := make(chan struct{})
+		job := m.Start(ctx, "SELECT 1", func(jobCtx context.Context) (*db.QueryResult, error) {
+			<-release
+			return &db.QueryResult{}, jobCtx.Err()
+		})
+
+		cancel()
+		close(release)
+		if done := waitFor(t, m, job.ID); done.Status != StatusDone {
+			t.Errorf("Expected done job, got %s", done.Status)
+		}
+	})
+
+	t.Run("calls the done handler", func(t *testing.T) {
+		m := NewManager()
+		notified := make(chan Job, 1)
+		m.SetDoneHandler(func(job Job) { notified <- job })
+
+		m.Start(context.Background(), "SELECT 1", func(ctx context.Context) (*db.QueryResult, error) {
+			return &db.QueryResult{}, nil
+		})
+
+		select {
+		case job := <-notified:
+			if job.Status != StatusDone {
+				t.Errorf("Expected done status, got %s", job.Status)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected done handler to be called")
+		}
+	})
+}
+
+// TestManager_Cancel tests cancelling running jobs
+func TestManager_Cancel(t *testing.T) {
+	blocking := func(ctx context.Context) (*db.QueryResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	t.Run("cancels a running job", func(t *testing.T) {
+		m := NewManager()
+		job := m.Start(context.Background(), "SELECT SLEEP(600)", blocking)
+
+		if err := m.Cancel(job.ID); err != nil {
+			t.Fatalf("Cancel failed: %v", err)
+		}
+		got, _ := m.Get(job.ID)
+		if got.Status != StatusCancelled {
+			t.Errorf("Expected cancelled status, got %s", got.Status)
+		}
+		if err := m.Cancel(job.ID); err == nil {
+			t.Error("Expected error cancelling a finished job")
+		}
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		if err := NewManager().Cancel(42); err == nil {
+			t.Error("Expected error for unknown job")
+		}
+	})
+
+	t.Run("cancel all", func(t *testing.T) {
+		m := NewManager()
+		m.Start(context.Background(), "SELECT 1", blocking)
+		m.Start(context.Background(), "SELECT 2", blocking)
+
+		m.CancelAll()
+		if m.Running() != 0 {
+			t.Errorf("Expected no running jobs, got %d", m.Running())
+		}
+	})
+}
+
+// TestManager_TakeFinished tests that finished jobs are attached once
+func TestManager_TakeFinished(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	first := m.Start(context.Background(), "SELECT 1", func(ctx context.Context) (*db.QueryResult, error) {
+		return &db.QueryResult{}, nil
+	})
+	second := m.Start(context.Background(), "SELECT 2", func(ctx context.Context) (*db.QueryResult, error) {
+		<-release
+		return &db.QueryResult{}, nil
+	})
+	waitFor(t, m, first.ID)
+
+	finished := m.TakeFinished()
+	if len(finished) != 1 || finished[0].ID != first.ID {
+		t.Fatalf("Expected only job #%d, got %+v", first.ID, finished)
+	}
+	if again := m.TakeFinished(); len(again) != 0 {
+		t.Errorf("Expected no jobs on second take, got %d", len(again))
+	}
+
+	close(release)
+	waitFor(t, m, second.ID)
+	if finished := m.TakeFinished(); len(finished) != 1 || finished[0].ID != second.ID {
+		t.Errorf("Expected job #%d, got %+v", second.ID, finished)
+	}
+	if len(m.List()) != 2 {
+		t.Errorf("Expected 2 jobs in list, got %d", len(m.List()))
+	}
+}
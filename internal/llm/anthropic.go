@@ -0,0 +1,375 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// anthropicVersion is the Messages API version sent with every request
+	anthropicVersion = "2023-06-01"
+	// anthropicMaxTokens bounds the length of a reply; the Messages API requires a limit
+	anthropicMaxTokens = 8192
+)
+
+// anthropicProvider talks to the Anthropic Messages API
+type anthropicProvider struct {
+	baseURL      string
+	apiKey       string
+	client       *http.Client
+	streamClient *http.Client
+}
+
+// anthropicRequest is a Messages API request
+type anthropicRequest struct {
+	Model      string                 `json:"model"`
+	MaxTokens  int                    `json:"max_tokens"`
+	Stream     bool                   `json:"stream,omitempty"`
+	System     string                 `json:"system,omitempty"`
+	Messages   []anthropicMessage     `json:"messages"`
+	Tools      []anthropicTool        `json:"tools,omitempty"`
+	ToolChoice map[string]interface{} `json:"tool_choice,omitempty"`
+}
+
+// anthropicMessage is a user or assistant turn made of content blocks
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is a text, tool_use or tool_result content block
+type anthropicBlock struct {
+	Type      string                 `json:"type"`
+	Text      string                 `json:"text,omitempty"`
+	ID        string                 `json:"id,omitempty"`          // tool_use
+	Name      string                 `json:"name,omitempty"`        // tool_use
+	Input     map[string]interface{} `json:"input,omitempty"`       // tool_use
+	ToolUseID string                 `json:"tool_use_id,omitempty"` // tool_result
+	Content   string                 `json:"content,omitempty"`     // tool_result
+}
+
+// anthropicTool is a tool definition of the Messages API
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// anthropicResponse is a Messages API response
+type anthropicResponse struct {
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error,omitempty"`
+}
+
+// Name returns the provider name
+func (p *anthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// Complete sends a Messages API request
+func (p *anthropicProvider) Complete(ctx context.Context, model string, messages []interface{}, tools []Function) (*ChatResponse, error) {
+	body, err := postJSON(ctx, p.client, buildAnthropicURL(p.baseURL), p.headers(), buildAnthropicRequest(model, messages, tools))
+	if err != nil {
+		return nil, err
+	}
+	return parseAnthropicResponse(body)
+}
+
+// Stream sends a Messages API request with stream enabled
+func (p *anthropicProvider) Stream(ctx context.Context, model string, messages []interface{}, tools []Function, onText func(string)) (*ChatResponse, error) {
+	reqBody := buildAnthropicRequest(model, messages, tools)
+	reqBody.Stream = true
+
+	resp, err := send(ctx, p.streamClient, buildAnthropicURL(p.baseURL), p.headers(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	acc := newStreamAccumulator(onText)
+	if !isEventStream(resp) {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		chatResp, err := parseAnthropicResponse(body)
+		if err != nil {
+			return nil, err
+		}
+		acc.addText(chatResp.Choices[0].Message.Content)
+		return chatResp, nil
+	}
+
+	usage := &Usage{}
+	err = readSSE(resp.Body, func(event, data string) error {
+		var ev struct {
+			Type    string `json:"type"`
+			Index   int    `json:"index"`
+			Message struct {
+				Usage struct {
+					InputTokens int `json:"input_tokens"`
+				} `json:"usage"`
+			} `json:"message"`
+			ContentBlock anthropicBlock `json:"content_block"`
+			Delta        struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+			Usage struct {
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+			Error *struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("failed to parse stream event: %w", err)
+		}
+
+		switch ev.Type {
+		case "message_start":
+			usage.PromptTokens = ev.Message.Usage.InputTokens
+		case "content_block_start":
+			if ev.ContentBlock.Type == "tool_use" {
+				call := acc.toolCall(ev.Index)
+				call.ID = ev.ContentBlock.ID
+				call.Function.Name = ev.ContentBlock.Name
+			} else {
+				acc.addText(ev.ContentBlock.Text)
+			}
+		case "content_block_delta":
+			switch ev.Delta.Type {
+			case "text_delta":
+				acc.addText(ev.Delta.Text)
+			case "input_json_delta":
+				call := acc.toolCall(ev.Index)
+				call.Function.Arguments += ev.Delta.PartialJSON
+			}
+		case "message_delta":
+			if ev.Delta.StopReason != "" {
+				acc.finishReason = anthropicFinishReason(ev.Delta.StopReason)
+			}
+			usage.CompletionTokens = ev.Usage.OutputTokens
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			if ev.Error != nil {
+				return fmt.Errorf("API error: %s (type: %s)", ev.Error.Message, ev.Error.Type)
+			}
+			return fmt.Errorf("API error: %s", data)
+		}
+		return nil
+	})
+	if usage.PromptTokens > 0 || usage.CompletionTokens > 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		acc.usage = usage
+	}
+	if ctx.Err() != nil {
+		return acc.response(), ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stream failed: %w", err)
+	}
+	return acc.response(), nil
+}
+
+// headers returns the authentication and version headers
+func (p *anthropicProvider) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+}
+
+// parseAnthropicResponse parses a Messages API response
+func parseAnthropicResponse(body []byte) (*ChatResponse, error) {
+	var resp anthropicResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("API error: %s (type: %s)", resp.Error.Message, resp.Error.Type)
+	}
+	return resp.toChatResponse(), nil
+}
+
+// buildAnthropicRequest translates OpenAI-style messages and tools into a Messages API request
+// System messages before the first turn become the system prompt; later ones are sent as user
+// text so they keep their place in the conversation. Consecutive messages of the same role are
+// merged, since the API requires user and assistant turns to alternate.
+func buildAnthropicRequest(model string, messages []interface{}, tools []Function) anthropicRequest {
+	req := anthropicRequest{
+		Model:     model,
+		MaxTokens: anthropicMaxTokens,
+	}
+	var system []string
+
+	for _, msg := range normalizeMessages(messages) {
+		role, _ := msg["role"].(string)
+		content, _ := msg["content"].(string)
+
+		var blocks []anthropicBlock
+		switch role {
+		case "system":
+			if len(req.Messages) == 0 {
+				system = append(system, content)
+				continue
+			}
+			role = "user"
+			blocks = textBlocks(content)
+		case "tool":
+			role = "user"
+			toolCallID, _ := msg["tool_call_id"].(string)
+			blocks = []anthropicBlock{{Type: "tool_result", ToolUseID: toolCallID, Content: content}}
+		case "assistant":
+			blocks = append(textBlocks(content), toolUseBlocks(msg["tool_calls"])...)
+		default:
+			role = "user"
+			blocks = textBlocks(content)
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if last := len(req.Messages) - 1; last >= 0 && req.Messages[last].Role == role {
+			req.Messages[last].Content = append(req.Messages[last].Content, blocks...)
+		} else {
+			req.Messages = append(req.Messages, anthropicMessage{Role: role, Content: blocks})
+		}
+	}
+	req.System = strings.Join(system, "\n\n")
+
+	for _, tool := range tools {
+		schema := tool.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		})
+	}
+	if len(tools) > 0 {
+		req.ToolChoice = map[string]interface{}{"type": "auto"}
+	}
+	return req
+}
+
+// textBlocks returns a text block for non-empty content; the API rejects empty text blocks
+func textBlocks(content string) []anthropicBlock {
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+	return []anthropicBlock{{Type: "text", Text: content}}
+}
+
+// toolUseBlocks converts the tool_calls of an assistant message into tool_use blocks
+// Tool calls may be []ToolCall (built in this process) or decoded JSON (restored from a session).
+func toolUseBlocks(toolCalls interface{}) []anthropicBlock {
+	if toolCalls == nil {
+		return nil
+	}
+	var calls []ToolCall
+	switch tc := toolCalls.(type) {
+	case []ToolCall:
+		calls = tc
+	default:
+		data, err := json.Marshal(tc)
+		if err != nil || json.Unmarshal(data, &calls) != nil {
+			return nil
+		}
+	}
+
+	blocks := make([]anthropicBlock, 0, len(calls))
+	for _, call := range calls {
+		input, err := call.ParseArguments()
+		if err != nil {
+			input = map[string]interface{}{}
+		}
+		blocks = append(blocks, anthropicBlock{
+			Type:  "tool_use",
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: input,
+		})
+	}
+	return blocks
+}
+
+// toChatResponse translates a Messages API response into the OpenAI-style response used by callers
+func (r *anthropicResponse) toChatResponse() *ChatResponse {
+	var message ResponseMessage
+	message.Role = "assistant"
+	var text []string
+	for _, block := range r.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			input := block.Input
+			if input == nil {
+				input = map[string]interface{}{}
+			}
+			arguments, _ := json.Marshal(input)
+			call := ToolCall{ID: block.ID, Type: "function"}
+			call.Function.Name = block.Name
+			call.Function.Arguments = string(arguments)
+			message.ToolCalls = append(message.ToolCalls, call)
+		}
+	}
+	message.Content = strings.Join(text, "")
+
+	resp := &ChatResponse{
+		Choices: []Choice{{Message: message, FinishReason: anthropicFinishReason(r.StopReason)}},
+	}
+	if r.Usage != nil {
+		resp.Usage = &Usage{
+			PromptTokens:     r.Usage.InputTokens,
+			CompletionTokens: r.Usage.OutputTokens,
+			TotalTokens:      r.Usage.InputTokens + r.Usage.OutputTokens,
+		}
+	}
+	return resp
+}
+
+// anthropicFinishReason maps a Messages API stop_reason to an OpenAI-style finish_reason
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	}
+	return "stop"
+}
+
+// buildAnthropicURL builds the Messages API URL from the base URL
+// - https://api.anthropic.com -> https://api.anthropic.com/v1/messages
+// - https://api.anthropic.com/v1 -> https://api.anthropic.com/v1/messages
+// - https://api.anthropic.com/v1/messages -> (no change)
+func buildAnthropicURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(baseURL, "/messages") {
+		return baseURL
+	}
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL + "/messages"
+	}
+	return baseURL + "/v1/messages"
+}
@@ -1,34 +1,51 @@
 package llm
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
 // Client represents an LLM API client
 type Client struct {
-	baseURL string
-	apiKey  string
-	model   string
-	client  *http.Client
+	baseURL     string
+	apiKey      string
+	model       string
+	provider    Provider
+	noStreaming atomic.Bool   // Set when streaming is disabled or the server rejected a streamed request
+	maxRetries  *atomic.Int32 // Shared with the transports of provider
+
+	usageMu sync.Mutex
+	usage   UsageStats // Token usage of all calls made through the client
 }
 
-// NewClient creates a new LLM client
+// NewClient creates a new LLM client for an OpenAI-compatible API
 func NewClient(baseURL, apiKey, model string) *Client {
-	return &Client{
-		baseURL: baseURL,
-		apiKey:  apiKey,
-		model:   model,
-		client: &http.Client{
-			Timeout: 60 * time.Second,
-		},
+	client, _ := NewProviderClient(ProviderOpenAI, baseURL, apiKey, model)
+	return client
+}
+
+// NewProviderClient creates a new LLM client for the named provider (see NewProvider)
+func NewProviderClient(providerName, baseURL, apiKey, model string) (*Client, error) {
+	maxRetries := &atomic.Int32{}
+	maxRetries.Store(DefaultMaxRetries)
+	provider, err := newProvider(providerName, baseURL, apiKey, maxRetries)
+	if err != nil {
+		return nil, err
 	}
+	return &Client{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		model:      model,
+		provider:   provider,
+		maxRetries: maxRetries,
+	}, nil
 }
 
 // BaseURL returns the base URL of the LLM client
@@ -46,6 +63,25 @@ func (c *Client) Model() string {
 	return c.model
 }
 
+// SetStreaming enables or disables streamed replies in ChatWithToolsStream
+func (c *Client) SetStreaming(enabled bool) {
+	c.noStreaming.Store(!enabled)
+}
+
+// SetMaxRetries sets how often a request that was rate limited or failed with a server or
+// network error is retried; 0 disables retries
+func (c *Client) SetMaxRetries(n int) {
+	if n < 0 {
+		n = 0
+	}
+	c.maxRetries.Store(int32(n))
+}
+
+// ProviderName returns the name of the provider the client talks to
+func (c *Client) ProviderName() string {
+	return c.provider.Name()
+}
+
 // ChatMessage represents a chat message
 type ChatMessage struct {
 	Role    string `json:"role"`
@@ -123,25 +159,46 @@ type ChatRequest struct {
 		Type     string   `json:"type"`
 		Function Function `json:"function"`
 	} `json:"tools,omitempty"`
-	ToolChoice interface{} `json:"tool_choice,omitempty"` // "auto", "none", or {"type": "function", "function": {"name": "..."}}
+	ToolChoice    interface{}    `json:"tool_choice,omitempty"` // "auto", "none", or {"type": "function", "function": {"name": "..."}}
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// StreamOptions configures a streamed chat request
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // Report token usage in the last chunk
 }
 
 // ChatResponse represents a chat API response
 type ChatResponse struct {
-	Choices []struct {
-		Message struct {
-			Role      string     `json:"role"`
-			Content   string     `json:"content"`
-			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
-		} `json:"message"`
-		FinishReason string `json:"finish_reason"` // "stop", "tool_calls", etc.
-	} `json:"choices"`
-	Error *struct {
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"` // Nil if the provider did not report token usage
+	Error   *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error,omitempty"`
 }
 
+// Choice is one completion of a chat response
+type Choice struct {
+	Message      ResponseMessage `json:"message"`
+	FinishReason string          `json:"finish_reason"` // "stop", "tool_calls", etc.
+}
+
+// ResponseMessage is the assistant message of a choice
+type ResponseMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// Usage is the token usage reported for one chat completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 // ChatResponseType indicates the type of response from LLM
 type ChatResponseType int
 
@@ -208,84 +265,9 @@ Remember: If the user wants SQL, return ONLY the SQL query. If it's just convers
 		messagesInterface[i] = msg
 	}
 
-	// Create request
-	reqBody := ChatRequest{
-		Model:    c.model,
-		Messages: messagesInterface,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Build the full API URL
-	apiURL := c.buildAPIURL()
-
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	// Execute request with retry
-	var resp *http.Response
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		resp, err = c.client.Do(req)
-		if err == nil {
-			break
-		}
-		if i < maxRetries-1 {
-			time.Sleep(time.Duration(i+1) * time.Second)
-		}
-	}
-	if err != nil {
-		return nil, fmt.Errorf("request failed after retries: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
+	chatResp, err := c.complete(ctx, messagesInterface, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var chatResp struct {
-		Choices []struct {
-			Message struct {
-				Role      string     `json:"role"`
-				Content   string     `json:"content"`
-				ToolCalls []ToolCall `json:"tool_calls,omitempty"`
-			} `json:"message"`
-			FinishReason string `json:"finish_reason"`
-		} `json:"choices"`
-		Error *struct {
-			Message string `json:"message"`
-			Type    string `json:"type"`
-		} `json:"error,omitempty"`
-	}
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Check for API errors
-	if chatResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
-	}
-
-	// Extract response
-	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
+		return nil, err
 	}
 
 	content := chatResp.Choices[0].Message.Content
@@ -329,152 +311,73 @@ Remember: If the user wants SQL, return ONLY the SQL query. If it's just convers
 // ChatWithTools handles conversation with tool support
 // messages can include ChatMessage or map[string]interface{} for tool messages
 func (c *Client) ChatWithTools(ctx context.Context, messages []interface{}, tools []Function) (*ChatResponse, error) {
+	// Return the response as-is, including tool_calls
+	// Let the caller handle tool_calls
+	return c.complete(ctx, messages, tools)
+}
 
-	// Build tools array for request
-	toolsArray := make([]struct {
-		Type     string   `json:"type"`
-		Function Function `json:"function"`
-	}, len(tools))
-	for i, tool := range tools {
-		toolsArray[i] = struct {
-			Type     string   `json:"type"`
-			Function Function `json:"function"`
-		}{
-			Type:     "function",
-			Function: tool,
-		}
-	}
-
-	// Normalize messages to ensure content field is always a string (not an object)
-	// This is critical for LLM API compatibility
-	// Messages from Session are already normalized in mode.go and tool_handler.go,
-	// but we do a final check here for safety
-	normalizedMessages := make([]interface{}, 0, len(messages))
-	for _, msg := range messages {
-		var msgMap map[string]interface{}
-
-		// Convert to map if needed
-		if m, ok := msg.(map[string]interface{}); ok {
-			msgMap = m
-		} else if chatMsg, ok := msg.(ChatMessage); ok {
-			// Convert ChatMessage struct to map
-			msgMap = map[string]interface{}{
-				"role":    chatMsg.Role,
-				"content": chatMsg.Content,
-			}
-		} else {
-			// Unknown type - try to convert via JSON
-			if jsonBytes, err := json.Marshal(msg); err == nil {
-				if json.Unmarshal(jsonBytes, &msgMap) != nil {
-					continue // Skip if conversion fails
-				}
-			} else {
-				continue // Skip if marshal fails
-			}
-		}
-
-		// Ensure content field is always a string
-		if content, exists := msgMap["content"]; exists && content != nil {
-			if _, isString := content.(string); !isString {
-				// Convert to string
-				if jsonBytes, err := json.Marshal(content); err == nil {
-					msgMap["content"] = string(jsonBytes)
-				} else {
-					msgMap["content"] = fmt.Sprintf("%v", content)
-				}
+// ChatWithToolsStream is ChatWithTools with assistant text passed to onText as it arrives
+// Providers without streaming support fall back to a regular request, as do servers that reject
+// a streamed request before sending anything (streaming then stays off for this client); onText
+// receives the whole text at once in that case. If ctx is cancelled mid-stream, the partial reply
+// is returned together with the context error.
+func (c *Client) ChatWithToolsStream(ctx context.Context, messages []interface{}, tools []Function, onText func(string)) (*ChatResponse, error) {
+	streamer, ok := c.provider.(StreamingProvider)
+	if ok && !c.noStreaming.Load() {
+		received := false
+		resp, err := streamer.Stream(ctx, c.model, messages, tools, func(text string) {
+			received = true
+			if onText != nil {
+				onText(text)
 			}
+		})
+		c.recordUsage(resp)
+		if err == nil || received || ctx.Err() != nil || !streamUnsupported(err) {
+			return resp, err
 		}
-
-		normalizedMessages = append(normalizedMessages, msgMap)
+		c.noStreaming.Store(true)
 	}
 
-	// Create request
-	reqBody := ChatRequest{
-		Model:      c.model,
-		Messages:   normalizedMessages,
-		Tools:      toolsArray,
-		ToolChoice: "auto", // Let LLM decide when to use tools
+	resp, err := c.ChatWithTools(ctx, messages, tools)
+	if err == nil && onText != nil && resp.Choices[0].Message.Content != "" {
+		onText(resp.Choices[0].Message.Content)
 	}
+	return resp, err
+}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Build the full API URL
-	apiURL := c.buildAPIURL()
-
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	// Execute request with retry
-	var resp *http.Response
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		resp, err = c.client.Do(req)
-		if err == nil {
-			break
+// streamUnsupported reports whether a streamed request that failed may succeed without streaming:
+// the server rejected the request or sent a stream that could not be read. Authentication, rate
+// limit, server and network errors would fail the same way again.
+func streamUnsupported(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+			return false
 		}
-		if i < maxRetries-1 {
-			time.Sleep(time.Duration(i+1) * time.Second)
-		}
-	}
-	if err != nil {
-		return nil, fmt.Errorf("request failed after retries: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return apiErr.StatusCode < 500
 	}
+	var urlErr *url.Error
+	return !errors.As(err, &urlErr)
+}
 
-	// Parse response
-	var chatResp struct {
-		Choices []struct {
-			Message struct {
-				Role      string     `json:"role"`
-				Content   string     `json:"content"`
-				ToolCalls []ToolCall `json:"tool_calls,omitempty"`
-			} `json:"message"`
-			FinishReason string `json:"finish_reason"`
-		} `json:"choices"`
-		Error *struct {
-			Message string `json:"message"`
-			Type    string `json:"type"`
-		} `json:"error,omitempty"`
+// Complete sends a single-turn request with a system prompt and returns the text of the reply
+// It is used for auxiliary requests such as skill matching and compression.
+func (c *Client) Complete(ctx context.Context, systemPrompt, prompt string) (string, error) {
+	messages := []interface{}{
+		ChatMessage{Role: "system", Content: systemPrompt},
+		ChatMessage{Role: "user", Content: prompt},
 	}
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Check for API errors
-	if chatResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
+	chatResp, err := c.complete(ctx, messages, nil)
+	if err != nil {
+		return "", err
 	}
 
-	// Extract response
-	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
+	content := chatResp.Choices[0].Message.Content
+	if content == "" {
+		return "", fmt.Errorf("empty content in response")
 	}
-
-	// Return the response as-is, including tool_calls
-	// Let the caller handle tool_calls
-	return &ChatResponse{
-		Choices: chatResp.Choices,
-	}, nil
+	return content, nil
 }
 
 // TranslateToSQL translates natural language to SQL using LLM
@@ -510,71 +413,9 @@ func (c *Client) TranslateToSQL(ctx context.Context, naturalLanguage string, sch
 		messagesInterface[i] = msg
 	}
 
-	// Create request
-	reqBody := ChatRequest{
-		Model:    c.model,
-		Messages: messagesInterface,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
+	chatResp, err := c.complete(ctx, messagesInterface, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Build the full API URL
-	apiURL := c.buildAPIURL()
-
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	// Execute request with retry
-	var resp *http.Response
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		resp, err = c.client.Do(req)
-		if err == nil {
-			break
-		}
-		if i < maxRetries-1 {
-			time.Sleep(time.Duration(i+1) * time.Second)
-		}
-	}
-	if err != nil {
-		return "", fmt.Errorf("request failed after retries: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Check for API errors
-	if chatResp.Error != nil {
-		return "", fmt.Errorf("API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
-	}
-
-	// Extract SQL
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		return "", err
 	}
 
 	sql := chatResp.Choices[0].Message.Content
@@ -588,28 +429,6 @@ func (c *Client) TranslateToSQL(ctx context.Context, naturalLanguage string, sch
 	return sql, nil
 }
 
-// buildAPIURL builds the full API URL from the base URL
-// Handles different URL formats:
-// - https://api.openai.com/v1 -> https://api.openai.com/v1/chat/completions
-// - https://api.openai.com/v1/chat/completions -> https://api.openai.com/v1/chat/completions (no change)
-// - https://api.example.com -> https://api.example.com/v1/chat/completions
-func (c *Client) buildAPIURL() string {
-	baseURL := strings.TrimSuffix(c.baseURL, "/")
-
-	// If URL already ends with /chat/completions, use it as-is
-	if strings.HasSuffix(baseURL, "/chat/completions") {
-		return baseURL
-	}
-
-	// If URL ends with /v1, append /chat/completions
-	if strings.HasSuffix(baseURL, "/v1") {
-		return baseURL + "/chat/completions"
-	}
-
-	// Otherwise, append /v1/chat/completions
-	return baseURL + "/v1/chat/completions"
-}
-
 // cleanSQL removes markdown code block markers and extra whitespace from SQL
 func cleanSQL(sql string) string {
 	sql = strings.TrimSpace(sql)
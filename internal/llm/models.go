@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ListModels returns the model IDs offered by the provider's models endpoint, sorted by name
+// Both supported APIs list models at /v1/models; an error means the list is not available,
+// and callers should let the user type a model name instead.
+func ListModels(ctx context.Context, providerName, baseURL, apiKey string) ([]string, error) {
+	headers := map[string]string{"Authorization": "Bearer " + apiKey}
+	if providerName == ProviderAnthropic {
+		headers = map[string]string{"x-api-key": apiKey, "anthropic-version": anthropicVersion}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", buildModelsURL(baseURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if providerName == ProviderAnthropic {
+		// The Messages API pages its list; ask for as much as one page allows
+		query := req.URL.Query()
+		query.Set("limit", "1000")
+		req.URL.RawQuery = query.Encode()
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse models list: %w", err)
+	}
+	models := make([]string, 0, len(list.Data))
+	for _, model := range list.Data {
+		if model.ID != "" {
+			models = append(models, model.ID)
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// buildModelsURL builds the models endpoint URL from the base URL
+// - https://api.openai.com/v1 -> https://api.openai.com/v1/models
+// - https://api.openai.com/v1/chat/completions -> https://api.openai.com/v1/models
+// - https://api.anthropic.com -> https://api.anthropic.com/v1/models
+func buildModelsURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	baseURL = strings.TrimSuffix(baseURL, "/chat/completions")
+	baseURL = strings.TrimSuffix(baseURL, "/messages")
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL + "/models"
+	}
+	return baseURL + "/v1/models"
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestListModels tests listing models from the models endpoint
+func TestListModels(t *testing.T) {
+	var lastRequest *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"data":[{"id":"gpt-b"},{"id":"gpt-a"},{"id":""}]}`)
+	}))
+	defer server.Close()
+
+	t.Run("openai", func(t *testing.T) {
+		models, err := ListModels(context.Background(), ProviderOpenAI, server.URL+"/v1/chat/completions", "key")
+		if err != nil {
+			t.Fatalf("ListModels failed: %v", err)
+		}
+		if len(models) != 2 || models[0] != "gpt-a" || models[1] != "gpt-b" {
+			t.Errorf("Expected sorted model IDs, got %v", models)
+		}
+		if lastRequest.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("Expected bearer token, got %v", lastRequest.Header)
+		}
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		if _, err := ListModels(context.Background(), ProviderAnthropic, server.URL, "key"); err != nil {
+			t.Fatalf("ListModels failed: %v", err)
+		}
+		if lastRequest.Header.Get("x-api-key") != "key" || lastRequest.Header.Get("anthropic-version") == "" {
+			t.Errorf("Expected Messages API headers, got %v", lastRequest.Header)
+		}
+	})
+
+	t.Run("endpoint missing", func(t *testing.T) {
+		if _, err := ListModels(context.Background(), ProviderOpenAI, server.URL+"/api", "key"); err == nil {
+			t.Error("Expected error when the models endpoint is missing")
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// openAIProvider talks to OpenAI-compatible chat/completions APIs
+type openAIProvider struct {
+	baseURL      string
+	apiKey       string
+	client       *http.Client
+	streamClient *http.Client
+}
+
+// Name returns the provider name
+func (p *openAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+// Complete sends a chat/completions request
+func (p *openAIProvider) Complete(ctx context.Context, model string, messages []interface{}, tools []Function) (*ChatResponse, error) {
+	body, err := postJSON(ctx, p.client, buildAPIURL(p.baseURL), p.headers(), buildOpenAIRequest(model, messages, tools))
+	if err != nil {
+		return nil, err
+	}
+	return parseOpenAIResponse(body)
+}
+
+// Stream sends a chat/completions request with stream enabled
+func (p *openAIProvider) Stream(ctx context.Context, model string, messages []interface{}, tools []Function, onText func(string)) (*ChatResponse, error) {
+	reqBody := buildOpenAIRequest(model, messages, tools)
+	reqBody.Stream = true
+	reqBody.StreamOptions = &StreamOptions{IncludeUsage: true}
+
+	resp, err := send(ctx, p.streamClient, buildAPIURL(p.baseURL), p.headers(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	acc := newStreamAccumulator(onText)
+	if !isEventStream(resp) {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		chatResp, err := parseOpenAIResponse(body)
+		if err != nil {
+			return nil, err
+		}
+		acc.addText(chatResp.Choices[0].Message.Content)
+		return chatResp, nil
+	}
+
+	err = readSSE(resp.Body, func(event, data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *Usage `json:"usage"`
+			Error *struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s (type: %s)", chunk.Error.Message, chunk.Error.Type)
+		}
+		if chunk.Usage != nil {
+			acc.usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			acc.addText(choice.Delta.Content)
+			for _, delta := range choice.Delta.ToolCalls {
+				call := acc.toolCall(delta.Index)
+				if delta.ID != "" {
+					call.ID = delta.ID
+				}
+				call.Function.Name += delta.Function.Name
+				call.Function.Arguments += delta.Function.Arguments
+			}
+			if choice.FinishReason != "" {
+				acc.finishReason = choice.FinishReason
+			}
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return acc.response(), ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stream failed: %w", err)
+	}
+	return acc.response(), nil
+}
+
+// headers returns the authentication headers
+func (p *openAIProvider) headers() map[string]string {
+	return map[string]string{"Authorization": "Bearer " + p.apiKey}
+}
+
+// buildOpenAIRequest builds a chat/completions request
+func buildOpenAIRequest(model string, messages []interface{}, tools []Function) ChatRequest {
+	normalized := normalizeMessages(messages)
+	reqBody := ChatRequest{
+		Model:    model,
+		Messages: make([]interface{}, len(normalized)),
+	}
+	for i, msg := range normalized {
+		reqBody.Messages[i] = msg
+	}
+	for _, tool := range tools {
+		reqBody.Tools = append(reqBody.Tools, struct {
+			Type     string   `json:"type"`
+			Function Function `json:"function"`
+		}{
+			Type:     "function",
+			Function: tool,
+		})
+	}
+	if len(tools) > 0 {
+		reqBody.ToolChoice = "auto" // Let LLM decide when to use tools
+	}
+	return reqBody
+}
+
+// parseOpenAIResponse parses a chat/completions response
+func parseOpenAIResponse(body []byte) (*ChatResponse, error) {
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if chatResp.Error != nil {
+		return nil, fmt.Errorf("API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
+	}
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+	return &chatResp, nil
+}
+
+// buildAPIURL builds the full API URL from the base URL
+// Handles different URL formats:
+// - https://api.openai.com/v1 -> https://api.openai.com/v1/chat/completions
+// - https://api.openai.com/v1/chat/completions -> https://api.openai.com/v1/chat/completions (no change)
+// - https://api.example.com -> https://api.example.com/v1/chat/completions
+func buildAPIURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	// If URL already ends with /chat/completions, use it as-is
+	if strings.HasSuffix(baseURL, "/chat/completions") {
+		return baseURL
+	}
+
+	// If URL ends with /v1, append /chat/completions
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL + "/chat/completions"
+	}
+
+	// Otherwise, append /v1/chat/completions
+	return baseURL + "/v1/chat/completions"
+}
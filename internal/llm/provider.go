@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Supported provider names
+const (
+	ProviderOpenAI    = "openai"    // OpenAI-compatible chat/completions API (default)
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+)
+
+// Provider sends chat requests to one kind of LLM API
+// Messages are always given in the OpenAI chat format: role and content, tool_calls on assistant
+// messages and tool messages with tool_call_id. Providers translate them to their own wire format
+// and translate the reply back into a ChatResponse.
+type Provider interface {
+	// Name returns the provider name, e.g. "openai"
+	Name() string
+	// Complete sends messages and the available tools to model and returns its reply
+	Complete(ctx context.Context, model string, messages []interface{}, tools []Function) (*ChatResponse, error)
+}
+
+// StreamingProvider is a Provider that can stream replies as server-sent events
+type StreamingProvider interface {
+	Provider
+	// Stream sends a request like Complete and calls onText with each piece of assistant text as it arrives
+	// If ctx is cancelled mid-stream, the partial reply is returned together with the context error.
+	Stream(ctx context.Context, model string, messages []interface{}, tools []Function, onText func(string)) (*ChatResponse, error)
+}
+
+// NewProvider creates the provider with the given name; an empty name selects the OpenAI-compatible API
+func NewProvider(name, baseURL, apiKey string) (Provider, error) {
+	retries := &atomic.Int32{}
+	retries.Store(DefaultMaxRetries)
+	return newProvider(name, baseURL, apiKey, retries)
+}
+
+// newProvider creates a provider whose requests are retried up to maxRetries times
+func newProvider(name, baseURL, apiKey string, maxRetries *atomic.Int32) (Provider, error) {
+	httpClient := &http.Client{
+		Transport: &retryTransport{base: http.DefaultTransport, maxRetries: maxRetries, timeout: 60 * time.Second},
+	}
+	// Streams are not bounded by a total timeout: long replies keep the connection busy, and the
+	// caller cancels the context to stop them
+	streamClient := &http.Client{
+		Transport: &retryTransport{base: http.DefaultTransport, maxRetries: maxRetries},
+	}
+	switch name {
+	case "", ProviderOpenAI:
+		return &openAIProvider{baseURL: baseURL, apiKey: apiKey, client: httpClient, streamClient: streamClient}, nil
+	case ProviderAnthropic:
+		return &anthropicProvider{baseURL: baseURL, apiKey: apiKey, client: httpClient, streamClient: streamClient}, nil
+	}
+	return nil, fmt.Errorf("unknown LLM provider %q (use %s or %s)", name, ProviderOpenAI, ProviderAnthropic)
+}
+
+// postJSON sends body to url and returns the response body
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}) ([]byte, error) {
+	resp, err := send(ctx, client, url, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return respBody, nil
+}
+
+// send posts body as JSON to url and returns the response if its status is 200 OK
+// Retries are left to the client's transport; other error statuses are returned as *APIError.
+// The caller closes the response body.
+func send(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, respBody)
+	}
+	return resp, nil
+}
+
+// normalizeMessages converts messages to maps whose content is always a string
+// This is critical for LLM API compatibility: messages restored from a session or built by
+// tool handlers may carry structured content.
+func normalizeMessages(messages []interface{}) []map[string]interface{} {
+	normalized := make([]map[string]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		var msgMap map[string]interface{}
+
+		// Convert to map if needed
+		if m, ok := msg.(map[string]interface{}); ok {
+			msgMap = m
+		} else if chatMsg, ok := msg.(ChatMessage); ok {
+			msgMap = map[string]interface{}{
+				"role":    chatMsg.Role,
+				"content": chatMsg.Content,
+			}
+		} else {
+			// Unknown type - try to convert via JSON
+			jsonBytes, err := json.Marshal(msg)
+			if err != nil || json.Unmarshal(jsonBytes, &msgMap) != nil {
+				continue // Skip if conversion fails
+			}
+		}
+
+		// Ensure content field is always a string
+		if content, exists := msgMap["content"]; exists && content != nil {
+			if _, isString := content.(string); !isString {
+				if jsonBytes, err := json.Marshal(content); err == nil {
+					msgMap["content"] = string(jsonBytes)
+				} else {
+					msgMap["content"] = fmt.Sprintf("%v", content)
+				}
+			}
+		}
+
+		normalized = append(normalized, msgMap)
+	}
+	return normalized
+}
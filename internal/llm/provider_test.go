@@ -0,0 +1,183 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// captureServer starts a stand-in API that records the last request and replies with reply
+func captureServer(t *testing.T, reply string) (*httptest.Server, *http.Request, map[string]interface{}) {
+	t.Helper()
+	var lastRequest http.Request
+	lastBody := map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = *r
+		data, _ := io.ReadAll(r.Body)
+		for k := range lastBody {
+			delete(lastBody, k)
+		}
+		json.Unmarshal(data, &lastBody)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(server.Close)
+	return server, &lastRequest, lastBody
+}
+
+// toolConversation is an OpenAI-style conversation with a completed tool call
+func toolConversation() []interface{} {
+	call := ToolCall{ID: "call_1", Type: "function"}
+	call.Function.Name = "execute_sql"
+	call.Function.Arguments = `{"sql":"SELECT 1"}`
+	return []interface{}{
+		ChatMessage{Role: "system", Content: "You are helpful."},
+		ChatMessage{Role: "user", Content: "Run a query"},
+		map[string]interface{}{"role": "assistant", "content": "", "tool_calls": []ToolCall{call}},
+		map[string]interface{}{"role": "tool", "tool_call_id": "call_1", "content": `{"status":"success"}`},
+		map[string]interface{}{"role": "system", "content": "Results are displayed."},
+	}
+}
+
+var testTools = []Function{{
+	Name:        "execute_sql",
+	Description: "Run SQL",
+	Parameters:  map[string]interface{}{"type": "object", "properties": map[string]interface{}{"sql": map[string]interface{}{"type": "string"}}},
+}}
+
+// TestOpenAIProvider tests requests to an OpenAI-compatible API
+func TestOpenAIProvider(t *testing.T) {
+	server, req, body := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"done"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`)
+	client, err := NewProviderClient(ProviderOpenAI, server.URL+"/v1", "key", "gpt-test")
+	if err != nil {
+		t.Fatalf("NewProviderClient failed: %v", err)
+	}
+
+	resp, err := client.ChatWithTools(context.Background(), toolConversation(), testTools)
+	if err != nil {
+		t.Fatalf("ChatWithTools failed: %v", err)
+	}
+	if req.URL.Path != "/v1/chat/completions" || req.Header.Get("Authorization") != "Bearer key" {
+		t.Errorf("Expected bearer request to /v1/chat/completions, got %s %v", req.URL.Path, req.Header)
+	}
+	if body["tool_choice"] != "auto" || len(body["tools"].([]interface{})) != 1 {
+		t.Errorf("Expected tools with tool_choice auto, got %v", body)
+	}
+	if resp.Choices[0].Message.Content != "done" || resp.Usage.TotalTokens != 12 {
+		t.Errorf("Expected content and usage, got %+v", resp)
+	}
+
+	t.Run("no tool_choice without tools", func(t *testing.T) {
+		if _, err := client.Complete(context.Background(), "system", "hi"); err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+		if _, ok := body["tool_choice"]; ok {
+			t.Errorf("Expected no tool_choice, got %v", body["tool_choice"])
+		}
+	})
+}
+
+// TestAnthropicProvider tests requests to the Anthropic Messages API
+func TestAnthropicProvider(t *testing.T) {
+	server, req, body := captureServer(t, `{
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_2", "name": "execute_sql", "input": {"sql": "SELECT 2"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 30, "output_tokens": 5}
+	}`)
+	client, err := NewProviderClient(ProviderAnthropic, server.URL, "key", "claude-test")
+	if err != nil {
+		t.Fatalf("NewProviderClient failed: %v", err)
+	}
+
+	resp, err := client.ChatWithTools(context.Background(), toolConversation(), testTools)
+	if err != nil {
+		t.Fatalf("ChatWithTools failed: %v", err)
+	}
+
+	t.Run("request", func(t *testing.T) {
+		if req.URL.Path != "/v1/messages" || req.Header.Get("x-api-key") != "key" || req.Header.Get("anthropic-version") == "" {
+			t.Errorf("Expected Messages API request, got %s %v", req.URL.Path, req.Header)
+		}
+		if body["system"] != "You are helpful." {
+			t.Errorf("Expected leading system message as system prompt, got %v", body["system"])
+		}
+		if body["max_tokens"] == nil {
+			t.Error("Expected max_tokens to be set")
+		}
+		tools := body["tools"].([]interface{})
+		if tools[0].(map[string]interface{})["input_schema"] == nil {
+			t.Errorf("Expected input_schema, got %v", tools[0])
+		}
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		messages := body["messages"].([]interface{})
+		if len(messages) != 3 {
+			t.Fatalf("Expected user, assistant and user turns, got %v", messages)
+		}
+		assistant := messages[1].(map[string]interface{})
+		toolUse := assistant["content"].([]interface{})[0].(map[string]interface{})
+		if assistant["role"] != "assistant" || toolUse["type"] != "tool_use" || toolUse["id"] != "call_1" {
+			t.Errorf("Expected tool_use block, got %v", assistant)
+		}
+		if toolUse["input"].(map[string]interface{})["sql"] != "SELECT 1" {
+			t.Errorf("Expected parsed tool input, got %v", toolUse["input"])
+		}
+		// The tool result and the later system message are merged into one user turn
+		results := messages[2].(map[string]interface{})["content"].([]interface{})
+		if len(results) != 2 {
+			t.Fatalf("Expected tool_result and text blocks, got %v", results)
+		}
+		toolResult := results[0].(map[string]interface{})
+		if toolResult["type"] != "tool_result" || toolResult["tool_use_id"] != "call_1" {
+			t.Errorf("Expected tool_result block, got %v", toolResult)
+		}
+		if results[1].(map[string]interface{})["text"] != "Results are displayed." {
+			t.Errorf("Expected later system message as user text, got %v", results[1])
+		}
+	})
+
+	t.Run("response", func(t *testing.T) {
+		choice := resp.Choices[0]
+		if choice.FinishReason != "tool_calls" || choice.Message.Content != "Let me check." {
+			t.Errorf("Expected tool_calls finish with text, got %+v", choice)
+		}
+		if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].ID != "toolu_2" {
+			t.Fatalf("Expected one tool call, got %+v", choice.Message.ToolCalls)
+		}
+		args, err := choice.Message.ToolCalls[0].ParseArguments()
+		if err != nil || args["sql"] != "SELECT 2" {
+			t.Errorf("Expected tool arguments, got %v (%v)", args, err)
+		}
+		if resp.Usage == nil || resp.Usage.TotalTokens != 35 {
+			t.Errorf("Expected usage of 35 tokens, got %+v", resp.Usage)
+		}
+	})
+}
+
+// TestAnthropicProvider_Error tests reporting Messages API errors
+func TestAnthropicProvider_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`)
+	}))
+	defer server.Close()
+
+	client, _ := NewProviderClient(ProviderAnthropic, server.URL+"/v1", "key", "claude-test")
+	if _, err := client.Complete(context.Background(), "system", "hi"); err == nil {
+		t.Error("Expected error for status 400")
+	}
+}
+
+// TestNewProvider_Unknown tests rejecting unknown provider names
+func TestNewProvider_Unknown(t *testing.T) {
+	if _, err := NewProvider("gemini", "http://localhost", "key"); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+}
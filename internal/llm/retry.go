@@ -0,0 +1,224 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is how often a failed request is retried unless configured otherwise
+	DefaultMaxRetries = 3
+	// retryBaseWait is the backoff before the first retry; it doubles with every further retry
+	retryBaseWait = time.Second
+	// maxRetryWait caps the backoff and the wait requested by a Retry-After header
+	maxRetryWait = 60 * time.Second
+)
+
+// Retry describes a request that is about to be retried
+type Retry struct {
+	Attempt    int           // 1 for the first retry
+	MaxRetries int           // Retries allowed in total
+	Wait       time.Duration // Time until the request is sent again
+	StatusCode int           // Status of the failed attempt; 0 if no response was received
+	Err        error         // Set if no response was received
+}
+
+// Reason returns a short description of why the request is retried
+func (r Retry) Reason() string {
+	switch {
+	case r.StatusCode == http.StatusTooManyRequests:
+		return "rate limited"
+	case r.StatusCode == 529 || r.StatusCode == http.StatusServiceUnavailable:
+		return "server overloaded"
+	case r.StatusCode != 0:
+		return fmt.Sprintf("server error %d", r.StatusCode)
+	}
+	return "connection failed"
+}
+
+type retryNotifyKey struct{}
+
+// WithRetryNotify returns a context whose LLM requests call notify before each retry, e.g. to show
+// the wait in a spinner
+func WithRetryNotify(ctx context.Context, notify func(Retry)) context.Context {
+	return context.WithValue(ctx, retryNotifyKey{}, notify)
+}
+
+// retryTransport retries requests that failed without a response, were rate limited or hit a
+// server error, with exponential backoff and jitter. A Retry-After header overrides the backoff.
+// The transports of one client share maxRetries.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries *atomic.Int32
+	timeout    time.Duration // Per attempt, until the response body is closed; 0 for no limit
+}
+
+// RoundTrip sends req, retrying it as needed
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	maxRetries := int(t.maxRetries.Load())
+	notify, _ := req.Context().Value(retryNotifyKey{}).(func(Retry))
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.send(req, attempt)
+		if attempt >= maxRetries || req.Context().Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+		// Bodies of requests built with bytes readers can be replayed; others cannot
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		retry := Retry{Attempt: attempt + 1, MaxRetries: maxRetries, Wait: backoff(attempt), Err: err}
+		if resp != nil {
+			retry.StatusCode = resp.StatusCode
+			if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				retry.Wait = wait
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		if notify != nil {
+			notify(retry)
+		}
+
+		timer := time.NewTimer(retry.Wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// send makes one attempt with a fresh copy of the request body
+func (t *retryTransport) send(req *http.Request, attempt int) (*http.Response, error) {
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the timeout of an attempt once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryable reports whether an attempt failed in a way that may succeed when repeated
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+// backoff returns the wait before retry attempt+1: the base wait doubled per attempt, of which
+// a random half is skipped so that clients hitting the same limit do not retry in lockstep
+func backoff(attempt int) time.Duration {
+	wait := retryBaseWait << uint(attempt)
+	if wait <= 0 || wait > maxRetryWait {
+		wait = maxRetryWait
+	}
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	var wait time.Duration
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		wait = time.Duration(seconds * float64(time.Second))
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	} else {
+		return 0, false
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxRetryWait {
+		wait = maxRetryWait
+	}
+	return wait, true
+}
+
+// APIError is a request the LLM API answered with an error status
+type APIError struct {
+	StatusCode int
+	Message    string // Error message from the response body, or the body itself
+}
+
+// Error describes the failure and, where the fix is on the user's side, how to fix it
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Message)
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return msg + " (check the LLM API key: run config and choose key)"
+	case e.StatusCode == http.StatusNotFound:
+		return msg + " (check the LLM API URL, provider and model name: run config)"
+	case e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity:
+		return msg + " (the API rejected the request: check the model name and provider in config)"
+	case e.StatusCode == http.StatusTooManyRequests:
+		return msg + " (still rate limited after retrying: wait a moment and try again, or raise llm.max_retries)"
+	case e.StatusCode >= 500:
+		return msg + " (the LLM service is failing: try again later)"
+	}
+	return msg
+}
+
+// newAPIError builds an APIError from an error response body
+// Both supported APIs report errors as {"error": {"message": ...}}.
+func newAPIError(statusCode int, body []byte) *APIError {
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &parsed) == nil && parsed.Error.Message != "" {
+		message = parsed.Error.Message
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	return &APIError{StatusCode: statusCode, Message: message}
+}
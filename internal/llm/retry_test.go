@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyServer starts a stand-in API that fails with the given statuses before answering normally
+func flakyServer(t *testing.T, statuses ...int) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"model":"m"`) {
+			t.Errorf("Expected the request body on every attempt, got %s", body)
+		}
+		if requests <= len(statuses) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(statuses[requests-1])
+			io.WriteString(w, `{"error":{"message":"slow down","type":"rate_limit_error"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// TestRetryTransport tests retrying rate-limited and failed requests
+func TestRetryTransport(t *testing.T) {
+	t.Run("retries rate limits and server errors", func(t *testing.T) {
+		server, requests := flakyServer(t, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+		var retries []Retry
+		ctx := WithRetryNotify(context.Background(), func(retry Retry) {
+			retries = append(retries, retry)
+		})
+
+		reply, err := NewClient(server.URL, "key", "m").Complete(ctx, "system", "hi")
+		if err != nil || reply != "ok" {
+			t.Fatalf("Expected reply after retries, got %q (%v)", reply, err)
+		}
+		if *requests != 3 || len(retries) != 2 {
+			t.Fatalf("Expected 3 requests and 2 retries, got %d and %d", *requests, len(retries))
+		}
+		if retries[0].Reason() != "rate limited" || retries[0].Attempt != 1 || retries[0].MaxRetries != DefaultMaxRetries {
+			t.Errorf("Expected first retry for rate limit, got %+v", retries[0])
+		}
+		if retries[1].StatusCode != http.StatusServiceUnavailable || retries[1].Wait != 0 {
+			t.Errorf("Expected second retry after Retry-After of 0, got %+v", retries[1])
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		server, requests := flakyServer(t, 500, 500, 500)
+		client := NewClient(server.URL, "key", "m")
+		client.SetMaxRetries(1)
+
+		_, err := client.Complete(context.Background(), "system", "hi")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 {
+			t.Fatalf("Expected APIError with status 500, got %v", err)
+		}
+		if *requests != 2 {
+			t.Errorf("Expected 2 requests, got %d", *requests)
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		server, requests := flakyServer(t, http.StatusUnauthorized)
+
+		_, err := NewClient(server.URL, "key", "m").Complete(context.Background(), "system", "hi")
+		if err == nil || !strings.Contains(err.Error(), "slow down") || !strings.Contains(err.Error(), "API key") {
+			t.Errorf("Expected error with message and hint, got %v", err)
+		}
+		if *requests != 1 {
+			t.Errorf("Expected 1 request, got %d", *requests)
+		}
+	})
+
+	t.Run("stops waiting when cancelled", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx = WithRetryNotify(ctx, func(Retry) { cancel() })
+		start := time.Now()
+		_, err := NewClient(server.URL, "key", "m").Complete(ctx, "system", "hi")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Errorf("Expected the wait to end on cancel, took %v", time.Since(start))
+		}
+	})
+}
+
+// TestRetryAfter tests parsing Retry-After headers
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"2", 2 * time.Second, true},
+		{"0.5", 500 * time.Millisecond, true},
+		{"3600", maxRetryWait, true},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q): expected %v, %v, got %v, %v", tt.value, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+// TestBackoff tests that waits grow exponentially within their jitter range
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		full := retryBaseWait << uint(attempt)
+		if full > maxRetryWait {
+			full = maxRetryWait
+		}
+		wait := backoff(attempt)
+		if wait < full/2 || wait > full {
+			t.Errorf("Expected backoff(%d) between %v and %v, got %v", attempt, full/2, full, wait)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// errStreamDone stops reading a stream at its end marker
+var errStreamDone = errors.New("stream done")
+
+// maxSSELine bounds one line of a server-sent event stream
+const maxSSELine = 4 * 1024 * 1024
+
+// isEventStream reports whether a response is a server-sent event stream
+// Servers that ignore the stream flag answer with a regular JSON body instead.
+func isEventStream(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// readSSE reads server-sent events from r and passes the event name and data of each to handle
+// Reading stops at the end of r, when handle returns an error, or when it returns errStreamDone.
+func readSSE(r io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELine)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := handle(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				if err == errStreamDone {
+					return nil
+				}
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment, used by some servers as keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := dispatch(); err != nil && err != errStreamDone {
+		return err
+	}
+	return nil
+}
+
+// streamAccumulator assembles a streamed reply into a ChatResponse
+type streamAccumulator struct {
+	onText       func(string)
+	text         strings.Builder
+	toolCalls    map[int]*ToolCall // By the index the stream assigns to each tool call
+	order        []int
+	finishReason string
+	usage        *Usage
+}
+
+func newStreamAccumulator(onText func(string)) *streamAccumulator {
+	return &streamAccumulator{onText: onText, toolCalls: map[int]*ToolCall{}}
+}
+
+// addText appends assistant text and passes it on
+func (a *streamAccumulator) addText(text string) {
+	if text == "" {
+		return
+	}
+	a.text.WriteString(text)
+	if a.onText != nil {
+		a.onText(text)
+	}
+}
+
+// toolCall returns the tool call with the given stream index, starting it if needed
+func (a *streamAccumulator) toolCall(index int) *ToolCall {
+	call, ok := a.toolCalls[index]
+	if !ok {
+		call = &ToolCall{Type: "function"}
+		a.toolCalls[index] = call
+		a.order = append(a.order, index)
+	}
+	return call
+}
+
+// response returns the reply received so far
+func (a *streamAccumulator) response() *ChatResponse {
+	message := ResponseMessage{Role: "assistant", Content: a.text.String()}
+	for _, index := range a.order {
+		call := *a.toolCalls[index]
+		if strings.TrimSpace(call.Function.Arguments) == "" {
+			call.Function.Arguments = "{}"
+		}
+		message.ToolCalls = append(message.ToolCalls, call)
+	}
+
+	finishReason := a.finishReason
+	if finishReason == "" {
+		finishReason = "stop"
+		if len(message.ToolCalls) > 0 {
+			finishReason = "tool_calls"
+		}
+	}
+	return &ChatResponse{
+		Choices: []Choice{{Message: message, FinishReason: finishReason}},
+		Usage:   a.usage,
+	}
+}
@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sseServer starts a stand-in API that answers streamed requests with events and other requests with reply
+func sseServer(t *testing.T, events []string, reply string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		if body["stream"] != true {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, reply)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			io.WriteString(w, event+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestChatWithToolsStream_OpenAI tests assembling an OpenAI-style stream
+func TestChatWithToolsStream_OpenAI(t *testing.T) {
+	server := sseServer(t, []string{
+		`data: {"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`data: {"choices":[{"delta":{"content":"lo"}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"execute_sql","arguments":"{\"sql\":"}}]}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"SELECT 1\"}"}}]}}]}`,
+		`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: {"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`,
+		`data: [DONE]`,
+	}, "")
+	client := NewClient(server.URL, "key", "gpt-test")
+
+	var pieces []string
+	resp, err := client.ChatWithToolsStream(context.Background(), toolConversation(), testTools, func(text string) {
+		pieces = append(pieces, text)
+	})
+	if err != nil {
+		t.Fatalf("ChatWithToolsStream failed: %v", err)
+	}
+	if strings.Join(pieces, "|") != "Hel|lo" {
+		t.Errorf("Expected text in two pieces, got %q", pieces)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.Content != "Hello" || choice.FinishReason != "tool_calls" {
+		t.Errorf("Expected assembled content and finish reason, got %+v", choice)
+	}
+	if len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call, got %+v", choice.Message.ToolCalls)
+	}
+	args, err := choice.Message.ToolCalls[0].ParseArguments()
+	if err != nil || args["sql"] != "SELECT 1" || choice.Message.ToolCalls[0].ID != "call_1" {
+		t.Errorf("Expected assembled tool call, got %+v (%v)", choice.Message.ToolCalls[0], err)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 10 {
+		t.Errorf("Expected usage from the last chunk, got %+v", resp.Usage)
+	}
+}
+
+// TestChatWithToolsStream_Anthropic tests assembling a Messages API stream
+func TestChatWithToolsStream_Anthropic(t *testing.T) {
+	server := sseServer(t, []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":20}}}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Checking\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"execute_sql\",\"input\":{}}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"sql\\\": \\\"SEL\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"ECT 1\\\"}\"}}",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":9}}",
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+	}, "")
+	client, _ := NewProviderClient(ProviderAnthropic, server.URL, "key", "claude-test")
+
+	var text strings.Builder
+	resp, err := client.ChatWithToolsStream(context.Background(), toolConversation(), testTools, func(piece string) {
+		text.WriteString(piece)
+	})
+	if err != nil {
+		t.Fatalf("ChatWithToolsStream failed: %v", err)
+	}
+	if text.String() != "Checking" {
+		t.Errorf("Expected streamed text, got %q", text.String())
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call, got %+v", choice)
+	}
+	args, err := choice.Message.ToolCalls[0].ParseArguments()
+	if err != nil || args["sql"] != "SELECT 1" {
+		t.Errorf("Expected assembled tool input, got %v (%v)", args, err)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 29 {
+		t.Errorf("Expected usage of 29 tokens, got %+v", resp.Usage)
+	}
+}
+
+// TestChatWithToolsStream_Fallback tests falling back to regular requests
+func TestChatWithToolsStream_Fallback(t *testing.T) {
+	reply := `{"choices":[{"message":{"role":"assistant","content":"whole reply"},"finish_reason":"stop"}]}`
+
+	t.Run("server ignores stream flag", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, reply)
+		}))
+		defer server.Close()
+
+		var text string
+		resp, err := NewClient(server.URL, "key", "m").ChatWithToolsStream(context.Background(), toolConversation(), nil, func(piece string) {
+			text += piece
+		})
+		if err != nil || resp.Choices[0].Message.Content != "whole reply" || text != "whole reply" {
+			t.Errorf("Expected whole reply, got %v, %q (%v)", resp, text, err)
+		}
+	})
+
+	t.Run("server rejects streaming", func(t *testing.T) {
+		streamed := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(data), `"stream":true`) {
+				streamed++
+				http.Error(w, `{"error":{"message":"stream not supported"}}`, http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, reply)
+		}))
+		defer server.Close()
+
+		client := NewClient(server.URL, "key", "m")
+		for i := 0; i < 2; i++ {
+			resp, err := client.ChatWithToolsStream(context.Background(), toolConversation(), nil, nil)
+			if err != nil || resp.Choices[0].Message.Content != "whole reply" {
+				t.Fatalf("Expected fallback reply, got %v (%v)", resp, err)
+			}
+		}
+		if streamed != 1 {
+			t.Errorf("Expected streaming to be tried once, got %d", streamed)
+		}
+	})
+}
+
+// TestChatWithToolsStream_Cancel tests keeping partial text when the request is cancelled
+func TestChatWithToolsStream_Cancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial \"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := NewClient(server.URL, "key", "m").ChatWithToolsStream(ctx, toolConversation(), nil, func(string) {
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if resp == nil || resp.Choices[0].Message.Content != "partial " {
+		t.Errorf("Expected partial text, got %+v", resp)
+	}
+}
@@ -0,0 +1,74 @@
+package llm
+
+import "context"
+
+// UsageStats accumulates the token usage of LLM calls
+type UsageStats struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	Calls            int `json:"calls"`
+	UnreportedCalls  int `json:"unreported_calls,omitempty"` // Calls whose response carried no token counts
+}
+
+// TotalTokens returns the prompt and completion tokens together
+func (s UsageStats) TotalTokens() int {
+	return s.PromptTokens + s.CompletionTokens
+}
+
+// Add returns the usage of both stats together
+func (s UsageStats) Add(other UsageStats) UsageStats {
+	return UsageStats{
+		PromptTokens:     s.PromptTokens + other.PromptTokens,
+		CompletionTokens: s.CompletionTokens + other.CompletionTokens,
+		Calls:            s.Calls + other.Calls,
+		UnreportedCalls:  s.UnreportedCalls + other.UnreportedCalls,
+	}
+}
+
+// Sub returns the usage added since earlier, a snapshot of the same stats
+func (s UsageStats) Sub(earlier UsageStats) UsageStats {
+	return UsageStats{
+		PromptTokens:     s.PromptTokens - earlier.PromptTokens,
+		CompletionTokens: s.CompletionTokens - earlier.CompletionTokens,
+		Calls:            s.Calls - earlier.Calls,
+		UnreportedCalls:  s.UnreportedCalls - earlier.UnreportedCalls,
+	}
+}
+
+// add counts one call with the usage its response reported
+func (s *UsageStats) add(usage *Usage) {
+	s.Calls++
+	if usage == nil || (usage.PromptTokens == 0 && usage.CompletionTokens == 0) {
+		s.UnreportedCalls++
+		return
+	}
+	s.PromptTokens += usage.PromptTokens
+	s.CompletionTokens += usage.CompletionTokens
+}
+
+// Usage returns the token usage of all calls made through the client so far
+// Take a snapshot before a piece of work and Sub it afterwards to get the usage of that work.
+func (c *Client) Usage() UsageStats {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	return c.usage
+}
+
+// recordUsage counts a call that received a response
+func (c *Client) recordUsage(resp *ChatResponse) {
+	if resp == nil {
+		return
+	}
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	c.usage.add(resp.Usage)
+}
+
+// complete sends a request through the provider and counts its usage
+func (c *Client) complete(ctx context.Context, messages []interface{}, tools []Function) (*ChatResponse, error) {
+	resp, err := c.provider.Complete(ctx, c.model, messages, tools)
+	if err == nil {
+		c.recordUsage(resp)
+	}
+	return resp, err
+}
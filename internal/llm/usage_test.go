@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestClient_Usage tests counting the token usage of all calls made through a client
+func TestClient_Usage(t *testing.T) {
+	withUsage := `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120}}`
+	withoutUsage := `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`
+	replies := []string{withUsage, withoutUsage, withUsage}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, replies[0])
+		replies = replies[1:]
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "key", "m")
+	if _, err := client.Complete(context.Background(), "system", "match skills"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	before := client.Usage()
+	if _, err := client.ChatWithTools(context.Background(), toolConversation(), nil); err != nil {
+		t.Fatalf("ChatWithTools failed: %v", err)
+	}
+	if _, err := client.ChatWithToolsStream(context.Background(), toolConversation(), nil, nil); err != nil {
+		t.Fatalf("ChatWithToolsStream failed: %v", err)
+	}
+
+	total := client.Usage()
+	if total.Calls != 3 || total.PromptTokens != 200 || total.CompletionTokens != 40 || total.UnreportedCalls != 1 {
+		t.Errorf("Expected usage of all calls, got %+v", total)
+	}
+	turn := total.Sub(before)
+	if turn.Calls != 2 || turn.TotalTokens() != 120 || turn.UnreportedCalls != 1 {
+		t.Errorf("Expected usage since the snapshot, got %+v", turn)
+	}
+}
@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helperServerEnv makes the test binary act as an MCP server (see TestMain)
+const helperServerEnv = "AIQ_MCP_HELPER_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperServerEnv) == "1" {
+		server := NewServer("helper", "1.0", &fakeHandler{})
+		if err := server.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// startHelper starts the test binary as an MCP server managed by m
+func startHelper(t *testing.T, m *Manager, name string) int {
+	t.Helper()
+	count, err := m.Start(context.Background(), name, os.Args[0], nil, []string{helperServerEnv + "=1"})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return count
+}
+
+// TestManager_Start tests starting external servers and listing their tools
+func TestManager_Start(t *testing.T) {
+	m := NewManager()
+	defer m.Close()
+
+	if count := startHelper(t, m, "tickets"); count != 1 {
+		t.Fatalf("Expected 1 tool, got %d", count)
+	}
+
+	functions := m.Functions()
+	if len(functions) != 1 {
+		t.Fatalf("Expected 1 function, got %d", len(functions))
+	}
+	if functions[0].Name != "tickets__echo" {
+		t.Errorf("Expected qualified name tickets__echo, got %s", functions[0].Name)
+	}
+	if !strings.HasPrefix(functions[0].Description, "[tickets]") {
+		t.Errorf("Expected description to name the server, got %q", functions[0].Description)
+	}
+	if !m.Has("tickets__echo") || m.Has("echo") {
+		t.Error("Expected only the qualified name to be routed")
+	}
+}
+
+// TestManager_Call tests routing tool calls to external servers
+func TestManager_Call(t *testing.T) {
+	m := NewManager()
+	defer m.Close()
+	startHelper(t, m, "tickets")
+
+	t.Run("success", func(t *testing.T) {
+		raw, err := m.Call(context.Background(), "tickets__echo", map[string]interface{}{"text": "hello", "risk_level": "low"})
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		var result map[string]interface{}
+		json.Unmarshal(raw, &result)
+		if result["status"] != "success" || result["output"] != "hello" {
+			t.Errorf("Expected success with output hello, got %v", result)
+		}
+	})
+
+	t.Run("tool errors are results", func(t *testing.T) {
+		raw, err := m.Call(context.Background(), "tickets__echo", map[string]interface{}{"fail": true})
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		var result map[string]interface{}
+		json.Unmarshal(raw, &result)
+		if result["status"] != "error" || result["error"] != "refused" {
+			t.Errorf("Expected error result refused, got %v", result)
+		}
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		if _, err := m.Call(context.Background(), "tickets__missing", nil); err == nil {
+			t.Error("Expected error for unknown tool")
+		}
+	})
+}
+
+// TestStartClient_Failure tests servers that cannot be started or exit early
+func TestStartClient_Failure(t *testing.T) {
+	t.Run("missing command", func(t *testing.T) {
+		if _, err := StartClient(context.Background(), "x", "/nonexistent/mcp-server", nil, nil); err == nil {
+			t.Error("Expected error for missing command")
+		}
+	})
+
+	t.Run("server exits", func(t *testing.T) {
+		_, err := StartClient(context.Background(), "x", "sh", []string{"-c", "echo boom >&2"}, nil)
+		if !errors.Is(err, ErrClientClosed) {
+			t.Fatalf("Expected ErrClientClosed, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "boom") {
+			t.Errorf("Expected stderr in error, got %v", err)
+		}
+	})
+}
+
+// TestClient_ReadLoopDuplicateResponse tests that a duplicate response does not block the read loop
+func TestClient_ReadLoopDuplicateResponse(t *testing.T) {
+	c := &Client{
+		pending: map[int]chan Response{1: make(chan Response, 1)},
+		done:    make(chan struct{}),
+	}
+	// Nobody receives the responses, so the second one finds the channel full
+	reply := `{"jsonrpc":"2.0","id":1,"result":{}}` + "\n"
+	go c.readLoop(strings.NewReader(reply + reply + `{"jsonrpc":"2.0","id":2,"result":{}}` + "\n"))
+
+	select {
+	case <-c.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the read loop to reach the end of the output")
+	}
+}
+
+// TestQualifiedToolName tests names offered to the LLM
+func TestQualifiedToolName(t *testing.T) {
+	tests := []struct {
+		server, tool, expected string
+	}{
+		{"tickets", "search", "tickets__search"},
+		{"my metrics", "get.value", "my_metrics__get_value"},
+	}
+	for _, tt := range tests {
+		if got := QualifiedToolName(tt.server, tt.tool); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+	if got := QualifiedToolName(strings.Repeat("s", 40), strings.Repeat("t", 40)); len(got) != 64 {
+		t.Errorf("Expected name truncated to 64 characters, got %d", len(got))
+	}
+}
@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aiq/aiq/internal/llm"
+)
+
+// toolNameSeparator joins server and tool names in the names offered to the LLM
+const toolNameSeparator = "__"
+
+// invalidNameChars matches characters not allowed in LLM function names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Manager holds the external MCP servers of a session and routes tool calls to them
+type Manager struct {
+	mu      sync.Mutex
+	clients []*Client
+	tools   map[string]externalTool // By qualified name
+}
+
+// externalTool is a tool of an external server
+type externalTool struct {
+	client *Client
+	tool   Tool
+}
+
+// NewManager creates a manager without servers
+func NewManager() *Manager {
+	return &Manager{tools: make(map[string]externalTool)}
+}
+
+// Start starts a server and registers its tools as <name>__<tool>
+// It returns the number of tools registered.
+func (m *Manager) Start(ctx context.Context, name, command string, args []string, env []string) (int, error) {
+	client, err := StartClient(ctx, name, command, args, env)
+	if err != nil {
+		return 0, err
+	}
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		client.Close()
+		return 0, fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients = append(m.clients, client)
+	for _, t := range tools {
+		m.tools[QualifiedToolName(name, t.Name)] = externalTool{client: client, tool: t}
+	}
+	return len(tools), nil
+}
+
+// QualifiedToolName returns the name under which a server's tool is offered to the LLM
+func QualifiedToolName(server, tool string) string {
+	name := invalidNameChars.ReplaceAllString(server, "_") + toolNameSeparator + invalidNameChars.ReplaceAllString(tool, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// Has reports whether name is an external tool
+func (m *Manager) Has(name string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.tools[name]
+	return ok
+}
+
+// Functions returns the external tools as LLM functions, sorted by name
+func (m *Manager) Functions() []llm.Function {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	functions := make([]llm.Function, 0, len(m.tools))
+	for name, t := range m.tools {
+		params := t.tool.InputSchema
+		if params == nil {
+			params = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		description := t.tool.Description
+		if description == "" {
+			description = t.tool.Name
+		}
+		functions = append(functions, llm.Function{
+			Name:        name,
+			Description: fmt.Sprintf("[%s] %s", t.client.Name(), description),
+			Parameters:  params,
+		})
+	}
+	sort.Slice(functions, func(i, j int) bool { return functions[i].Name < functions[j].Name })
+	return functions
+}
+
+// Call calls an external tool and returns a tool result for the LLM
+// Results flagged as errors by the server are returned as {"status":"error"} rather than a Go error.
+func (m *Manager) Call(ctx context.Context, name string, args map[string]interface{}) (json.RawMessage, error) {
+	m.mu.Lock()
+	t, ok := m.tools[name]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown tool: %s", name)
+	}
+
+	// risk_level is aiq's own annotation and not part of the server's schema
+	if _, ok := args["risk_level"]; ok {
+		args = copyWithout(args, "risk_level")
+	}
+
+	resultJSON := map[string]interface{}{"status": "success"}
+	result, err := t.client.CallTool(ctx, t.tool.Name, args)
+	switch {
+	case err != nil:
+		resultJSON["status"] = "error"
+		resultJSON["error"] = fmt.Sprintf("MCP server %s: %v", t.client.Name(), err)
+	case result.IsError:
+		resultJSON["status"] = "error"
+		resultJSON["error"] = result.Text()
+	default:
+		resultJSON["output"] = result.Text()
+	}
+	return json.Marshal(resultJSON)
+}
+
+// Close stops all servers
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = nil
+	m.tools = make(map[string]externalTool)
+	m.mu.Unlock()
+	for _, c := range clients {
+		c.Close()
+	}
+}
+
+func copyWithout(args map[string]interface{}, key string) map[string]interface{} {
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if !strings.EqualFold(k, key) {
+			out[k] = v
+		}
+	}
+	return out
+}
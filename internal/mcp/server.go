@@ -0,0 +1,213 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Handler provides the tools, resources and prompts of a server
+// Errors returned by CallTool are reported to the client as tool results with isError set.
+type Handler interface {
+	Tools() []Tool
+	CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error)
+	Resources() []Resource
+	ReadResource(ctx context.Context, uri string) ([]ResourceContents, error)
+	Prompts() []Prompt
+	GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error)
+}
+
+// Server serves a Handler over newline-delimited JSON-RPC (the MCP stdio transport)
+type Server struct {
+	info         Implementation
+	instructions string
+	handler      Handler
+
+	mu  sync.Mutex // Serializes writes to out
+	out io.Writer
+}
+
+// NewServer creates a server announcing itself with the given name and version
+func NewServer(name, version string, handler Handler) *Server {
+	return &Server{info: Implementation{Name: name, Version: version}, handler: handler}
+}
+
+// SetInstructions sets usage instructions returned to the client on initialize
+func (s *Server) SetInstructions(instructions string) {
+	s.instructions = instructions
+}
+
+// Serve reads requests from in and writes responses to out until in is closed or ctx is done
+// Requests are handled one at a time, in order.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.out = out
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.write(Response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}})
+			continue
+		}
+		result, rpcErr := s.handle(ctx, &req)
+		if req.IsNotification() {
+			continue
+		}
+
+		resp := Response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+		if rpcErr == nil {
+			data, err := json.Marshal(result)
+			if err != nil {
+				resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+			} else {
+				resp.Result = data
+			}
+		}
+		s.write(resp)
+	}
+	return scanner.Err()
+}
+
+// write sends one JSON line to the client
+func (s *Server) write(resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+	_, _ = s.out.Write(data)
+}
+
+// handle dispatches a request to the handler
+func (s *Server) handle(ctx context.Context, req *Request) (interface{}, *Error) {
+	switch req.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return InitializeResult{
+			ProtocolVersion: negotiateVersion(params.ProtocolVersion),
+			Capabilities: map[string]interface{}{
+				"tools":     map[string]interface{}{},
+				"resources": map[string]interface{}{},
+				"prompts":   map[string]interface{}{},
+			},
+			ServerInfo:   s.info,
+			Instructions: s.instructions,
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		return map[string]interface{}{"tools": nonNil(s.handler.Tools())}, nil
+
+	case "tools/call":
+		var params CallToolParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if !hasTool(s.handler.Tools(), params.Name) {
+			return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+		}
+		result, err := s.handler.CallTool(ctx, params.Name, params.Arguments)
+		if err != nil {
+			return ErrorResult(err.Error()), nil
+		}
+		return result, nil
+
+	case "resources/list":
+		return map[string]interface{}{"resources": nonNil(s.handler.Resources())}, nil
+
+	case "resources/templates/list":
+		return map[string]interface{}{"resourceTemplates": []interface{}{}}, nil
+
+	case "resources/read":
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		contents, err := s.handler.ReadResource(ctx, params.URI)
+		if err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return map[string]interface{}{"contents": contents}, nil
+
+	case "prompts/list":
+		return map[string]interface{}{"prompts": nonNil(s.handler.Prompts())}, nil
+
+	case "prompts/get":
+		var params struct {
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		}
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		result, err := s.handler.GetPrompt(ctx, params.Name, params.Arguments)
+		if err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		return result, nil
+	}
+
+	if req.IsNotification() {
+		// Notifications such as notifications/initialized need no handling
+		return nil, nil
+	}
+	return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+// negotiateVersion answers with the client's protocol version if supported, otherwise the newest one
+func negotiateVersion(requested string) string {
+	for _, v := range supportedVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return ProtocolVersion
+}
+
+func decodeParams(raw json.RawMessage, v interface{}) *Error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return nil
+}
+
+func hasTool(tools []Tool, name string) bool {
+	for _, t := range tools {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNil makes empty lists encode as [] rather than null
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
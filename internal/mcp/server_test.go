@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeHandler is a Handler with one tool, one resource and one prompt
+type fakeHandler struct {
+	calls []string
+}
+
+func (f *fakeHandler) Tools() []Tool {
+	return []Tool{{Name: "echo", InputSchema: map[string]interface{}{"type": "object"}}}
+}
+
+func (f *fakeHandler) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
+	f.calls = append(f.calls, name)
+	if args["fail"] == true {
+		return nil, errors.New("refused")
+	}
+	return &CallToolResult{Content: []Content{TextContent(fmt.Sprintf("%v", args["text"]))}}, nil
+}
+
+func (f *fakeHandler) Resources() []Resource {
+	return []Resource{{URI: "test://a", Name: "a"}}
+}
+
+func (f *fakeHandler) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	if uri != "test://a" {
+		return nil, fmt.Errorf("resource not found: %s", uri)
+	}
+	return []ResourceContents{{URI: uri, Text: "contents"}}, nil
+}
+
+func (f *fakeHandler) Prompts() []Prompt {
+	return nil
+}
+
+func (f *fakeHandler) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	return nil, fmt.Errorf("prompt not found: %s", name)
+}
+
+// serve runs the server on the given request lines and returns the decoded responses
+func serve(t *testing.T, handler Handler, lines ...string) []Response {
+	t.Helper()
+	var out bytes.Buffer
+	server := NewServer("test", "1.0", handler)
+	if err := server.Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	var responses []Response
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp Response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("Invalid response line %q: %v", line, err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// TestServer_Initialize tests the handshake and version negotiation
+func TestServer_Initialize(t *testing.T) {
+	t.Run("echoes a supported version", func(t *testing.T) {
+		responses := serve(t, &fakeHandler{},
+			`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`,
+			`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		)
+		if len(responses) != 1 {
+			t.Fatalf("Expected 1 response (notifications get none), got %d", len(responses))
+		}
+		var result InitializeResult
+		if err := json.Unmarshal(responses[0].Result, &result); err != nil {
+			t.Fatalf("Invalid initialize result: %v", err)
+		}
+		if result.ProtocolVersion != "2024-11-05" {
+			t.Errorf("Expected version 2024-11-05, got %s", result.ProtocolVersion)
+		}
+		if result.ServerInfo.Name != "test" {
+			t.Errorf("Expected server name test, got %s", result.ServerInfo.Name)
+		}
+	})
+
+	t.Run("falls back to the newest version", func(t *testing.T) {
+		responses := serve(t, &fakeHandler{}, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+		var result InitializeResult
+		json.Unmarshal(responses[0].Result, &result)
+		if result.ProtocolVersion != ProtocolVersion {
+			t.Errorf("Expected version %s, got %s", ProtocolVersion, result.ProtocolVersion)
+		}
+	})
+}
+
+// TestServer_Tools tests listing and calling tools
+func TestServer_Tools(t *testing.T) {
+	handler := &fakeHandler{}
+	responses := serve(t, handler,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"fail":true}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"missing"}}`,
+	)
+	if len(responses) != 4 {
+		t.Fatalf("Expected 4 responses, got %d", len(responses))
+	}
+
+	t.Run("list", func(t *testing.T) {
+		var result struct {
+			Tools []Tool `json:"tools"`
+		}
+		json.Unmarshal(responses[0].Result, &result)
+		if len(result.Tools) != 1 || result.Tools[0].Name != "echo" {
+			t.Errorf("Expected tool echo, got %+v", result.Tools)
+		}
+	})
+
+	t.Run("call", func(t *testing.T) {
+		var result CallToolResult
+		json.Unmarshal(responses[1].Result, &result)
+		if result.IsError || result.Text() != "hi" {
+			t.Errorf("Expected text hi, got %+v", result)
+		}
+	})
+
+	t.Run("handler errors become error results", func(t *testing.T) {
+		if responses[2].Error != nil {
+			t.Fatalf("Expected a result, got JSON-RPC error %v", responses[2].Error)
+		}
+		var result CallToolResult
+		json.Unmarshal(responses[2].Result, &result)
+		if !result.IsError || result.Text() != "refused" {
+			t.Errorf("Expected error result refused, got %+v", result)
+		}
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		if responses[3].Error == nil || responses[3].Error.Code != CodeInvalidParams {
+			t.Errorf("Expected invalid params error, got %+v", responses[3].Error)
+		}
+		if len(handler.calls) != 2 {
+			t.Errorf("Expected 2 handler calls, got %d", len(handler.calls))
+		}
+	})
+}
+
+// TestServer_ResourcesAndPrompts tests resources, prompts and unknown methods
+func TestServer_ResourcesAndPrompts(t *testing.T) {
+	responses := serve(t, &fakeHandler{},
+		`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"test://a"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"test://b"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"prompts/list"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"bogus"}`,
+		`not json`,
+	)
+	if len(responses) != 5 {
+		t.Fatalf("Expected 5 responses, got %d", len(responses))
+	}
+
+	if !strings.Contains(string(responses[0].Result), `"text":"contents"`) {
+		t.Errorf("Expected resource contents, got %s", responses[0].Result)
+	}
+	if responses[1].Error == nil {
+		t.Error("Expected error for unknown resource")
+	}
+	if string(responses[2].Result) != `{"prompts":[]}` {
+		t.Errorf("Expected empty prompt list, got %s", responses[2].Result)
+	}
+	if responses[3].Error == nil || responses[3].Error.Code != CodeMethodNotFound {
+		t.Errorf("Expected method not found, got %+v", responses[3].Error)
+	}
+	if responses[4].Error == nil || responses[4].Error.Code != CodeParseError {
+		t.Errorf("Expected parse error, got %+v", responses[4].Error)
+	}
+}
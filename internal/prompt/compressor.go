@@ -1,14 +1,10 @@
 package prompt
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -318,103 +314,14 @@ func (c *Compressor) buildSkillCompressionPrompt(skill *skills.Skill) string {
 	return builder.String()
 }
 
-// callLLMForCompression calls LLM API for compression (similar to matcher's callLLMAPI)
+// callLLMForCompression calls the LLM to compress text
 func (c *Compressor) callLLMForCompression(ctx context.Context, prompt string) (string, error) {
-	// Build messages
-	messages := []llm.ChatMessage{
-		{
-			Role:    "system",
-			Content: "You are a helpful assistant that compresses text while preserving key information. Return only the compressed content, no explanations.",
-		},
-		{
-			Role:    "user",
-			Content: prompt,
-		},
-	}
+	systemPrompt := "You are a helpful assistant that compresses text while preserving key information. Return only the compressed content, no explanations."
 
-	// Convert messages to []interface{}
-	messagesInterface := make([]interface{}, len(messages))
-	for i, msg := range messages {
-		messagesInterface[i] = msg
-	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
-	// Create request body
-	reqBody := struct {
-		Model    string        `json:"model"`
-		Messages []interface{} `json:"messages"`
-	}{
-		Model:    c.llmClient.Model(),
-		Messages: messagesInterface,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Build API URL
-	apiURL := c.buildAPIURL()
-
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.llmClient.APIKey())
-
-	// Execute request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var chatResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Error *struct {
-			Message string `json:"message"`
-			Type    string `json:"type"`
-		} `json:"error,omitempty"`
-	}
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Check for API errors
-	if chatResp.Error != nil {
-		return "", fmt.Errorf("API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
-	}
-
-	// Extract response
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
-	}
-
-	content := chatResp.Choices[0].Message.Content
-	if content == "" {
-		return "", fmt.Errorf("empty content in response")
-	}
-
-	return content, nil
+	return c.llmClient.Complete(ctx, systemPrompt, prompt)
 }
 
 // parseCompressedHistory parses LLM response into compressed history
@@ -431,18 +338,6 @@ func (c *Compressor) parseCompressedHistory(response string) []string {
 	return []string{response}
 }
 
-// buildAPIURL builds the API URL (similar to matcher's buildAPIURL)
-func (c *Compressor) buildAPIURL() string {
-	baseURL := strings.TrimSuffix(c.llmClient.BaseURL(), "/")
-	if strings.HasSuffix(baseURL, "/chat/completions") {
-		return baseURL
-	}
-	if strings.HasSuffix(baseURL, "/v1") {
-		return baseURL + "/chat/completions"
-	}
-	return baseURL + "/v1/chat/completions"
-}
-
 // hashContent creates a hash of content for caching
 func (c *Compressor) hashContent(content string) string {
 	hash := sha256.Sum256([]byte(content))
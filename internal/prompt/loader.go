@@ -52,7 +52,7 @@ func NewLoader() (*Loader, error) {
 	// This ensures we don't overwrite user files without consent
 	if err := loader.checkAndHandleVersionMismatch(); err != nil {
 		// Non-fatal: log warning but continue with initialization
-		fmt.Printf("Warning: Version check failed: %v. Continuing with default behavior.\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Version check failed: %v. Continuing with default behavior.\n", err)
 	}
 
 	// Initialize default prompts if they don't exist (or overwrite if user chose to)
@@ -415,6 +415,7 @@ You are a helpful AI assistant for database queries and related tasks.
 
 <POLICY>
 - Use execute_sql for database queries. Do not use execute_command to run mysql/psql.
+- Pass values taken from the user's message (names, emails, search terms, dates) in the execute_sql params array and reference them with placeholders. Never quote user-supplied strings into the SQL text.
 - Respect engine-specific syntax differences. Database-specific syntax guidance is provided in separate sections.
 - If a request is not a database query, use the appropriate non-SQL tools.
 - When unsure about syntax, rely on schema context or ask a clarifying question.
@@ -0,0 +1,175 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PassphraseEnv holds the passphrase that unlocks the secrets file
+	PassphraseEnv = "AIQ_SECRETS_PASSPHRASE"
+	// KeyFileEnv points at a key file whose contents unlock the secrets file
+	KeyFileEnv = "AIQ_SECRETS_KEY_FILE"
+
+	commandTimeout = 30 * time.Second
+)
+
+// PromptPassphrase asks the user for the secrets passphrase. It is set by the interactive CLI;
+// when nil, the passphrase must come from a key file or the environment.
+var PromptPassphrase func(label string) (string, error)
+
+// Ref points at a secret kept outside the config file
+type Ref struct {
+	Env  string // Environment variable holding the value
+	Cmd  string // Shell command printing the value, e.g. "pass show db/prod"
+	Name string // Name of the entry in the encrypted secrets file
+}
+
+// IsZero reports whether the reference points nowhere
+func (r Ref) IsZero() bool {
+	return r.Env == "" && r.Cmd == "" && r.Name == ""
+}
+
+// Resolve returns the plaintext value if set, otherwise the value the reference points at.
+// open is only called when the secrets file is needed.
+func Resolve(plain string, ref Ref, open func() (*Store, error)) (string, error) {
+	if plain != "" {
+		return plain, nil
+	}
+
+	if ref.Env != "" {
+		v, ok := os.LookupEnv(ref.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref.Env)
+		}
+		return v, nil
+	}
+
+	if ref.Cmd != "" {
+		return runCommand(ref.Cmd)
+	}
+
+	if ref.Name != "" {
+		store, err := open()
+		if err != nil {
+			return "", err
+		}
+		v, ok := store.Get(ref.Name)
+		if !ok {
+			return "", fmt.Errorf("secret %q not found in secrets file", ref.Name)
+		}
+		return v, nil
+	}
+
+	return "", nil
+}
+
+// runCommand runs a secret command through the shell and returns the first line of its output
+func runCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	// Stdin is left unset: it may carry protocol input (aiq mcp, stream-json) that a command
+	// must not consume. Commands like `pass` or `op` prompt through the terminal or pinentry.
+	cmd.Stderr = os.Stderr
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		// The output is not included: it may contain part of the secret
+		return "", fmt.Errorf("secret command %q failed: %w", command, err)
+	}
+
+	line, _, _ := strings.Cut(out.String(), "\n")
+	return strings.TrimRight(line, "\r"), nil
+}
+
+var (
+	openMu      sync.Mutex
+	openedStore = make(map[string]*Store)
+)
+
+// Load opens the secrets file at path, unlocking it with (in order) the key file named by
+// $AIQ_SECRETS_KEY_FILE, defaultKeyFile if it exists, $AIQ_SECRETS_PASSPHRASE, or a prompt.
+// The unlocked store is cached for the rest of the process so the passphrase is asked once.
+func Load(path, defaultKeyFile string) (*Store, error) {
+	openMu.Lock()
+	defer openMu.Unlock()
+
+	if store, ok := openedStore[path]; ok {
+		return store, nil
+	}
+
+	passphrase, err := passphrase(defaultKeyFile, !Exists(path))
+	if err != nil {
+		return nil, err
+	}
+	store, err := Open(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	openedStore[path] = store
+	return store, nil
+}
+
+// passphrase obtains the secrets passphrase; a new secrets file asks for confirmation
+func passphrase(defaultKeyFile string, isNew bool) ([]byte, error) {
+	keyFile := os.Getenv(KeyFileEnv)
+	if keyFile == "" && defaultKeyFile != "" && Exists(defaultKeyFile) {
+		keyFile = defaultKeyFile
+	}
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secrets key file: %w", err)
+		}
+		key := bytes.TrimRight(data, "\r\n")
+		if len(key) == 0 {
+			return nil, fmt.Errorf("secrets key file %s is empty", keyFile)
+		}
+		return key, nil
+	}
+
+	if v := os.Getenv(PassphraseEnv); v != "" {
+		return []byte(v), nil
+	}
+
+	if PromptPassphrase == nil {
+		return nil, fmt.Errorf("secrets file is locked and the passphrase cannot be prompted for here: set %s or %s", PassphraseEnv, KeyFileEnv)
+	}
+
+	label := "Enter secrets passphrase"
+	if isNew {
+		label = "Choose a passphrase for the new secrets file"
+	}
+	first, err := PromptPassphrase(label)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets passphrase: %w", err)
+	}
+	if first == "" {
+		return nil, fmt.Errorf("secrets passphrase is empty")
+	}
+	if isNew {
+		second, err := PromptPassphrase("Confirm passphrase")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secrets passphrase: %w", err)
+		}
+		if first != second {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+	return []byte(first), nil
+}
@@ -0,0 +1,174 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStore_RoundTrip tests that secrets survive an encrypt/decrypt cycle
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store, err := Open(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	store.Set("source/prod/password", "s3cret")
+	store.Set("llm/api_key", "sk-test")
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") || strings.Contains(string(data), "sk-test") {
+		t.Error("Expected secrets file not to contain plaintext values")
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	reopened, err := Open(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	if v, ok := reopened.Get("source/prod/password"); !ok || v != "s3cret" {
+		t.Errorf("Expected 's3cret', got %q (found=%v)", v, ok)
+	}
+	if names := reopened.Names(); len(names) != 2 || names[0] != "llm/api_key" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		_, err := Open(path, []byte("wrong"))
+		if !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+		}
+	})
+
+	t.Run("empty passphrase", func(t *testing.T) {
+		if _, err := Open(path, nil); err == nil {
+			t.Error("Expected error for empty passphrase")
+		}
+	})
+}
+
+// TestResolve tests resolution of plaintext values and references
+func TestResolve(t *testing.T) {
+	noStore := func() (*Store, error) {
+		t.Fatal("secrets file should not be opened")
+		return nil, nil
+	}
+
+	t.Run("plaintext wins", func(t *testing.T) {
+		v, err := Resolve("plain", Ref{Env: "UNUSED"}, noStore)
+		if err != nil || v != "plain" {
+			t.Errorf("Expected 'plain', got %q, %v", v, err)
+		}
+	})
+
+	t.Run("environment variable", func(t *testing.T) {
+		t.Setenv("AIQ_TEST_DB_PASSWORD", "from-env")
+		v, err := Resolve("", Ref{Env: "AIQ_TEST_DB_PASSWORD"}, noStore)
+		if err != nil || v != "from-env" {
+			t.Errorf("Expected 'from-env', got %q, %v", v, err)
+		}
+
+		if _, err := Resolve("", Ref{Env: "AIQ_TEST_UNSET_VARIABLE"}, noStore); err == nil {
+			t.Error("Expected error for unset environment variable")
+		}
+	})
+
+	t.Run("command uses first line", func(t *testing.T) {
+		v, err := Resolve("", Ref{Cmd: "printf 'from-cmd\\nmetadata: x\\n'"}, noStore)
+		if err != nil || v != "from-cmd" {
+			t.Errorf("Expected 'from-cmd', got %q, %v", v, err)
+		}
+	})
+
+	t.Run("failing command hides output", func(t *testing.T) {
+		_, err := Resolve("", Ref{Cmd: "v=partial; echo \"$v-secret\"; exit 3"}, noStore)
+		if err == nil {
+			t.Fatal("Expected error for failing command")
+		}
+		if strings.Contains(err.Error(), "partial-secret") {
+			t.Errorf("Expected command output not to be included, got %v", err)
+		}
+	})
+
+	t.Run("secrets file", func(t *testing.T) {
+		store, err := Open(filepath.Join(t.TempDir(), "secrets.enc"), []byte("pw"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Set("db", "from-store")
+		open := func() (*Store, error) { return store, nil }
+
+		v, err := Resolve("", Ref{Name: "db"}, open)
+		if err != nil || v != "from-store" {
+			t.Errorf("Expected 'from-store', got %q, %v", v, err)
+		}
+		if _, err := Resolve("", Ref{Name: "missing"}, open); err == nil {
+			t.Error("Expected error for missing secret")
+		}
+	})
+
+	t.Run("no value", func(t *testing.T) {
+		v, err := Resolve("", Ref{}, noStore)
+		if err != nil || v != "" {
+			t.Errorf("Expected empty value, got %q, %v", v, err)
+		}
+	})
+}
+
+// TestLoad tests unlocking the secrets file with a key file or the environment
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.enc")
+	keyFile := filepath.Join(dir, "secrets.key")
+	if err := os.WriteFile(keyFile, []byte("key-file-passphrase\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(path, []byte("key-file-passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("a", "1")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(KeyFileEnv, "")
+	t.Setenv(PassphraseEnv, "")
+	loaded, err := Load(path, keyFile)
+	if err != nil {
+		t.Fatalf("Load with key file failed: %v", err)
+	}
+	if v, _ := loaded.Get("a"); v != "1" {
+		t.Errorf("Expected '1', got %q", v)
+	}
+
+	otherPath := filepath.Join(dir, "other.enc")
+	t.Run("locked without passphrase source", func(t *testing.T) {
+		prev := PromptPassphrase
+		PromptPassphrase = nil
+		defer func() { PromptPassphrase = prev }()
+
+		if _, err := Load(otherPath, ""); err == nil {
+			t.Error("Expected error when no passphrase is available")
+		}
+	})
+
+	t.Run("passphrase from environment", func(t *testing.T) {
+		t.Setenv(PassphraseEnv, "env-passphrase")
+		if _, err := Load(otherPath, ""); err != nil {
+			t.Errorf("Expected Load to succeed, got %v", err)
+		}
+	})
+}
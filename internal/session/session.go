@@ -2,6 +2,7 @@ package session
 
 import (
 	"encoding/json"
+	"sort"
 	"time"
 )
 
@@ -18,6 +19,39 @@ type SessionMetadata struct {
 	LastUpdated  time.Time `json:"last_updated"`
 	DataSource   string    `json:"data_source"`
 	DatabaseType string    `json:"database_type"`
+
+	Usage     TokenUsage  `json:"usage"`                // Tokens used by all requests of the session
+	TurnUsage []TurnUsage `json:"turn_usage,omitempty"` // Tokens used by each request
+}
+
+// TokenUsage counts the tokens used by LLM calls, including skill matching and compression
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	Calls            int `json:"calls"`
+	UnreportedCalls  int `json:"unreported_calls,omitempty"` // Calls whose response carried no token counts
+}
+
+// TurnUsage is the token usage of one request with one model
+// A request whose tasks use different models has an entry per model, all with the same Turn.
+type TurnUsage struct {
+	Turn      int       `json:"turn"` // Number of the request among those with usage, from 1
+	Timestamp time.Time `json:"timestamp"`
+	Model     string    `json:"model"`
+	TokenUsage
+}
+
+// Add adds the counts of other to u
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.Calls += other.Calls
+	u.UnreportedCalls += other.UnreportedCalls
+}
+
+// TotalTokens returns the prompt and completion tokens together
+func (u TokenUsage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
 }
 
 // Session represents a conversation session
@@ -25,6 +59,14 @@ type Session struct {
 	Metadata    SessionMetadata   `json:"metadata"`
 	Messages    []Message         `json:"messages,omitempty"`     // Legacy format, for backward compatibility
 	RawMessages []json.RawMessage `json:"raw_messages,omitempty"` // Complete messages array (includes tool calls and results)
+	LimitEvents []LimitEvent      `json:"limit_events,omitempty"` // Tool-loop limits reached during the session
+}
+
+// LimitEvent records a tool-loop limit that was reached and the action taken
+type LimitEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason"`
+	Action    string    `json:"action"` // "continue", "summarize" or "stop"
 }
 
 // NewSession creates a new session with the given data source and database type
@@ -46,6 +88,70 @@ func (s *Session) UpdateLastUpdated() {
 	s.Metadata.LastUpdated = time.Now().UTC()
 }
 
+// RecordLimit records that a tool-loop limit was reached
+func (s *Session) RecordLimit(reason, action string) {
+	s.LimitEvents = append(s.LimitEvents, LimitEvent{
+		Timestamp: time.Now().UTC(),
+		Reason:    reason,
+		Action:    action,
+	})
+}
+
+// RecordUsage records the token usage of one request, per model
+func (s *Session) RecordUsage(byModel map[string]TokenUsage) {
+	models := make([]string, 0, len(byModel))
+	for model, usage := range byModel {
+		if usage.Calls > 0 {
+			models = append(models, model)
+		}
+	}
+	if len(models) == 0 {
+		return
+	}
+	sort.Strings(models)
+
+	turn := s.UsageTurns() + 1
+	now := time.Now().UTC()
+	for _, model := range models {
+		s.Metadata.Usage.Add(byModel[model])
+		s.Metadata.TurnUsage = append(s.Metadata.TurnUsage, TurnUsage{
+			Turn:       turn,
+			Timestamp:  now,
+			Model:      model,
+			TokenUsage: byModel[model],
+		})
+	}
+}
+
+// UsageTurns returns the number of requests with recorded token usage
+func (s *Session) UsageTurns() int {
+	if n := len(s.Metadata.TurnUsage); n > 0 {
+		return s.Metadata.TurnUsage[n-1].Turn
+	}
+	return 0
+}
+
+// LastTurnUsage returns the token usage of the last request, one entry per model
+func (s *Session) LastTurnUsage() []TurnUsage {
+	turn := s.UsageTurns()
+	i := len(s.Metadata.TurnUsage)
+	for i > 0 && s.Metadata.TurnUsage[i-1].Turn == turn {
+		i--
+	}
+	return s.Metadata.TurnUsage[i:]
+}
+
+// UsageByModel returns the token usage of the session per model
+func (s *Session) UsageByModel() map[string]TokenUsage {
+	byModel := make(map[string]TokenUsage)
+	for _, turn := range s.Metadata.TurnUsage {
+		usage := byModel[turn.Model]
+		usage.Add(turn.TokenUsage)
+		byModel[turn.Model] = usage
+	}
+	return byModel
+}
+
 // GetTimestamp generates a timestamp string for session file naming
 // Format: YYYYMMDDHHMMSS (UTC)
 func GetTimestamp() string {
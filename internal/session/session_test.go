@@ -67,3 +67,50 @@ func TestGetTimestamp(t *testing.T) {
 		t.Errorf("Timestamp format invalid: %v", err)
 	}
 }
+
+func TestRecordLimit(t *testing.T) {
+	sess := NewSession("test", "mysql")
+	sess.RecordLimit("reached the limit of 10 iterations", "summarize")
+
+	if len(sess.LimitEvents) != 1 {
+		t.Fatalf("Expected 1 limit event, got %d", len(sess.LimitEvents))
+	}
+	event := sess.LimitEvents[0]
+	if event.Reason != "reached the limit of 10 iterations" || event.Action != "summarize" {
+		t.Errorf("Expected recorded reason and action, got %+v", event)
+	}
+	if event.Timestamp.IsZero() {
+		t.Error("Expected limit event timestamp to be set")
+	}
+}
+
+func TestRecordUsage(t *testing.T) {
+	sess := NewSession("test", "mysql")
+	sess.RecordUsage(map[string]TokenUsage{"model-a": {PromptTokens: 100, CompletionTokens: 20, Calls: 2}})
+	sess.RecordUsage(map[string]TokenUsage{
+		"model-b": {PromptTokens: 50, CompletionTokens: 5, Calls: 1, UnreportedCalls: 1},
+		"model-a": {PromptTokens: 10, CompletionTokens: 1, Calls: 1},
+	})
+	sess.RecordUsage(map[string]TokenUsage{"model-a": {}}) // No LLM calls, not recorded
+
+	if len(sess.Metadata.TurnUsage) != 3 || sess.UsageTurns() != 2 {
+		t.Fatalf("Expected 3 entries over 2 turns, got %+v", sess.Metadata.TurnUsage)
+	}
+	total := sess.Metadata.Usage
+	if total.PromptTokens != 160 || total.CompletionTokens != 26 || total.Calls != 4 || total.UnreportedCalls != 1 {
+		t.Errorf("Expected session totals, got %+v", total)
+	}
+	if total.TotalTokens() != 186 {
+		t.Errorf("Expected 186 total tokens, got %d", total.TotalTokens())
+	}
+
+	last := sess.LastTurnUsage()
+	if len(last) != 2 || last[0].Model != "model-a" || last[1].Model != "model-b" || last[0].Turn != 2 {
+		t.Errorf("Expected last turn with one entry per model, got %+v", last)
+	}
+
+	byModel := sess.UsageByModel()
+	if byModel["model-a"].PromptTokens != 110 || byModel["model-a"].Calls != 3 || byModel["model-b"].CompletionTokens != 5 {
+		t.Errorf("Expected usage per model, got %+v", byModel)
+	}
+}
@@ -1,14 +1,11 @@
 package skills
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -152,7 +149,7 @@ func (m *Matcher) MatchWithLLM(ctx context.Context, query string, metadataList [
 	return m.metadataFromNames(skillNames, metadataList), nil
 }
 
-// callLLMAPI makes a direct API call to LLM for semantic matching
+// callLLMAPI asks the LLM which skills match the query
 func (m *Matcher) callLLMAPI(ctx context.Context, prompt string) (string, error) {
 	// Build messages with carefully designed system prompt
 	systemPrompt := `You are a skill matcher for a database query assistant. Your task is to determine which skills (if any) would help answer the user's query.
@@ -172,122 +169,11 @@ EXAMPLES OF CORRECT MATCHING:
 Return a JSON array of skill names. Return [] if no skills are needed.
 Format: ["skill-name-1", "skill-name-2"] or []`
 
-	messages := []llm.ChatMessage{
-		{
-			Role:    "system",
-			Content: systemPrompt,
-		},
-		{
-			Role:    "user",
-			Content: prompt,
-		},
-	}
-
-	// Convert messages to []interface{}
-	messagesInterface := make([]interface{}, len(messages))
-	for i, msg := range messages {
-		messagesInterface[i] = msg
-	}
-
-	// Create request body
-	reqBody := struct {
-		Model    string        `json:"model"`
-		Messages []interface{} `json:"messages"`
-	}{
-		Model:    m.llmClient.Model(),
-		Messages: messagesInterface,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Build API URL (reuse LLM client's buildAPIURL logic)
-	apiURL := m.buildAPIURL()
-
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+m.llmClient.APIKey())
-
-	// Execute request with retry
-	client := &http.Client{Timeout: 30 * time.Second}
-	var resp *http.Response
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		resp, err = client.Do(req)
-		if err == nil {
-			break
-		}
-		if i < maxRetries-1 {
-			time.Sleep(time.Duration(i+1) * time.Second)
-		}
-	}
-	if err != nil {
-		return "", fmt.Errorf("request failed after retries: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-	}
+	// Skill matching should not hold up the request for long
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
-	// Parse response
-	var chatResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Error *struct {
-			Message string `json:"message"`
-			Type    string `json:"type"`
-		} `json:"error,omitempty"`
-	}
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	// Check for API errors
-	if chatResp.Error != nil {
-		return "", fmt.Errorf("API error: %s (type: %s)", chatResp.Error.Message, chatResp.Error.Type)
-	}
-
-	// Extract response
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
-	}
-
-	content := chatResp.Choices[0].Message.Content
-	if content == "" {
-		return "", fmt.Errorf("empty content in response")
-	}
-
-	return content, nil
-}
-
-// buildAPIURL builds the API URL (similar to LLM client's buildAPIURL)
-func (m *Matcher) buildAPIURL() string {
-	baseURL := strings.TrimSuffix(m.llmClient.BaseURL(), "/")
-	if strings.HasSuffix(baseURL, "/chat/completions") {
-		return baseURL
-	}
-	if strings.HasSuffix(baseURL, "/v1") {
-		return baseURL + "/chat/completions"
-	}
-	return baseURL + "/v1/chat/completions"
+	return m.llmClient.Complete(ctx, systemPrompt, prompt)
 }
 
 // buildMatchingPrompt builds the prompt for LLM semantic matching
@@ -0,0 +1,353 @@
+package source
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Kinds of client files a source's password can be read from (Source.PasswordFrom)
+const (
+	PasswordFromPgpass = "pgpass"
+	PasswordFromMyCnf  = "mycnf"
+)
+
+// OptionGroup is a [group] section of a MySQL option file such as ~/.my.cnf
+type OptionGroup struct {
+	Name    string
+	Options map[string]string
+}
+
+// PgpassEntry is one hostname:port:database:username:password line of a ~/.pgpass file
+type PgpassEntry struct {
+	Host     string
+	Port     string
+	Database string
+	Username string
+	Password string
+}
+
+// DefaultMyCnfPath returns the path of the user's MySQL option file (~/.my.cnf)
+func DefaultMyCnfPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".my.cnf")
+}
+
+// DefaultPgpassPath returns the path of the user's password file ($PGPASSFILE or ~/.pgpass)
+func DefaultPgpassPath() string {
+	if p := os.Getenv("PGPASSFILE"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".pgpass")
+}
+
+// ParseMyCnf reads the option groups of a MySQL option file in file order.
+// !include directives are ignored; option names are normalized to use underscores.
+func ParseMyCnf(path string) ([]OptionGroup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var groups []OptionGroup
+	var current *OptionGroup
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '!' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			groups = append(groups, OptionGroup{Name: name, Options: make(map[string]string)})
+			current = &groups[len(groups)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+		current.Options[key] = unquoteOption(strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return groups, nil
+}
+
+// unquoteOption strips matching quotes from an option value
+func unquoteOption(v string) string {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// MyCnfDefaults merges the [client] and [mysql] groups, which the mysql client reads by default
+func MyCnfDefaults(groups []OptionGroup) map[string]string {
+	merged := make(map[string]string)
+	for _, name := range []string{"client", "mysql"} {
+		for _, g := range groups {
+			if g.Name != name {
+				continue
+			}
+			for k, v := range g.Options {
+				merged[k] = v
+			}
+		}
+	}
+	return merged
+}
+
+// myCnfGroupOptions returns the options of the named group, inheriting unset options from [client] and [mysql]
+func myCnfGroupOptions(groups []OptionGroup, name string) map[string]string {
+	opts := MyCnfDefaults(groups)
+	for _, g := range groups {
+		if g.Name != name {
+			continue
+		}
+		for k, v := range g.Options {
+			opts[k] = v
+		}
+	}
+	return opts
+}
+
+// ParsePgpass reads the entries of a PostgreSQL password file.
+// Backslash escapes ":" and "\" within fields; lines with the wrong field count are skipped.
+func ParsePgpass(path string) ([]PgpassEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []PgpassEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPgpassLine(line)
+		if len(fields) != 5 {
+			continue
+		}
+		entries = append(entries, PgpassEntry{
+			Host:     fields[0],
+			Port:     fields[1],
+			Database: fields[2],
+			Username: fields[3],
+			Password: fields[4],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// splitPgpassLine splits a .pgpass line on unescaped colons
+func splitPgpassLine(line string) []string {
+	var fields []string
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			b.WriteByte(line[i])
+		case line[i] == ':':
+			fields = append(fields, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(line[i])
+		}
+	}
+	return append(fields, b.String())
+}
+
+// Matches reports whether the entry applies to the connection; "*" matches any value
+func (e PgpassEntry) Matches(host string, port int, database, username string) bool {
+	match := func(pattern, value string) bool {
+		return pattern == "*" || pattern == value
+	}
+	// libpq treats a Unix-socket connection as host "localhost"
+	if host == "" || strings.HasPrefix(host, "/") {
+		host = "localhost"
+	}
+	return match(e.Host, host) && match(e.Port, strconv.Itoa(port)) &&
+		match(e.Database, database) && match(e.Username, username)
+}
+
+// LookupPgpass returns the password of the first matching entry, or "" if none matches
+func LookupPgpass(entries []PgpassEntry, host string, port int, database, username string) string {
+	for _, e := range entries {
+		if e.Matches(host, port, database, username) {
+			return e.Password
+		}
+	}
+	return ""
+}
+
+// DiscoverSources builds sources from ~/.my.cnf option groups and ~/.pgpass entries.
+// Sources are returned without names; missing files are skipped.
+func DiscoverSources() ([]*Source, error) {
+	var sources []*Source
+
+	if path := DefaultMyCnfPath(); path != "" {
+		groups, err := ParseMyCnf(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		sources = append(sources, sourcesFromMyCnf(groups)...)
+	}
+
+	if path := DefaultPgpassPath(); path != "" {
+		entries, err := ParsePgpass(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		sources = append(sources, sourcesFromPgpass(entries)...)
+	}
+
+	return sources, nil
+}
+
+// sourcesFromMyCnf creates one source per client group ([client], [mysql], [client<suffix>], ...)
+// that has a user, inheriting unset options from [client]
+func sourcesFromMyCnf(groups []OptionGroup) []*Source {
+	var sources []*Source
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		// [client<suffix>] groups are what --defaults-group-suffix selects
+		if g.Name != "mysql" && !strings.HasPrefix(g.Name, "client") {
+			continue
+		}
+
+		opts := myCnfGroupOptions(groups, g.Name)
+		src := sourceFromMySQLOptions(opts)
+		// The password stays in the option file and is read from its group when connecting
+		if opts["password"] != "" {
+			src.PasswordFrom = PasswordFromMyCnf + ":" + g.Name
+		}
+		if src.Username == "" {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%d|%s|%s", src.Host, src.Socket, src.Port, src.Username, src.Database)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// sourceFromMySQLOptions maps mysql client options to a source
+func sourceFromMySQLOptions(opts map[string]string) *Source {
+	src := &Source{
+		Type:     DatabaseTypeMySQL,
+		Host:     opts["host"],
+		Username: opts["user"],
+		Database: opts["database"],
+		Socket:   opts["socket"],
+	}
+	if src.Host == "" && src.Socket == "" {
+		src.Host = "localhost"
+	}
+	src.Port = defaultPort(DatabaseTypeMySQL)
+	if port, err := strconv.Atoi(opts["port"]); err == nil {
+		src.Port = port
+	}
+	if mode := opts["ssl_mode"]; mode != "" {
+		src.TLS = &TLSConfig{Mode: mode, CA: opts["ssl_ca"], Cert: opts["ssl_cert"], Key: opts["ssl_key"]}
+	}
+	return src
+}
+
+// clientFilePassword reads the source's password from the client file named by PasswordFrom
+func (s *Source) clientFilePassword() (string, error) {
+	kind, group, _ := strings.Cut(s.PasswordFrom, ":")
+	switch kind {
+	case PasswordFromPgpass:
+		path := DefaultPgpassPath()
+		entries, err := ParsePgpass(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		host := s.Host
+		if s.Socket != "" {
+			host = s.Socket
+		}
+		if password := LookupPgpass(entries, host, s.Port, s.Database, s.Username); password != "" {
+			return password, nil
+		}
+		return "", fmt.Errorf("no entry in %s matches %s@%s:%d/%s", path, s.Username, host, s.Port, s.Database)
+	case PasswordFromMyCnf:
+		path := DefaultMyCnfPath()
+		groups, err := ParseMyCnf(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read option file: %w", err)
+		}
+		opts := MyCnfDefaults(groups)
+		if group != "" {
+			opts = myCnfGroupOptions(groups, group)
+		}
+		if opts["password"] != "" {
+			return opts["password"], nil
+		}
+		return "", fmt.Errorf("no password set in %s", path)
+	}
+	return "", fmt.Errorf("unknown password_from %q (use %s or %s[:group])", s.PasswordFrom, PasswordFromPgpass, PasswordFromMyCnf)
+}
+
+// sourcesFromPgpass creates a source per entry with a concrete host, port and user.
+// A wildcard database defaults to the user name, as in libpq.
+func sourcesFromPgpass(entries []PgpassEntry) []*Source {
+	var sources []*Source
+	for _, e := range entries {
+		if e.Host == "*" || e.Username == "*" {
+			continue
+		}
+		port := defaultPort(DatabaseTypePostgreSQL)
+		if e.Port != "*" {
+			p, err := strconv.Atoi(e.Port)
+			if err != nil {
+				continue
+			}
+			port = p
+		}
+		database := e.Database
+		if database == "*" {
+			database = e.Username
+		}
+		src := &Source{
+			Type:     DatabaseTypePostgreSQL,
+			Host:     e.Host,
+			Port:     port,
+			Database: database,
+			Username: e.Username,
+		}
+		if e.Password != "" {
+			// The password stays in ~/.pgpass and is looked up when connecting, as libpq does
+			src.PasswordFrom = PasswordFromPgpass
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
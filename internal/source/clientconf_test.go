@@ -0,0 +1,159 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestParseMyCnf tests reading option groups from a MySQL option file
+func TestParseMyCnf(t *testing.T) {
+	path := writeTempFile(t, "my.cnf", `# comment
+!includedir /etc/mysql/conf.d/
+[client]
+user = app
+password = "s3cret"
+port=3307
+
+[mysqld]
+port = 3306
+
+[mysql]
+database = shop
+
+[client_prod]
+host = prod.db.internal
+user = readonly
+ssl-mode = VERIFY_IDENTITY
+`)
+
+	groups, err := ParseMyCnf(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(groups) != 4 {
+		t.Fatalf("Expected 4 groups, got %d", len(groups))
+	}
+
+	defaults := MyCnfDefaults(groups)
+	if defaults["user"] != "app" || defaults["password"] != "s3cret" || defaults["database"] != "shop" || defaults["port"] != "3307" {
+		t.Errorf("Unexpected defaults: %v", defaults)
+	}
+
+	sources := sourcesFromMyCnf(groups)
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources ([client]/[mysql] and [client_prod]), got %d", len(sources))
+	}
+	local := sources[0]
+	if local.Host != "localhost" || local.Port != 3307 || local.Username != "app" || local.Database != "shop" {
+		t.Errorf("Unexpected local source: %+v", local)
+	}
+	prod := sources[1]
+	if prod.Host != "prod.db.internal" || prod.Username != "readonly" {
+		t.Errorf("Expected [client_prod] to inherit from [client], got %+v", prod)
+	}
+	if prod.Password != "" || prod.PasswordFrom != "mycnf:client_prod" {
+		t.Errorf("Expected the password to be left in the option file, got %+v", prod)
+	}
+	if prod.TLS == nil || prod.TLS.normalizedMode() != TLSModeVerifyFull {
+		t.Errorf("Expected VERIFY_IDENTITY to map to verify-full, got %+v", prod.TLS)
+	}
+}
+
+// TestParsePgpass tests reading and matching PostgreSQL password file entries
+func TestParsePgpass(t *testing.T) {
+	path := writeTempFile(t, "pgpass", `# hostname:port:database:username:password
+db.internal:5432:analytics:report:pa\:ss
+localhost:*:*:postgres:local
+*:*:*:fallback:any
+broken:line
+`)
+
+	entries, err := ParsePgpass(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].Password != "pa:ss" {
+		t.Errorf("Expected escaped colon in password, got %q", entries[0].Password)
+	}
+
+	tests := []struct {
+		host, db, user string
+		port           int
+		expected       string
+	}{
+		{"db.internal", "analytics", "report", 5432, "pa:ss"},
+		{"db.internal", "analytics", "report", 5433, ""},
+		{"/var/run/postgresql", "app", "postgres", 5432, "local"},
+		{"other", "x", "fallback", 6432, "any"},
+	}
+	for _, tt := range tests {
+		if got := LookupPgpass(entries, tt.host, tt.port, tt.db, tt.user); got != tt.expected {
+			t.Errorf("LookupPgpass(%s, %d, %s, %s) = %q, expected %q", tt.host, tt.port, tt.db, tt.user, got, tt.expected)
+		}
+	}
+
+	sources := sourcesFromPgpass(entries)
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 importable entries (wildcard host skipped), got %d", len(sources))
+	}
+	if sources[1].Database != "postgres" || sources[1].Port != 5432 {
+		t.Errorf("Expected wildcard database/port to default to user name and 5432, got %+v", sources[1])
+	}
+	if sources[0].Password != "" || sources[0].PasswordFrom != PasswordFromPgpass {
+		t.Errorf("Expected the password to be left in the password file, got %+v", sources[0])
+	}
+}
+
+// TestSource_ClientFilePassword tests reading passwords from ~/.pgpass and ~/.my.cnf at connect time
+func TestSource_ClientFilePassword(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PGPASSFILE", writeTempFile(t, "pgpass", "db.internal:5432:analytics:report:pg-secret\n"))
+	if err := os.WriteFile(filepath.Join(home, ".my.cnf"), []byte("[client]\npassword = base\n[client_prod]\npassword = prod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		src      Source
+		expected string
+		wantErr  bool
+	}{
+		{"pgpass", Source{Type: DatabaseTypePostgreSQL, Host: "db.internal", Port: 5432, Database: "analytics", Username: "report", PasswordFrom: PasswordFromPgpass}, "pg-secret", false},
+		{"pgpass without match", Source{Type: DatabaseTypePostgreSQL, Host: "db.internal", Port: 5432, Database: "other", Username: "report", PasswordFrom: PasswordFromPgpass}, "", true},
+		{"my.cnf defaults", Source{Type: DatabaseTypeMySQL, PasswordFrom: PasswordFromMyCnf}, "base", false},
+		{"my.cnf group", Source{Type: DatabaseTypeMySQL, PasswordFrom: "mycnf:client_prod"}, "prod", false},
+		{"plaintext wins", Source{Type: DatabaseTypeMySQL, Password: "plain", PasswordFrom: PasswordFromMyCnf}, "plain", false},
+		{"unknown kind", Source{Type: DatabaseTypeMySQL, PasswordFrom: "netrc"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.src.ResolvePassword()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got password %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected password %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
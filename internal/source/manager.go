@@ -7,6 +7,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/secret"
 )
 
 // GetSourcesPath returns the full path to the sources file
@@ -63,6 +64,43 @@ func SaveSources(sources []*Source) error {
 	return nil
 }
 
+// MigratePasswords moves plaintext passwords into the secrets store and rewrites sources.yaml
+// Returns the names of the migrated sources
+func MigratePasswords(store *secret.Store) ([]string, error) {
+	sources, err := LoadSources()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrated []string
+	for _, s := range sources {
+		if s.Password == "" {
+			continue
+		}
+		name := s.PasswordSecretName()
+		store.Set(name, s.Password)
+		s.Password = ""
+		s.PasswordEnv = ""
+		s.PasswordCmd = ""
+		s.PasswordFrom = ""
+		s.PasswordSecret = name
+		migrated = append(migrated, s.Name)
+	}
+
+	if len(migrated) == 0 {
+		return nil, nil
+	}
+
+	// Save the secrets first so a failure never loses a password
+	if err := store.Save(); err != nil {
+		return nil, err
+	}
+	if err := SaveSources(sources); err != nil {
+		return nil, err
+	}
+	return migrated, nil
+}
+
 // AddSource adds a new source
 func AddSource(source *Source) error {
 	sources, err := LoadSources()
@@ -248,7 +286,6 @@ func UpdateSource(name string, updated *Source) error {
 	}
 
 	// Update the source
-	updated.Name = updated.Name // Ensure name is set
 	for i, s := range sources {
 		if s.Name == name {
 			sources[i] = updated
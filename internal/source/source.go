@@ -1,6 +1,20 @@
 package source
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/secret"
+)
 
 // DatabaseType represents the type of database
 type DatabaseType string
@@ -19,27 +33,241 @@ type Source struct {
 	Port     int          `yaml:"port"`
 	Database string       `yaml:"database"`
 	Username string       `yaml:"username"`
-	Password string       `yaml:"password"`
+	Password string       `yaml:"password,omitempty"`
+
+	// The password can instead be read from the environment, a command, or the encrypted secrets file
+	PasswordEnv    string `yaml:"password_env,omitempty"`
+	PasswordCmd    string `yaml:"password_cmd,omitempty"`
+	PasswordSecret string `yaml:"password_secret,omitempty"`
+	// PasswordFrom reads the password at connect time from the client's own file instead of storing it:
+	// "pgpass" (~/.pgpass or $PGPASSFILE), or "mycnf" with an optional ":<group>" naming a ~/.my.cnf group
+	PasswordFrom string `yaml:"password_from,omitempty"`
+
+	// Socket is a Unix socket path (MySQL) or socket directory (PostgreSQL) used instead of host/port
+	Socket string `yaml:"socket,omitempty"`
+	// TLS configures encrypted connections
+	TLS *TLSConfig `yaml:"tls,omitempty"`
+	// SSH tunnels connections through a bastion host
+	SSH *SSHConfig `yaml:"ssh,omitempty"`
+	// Params are driver-specific DSN options merged into the connection string
+	Params map[string]string `yaml:"params,omitempty"`
+
+	// InitSQL statements run on every new physical connection, e.g. SET time_zone='+00:00'
+	InitSQL []string `yaml:"init_sql,omitempty"`
+
+	// ProcedureErrorPattern is a regular expression matching error messages that stored procedures
+	// return as a single status row instead of raising an error (default: "ERROR <code> (<SQLSTATE>): ...")
+	ProcedureErrorPattern string `yaml:"procedure_error_pattern,omitempty"`
 }
 
 // DSN returns the Data Source Name for the database driver
-func (s *Source) DSN() string {
+// The password is resolved here, at connect time; the DSN must not be stored or logged
+// For MySQL sources with custom TLS settings, the TLS configuration is registered with the driver
+func (s *Source) DSN() (string, error) {
+	password, err := s.ResolvePassword()
+	if err != nil {
+		return "", err
+	}
+
 	switch s.Type {
-	case DatabaseTypeMySQL:
-		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-			s.Username, s.Password, s.Host, s.Port, s.Database)
 	case DatabaseTypePostgreSQL:
-		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-			s.Host, s.Port, s.Username, s.Password, s.Database)
-	case DatabaseTypeSeekDB:
-		// SeekDB uses MySQL-compatible protocol, so use MySQL DSN format
-		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-			s.Username, s.Password, s.Host, s.Port, s.Database)
+		return s.postgresDSN(password), nil
 	default:
-		// Default to MySQL format
-		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-			s.Username, s.Password, s.Host, s.Port, s.Database)
+		// MySQL, and SeekDB which uses the MySQL-compatible protocol
+		return s.mysqlDSN(password)
+	}
+}
+
+// HasPassword reports whether a password or a reference to one is configured
+func (s *Source) HasPassword() bool {
+	return s.Password != "" || !s.passwordRef().IsZero() || s.PasswordFrom != ""
+}
+
+func (s *Source) passwordRef() secret.Ref {
+	return secret.Ref{Env: s.PasswordEnv, Cmd: s.PasswordCmd, Name: s.PasswordSecret}
+}
+
+// ResolvePassword returns the password, reading it from its env var, command or secrets file if needed
+func (s *Source) ResolvePassword() (string, error) {
+	password, err := secret.Resolve(s.Password, s.passwordRef(), config.OpenSecrets)
+	if err == nil && password == "" && s.PasswordFrom != "" {
+		password, err = s.clientFilePassword()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve password for source '%s': %w", s.Name, err)
+	}
+	return password, nil
+}
+
+// PasswordSecretName returns the secrets file entry used for the source's password
+func (s *Source) PasswordSecretName() string {
+	return "source/" + s.Name + "/password"
+}
+
+// mysqlDSN builds a go-sql-driver/mysql DSN
+func (s *Source) mysqlDSN(password string) (string, error) {
+	cfg := mysql.NewConfig()
+	cfg.User = s.Username
+	cfg.Passwd = password
+	cfg.DBName = s.Database
+	cfg.ParseTime = true
+	if s.Socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = expandHome(s.Socket)
+	} else {
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	}
+
+	tlsParam, err := s.mysqlTLSParam()
+	if err != nil {
+		return "", err
+	}
+	cfg.TLSConfig = tlsParam
+	// With certificates, preferred registers a custom TLS config, which the driver would otherwise require
+	cfg.AllowFallbackToPlaintext = s.TLS.normalizedMode() == TLSModePreferred
+
+	// Free-form params are appended last so they override the defaults above
+	dsn := cfg.FormatDSN()
+	if len(s.Params) > 0 {
+		values := url.Values{}
+		for k, v := range s.Params {
+			values.Set(k, v)
+		}
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + values.Encode()
+	}
+	return dsn, nil
+}
+
+// postgresDSN builds a lib/pq key=value connection string
+func (s *Source) postgresDSN(password string) string {
+	host := s.Host
+	if s.Socket != "" {
+		// lib/pq connects over a Unix socket when host is a directory
+		host = expandHome(s.Socket)
+	}
+
+	opts := map[string]string{
+		"host":     host,
+		"user":     s.Username,
+		"password": password,
+		"dbname":   s.Database,
+		"sslmode":  "disable",
+	}
+	if s.Port > 0 {
+		// With a socket, the port selects the .s.PGSQL.<port> file; lib/pq defaults to 5432
+		opts["port"] = strconv.Itoa(s.Port)
+	}
+
+	if s.TLS != nil {
+		switch mode := s.TLS.normalizedMode(); mode {
+		case "", TLSModeDisable:
+		case TLSModeRequired:
+			opts["sslmode"] = "require"
+		default:
+			opts["sslmode"] = mode
+		}
+		if s.TLS.CA != "" {
+			opts["sslrootcert"] = expandHome(s.TLS.CA)
+		}
+		if s.TLS.Cert != "" {
+			opts["sslcert"] = expandHome(s.TLS.Cert)
+			opts["sslkey"] = expandHome(s.TLS.Key)
+		}
+	}
+
+	for k, v := range s.Params {
+		opts[k] = v
+	}
+
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+quotePostgresValue(opts[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quotePostgresValue quotes a connection string value if it is empty or contains spaces, quotes or backslashes
+func quotePostgresValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " '\\") {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// ParseParams parses driver params given as "key=value,key2=value2"
+func ParseParams(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	params := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid param %q (expected key=value)", pair)
+		}
+		params[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return params, nil
+}
+
+// FormatParams formats driver params as "key=value,key2=value2" in key order
+func FormatParams(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+params[k])
+	}
+	return strings.Join(parts, ",")
+}
+
+// expandHome expands a leading ~ in path to the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// SessionSettingsContext describes the init_sql statements applied to every connection for the LLM context
+// Returns an empty string if no init statements are configured
+func (s *Source) SessionSettingsContext() string {
+	if len(s.InitSQL) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Session settings applied on every connection (init_sql); queries run with these in effect:\n")
+	for _, stmt := range s.InitSQL {
+		b.WriteString("- ")
+		b.WriteString(stmt)
+		b.WriteString("\n")
 	}
+	return strings.TrimRight(b.String(), "\n")
 }
 
 // GetDatabaseType returns the database type as string for LLM context
@@ -0,0 +1,352 @@
+package source
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSource_MySQLDSN tests MySQL DSN building with sockets, TLS modes and params
+func TestSource_MySQLDSN(t *testing.T) {
+	base := Source{
+		Type:     DatabaseTypeMySQL,
+		Host:     "db.example.com",
+		Port:     3306,
+		Database: "shop",
+		Username: "app",
+		Password: "p@ss:word",
+	}
+
+	t.Run("tcp with defaults", func(t *testing.T) {
+		dsn, err := base.DSN()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := "app:p@ss:word@tcp(db.example.com:3306)/shop?parseTime=true"
+		if dsn != expected {
+			t.Errorf("Expected %q, got %q", expected, dsn)
+		}
+	})
+
+	t.Run("socket replaces host and port", func(t *testing.T) {
+		src := base
+		src.Socket = "/var/run/mysqld/mysqld.sock"
+		dsn, err := src.DSN()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(dsn, "@unix(/var/run/mysqld/mysqld.sock)/shop") {
+			t.Errorf("Expected unix socket address, got %q", dsn)
+		}
+	})
+
+	t.Run("builtin TLS modes", func(t *testing.T) {
+		tests := map[string]string{
+			TLSModeDisable:   "tls=false",
+			TLSModePreferred: "tls=preferred",
+			"require":        "tls=skip-verify",
+		}
+		for mode, expected := range tests {
+			src := base
+			src.TLS = &TLSConfig{Mode: mode}
+			dsn, err := src.DSN()
+			if err != nil {
+				t.Fatalf("Expected no error for mode %q, got %v", mode, err)
+			}
+			if !strings.Contains(dsn, expected) {
+				t.Errorf("Expected %q in DSN for mode %q, got %q", expected, mode, dsn)
+			}
+		}
+	})
+
+	t.Run("params are appended", func(t *testing.T) {
+		src := base
+		src.Params = map[string]string{"charset": "utf8mb4", "loc": "Asia/Shanghai"}
+		dsn, err := src.DSN()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.HasSuffix(dsn, "&charset=utf8mb4&loc=Asia%2FShanghai") {
+			t.Errorf("Expected encoded params at the end, got %q", dsn)
+		}
+	})
+}
+
+// writeTestCA writes a self-signed CA certificate and returns its path and DER bytes
+func writeTestCA(t *testing.T, name string) (string, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, der
+}
+
+// TestSource_MySQLTLSWithCA tests that a CA is verified in required mode and that preferred mode stays optional
+func TestSource_MySQLTLSWithCA(t *testing.T) {
+	caPath, caDER := writeTestCA(t, "trusted")
+	_, otherDER := writeTestCA(t, "other")
+	src := Source{Type: DatabaseTypeMySQL, Host: "db.example.com", Port: 3306, Database: "shop", Username: "app", Password: "pw"}
+
+	t.Run("required verifies the CA", func(t *testing.T) {
+		src.TLS = &TLSConfig{Mode: TLSModeRequired, CA: caPath}
+		cfg, err := src.buildTLSConfig(TLSModeRequired)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if cfg.VerifyPeerCertificate == nil {
+			t.Fatal("Expected the server certificate to be verified against the CA")
+		}
+		if err := cfg.VerifyPeerCertificate([][]byte{caDER}, nil); err != nil {
+			t.Errorf("Expected certificate signed by the CA to pass, got %v", err)
+		}
+		if err := cfg.VerifyPeerCertificate([][]byte{otherDER}, nil); err == nil {
+			t.Error("Expected certificate from another CA to be rejected")
+		}
+	})
+
+	t.Run("required without CA skips verification", func(t *testing.T) {
+		src.TLS = &TLSConfig{Mode: TLSModeRequired}
+		cfg, err := src.buildTLSConfig(TLSModeRequired)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !cfg.InsecureSkipVerify || cfg.VerifyPeerCertificate != nil {
+			t.Error("Expected no certificate verification without a CA")
+		}
+	})
+
+	t.Run("preferred with CA falls back to plaintext", func(t *testing.T) {
+		src.TLS = &TLSConfig{Mode: TLSModePreferred, CA: caPath}
+		dsn, err := src.DSN()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(dsn, "allowFallbackToPlaintext=true") || !strings.Contains(dsn, "tls=aiq-") {
+			t.Errorf("Expected custom TLS config with plaintext fallback, got %q", dsn)
+		}
+	})
+
+	t.Run("required with CA does not fall back", func(t *testing.T) {
+		src.TLS = &TLSConfig{Mode: TLSModeRequired, CA: caPath}
+		dsn, err := src.DSN()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if strings.Contains(dsn, "allowFallbackToPlaintext") {
+			t.Errorf("Expected TLS to be mandatory, got %q", dsn)
+		}
+	})
+}
+
+// TestSource_PostgresDSN tests PostgreSQL connection string building
+func TestSource_PostgresDSN(t *testing.T) {
+	base := Source{
+		Type:     DatabaseTypePostgreSQL,
+		Host:     "localhost",
+		Port:     5432,
+		Database: "analytics",
+		Username: "report",
+		Password: "it's secret",
+	}
+
+	t.Run("default sslmode and quoting", func(t *testing.T) {
+		dsn, err := base.DSN()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := `dbname=analytics host=localhost password='it\'s secret' port=5432 sslmode=disable user=report`
+		if dsn != expected {
+			t.Errorf("Expected %q, got %q", expected, dsn)
+		}
+	})
+
+	t.Run("TLS and params", func(t *testing.T) {
+		src := base
+		src.TLS = &TLSConfig{Mode: "verify-full", CA: "/etc/ssl/ca.pem"}
+		src.Params = map[string]string{"application_name": "aiq", "sslmode": "verify-ca"}
+		dsn, err := src.DSN()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, part := range []string{"sslrootcert=/etc/ssl/ca.pem", "application_name=aiq", "sslmode=verify-ca"} {
+			if !strings.Contains(dsn, part) {
+				t.Errorf("Expected %q in DSN, got %q", part, dsn)
+			}
+		}
+	})
+
+	t.Run("socket directory as host", func(t *testing.T) {
+		src := base
+		src.Host = ""
+		src.Port = 0
+		src.Socket = "/var/run/postgresql"
+		dsn, err := src.DSN()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(dsn, "host=/var/run/postgresql") || strings.Contains(dsn, "port=") {
+			t.Errorf("Expected socket host without port, got %q", dsn)
+		}
+	})
+}
+
+// TestSource_ValidateTLS tests TLS validation rules
+func TestSource_ValidateTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(ca, []byte("ca"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		src     Source
+		wantErr bool
+	}{
+		{"no TLS", Source{Type: DatabaseTypeMySQL}, false},
+		{"mysql preferred", Source{Type: DatabaseTypeMySQL, TLS: &TLSConfig{Mode: "preferred"}}, false},
+		{"unknown mode", Source{Type: DatabaseTypeMySQL, TLS: &TLSConfig{Mode: "sometimes"}}, true},
+		{"postgres preferred", Source{Type: DatabaseTypePostgreSQL, TLS: &TLSConfig{Mode: "preferred"}}, true},
+		{"postgres verify without CA", Source{Type: DatabaseTypePostgreSQL, TLS: &TLSConfig{Mode: "verify-full"}}, true},
+		{"postgres verify with CA", Source{Type: DatabaseTypePostgreSQL, TLS: &TLSConfig{Mode: "verify-full", CA: ca}}, false},
+		{"cert without key", Source{Type: DatabaseTypeMySQL, TLS: &TLSConfig{Mode: "required", Cert: ca}}, true},
+		{"missing CA file", Source{Type: DatabaseTypeMySQL, TLS: &TLSConfig{CA: filepath.Join(dir, "missing.pem")}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTLS(&tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestSource_ParseParams tests parsing and formatting of driver params
+func TestSource_ParseParams(t *testing.T) {
+	params, err := ParseParams(" charset=utf8mb4, timeout = 5s ,")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(params) != 2 || params["charset"] != "utf8mb4" || params["timeout"] != "5s" {
+		t.Errorf("Unexpected params: %v", params)
+	}
+	if got := FormatParams(params); got != "charset=utf8mb4,timeout=5s" {
+		t.Errorf("Expected 'charset=utf8mb4,timeout=5s', got %q", got)
+	}
+
+	if _, err := ParseParams("novalue"); err == nil {
+		t.Error("Expected error for param without '='")
+	}
+	if params, err := ParseParams(""); err != nil || params != nil {
+		t.Errorf("Expected nil params for empty input, got %v, %v", params, err)
+	}
+}
+
+// TestSource_ValidateSSH tests SSH tunnel validation and connect options
+func TestSource_ValidateSSH(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(key, []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ssh     *SSHConfig
+		wantErr bool
+	}{
+		{"no ssh", nil, false},
+		{"key file", &SSHConfig{Host: "bastion", User: "ops", KeyPath: key}, false},
+		{"agent", &SSHConfig{Host: "bastion", User: "ops", Agent: true}, false},
+		{"missing host", &SSHConfig{User: "ops", Agent: true}, true},
+		{"missing user", &SSHConfig{Host: "bastion", Agent: true}, true},
+		{"no auth", &SSHConfig{Host: "bastion", User: "ops"}, true},
+		{"missing key file", &SSHConfig{Host: "bastion", User: "ops", KeyPath: filepath.Join(dir, "missing")}, true},
+		{"bad port", &SSHConfig{Host: "bastion", Port: 70000, User: "ops", Agent: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSSH(&Source{SSH: tt.ssh})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := validateSSH(&Source{Socket: "/var/run/mysqld/mysqld.sock", SSH: &SSHConfig{Host: "bastion", User: "ops", Agent: true}}); err == nil {
+		t.Error("Expected error for ssh combined with socket")
+	}
+
+	src := &Source{InitSQL: []string{"SET a=1"}, SSH: &SSHConfig{Host: "bastion", User: "ops", KeyPath: key, KeepaliveInterval: 15}}
+	opts := src.ConnectOptions()
+	if opts.SSH == nil || opts.SSH.KeyFile != key || opts.SSH.KeepAlive.Seconds() != 15 {
+		t.Errorf("Unexpected SSH connect options: %+v", opts.SSH)
+	}
+	if len(opts.InitSQL) != 1 {
+		t.Errorf("Expected init statements to be passed through, got %v", opts.InitSQL)
+	}
+}
+
+// TestSource_ResolvePassword tests that password references are resolved into the DSN
+func TestSource_ResolvePassword(t *testing.T) {
+	t.Setenv("AIQ_TEST_PG_PASSWORD", "env-secret")
+	src := &Source{
+		Name:        "reporting",
+		Type:        DatabaseTypePostgreSQL,
+		Host:        "localhost",
+		Port:        5432,
+		Database:    "analytics",
+		Username:    "report",
+		PasswordEnv: "AIQ_TEST_PG_PASSWORD",
+	}
+
+	if !src.HasPassword() {
+		t.Error("Expected password reference to count as a password")
+	}
+	dsn, err := src.DSN()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(dsn, "password=env-secret") {
+		t.Errorf("Expected resolved password in DSN, got %q", dsn)
+	}
+	if src.Password != "" {
+		t.Error("Expected resolving not to store the password on the source")
+	}
+
+	src.PasswordEnv = "AIQ_TEST_UNSET_PASSWORD"
+	if _, err := src.DSN(); err == nil {
+		t.Error("Expected error for unset password variable")
+	}
+}
@@ -0,0 +1,75 @@
+package source
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aiq/aiq/internal/db"
+)
+
+// SSHConfig holds settings for reaching a database through an SSH bastion host
+type SSHConfig struct {
+	Host       string `yaml:"host"`
+	Port       int    `yaml:"port,omitempty"` // Defaults to 22
+	User       string `yaml:"user"`
+	KeyPath    string `yaml:"key_path,omitempty"`    // Private key for public key authentication
+	Agent      bool   `yaml:"agent,omitempty"`       // Authenticate with keys from $SSH_AUTH_SOCK
+	KnownHosts string `yaml:"known_hosts,omitempty"` // Defaults to ~/.ssh/known_hosts
+	// KeepaliveInterval is the number of seconds between keepalive requests (default 30)
+	KeepaliveInterval int `yaml:"keepalive_interval,omitempty"`
+}
+
+// validateSSH validates the ssh block
+func validateSSH(s *Source) error {
+	c := s.SSH
+	if c == nil {
+		return nil
+	}
+	if strings.TrimSpace(c.Host) == "" {
+		return fmt.Errorf("ssh host is required")
+	}
+	if strings.TrimSpace(c.User) == "" {
+		return fmt.Errorf("ssh user is required")
+	}
+	if c.Port != 0 && (c.Port < minPort || c.Port > maxPort) {
+		return fmt.Errorf("ssh port must be between %d and %d", minPort, maxPort)
+	}
+	if c.KeyPath == "" && !c.Agent {
+		return fmt.Errorf("ssh key_path or agent is required")
+	}
+	if s.Socket != "" {
+		// The tunnel forwards TCP connections; a socket would be dialled on the bastion itself
+		return fmt.Errorf("ssh cannot be combined with socket; set host and port as seen from the bastion")
+	}
+	if c.KeepaliveInterval < 0 {
+		return fmt.Errorf("ssh keepalive_interval must not be negative")
+	}
+	for name, path := range map[string]string{"key_path": c.KeyPath, "known_hosts": c.KnownHosts} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(expandHome(path)); err != nil {
+			return fmt.Errorf("ssh %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ConnectOptions returns the connection options for the source (init statements, SSH tunnel)
+func (s *Source) ConnectOptions() db.ConnectOptions {
+	opts := db.ConnectOptions{InitSQL: s.InitSQL}
+	if s.SSH != nil {
+		opts.SSH = &db.SSHConfig{
+			Host:       s.SSH.Host,
+			Port:       s.SSH.Port,
+			User:       s.SSH.User,
+			KeyFile:    expandHome(s.SSH.KeyPath),
+			UseAgent:   s.SSH.Agent,
+			KnownHosts: expandHome(s.SSH.KnownHosts),
+			KeepAlive:  time.Duration(s.SSH.KeepaliveInterval) * time.Second,
+		}
+	}
+	return opts
+}
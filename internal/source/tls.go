@@ -0,0 +1,210 @@
+package source
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// TLS modes supported in a source's tls block
+const (
+	TLSModeDisable    = "disable"     // Plain connection
+	TLSModePreferred  = "preferred"   // TLS if the server supports it, otherwise plain (MySQL only)
+	TLSModeRequired   = "required"    // TLS; the certificate is only verified against the CA if one is set
+	TLSModeVerifyCA   = "verify-ca"   // TLS, server certificate must be signed by the CA
+	TLSModeVerifyFull = "verify-full" // TLS, CA and server host name are verified
+)
+
+// TLSConfig holds TLS settings for a source
+type TLSConfig struct {
+	Mode       string `yaml:"mode,omitempty"`
+	CA         string `yaml:"ca,omitempty"`          // Path to CA certificate (PEM)
+	Cert       string `yaml:"cert,omitempty"`        // Path to client certificate (PEM)
+	Key        string `yaml:"key,omitempty"`         // Path to client private key (PEM)
+	ServerName string `yaml:"server_name,omitempty"` // Expected server host name if it differs from host (MySQL only)
+}
+
+// normalizedMode returns the TLS mode with PostgreSQL and MySQL client aliases mapped to the canonical names
+// If no mode is set, it is inferred from the presence of certificate settings
+func (t *TLSConfig) normalizedMode() string {
+	if t == nil {
+		return ""
+	}
+	// MySQL client spellings (DISABLED, VERIFY_IDENTITY, ...) are accepted as well
+	mode := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(t.Mode)), "_", "-")
+	switch mode {
+	case "disabled":
+		return TLSModeDisable
+	case "prefer":
+		return TLSModePreferred
+	case "require":
+		return TLSModeRequired
+	case "verify-identity":
+		return TLSModeVerifyFull
+	case "":
+		if t.CA != "" {
+			return TLSModeVerifyFull
+		}
+		if t.Cert != "" || t.Key != "" {
+			return TLSModeRequired
+		}
+	}
+	return mode
+}
+
+// ValidateTLS validates the TLS block for the source's database type
+func ValidateTLS(s *Source) error {
+	t := s.TLS
+	if t == nil {
+		return nil
+	}
+
+	switch t.normalizedMode() {
+	case "", TLSModeDisable, TLSModeRequired, TLSModeVerifyCA, TLSModeVerifyFull:
+	case TLSModePreferred:
+		if s.Type == DatabaseTypePostgreSQL {
+			return fmt.Errorf("tls mode %q is not supported for PostgreSQL (use required, verify-ca or verify-full)", t.Mode)
+		}
+	default:
+		return fmt.Errorf("invalid tls mode: %s (must be disable, preferred, required, verify-ca or verify-full)", t.Mode)
+	}
+
+	if (t.Cert == "") != (t.Key == "") {
+		return fmt.Errorf("tls cert and key must be set together")
+	}
+	mode := t.normalizedMode()
+	if (mode == TLSModeVerifyCA || mode == TLSModeVerifyFull) && t.CA == "" && s.Type == DatabaseTypePostgreSQL {
+		return fmt.Errorf("tls ca is required for mode %s", mode)
+	}
+	if t.ServerName != "" && s.Type == DatabaseTypePostgreSQL {
+		return fmt.Errorf("tls server_name is not supported for PostgreSQL")
+	}
+
+	for _, path := range []string{t.CA, t.Cert, t.Key} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(expandHome(path)); err != nil {
+			return fmt.Errorf("tls file not accessible: %w", err)
+		}
+	}
+	return nil
+}
+
+// mysqlTLSParam returns the value of the MySQL driver's tls DSN parameter,
+// registering a custom TLS configuration with the driver when certificates are involved
+func (s *Source) mysqlTLSParam() (string, error) {
+	t := s.TLS
+	mode := t.normalizedMode()
+
+	switch mode {
+	case "":
+		return "", nil
+	case TLSModeDisable:
+		return "false", nil
+	case TLSModePreferred:
+		if t.CA == "" && t.Cert == "" {
+			return "preferred", nil
+		}
+	case TLSModeRequired:
+		if t.CA == "" && t.Cert == "" {
+			return "skip-verify", nil
+		}
+	}
+
+	cfg, err := s.buildTLSConfig(mode)
+	if err != nil {
+		return "", err
+	}
+
+	// Register under a name derived from the settings so repeated connects reuse the same key
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%s", mode, t.CA, t.Cert, t.Key, cfg.ServerName, s.Host)
+	name := fmt.Sprintf("aiq-%x", h.Sum32())
+	if err := mysql.RegisterTLSConfig(name, cfg); err != nil {
+		return "", fmt.Errorf("failed to register TLS config: %w", err)
+	}
+	return name, nil
+}
+
+// buildTLSConfig loads certificates and builds a tls.Config for the given mode
+func (s *Source) buildTLSConfig(mode string) (*tls.Config, error) {
+	t := s.TLS
+	cfg := &tls.Config{
+		ServerName: t.ServerName,
+	}
+	if cfg.ServerName == "" && s.Socket == "" {
+		cfg.ServerName = s.Host
+	}
+
+	if t.CA != "" {
+		pem, err := os.ReadFile(expandHome(t.CA))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse tls ca %s: no PEM certificates found", t.CA)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if t.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(expandHome(t.Cert), expandHome(t.Key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch mode {
+	case TLSModeRequired, TLSModePreferred:
+		cfg.InsecureSkipVerify = true
+		if cfg.RootCAs != nil {
+			// As libpq does for require with a root certificate, a given CA is verified like verify-ca
+			roots := cfg.RootCAs
+			cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				return verifyCertChain(rawCerts, roots)
+			}
+		}
+	case TLSModeVerifyCA:
+		// Verify the chain against the CA but not the host name
+		cfg.InsecureSkipVerify = true
+		roots := cfg.RootCAs
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyCertChain(rawCerts, roots)
+		}
+	}
+
+	return cfg, nil
+}
+
+// verifyCertChain verifies a peer certificate chain against roots without checking the host name
+func verifyCertChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("server presented no certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse server certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
@@ -3,6 +3,7 @@ package source
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -31,14 +32,25 @@ func Validate(source *Source) error {
 		return fmt.Errorf("invalid database type: %s (must be mysql, postgresql, or seekdb)", source.Type)
 	}
 
-	// Validate host
-	if strings.TrimSpace(source.Host) == "" {
-		return fmt.Errorf("host is required")
+	// Validate host and port (not needed when connecting through a socket)
+	if strings.TrimSpace(source.Socket) == "" {
+		if strings.TrimSpace(source.Host) == "" {
+			return fmt.Errorf("host is required")
+		}
+
+		if source.Port < minPort || source.Port > maxPort {
+			return fmt.Errorf("port must be between %d and %d", minPort, maxPort)
+		}
 	}
 
-	// Validate port
-	if source.Port < minPort || source.Port > maxPort {
-		return fmt.Errorf("port must be between %d and %d", minPort, maxPort)
+	// Validate TLS settings
+	if err := ValidateTLS(source); err != nil {
+		return err
+	}
+
+	// Validate SSH tunnel settings
+	if err := validateSSH(source); err != nil {
+		return err
 	}
 
 	// Validate database
@@ -51,9 +63,32 @@ func Validate(source *Source) error {
 		return fmt.Errorf("username is required")
 	}
 
-	// Validate password (can be empty, but warn)
-	if source.Password == "" {
-		return fmt.Errorf("password is required")
+	// Validate password (plaintext or a reference to it)
+	if !source.HasPassword() {
+		return fmt.Errorf("password is required (set password, password_env, password_cmd, password_secret or password_from)")
+	}
+	if source.PasswordFrom != "" {
+		kind, _, _ := strings.Cut(source.PasswordFrom, ":")
+		switch {
+		case kind == PasswordFromPgpass && source.Type == DatabaseTypePostgreSQL:
+		case kind == PasswordFromMyCnf && source.Type != DatabaseTypePostgreSQL:
+		default:
+			return fmt.Errorf("invalid password_from %q for %s (use %s for postgresql, %s[:group] for mysql)", source.PasswordFrom, source.Type, PasswordFromPgpass, PasswordFromMyCnf)
+		}
+	}
+
+	// Validate init statements
+	for i, stmt := range source.InitSQL {
+		if strings.TrimSpace(stmt) == "" {
+			return fmt.Errorf("init_sql[%d] is empty", i)
+		}
+	}
+
+	// Validate procedure error pattern
+	if source.ProcedureErrorPattern != "" {
+		if _, err := regexp.Compile(source.ProcedureErrorPattern); err != nil {
+			return fmt.Errorf("invalid procedure_error_pattern: %w", err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,377 @@
+package sql
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/db"
+	"github.com/aiq/aiq/internal/mcp"
+	"github.com/aiq/aiq/internal/session"
+	"github.com/aiq/aiq/internal/skills"
+	"github.com/aiq/aiq/internal/source"
+	"github.com/aiq/aiq/internal/tool"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// Output formats of RunAsk
+const (
+	FormatText       = "text"
+	FormatJSON       = "json"
+	FormatCSV        = "csv"
+	FormatStreamJSON = "stream-json"
+)
+
+// AskOptions configures a non-interactive, one-shot question
+type AskOptions struct {
+	SourceName string        // Source to query; empty runs in free mode
+	Database   string        // Optional database overriding the source's database
+	Question   string        // Natural-language request; in stream-json mode, empty reads requests from input
+	Format     string        // Output format: text, json, csv or stream-json
+	Policy     ConfirmPolicy // Decides operations that would otherwise need confirmation
+	Profile    string        // LLM profile for the chat task instead of the configured one
+	Log        io.Writer     // Progress output (tool calls, warnings); nil writes to stderr
+}
+
+// RunAsk runs an agent turn without prompting and writes the answer and query results to out
+// Progress messages go to opts.Log so out only carries the requested format.
+// In stream-json mode, in supplies user requests and confirmation answers as JSON lines.
+// An error is returned if a turn failed, its last statement failed, or the policy denied an operation.
+func RunAsk(opts AskOptions, in io.Reader, out io.Writer) error {
+	switch opts.Format {
+	case FormatText, FormatJSON, FormatCSV, FormatStreamJSON:
+	default:
+		return fmt.Errorf("unknown output format %q (use text, json, csv or stream-json)", opts.Format)
+	}
+	if opts.Policy == PolicyPrompt && opts.Format != FormatStreamJSON {
+		// Only stream-json can ask for confirmations (over its input)
+		opts.Policy = PolicyDeny
+	}
+
+	if opts.Format == FormatStreamJSON {
+		return runAskStream(opts, NewStreamIO(in, out))
+	}
+
+	var answer string
+	var outcome TurnOutcome
+	session, err := openAskSession(opts)
+	if err == nil {
+		answer, outcome, err = session.run(opts.Question, nil)
+		session.Close()
+	}
+	if writeErr := writeAskOutput(out, opts.Format, answer, outcome, err); writeErr != nil && err == nil {
+		err = fmt.Errorf("failed to write output: %w", writeErr)
+	}
+	if err != nil {
+		return err
+	}
+	return outcomeError(outcome, opts.Policy)
+}
+
+// runAskStream runs turns with stream-json events until the input is closed
+// With a question on the command line only that turn is run.
+func runAskStream(opts AskOptions, stream *StreamIO) error {
+	session, err := openAskSession(opts)
+	if err != nil {
+		stream.Emit(StreamEvent{Type: EventFinal, Status: "error", Error: err.Error()})
+		return err
+	}
+	defer session.Close()
+
+	var failed error
+	question := opts.Question
+	for {
+		if question == "" {
+			input, err := stream.ReadInput()
+			if err == io.EOF {
+				break
+			}
+			if err != nil || input.Type != "user" {
+				if err == nil {
+					err = fmt.Errorf("expected a user message, got %q", input.Type)
+				}
+				stream.Emit(StreamEvent{Type: EventFinal, Status: "error", Error: err.Error()})
+				failed = err
+				continue
+			}
+			question = strings.TrimSpace(input.Content)
+			if question == "" {
+				continue
+			}
+		}
+
+		stream.NextTurn()
+		answer, outcome, err := session.run(question, func(h *ToolHandler) {
+			h.SetEventHandler(stream.Emit)
+			if opts.Policy == PolicyPrompt {
+				h.SetConfirmer(stream.Confirm)
+			}
+		})
+		if err == nil {
+			err = outcomeError(outcome, opts.Policy)
+		}
+
+		final := StreamEvent{
+			Type:    EventFinal,
+			Status:  "success",
+			Content: answer,
+			Results: resultSetsToJSON(outcome.ResultSets),
+			Denied:  outcome.Denied,
+			Usage:   turnUsage(outcome),
+		}
+		if err != nil {
+			final.Status = "error"
+			final.Error = err.Error()
+			failed = err
+		}
+		stream.Emit(final)
+
+		if opts.Question != "" {
+			break
+		}
+		question = ""
+	}
+	return failed
+}
+
+// outcomeError reports a turn whose last statement failed or whose operations were denied
+func outcomeError(outcome TurnOutcome, policy ConfirmPolicy) error {
+	if len(outcome.Denied) > 0 {
+		return fmt.Errorf("%d operation(s) denied by the %s policy: %s", len(outcome.Denied), policyName(policy), strings.Join(outcome.Denied, "; "))
+	}
+	if outcome.SQLError != "" {
+		return fmt.Errorf("query failed: %s", outcome.SQLError)
+	}
+	return nil
+}
+
+// policyName names a policy in messages
+func policyName(policy ConfirmPolicy) string {
+	if policy == PolicyPrompt {
+		return "prompt"
+	}
+	return string(policy)
+}
+
+// askSession holds the connection and conversation of non-interactive turns
+type askSession struct {
+	opts          AskOptions
+	src           *source.Source
+	conn          *db.Connection
+	schema        *db.Schema
+	databaseName  string
+	skillsManager *skills.Manager
+	llmClients    *llmClients
+	out           *ui.Output        // Progress output of the session's turns
+	mcpManager    *mcp.Manager      // External MCP servers
+	limits        config.LoopLimits // Tool-loop budgets from the config
+	sess          *session.Session  // Conversation so far, carried into the next turn
+}
+
+// openAskSession loads the configuration and connects to the source, if any
+func openAskSession(opts AskOptions) (*askSession, error) {
+	exists, err := config.Exists()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check config: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("aiq is not configured yet; run aiq interactively once to set up the LLM")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	clients, err := newLLMClients(cfg, opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	log := opts.Log
+	if log == nil {
+		log = os.Stderr
+	}
+	s := &askSession{opts: opts, limits: cfg.Limits, llmClients: clients, out: ui.NewOutput(log)}
+	if opts.SourceName != "" {
+		s.src, err = source.GetSource(opts.SourceName)
+		if err != nil {
+			return nil, err
+		}
+
+		actualSource := s.src
+		if opts.Database != "" {
+			tempSource := *s.src
+			tempSource.Database = opts.Database
+			actualSource = &tempSource
+		}
+		s.conn, err = openConnection(actualSource)
+		if err != nil {
+			return nil, err
+		}
+
+		s.databaseName = actualSource.Database
+		s.schema, err = s.conn.GetSchema(context.Background(), s.databaseName)
+		if err != nil {
+			s.out.Warning(fmt.Sprintf("Failed to fetch schema: %v. Continuing without schema context.", err))
+			s.schema = &db.Schema{}
+		}
+	}
+
+	s.skillsManager = skills.NewManager()
+	if err := s.skillsManager.Initialize(); err != nil {
+		s.out.Warning(fmt.Sprintf("Failed to initialize Skills manager: %v. Continuing without Skills.", err))
+	}
+	s.mcpManager = startMCPServers(s.out, cfg.MCPServers)
+	checkLoopLimits(s.out, cfg.Limits)
+	if s.src != nil {
+		s.sess = session.NewSession(s.src.Name, string(s.src.Type))
+	} else {
+		s.sess = session.NewSession("", "")
+	}
+	return s, nil
+}
+
+// Close stops external MCP servers and closes the database connection
+func (s *askSession) Close() {
+	s.mcpManager.Close()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// run runs one tool-calling turn; configure may attach event and confirmation hooks
+func (s *askSession) run(question string, configure func(*ToolHandler)) (string, TurnOutcome, error) {
+	llmClient := s.llmClients.client(config.TaskChat)
+	toolHandler := NewToolHandler(s.conn, s.skillsManager, llmClient)
+	toolHandler.out = s.out
+	s.llmClients.configure(toolHandler)
+	if s.schema != nil {
+		toolHandler.SetSchema(s.schema, s.databaseName)
+	}
+	toolHandler.SetConfirmPolicy(s.opts.Policy)
+	toolHandler.SetExternalTools(s.mcpManager)
+	toolHandler.SetLimits(s.limits)
+	// Results and the answer are written in the requested format instead of printed while the loop runs
+	toolHandler.SetRenderResults(false)
+	toolHandler.SetStreamText(false)
+	if configure != nil {
+		configure(toolHandler)
+	}
+
+	schemaContext, databaseType := buildSchemaContext(s.src, s.schema)
+	tools := append(tool.GetLLMFunctionsWithBuiltin(s.conn), s.mcpManager.Functions()...)
+	answer, _, messages, err := toolHandler.HandleToolCallLoop(context.Background(), llmClient, question, schemaContext, databaseType, nil, tools, loadRawMessages(s.sess))
+	for _, limit := range toolHandler.Outcome().Limits {
+		s.sess.RecordLimit(limit.Reason, limit.Action)
+	}
+	recordUsage(s.sess, toolHandler.Outcome())
+	if err != nil {
+		return "", toolHandler.Outcome(), err
+	}
+
+	storeRawMessages(s.sess, messages)
+	s.sess.AddMessage("user", question)
+	if answer != "" {
+		s.sess.AddMessage("assistant", answer)
+	} else {
+		s.sess.AddMessage("assistant", "Operation completed.")
+	}
+	return answer, toolHandler.Outcome(), nil
+}
+
+// writeAskOutput writes the answer and result sets in the requested format
+// In JSON format a failed turn is reported as {"status": "error"} so scripts can always parse the output
+func writeAskOutput(out io.Writer, format, answer string, outcome TurnOutcome, turnErr error) error {
+	switch format {
+	case FormatJSON:
+		doc := map[string]interface{}{
+			"status":  "success",
+			"answer":  answer,
+			"results": resultSetsToJSON(outcome.ResultSets),
+		}
+		if len(outcome.Denied) > 0 {
+			doc["status"] = "error"
+			doc["denied"] = outcome.Denied
+		}
+		if outcome.SQLError != "" {
+			doc["status"] = "error"
+			doc["error"] = outcome.SQLError
+		}
+		if len(outcome.Limits) > 0 {
+			doc["limits_reached"] = outcome.Limits
+		}
+		if outcome.Usage.Calls > 0 {
+			doc["usage"] = outcome.Usage
+		}
+		if turnErr != nil {
+			doc["status"] = "error"
+			doc["error"] = turnErr.Error()
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+
+	case FormatCSV:
+		if turnErr != nil {
+			return nil
+		}
+		first := true
+		for _, set := range outcome.ResultSets {
+			if len(set.Columns) == 0 {
+				continue
+			}
+			// Result sets are separated by an empty line
+			if !first {
+				if _, err := fmt.Fprintln(out); err != nil {
+					return err
+				}
+			}
+			first = false
+
+			w := csv.NewWriter(out)
+			if err := w.Write(set.Columns); err != nil {
+				return err
+			}
+			if err := w.WriteAll(set.Rows); err != nil {
+				return err
+			}
+		}
+		// The answer is not part of the CSV data
+		if answer != "" {
+			fmt.Fprintln(os.Stderr, answer)
+		}
+		return nil
+
+	default:
+		if turnErr != nil {
+			return nil
+		}
+		for _, set := range outcome.ResultSets {
+			if len(set.Columns) == 0 {
+				if set.RowsAffected >= 0 {
+					if _, err := fmt.Fprintf(out, "Query OK, %d row(s) affected\n\n", set.RowsAffected); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			table, err := tool.RenderTableString(set.Columns, set.Rows)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(out, "%s\n%d row(s) in set\n\n", table, len(set.Rows)); err != nil {
+				return err
+			}
+		}
+		if answer != "" {
+			if _, err := fmt.Fprintln(out, answer); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,158 @@
+package sql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aiq/aiq/internal/db"
+)
+
+// TestWriteAskOutput tests the output shapes of aiq ask
+func TestWriteAskOutput(t *testing.T) {
+	sets := []db.ResultSet{
+		{Columns: []string{"id", "name"}, Rows: [][]string{{"1", "Ada"}, {"2", "Lin, Bo"}}, RowsAffected: -1},
+		{Rows: [][]string{}, RowsAffected: 3},
+		{Columns: []string{"total"}, Rows: [][]string{{"2"}}, RowsAffected: -1},
+	}
+
+	decode := func(t *testing.T, out *bytes.Buffer) map[string]interface{} {
+		t.Helper()
+		var doc map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+			t.Fatalf("Expected valid JSON, got %q: %v", out.String(), err)
+		}
+		return doc
+	}
+
+	t.Run("json success", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := writeAskOutput(&out, FormatJSON, "Two users.", TurnOutcome{ResultSets: sets}, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		doc := decode(t, &out)
+		if doc["status"] != "success" || doc["answer"] != "Two users." {
+			t.Errorf("Expected success with the answer, got %v", doc)
+		}
+		results, ok := doc["results"].([]interface{})
+		if !ok || len(results) != 3 {
+			t.Fatalf("Expected 3 results, got %v", doc["results"])
+		}
+		first := results[0].(map[string]interface{})
+		if first["row_count"] != float64(2) || len(first["rows"].([]interface{})) != 2 {
+			t.Errorf("Expected the first result set with 2 rows, got %v", first)
+		}
+		if affected := results[1].(map[string]interface{})["rows_affected"]; affected != float64(3) {
+			t.Errorf("Expected rows_affected 3 for the second set, got %v", affected)
+		}
+		if _, ok := doc["error"]; ok {
+			t.Error("Expected no error field on success")
+		}
+	})
+
+	t.Run("json reports denials and failures as errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			outcome TurnOutcome
+			turnErr error
+			errText string
+		}{
+			{"denied", TurnOutcome{Denied: []string{"DROP TABLE users"}}, nil, ""},
+			{"sql error", TurnOutcome{SQLError: "Table 'shop.missing' doesn't exist"}, nil, "Table 'shop.missing' doesn't exist"},
+			{"turn error", TurnOutcome{}, errors.New("LLM call failed"), "LLM call failed"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var out bytes.Buffer
+				if err := writeAskOutput(&out, FormatJSON, "", tt.outcome, tt.turnErr); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				doc := decode(t, &out)
+				if doc["status"] != "error" {
+					t.Errorf("Expected status error, got %v", doc["status"])
+				}
+				if tt.errText != "" && doc["error"] != tt.errText {
+					t.Errorf("Expected error %q, got %v", tt.errText, doc["error"])
+				}
+				if len(tt.outcome.Denied) > 0 && doc["denied"] == nil {
+					t.Error("Expected the denied operations to be listed")
+				}
+			})
+		}
+	})
+
+	t.Run("csv writes sets with columns separated by an empty line", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := writeAskOutput(&out, FormatCSV, "answer goes to stderr", TurnOutcome{ResultSets: sets}, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := "id,name\n1,Ada\n2,\"Lin, Bo\"\n\ntotal\n2\n"
+		if out.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, out.String())
+		}
+	})
+
+	t.Run("csv and text write nothing for a failed turn", func(t *testing.T) {
+		for _, format := range []string{FormatCSV, FormatText} {
+			var out bytes.Buffer
+			if err := writeAskOutput(&out, format, "partial", TurnOutcome{ResultSets: sets}, errors.New("failed")); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("Expected no %s output, got %q", format, out.String())
+			}
+		}
+	})
+
+	t.Run("text renders tables, affected rows and the answer", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := writeAskOutput(&out, FormatText, "Two users.", TurnOutcome{ResultSets: sets}, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		text := out.String()
+		for _, want := range []string{"Ada", "2 row(s) in set", "Query OK, 3 row(s) affected", "1 row(s) in set", "Two users."} {
+			if !strings.Contains(text, want) {
+				t.Errorf("Expected output to contain %q, got %q", want, text)
+			}
+		}
+		if strings.Index(text, "Ada") > strings.Index(text, "Two users.") {
+			t.Error("Expected the answer after the results")
+		}
+	})
+}
+
+// TestOutcomeError tests which turn outcomes make aiq ask fail
+func TestOutcomeError(t *testing.T) {
+	if err := outcomeError(TurnOutcome{ResultSets: []db.ResultSet{{RowsAffected: 1}}}, PolicyDeny); err != nil {
+		t.Errorf("Expected no error for a successful turn, got %v", err)
+	}
+
+	err := outcomeError(TurnOutcome{Denied: []string{"DROP TABLE users", "DELETE FROM orders"}}, PolicyAllowLowRisk)
+	if err == nil || !strings.Contains(err.Error(), "2 operation(s) denied by the allow-low-risk policy") {
+		t.Errorf("Expected a denial error naming the policy, got %v", err)
+	}
+
+	err = outcomeError(TurnOutcome{Denied: []string{"DROP TABLE users"}}, PolicyPrompt)
+	if err == nil || !strings.Contains(err.Error(), "prompt policy") {
+		t.Errorf("Expected the prompt policy to be named, got %v", err)
+	}
+
+	err = outcomeError(TurnOutcome{SQLError: "syntax error"}, PolicyDeny)
+	if err == nil || !strings.Contains(err.Error(), "query failed: syntax error") {
+		t.Errorf("Expected the failed query to be reported, got %v", err)
+	}
+}
+
+// TestRunAsk_Format tests that unknown formats are rejected before anything runs
+func TestRunAsk_Format(t *testing.T) {
+	var out bytes.Buffer
+	err := RunAsk(AskOptions{Question: "how many users?", Format: "xml"}, strings.NewReader(""), &out)
+	if err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Errorf("Expected unknown format error, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output, got %q", out.String())
+	}
+}
@@ -0,0 +1,41 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/mcp"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// mcpStartTimeout bounds starting an external MCP server and listing its tools
+const mcpStartTimeout = 30 * time.Second
+
+// startMCPServers starts the external MCP servers declared in config
+// Servers that fail to start are reported on out and skipped; the session continues without their tools.
+func startMCPServers(out *ui.Output, servers []config.MCPServerConfig) *mcp.Manager {
+	manager := mcp.NewManager()
+	for _, server := range servers {
+		if err := config.ValidateMCPServer(server); err != nil {
+			out.Warning(fmt.Sprintf("Skipping MCP server: %v", err))
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), mcpStartTimeout)
+		count, err := manager.Start(ctx, server.Name, server.Command, server.Args, server.Environ())
+		cancel()
+		if err != nil {
+			out.Warning(fmt.Sprintf("MCP server %s unavailable: %v", server.Name, err))
+			continue
+		}
+		out.Info(fmt.Sprintf("MCP server %s connected (%d tool(s)).", server.Name, count))
+	}
+	return manager
+}
+
+// SetExternalTools routes calls to tools of external MCP servers through manager
+func (h *ToolHandler) SetExternalTools(manager *mcp.Manager) {
+	h.external = manager
+}
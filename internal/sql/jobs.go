@@ -0,0 +1,132 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aiq/aiq/internal/jobs"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// handleJobsCommand handles /jobs [list|show <id>|cancel <id>]
+func handleJobsCommand(manager *jobs.Manager, args []string) {
+	fmt.Println()
+	if len(args) == 0 || strings.ToLower(args[0]) == "list" {
+		listJobs(manager)
+		fmt.Println()
+		return
+	}
+
+	action := strings.ToLower(args[0])
+	if len(args) < 2 || (action != "show" && action != "cancel") {
+		ui.ShowWarning("Usage: /jobs [list | show <id> | cancel <id>]")
+		fmt.Println()
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+	if err != nil {
+		ui.ShowWarning(fmt.Sprintf("Invalid job ID: %s", args[1]))
+		fmt.Println()
+		return
+	}
+
+	switch action {
+	case "show":
+		showJob(manager, id)
+	case "cancel":
+		if err := manager.Cancel(id); err != nil {
+			ui.ShowError(err.Error())
+		} else {
+			ui.ShowSuccess(fmt.Sprintf("Job #%d cancelled.", id))
+		}
+	}
+	fmt.Println()
+}
+
+// listJobs prints all background jobs of the session
+func listJobs(manager *jobs.Manager) {
+	list := manager.List()
+	if len(list) == 0 {
+		ui.ShowInfo("No background jobs. Ask for a query to run in the background, or prefix a request with /bg.")
+		return
+	}
+
+	rows := make([][]string, 0, len(list))
+	for _, job := range list {
+		sqlText := strings.Join(strings.Fields(job.SQL), " ")
+		if len(sqlText) > 60 {
+			sqlText = sqlText[:60] + "..."
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("#%d", job.ID),
+			string(job.Status),
+			formatElapsed(job.Elapsed()),
+			sqlText,
+		})
+	}
+	ui.PrintTable([]string{"Job", "Status", "Elapsed", "SQL"}, rows)
+}
+
+// showJob prints the SQL and, once finished, the result of a background job
+func showJob(manager *jobs.Manager, id int) {
+	job, err := manager.Get(id)
+	if err != nil {
+		ui.ShowError(err.Error())
+		return
+	}
+
+	ui.ShowInfo(fmt.Sprintf("Job #%d (%s, %s):", job.ID, job.Status, formatElapsed(job.Elapsed())))
+	fmt.Println(ui.HighlightSQL(job.SQL))
+
+	switch job.Status {
+	case jobs.StatusRunning:
+		ui.ShowInfo("Still running. Use /jobs cancel to stop it.")
+	case jobs.StatusFailed:
+		ui.ShowError(job.Err.Error())
+	case jobs.StatusDone:
+		for i, set := range job.Result.ResultSets {
+			renderResultSet(nil, set, i, len(job.Result.ResultSets))
+		}
+	}
+}
+
+// jobDoneNotice returns the line shown when a background job finishes
+// Cancelled jobs return "" because the cancel command already reports them
+func jobDoneNotice(job jobs.Job) string {
+	elapsed := formatElapsed(job.Elapsed())
+	switch job.Status {
+	case jobs.StatusDone:
+		return ui.SuccessText(fmt.Sprintf("✓ Background job #%d finished (%s). Use /jobs show %d to view the result; it will be attached to your next message.", job.ID, elapsed, job.ID))
+	case jobs.StatusFailed:
+		return ui.ErrorText(fmt.Sprintf("✗ Background job #%d failed (%s): %v", job.ID, elapsed, job.Err))
+	default:
+		return ""
+	}
+}
+
+// jobResultMessage describes a finished background job for the LLM
+func jobResultMessage(job jobs.Job) string {
+	elapsed := formatElapsed(job.Elapsed())
+	switch job.Status {
+	case jobs.StatusDone:
+		summary := formatQueryResultSummary(job.Result)
+		if job.Result.RowsAffected >= 0 {
+			summary = fmt.Sprintf("Query executed successfully. %d row(s) affected.", job.Result.RowsAffected)
+		}
+		return fmt.Sprintf("Background job #%d finished after %s.\nSQL: %s\n%s", job.ID, elapsed, job.SQL, summary)
+	case jobs.StatusFailed:
+		return fmt.Sprintf("Background job #%d failed after %s.\nSQL: %s\nError: %v", job.ID, elapsed, job.SQL, job.Err)
+	default:
+		return fmt.Sprintf("Background job #%d was cancelled by the user after %s.\nSQL: %s", job.ID, elapsed, job.SQL)
+	}
+}
+
+// formatElapsed formats a job duration for display
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,187 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/llm"
+	"github.com/aiq/aiq/internal/prompt"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// Actions taken when a loop limit is reached
+const (
+	LimitContinue  = "continue"
+	LimitSummarize = "summarize"
+	LimitStop      = "stop"
+)
+
+// EventLimitReached is emitted with --format stream-json when a loop limit is reached
+const EventLimitReached = "limit_reached"
+
+// LimitHit records a loop limit that was reached and what was done about it
+type LimitHit struct {
+	Reason string `json:"reason"`
+	Action string `json:"action"`
+}
+
+// SetLimits sets the budgets of the tool-calling loop; unset limits use the defaults
+func (h *ToolHandler) SetLimits(limits config.LoopLimits) {
+	h.limits = limits
+}
+
+// checkLoopLimits warns on out about invalid limits in the config; invalid values fall back to the defaults
+func checkLoopLimits(out *ui.Output, limits config.LoopLimits) {
+	if err := config.ValidateLoopLimits(limits); err != nil {
+		out.Warning(fmt.Sprintf("Invalid limits config: %v. Using the defaults instead.", err))
+	}
+}
+
+// loopBudget tracks the iterations, wall time, tokens and consecutive tool errors of one loop run
+type loopBudget struct {
+	limits            config.LoopLimits
+	maxIterations     int
+	maxTokens         int
+	maxErrors         int
+	deadline          time.Time
+	iterations        int
+	tokens            int
+	consecutiveErrors int
+}
+
+// newLoopBudget starts a budget with the given limits
+func newLoopBudget(limits config.LoopLimits) *loopBudget {
+	limits = limits.WithDefaults()
+	return &loopBudget{
+		limits:        limits,
+		maxIterations: limits.MaxIterations,
+		maxTokens:     limits.MaxTokens,
+		maxErrors:     limits.MaxConsecutiveErrors,
+		deadline:      time.Now().Add(limits.Duration()),
+	}
+}
+
+// addCall records one LLM call and its token usage
+// Providers that do not report usage are charged an estimate of the request and reply size.
+func (b *loopBudget) addCall(messages []interface{}, response *llm.ChatResponse) {
+	b.iterations++
+	if response.Usage != nil && response.Usage.TotalTokens > 0 {
+		b.tokens += response.Usage.TotalTokens
+		return
+	}
+	if data, err := json.Marshal(messages); err == nil {
+		b.tokens += prompt.EstimateTokens(string(data))
+	}
+}
+
+// addToolResults records the tool result messages of one iteration
+// A failed call adds to the consecutive error count; a successful one resets it.
+func (b *loopBudget) addToolResults(messages []interface{}) {
+	for _, msg := range messages {
+		m, ok := msg.(map[string]interface{})
+		if !ok || m["role"] != "tool" {
+			continue
+		}
+		content, _ := m["content"].(string)
+		if toolResultFailed(content) {
+			b.consecutiveErrors++
+		} else {
+			b.consecutiveErrors = 0
+		}
+	}
+}
+
+// exceeded returns the reason the loop must not make another LLM call, or "" if it may
+func (b *loopBudget) exceeded() string {
+	switch {
+	case b.iterations >= b.maxIterations:
+		return fmt.Sprintf("reached the limit of %d iterations", b.maxIterations)
+	case !time.Now().Before(b.deadline):
+		return fmt.Sprintf("reached the time limit of %s", b.limits.Duration())
+	case b.maxTokens > 0 && b.tokens >= b.maxTokens:
+		return fmt.Sprintf("used %d tokens, over the limit of %d", b.tokens, b.maxTokens)
+	case b.consecutiveErrors >= b.maxErrors:
+		return fmt.Sprintf("%d tool calls failed in a row", b.consecutiveErrors)
+	}
+	return ""
+}
+
+// extend raises every exhausted limit by its configured amount
+func (b *loopBudget) extend() {
+	if b.iterations >= b.maxIterations {
+		b.maxIterations = b.iterations + b.limits.MaxIterations
+	}
+	if !time.Now().Before(b.deadline) {
+		b.deadline = time.Now().Add(b.limits.Duration())
+	}
+	if b.maxTokens > 0 && b.tokens >= b.maxTokens {
+		b.maxTokens = b.tokens + b.limits.MaxTokens
+	}
+	b.consecutiveErrors = 0
+}
+
+// toolResultFailed reports whether a tool result returned to the LLM describes a failure
+func toolResultFailed(result string) bool {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &data); err != nil {
+		return false
+	}
+	if status, _ := data["status"].(string); status == "error" {
+		return true
+	}
+	errorMsg, _ := data["error"].(string)
+	return errorMsg != ""
+}
+
+// limitAction decides what to do when a loop limit is reached
+// The user is asked in interactive chat; non-interactive runs summarize what was done so far.
+func (h *ToolHandler) limitAction(reason string) string {
+	if h.policy != PolicyPrompt || h.confirmer != nil {
+		h.out.Warning(fmt.Sprintf("The request %s; summarizing the progress so far.", reason))
+		return LimitSummarize
+	}
+	h.out.Println()
+	h.out.Warning(fmt.Sprintf("The request %s.", reason))
+	action, err := ui.ShowMenu("How should aiq proceed", []ui.MenuItem{
+		{Label: "Continue", Value: LimitContinue},
+		{Label: "Summarize the progress so far", Value: LimitSummarize},
+		{Label: "Stop", Value: LimitStop},
+	})
+	if err != nil {
+		// Treat an interrupted menu as stop
+		return LimitStop
+	}
+	return action
+}
+
+// summarizeProgress asks the LLM, without tools, for a final answer based on the work done so far
+func (h *ToolHandler) summarizeProgress(ctx context.Context, llmClient *llm.Client, messages []interface{}, reason string) (string, []interface{}, error) {
+	messages = append(messages, map[string]interface{}{
+		"role":    "user",
+		"content": fmt.Sprintf("The request %s and no more tools can be called. Summarize what has been done and found so far, and say what is left to do.", reason),
+	})
+
+	setStatus, stopThinking := h.out.LoadingStatus("Summarizing...")
+	response, err := h.chat(ctx, llmClient, messages, nil, setStatus, stopThinking)
+	stopThinking()
+	if err != nil {
+		return "", messages, fmt.Errorf("LLM call failed: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return "", messages, fmt.Errorf("no choices in response")
+	}
+
+	content := response.Choices[0].Message.Content
+	assistantMsg := map[string]interface{}{
+		"role":    "assistant",
+		"content": content,
+	}
+	messages = append(messages, assistantMsg)
+	if content != "" {
+		h.emit(StreamEvent{Type: EventAssistant, Content: content, Message: assistantMsg})
+	}
+	return content, messages, nil
+}
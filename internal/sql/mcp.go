@@ -0,0 +1,308 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aiq/aiq/internal/db"
+	"github.com/aiq/aiq/internal/llm"
+	"github.com/aiq/aiq/internal/mcp"
+	"github.com/aiq/aiq/internal/skills"
+	"github.com/aiq/aiq/internal/source"
+	"github.com/aiq/aiq/internal/tool"
+	"github.com/aiq/aiq/internal/ui"
+	"github.com/aiq/aiq/internal/version"
+)
+
+// Resource URIs of the schema exposed by aiq mcp
+const (
+	mcpSchemaURI      = "aiq://schema"
+	mcpTableURIPrefix = "aiq://schema/"
+)
+
+// MCPOptions configures the MCP server
+type MCPOptions struct {
+	SourceName string        // Source whose database is exposed; empty exposes only rendering and skills
+	Database   string        // Optional database overriding the source's database
+	Policy     ConfirmPolicy // Decides which SQL runs, as nobody can confirm it; PolicyPrompt means PolicyDeny
+	Log        io.Writer     // Warnings and progress output; nil writes to stderr
+}
+
+// RunMCP serves aiq's database tools, schema and skills over the MCP stdio transport
+// Requests are read from in and responses written to out; all other output goes to opts.Log.
+func RunMCP(ctx context.Context, opts MCPOptions, in io.Reader, out io.Writer) error {
+	log := opts.Log
+	if log == nil {
+		log = os.Stderr
+	}
+	progress := ui.NewOutput(log)
+
+	// Clients call tools without a user at hand, so calls needing confirmation are refused
+	policy := opts.Policy
+	if policy == PolicyPrompt {
+		policy = PolicyDeny
+	}
+
+	handler := &mcpHandler{policy: policy}
+	if opts.SourceName != "" {
+		src, err := source.GetSource(opts.SourceName)
+		if err != nil {
+			return err
+		}
+		if opts.Database != "" {
+			tempSource := *src
+			tempSource.Database = opts.Database
+			src = &tempSource
+		}
+		conn, err := openConnection(src)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		schema, err := conn.GetSchema(ctx, src.Database)
+		if err != nil {
+			progress.Warning(fmt.Sprintf("Failed to fetch schema: %v. Continuing without schema resources.", err))
+			schema = &db.Schema{}
+		}
+		handler.src = src
+		handler.conn = conn
+		handler.schema = schema
+	}
+
+	handler.skillsManager = skills.NewManager()
+	if err := handler.skillsManager.Initialize(); err != nil {
+		progress.Warning(fmt.Sprintf("Failed to initialize Skills manager: %v. Continuing without Skills.", err))
+	}
+
+	handler.toolHandler = NewToolHandler(handler.conn, handler.skillsManager, nil)
+	// Out carries the protocol, so the tool layer's output must not reach it
+	handler.toolHandler.out = progress
+	if handler.schema != nil {
+		handler.toolHandler.SetSchema(handler.schema, handler.src.Database)
+	}
+	handler.toolHandler.SetRenderResults(false)
+	handler.toolHandler.SetConfirmPolicy(policy)
+
+	server := mcp.NewServer("aiq", version.GetVersion(), handler)
+	server.SetInstructions(handler.instructions())
+	return server.Serve(ctx, in, out)
+}
+
+// mcpHandler implements mcp.Handler on top of the chat mode's tool layer
+type mcpHandler struct {
+	src           *source.Source
+	conn          *db.Connection
+	schema        *db.Schema // Refreshed in place by the tool handler after DDL
+	skillsManager *skills.Manager
+	toolHandler   *ToolHandler
+	policy        ConfirmPolicy // PolicyDeny or PolicyAllowLowRisk
+}
+
+// instructions tells the client what the server is connected to
+func (h *mcpHandler) instructions() string {
+	if h.src == nil {
+		return "aiq without a data source: render_table and render_chart format data as text; skills are available as prompts."
+	}
+	return fmt.Sprintf("aiq connected to %s database %q (source %s). Read %s for the schema. "+
+		"execute_sql runs one statement per call: %s",
+		h.src.GetDatabaseType(), h.src.Database, h.src.Name, mcpSchemaURI, h.allowedSQL())
+}
+
+// allowedSQL describes the statements the policy lets execute_sql run
+func (h *mcpHandler) allowedSQL() string {
+	if h.policy == PolicyAllowLowRisk {
+		return "reads (SELECT, SHOW, DESCRIBE, EXPLAIN without ANALYZE) and CREATE TABLE; other writes and DDL are refused."
+	}
+	return "reads only (SELECT, SHOW, DESCRIBE, EXPLAIN without ANALYZE); writes and DDL are refused."
+}
+
+// Tools lists execute_sql (with a source) and the render tools
+func (h *mcpHandler) Tools() []mcp.Tool {
+	var tools []mcp.Tool
+	if h.conn != nil {
+		tools = append(tools, mcp.Tool{
+			Name: "execute_sql",
+			Description: fmt.Sprintf("Execute one SQL statement against the %s database %q and return the result sets as JSON. "+
+				"Several statements per call are refused. Allowed statements: %s", h.src.GetDatabaseType(), h.src.Database, h.allowedSQL()),
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"sql": map[string]interface{}{
+						"type":        "string",
+						"description": fmt.Sprintf("The SQL statement. Use placeholders (%s, %s, ...) for values and pass them in params", h.conn.Placeholder(1), h.conn.Placeholder(2)),
+					},
+					"params": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": []string{"string", "number", "boolean", "null"}},
+						"description": "Optional: Values bound to the placeholders in sql, in order",
+					},
+				},
+				"required": []string{"sql"},
+			},
+		})
+	}
+
+	// The render tools keep the parameters of the chat mode's definitions
+	for _, fn := range tool.GetLLMFunctions() {
+		switch fn.Name {
+		case "render_table":
+			tools = append(tools, mcp.Tool{Name: fn.Name, Description: "Format columns and rows as a text table.", InputSchema: fn.Parameters})
+		case "render_chart":
+			tools = append(tools, mcp.Tool{Name: fn.Name, Description: "Render columns and rows as a text chart (bar, line, pie or scatter).", InputSchema: fn.Parameters})
+		}
+	}
+	return tools
+}
+
+// CallTool runs a tool; refusals and SQL errors are returned as error results for the model to read
+func (h *mcpHandler) CallTool(ctx context.Context, name string, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	switch name {
+	case "execute_sql":
+		return h.executeSQL(ctx, args)
+	case "render_table", "render_chart":
+		argsJSON, err := json.Marshal(args)
+		if err != nil {
+			return nil, err
+		}
+		toolCall := llm.ToolCall{ID: name, Type: "function"}
+		toolCall.Function.Name = name
+		toolCall.Function.Arguments = string(argsJSON)
+		raw, err := h.toolHandler.ExecuteTool(ctx, toolCall)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Output string `json:"output"`
+			Error  string `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("unexpected %s result: %w", name, err)
+		}
+		if result.Error != "" {
+			return mcp.ErrorResult(result.Error), nil
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent(result.Output)}}, nil
+	}
+	return nil, fmt.Errorf("unknown tool: %s", name)
+}
+
+// executeSQL refuses several statements and statements denied by the policy, then validates and runs the statement
+func (h *mcpHandler) executeSQL(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	sqlText, ok := args["sql"].(string)
+	if !ok || strings.TrimSpace(sqlText) == "" {
+		return mcp.ErrorResult("sql is required"), nil
+	}
+	params, err := tool.ParseSQLParams(args)
+	if err != nil {
+		return mcp.ErrorResult(err.Error()), nil
+	}
+
+	// Classify by code only; a risk_level supplied by the client is not trusted. Drivers run
+	// argless SQL through the simple protocol, which executes every statement, so only one is accepted.
+	if tool.CountSQLStatements(sqlText) > 1 {
+		return mcp.ErrorResult("Refused: over MCP aiq runs one statement per call. Send each statement separately."), nil
+	}
+	// The same policy decides for aiq ask, so both refuse the same statements
+	if reason := h.toolHandler.policyDenial("execute_sql", args); reason != "" {
+		return mcp.ErrorResult(fmt.Sprintf("Refused: %s. Statements allowed over MCP: %s", reason, h.allowedSQL())), nil
+	}
+
+	result, err := h.toolHandler.executeSQL(ctx, sqlText, params)
+	if err != nil {
+		return mcp.ErrorResult(string(sqlErrorResult(err))), nil
+	}
+
+	sets := result.ResultSets
+	if len(sets) == 0 {
+		sets = []db.ResultSet{{Columns: result.Columns, Rows: result.Rows, RowsAffected: result.RowsAffected}}
+	}
+	data, err := json.Marshal(map[string]interface{}{"status": "success", "result_sets": resultSetsToJSON(sets)})
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent(string(data))}}, nil
+}
+
+// Resources lists the whole schema and each table
+func (h *mcpHandler) Resources() []mcp.Resource {
+	if h.schema == nil {
+		return nil
+	}
+	resources := []mcp.Resource{{
+		URI:         mcpSchemaURI,
+		Name:        "schema",
+		Description: fmt.Sprintf("Tables and columns of database %s", h.src.Database),
+		MimeType:    "text/plain",
+	}}
+	for _, table := range h.schema.Tables {
+		resources = append(resources, mcp.Resource{
+			URI:         mcpTableURIPrefix + table.Name,
+			Name:        table.Name,
+			Description: fmt.Sprintf("Columns of table %s", table.Name),
+			MimeType:    "text/plain",
+		})
+	}
+	return resources
+}
+
+// ReadResource returns the schema formatted as it is given to aiq's own LLM
+func (h *mcpHandler) ReadResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	if h.schema == nil {
+		return nil, fmt.Errorf("no data source selected")
+	}
+	if uri == mcpSchemaURI {
+		text, _ := buildSchemaContext(h.src, h.schema)
+		return []mcp.ResourceContents{{URI: uri, MimeType: "text/plain", Text: text}}, nil
+	}
+
+	name := strings.TrimPrefix(uri, mcpTableURIPrefix)
+	if name != uri {
+		for _, table := range h.schema.Tables {
+			if table.Name == name {
+				single := &db.Schema{Tables: []db.TableInfo{table}}
+				return []mcp.ResourceContents{{URI: uri, MimeType: "text/plain", Text: single.FormatSchema()}}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("resource not found: %s", uri)
+}
+
+// Prompts lists the loaded skills
+func (h *mcpHandler) Prompts() []mcp.Prompt {
+	metadata := h.skillsManager.GetMetadata()
+	prompts := make([]mcp.Prompt, 0, len(metadata))
+	for _, md := range metadata {
+		prompts = append(prompts, mcp.Prompt{
+			Name:        md.Name,
+			Description: md.Description,
+			Arguments: []mcp.PromptArgument{{
+				Name:        "question",
+				Description: "Optional request to answer with this skill",
+			}},
+		})
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts
+}
+
+// GetPrompt returns a skill's content as a user message, followed by the question if given
+func (h *mcpHandler) GetPrompt(ctx context.Context, name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	skill, err := h.skillsManager.LoadSkill(name)
+	if err != nil {
+		return nil, fmt.Errorf("prompt not found: %s", name)
+	}
+	text := fmt.Sprintf("Use the following skill (%s):\n\n%s", skill.Name, skill.Content)
+	if question := strings.TrimSpace(args["question"]); question != "" {
+		text += "\n\n" + question
+	}
+	return &mcp.GetPromptResult{
+		Description: skill.Description,
+		Messages:    []mcp.PromptMessage{{Role: "user", Content: mcp.TextContent(text)}},
+	}, nil
+}
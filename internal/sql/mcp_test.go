@@ -0,0 +1,41 @@
+package sql
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestMCPHandler_ExecuteSQLPolicy tests that execute_sql refuses the statements the confirmation policy denies
+func TestMCPHandler_ExecuteSQLPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ConfirmPolicy
+		sql     string
+		refusal string
+	}{
+		{"deny refuses create table", PolicyDeny, "CREATE TABLE t (id INT)", "deny policy"},
+		{"deny refuses delete", PolicyDeny, "DELETE FROM users", "deny policy"},
+		{"allow-low-risk refuses delete", PolicyAllowLowRisk, "DELETE FROM users", "allow-low-risk policy"},
+		{"allow-low-risk refuses drop", PolicyAllowLowRisk, "DROP TABLE users", "allow-low-risk policy"},
+		{"several statements", PolicyAllowLowRisk, "SELECT 1; SELECT 2", "one statement per call"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toolHandler := &ToolHandler{}
+			toolHandler.SetConfirmPolicy(tt.policy)
+			h := &mcpHandler{policy: tt.policy, toolHandler: toolHandler}
+
+			result, err := h.executeSQL(context.Background(), map[string]interface{}{"sql": tt.sql})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !result.IsError || !strings.Contains(result.Text(), tt.refusal) {
+				t.Errorf("Expected a refusal mentioning %q, got %q", tt.refusal, result.Text())
+			}
+			if strings.Contains(result.Text(), "chat mode") {
+				t.Errorf("Expected the refusal not to point to chat mode, got %q", result.Text())
+			}
+		})
+	}
+}
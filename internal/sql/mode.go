@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/chzyer/readline"
@@ -12,6 +14,7 @@ import (
 	"github.com/aiq/aiq/internal/chart"
 	"github.com/aiq/aiq/internal/config"
 	"github.com/aiq/aiq/internal/db"
+	"github.com/aiq/aiq/internal/jobs"
 	"github.com/aiq/aiq/internal/llm"
 	"github.com/aiq/aiq/internal/session"
 	"github.com/aiq/aiq/internal/skills"
@@ -274,6 +277,11 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	// LLM clients of the profiles used for chat, skill matching and compression
+	llmClients, err := newLLMClients(cfg, "")
+	if err != nil {
+		return err
+	}
 
 	// Create database connection only if source exists
 	var conn *db.Connection
@@ -289,9 +297,9 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 			tempSource.Database = overrideDatabase
 			actualSource = &tempSource
 		}
-		conn, err = db.NewConnection(actualSource.DSN(), string(actualSource.Type))
+		conn, err = openConnection(actualSource)
 		if err != nil {
-			return fmt.Errorf("failed to connect to database: %w", err)
+			return err
 		}
 		defer conn.Close()
 
@@ -303,14 +311,25 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 		}
 	}
 
+	// Background queries; stopped before the connection is closed
+	jobManager := jobs.NewManager()
+	defer func() {
+		if n := jobManager.Running(); n > 0 {
+			ui.ShowWarning(fmt.Sprintf("Cancelling %d running background job(s).", n))
+		}
+		jobManager.CancelAll()
+	}()
+
 	// Initialize Skills manager
 	skillsManager := skills.NewManager()
 	if err := skillsManager.Initialize(); err != nil {
 		ui.ShowWarning(fmt.Sprintf("Failed to initialize Skills manager: %v. Continuing without Skills.", err))
 	}
 
-	// Create LLM client
-	llmClient := llm.NewClient(cfg.LLM.URL, cfg.LLM.APIKey, cfg.LLM.Model)
+	// Start external MCP servers; their tools are offered alongside the built-in ones
+	mcpManager := startMCPServers(nil, cfg.MCPServers)
+	defer mcpManager.Close()
+	checkLoopLimits(nil, cfg.Limits)
 
 	// Show mode info
 	if src != nil {
@@ -402,12 +421,16 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 	}
 
 	// Define available commands for hint display
-	commands := []string{"/exit", "/help", "/history", "/clear", "/paste", "/multiline", "/singleline"}
+	commands := []string{"/exit", "/help", "/history", "/clear", "/paste", "/multiline", "/singleline", "/jobs", "/bg", "/usage", "/model"}
 	commandDescriptions := map[string]string{
 		"/exit":       "Exit chat mode",
 		"/help":       "Show help",
 		"/history":    "View history",
 		"/clear":      "Clear history",
+		"/jobs":       "List, show or cancel background queries",
+		"/bg":         "Run the queries of a request in the background",
+		"/usage":      "Show token usage and estimated cost",
+		"/model":      "List LLM profiles or switch with /model <profile>",
 		"/paste":      "Enter paste mode for multi-line SQL",
 		"/multiline":  "Switch to multi-line input mode (Enter continues, empty line submits)",
 		"/singleline": "Switch to single-line input mode (Enter executes immediately)",
@@ -439,6 +462,15 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 	}
 	defer rl.Close()
 
+	// Report finished background jobs without waiting for the next prompt
+	jobManager.SetDoneHandler(func(job jobs.Job) {
+		if notice := jobDoneNotice(job); notice != "" {
+			fmt.Fprintln(rl.Stdout(), notice)
+		}
+	})
+	// Jobs cancelled on exit finish after readline is closed
+	defer jobManager.SetDoneHandler(nil)
+
 	// Update prompt dynamically (readline doesn't support dynamic prompts directly,
 	// but we can recreate it if source changes in future)
 	// For now, prompt is set once at initialization
@@ -512,6 +544,10 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 				fmt.Println("  /paste      - Enter paste mode for multi-line SQL (press Ctrl+D to finish)")
 				fmt.Println("  /multiline  - Switch to multi-line input mode (Enter continues, empty line submits)")
 				fmt.Println("  /singleline - Switch to single-line input mode (Enter executes immediately)")
+				fmt.Println("  /jobs       - List background queries (/jobs show <id>, /jobs cancel <id>)")
+				fmt.Println("  /bg <text>  - Ask a question and run its queries in the background")
+				fmt.Println("  /usage      - Show token usage of the last request and the session, with estimated cost")
+				fmt.Println("  /model      - List LLM profiles (/model <profile> [chat|skills|compression|all] to switch)")
 				fmt.Println()
 				modeText := "single-line"
 				if inputMode == InputModeMultiLine {
@@ -601,6 +637,29 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 			}
 		}
 
+		// Handle /jobs command
+		if fields := strings.Fields(query); strings.ToLower(fields[0]) == "/jobs" {
+			handleJobsCommand(jobManager, fields[1:])
+			continue
+		}
+
+		// Handle /bg command - run the request's queries in the background
+		backgroundTurn := false
+		if fields := strings.Fields(query); strings.ToLower(fields[0]) == "/bg" {
+			if conn == nil {
+				ui.ShowWarning("Background queries need a database source.")
+				fmt.Println()
+				continue
+			}
+			query = strings.TrimSpace(query[len(fields[0]):])
+			if query == "" {
+				ui.ShowWarning("Usage: /bg <request>")
+				fmt.Println()
+				continue
+			}
+			backgroundTurn = true
+		}
+
 		// Handle /history command
 		if strings.ToLower(query) == "/history" {
 			history := sess.GetHistory()
@@ -628,6 +687,18 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 			continue
 		}
 
+		// Handle /model command - list or switch LLM profiles
+		if fields := strings.Fields(query); strings.ToLower(fields[0]) == "/model" {
+			handleModelCommand(llmClients, fields[1:])
+			continue
+		}
+
+		// Handle /usage command
+		if strings.ToLower(query) == "/usage" {
+			showUsage(sess, cfg)
+			continue
+		}
+
 		// Handle /clear command
 		if strings.ToLower(query) == "/clear" {
 			confirm, err := ui.ShowConfirm("Clear conversation history?")
@@ -664,71 +735,36 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 			}
 			// Tool success message is displayed by tool.ExecuteSQL
 
-			// Display results
+			// Display results, one table per result set
 			fmt.Println()
-			if len(result.Rows) == 0 {
-				ui.ShowInfo("Query executed successfully. No rows returned.")
+			if result.RowsAffected >= 0 {
+				ui.ShowSuccess(fmt.Sprintf("Query executed successfully. %d row(s) affected.", result.RowsAffected))
 				fmt.Println()
 				continue
 			}
 
-			ui.ShowSuccess(fmt.Sprintf("Query executed successfully. %d row(s) returned.", len(result.Rows)))
-			fmt.Println()
+			for i, set := range result.ResultSets {
+				if len(result.ResultSets) > 1 {
+					ui.ShowInfo(fmt.Sprintf("Result set %d of %d", i+1, len(result.ResultSets)))
+				}
+				if len(set.Rows) == 0 {
+					ui.ShowInfo("Query executed successfully. No rows returned.")
+					fmt.Println()
+					continue
+				}
 
-			// Automatically display as table for execute command
-			ui.PrintTable(result.Columns, result.Rows)
-			fmt.Println()
+				ui.ShowSuccess(fmt.Sprintf("Query executed successfully. %d row(s) returned.", len(set.Rows)))
+				fmt.Println()
+
+				// Automatically display as table for execute command
+				ui.PrintTable(set.Columns, set.Rows)
+				fmt.Println()
+			}
 			continue
 		}
 
 		// Load complete messages array from session (includes tool calls and results)
-		var rawMessages []interface{}
-		if rawMsgs := sess.GetRawMessages(); len(rawMsgs) > 0 {
-			// Convert json.RawMessage to interface{} for use in HandleToolCallLoop
-			rawMessages = make([]interface{}, 0, len(rawMsgs))
-			for _, rawMsg := range rawMsgs {
-				var msg map[string]interface{}
-				if err := json.Unmarshal(rawMsg, &msg); err == nil {
-					// Ensure content field is always a string (not an object) or doesn't exist
-					// This is critical for LLM API compatibility
-					if content, exists := msg["content"]; exists {
-						switch v := content.(type) {
-						case string:
-							// Already a string, keep it (even if empty)
-						case map[string]interface{}, []interface{}:
-							// Content is an object/array, convert to JSON string
-							if jsonBytes, err := json.Marshal(v); err == nil {
-								msg["content"] = string(jsonBytes)
-							} else {
-								// If marshal fails, set to empty string
-								msg["content"] = ""
-							}
-						case nil:
-							// Content is null, remove it (assistant messages with tool_calls may not have content)
-							delete(msg, "content")
-						default:
-							// Convert other types to string
-							msg["content"] = fmt.Sprintf("%v", v)
-						}
-					}
-					// For assistant messages with tool_calls but no content, ensure content is not null
-					if role, ok := msg["role"].(string); ok && role == "assistant" {
-						if _, hasContent := msg["content"]; !hasContent {
-							// Assistant message without content - set to empty string
-							msg["content"] = ""
-						}
-					}
-					rawMessages = append(rawMessages, msg)
-				} else {
-					// If unmarshal fails, try to parse as ChatMessage
-					var chatMsg llm.ChatMessage
-					if err2 := json.Unmarshal(rawMsg, &chatMsg); err2 == nil {
-						rawMessages = append(rawMessages, chatMsg)
-					}
-					// If both fail, skip this message
-				}
-			}
-		}
+		rawMessages := loadRawMessages(sess)
 
 		// Convert existing session messages to LLM chat messages (for backward compatibility)
 		// Only used if rawMessages is empty
@@ -742,33 +778,72 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 			}
 		}
 
-		// Prepare schema context (empty for free mode)
-		var schemaContext string
-		var databaseType string
-		if src != nil && schema != nil {
-			schemaContext = schema.FormatSchema()
-			if schemaContext == "" {
-				schemaContext = fmt.Sprintf("Currently connected to database: %s\nNo schema information available yet.", src.Database)
-			} else {
-				schemaContext = fmt.Sprintf("Currently connected to database: %s\n\n%s", src.Database, schemaContext)
+		// Attach results of background jobs that finished since the last request
+		if finished := jobManager.TakeFinished(); len(finished) > 0 {
+			ids := make([]string, len(finished))
+			for i, job := range finished {
+				ids[i] = fmt.Sprintf("#%d", job.ID)
+				if len(rawMessages) > 0 {
+					rawMessages = append(rawMessages, map[string]interface{}{
+						"role":    "system",
+						"content": jobResultMessage(job),
+					})
+				} else {
+					conversationHistory = append(conversationHistory, llm.ChatMessage{
+						Role:    "system",
+						Content: jobResultMessage(job),
+					})
+				}
 			}
-			databaseType = src.GetDatabaseType()
-		} else {
-			// Free mode: no schema context
-			schemaContext = ""
-			databaseType = ""
+			ui.ShowInfo(fmt.Sprintf("Attached background job result(s) %s to the conversation.", strings.Join(ids, ", ")))
 		}
 
-		// Get tool definitions (including built-in tools)
-		tools := tool.GetLLMFunctionsWithBuiltin(conn)
+		// Prepare schema context (empty for free mode)
+		schemaContext, databaseType := buildSchemaContext(src, schema)
+
+		// Get tool definitions (including built-in tools and tools of external MCP servers)
+		tools := append(tool.GetLLMFunctionsWithBuiltin(conn), mcpManager.Functions()...)
 
 		// Create tool handler
+		llmClient := llmClients.client(config.TaskChat)
 		toolHandler := NewToolHandler(conn, skillsManager, llmClient)
+		llmClients.configure(toolHandler)
+		if schema != nil {
+			toolHandler.SetSchema(schema, actualDatabase)
+		}
+		toolHandler.SetExternalTools(mcpManager)
+		toolHandler.SetLimits(cfg.Limits)
+		if conn != nil {
+			toolHandler.SetJobs(jobManager, backgroundTurn)
+		}
 
 		// Use tool calling loop - LLM decides which tools to call
 		// Note: "Thinking..." and "Waiting..." messages are handled inside HandleToolCallLoop
-		finalResponse, queryResult, completeMessages, err := toolHandler.HandleToolCallLoop(ctx, llmClient, query, schemaContext, databaseType, conversationHistory, tools, rawMessages)
+		// Ctrl-C cancels the request instead of exiting aiq
+		turnCtx, stopInterrupt := signal.NotifyContext(ctx, os.Interrupt)
+		finalResponse, queryResult, completeMessages, err := toolHandler.HandleToolCallLoop(turnCtx, llmClient, query, schemaContext, databaseType, conversationHistory, tools, rawMessages)
+		stopInterrupt()
+		for _, limit := range toolHandler.Outcome().Limits {
+			sess.RecordLimit(limit.Reason, limit.Action)
+		}
+		recordUsage(sess, toolHandler.Outcome())
 
+		if err != nil && errors.Is(err, context.Canceled) {
+			// Keep the request and any partial reply so the conversation can go on from here
+			fmt.Println()
+			ui.ShowWarning("Request cancelled.")
+			fmt.Println()
+			if completeMessages != nil {
+				storeRawMessages(sess, completeMessages)
+			}
+			sess.AddMessage("user", query)
+			if finalResponse != "" {
+				sess.AddMessage("assistant", finalResponse)
+			} else {
+				sess.AddMessage("assistant", "Request cancelled.")
+			}
+			continue
+		}
 		if err != nil {
 			ui.ShowError(fmt.Sprintf("Failed to process request: %v", err))
 			ui.ShowInfo("Please check your LLM configuration and try again.")
@@ -788,8 +863,8 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 		// Note: We don't display summary if finalResponse is empty and queryResult exists,
 		// because results are already displayed (e.g., table format for SQL queries)
 
-		// Display response to user (only if there's actual text to display)
-		if displayText != "" {
+		// Display response to user (only if there's actual text to display and it was not streamed already)
+		if displayText != "" && !toolHandler.Outcome().Streamed {
 			fmt.Println()
 			fmt.Println(displayText)
 			fmt.Println()
@@ -797,21 +872,7 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 
 		// Save complete messages array to session (includes tool calls and results)
 		// This preserves full conversation context for next request
-		// We save the entire messages array (including system message) so next request can use it
-		if completeMessages != nil && len(completeMessages) > 0 {
-			// Convert to json.RawMessage array for storage
-			rawMsgs := make([]json.RawMessage, 0, len(completeMessages))
-			for _, msg := range completeMessages {
-				if msgBytes, err := json.Marshal(msg); err == nil {
-					rawMsgs = append(rawMsgs, json.RawMessage(msgBytes))
-				}
-			}
-			if len(rawMsgs) > 0 {
-				// Replace entire RawMessages array (not append) to avoid duplicates
-				// The completeMessages array already includes all previous messages + new ones
-				sess.SetRawMessages(rawMsgs)
-			}
-		}
+		storeRawMessages(sess, completeMessages)
 
 		// Also save legacy format for backward compatibility (user and assistant text only)
 		sess.AddMessage("user", query)
@@ -833,6 +894,77 @@ func RunSQLModeWithSource(providedSourceName string, sessionFile string, overrid
 	}
 }
 
+// loadRawMessages converts the complete messages stored in a session for use in HandleToolCallLoop
+func loadRawMessages(sess *session.Session) []interface{} {
+	var rawMessages []interface{}
+	if rawMsgs := sess.GetRawMessages(); len(rawMsgs) > 0 {
+		// Convert json.RawMessage to interface{} for use in HandleToolCallLoop
+		rawMessages = make([]interface{}, 0, len(rawMsgs))
+		for _, rawMsg := range rawMsgs {
+			var msg map[string]interface{}
+			if err := json.Unmarshal(rawMsg, &msg); err == nil {
+				// Ensure content field is always a string (not an object) or doesn't exist
+				// This is critical for LLM API compatibility
+				if content, exists := msg["content"]; exists {
+					switch v := content.(type) {
+					case string:
+						// Already a string, keep it (even if empty)
+					case map[string]interface{}, []interface{}:
+						// Content is an object/array, convert to JSON string
+						if jsonBytes, err := json.Marshal(v); err == nil {
+							msg["content"] = string(jsonBytes)
+						} else {
+							// If marshal fails, set to empty string
+							msg["content"] = ""
+						}
+					case nil:
+						// Content is null, remove it (assistant messages with tool_calls may not have content)
+						delete(msg, "content")
+					default:
+						// Convert other types to string
+						msg["content"] = fmt.Sprintf("%v", v)
+					}
+				}
+				// For assistant messages with tool_calls but no content, ensure content is not null
+				if role, ok := msg["role"].(string); ok && role == "assistant" {
+					if _, hasContent := msg["content"]; !hasContent {
+						// Assistant message without content - set to empty string
+						msg["content"] = ""
+					}
+				}
+				rawMessages = append(rawMessages, msg)
+			} else {
+				// If unmarshal fails, try to parse as ChatMessage
+				var chatMsg llm.ChatMessage
+				if err2 := json.Unmarshal(rawMsg, &chatMsg); err2 == nil {
+					rawMessages = append(rawMessages, chatMsg)
+				}
+				// If both fail, skip this message
+			}
+		}
+	}
+	return rawMessages
+}
+
+// storeRawMessages saves the complete messages of a turn to the session (includes tool calls and results)
+// We save the entire messages array (including system message) so next request can use it
+func storeRawMessages(sess *session.Session, completeMessages []interface{}) {
+	if completeMessages != nil && len(completeMessages) > 0 {
+		// Convert to json.RawMessage array for storage
+		rawMsgs := make([]json.RawMessage, 0, len(completeMessages))
+		for _, msg := range completeMessages {
+			if msgBytes, err := json.Marshal(msg); err == nil {
+				rawMsgs = append(rawMsgs, json.RawMessage(msgBytes))
+			}
+		}
+		if len(rawMsgs) > 0 {
+			// Replace entire RawMessages array (not append) to avoid duplicates
+			// The completeMessages array already includes all previous messages + new ones
+			sess.SetRawMessages(rawMsgs)
+		}
+	}
+}
+
 // displayChart displays query results as a chart
 func displayChart(result *db.QueryResult) error {
 	// Check for single column result
@@ -916,3 +1048,51 @@ func getChartTypeLabel(ct chart.ChartType) string {
 		return "Unknown chart type"
 	}
 }
+
+// openConnection connects to a source and applies its per-connection settings
+func openConnection(src *source.Source) (*db.Connection, error) {
+	dsn, err := src.DSN()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build connection settings: %w", err)
+	}
+	conn, err := db.NewConnectionWithOptions(dsn, string(src.Type), src.ConnectOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := conn.SetProcedureErrorPattern(src.ProcedureErrorPattern); err != nil {
+		ui.ShowWarning(fmt.Sprintf("%v. Using the default pattern.", err))
+	}
+	conn.SetReconnectHandler(showReconnectEvent)
+	return conn, nil
+}
+
+// buildSchemaContext returns the schema context and database type for the system prompt
+// Both are empty in free mode (no source)
+func buildSchemaContext(src *source.Source, schema *db.Schema) (string, string) {
+	if src == nil || schema == nil {
+		return "", ""
+	}
+
+	schemaContext := schema.FormatSchema()
+	if schemaContext == "" {
+		schemaContext = fmt.Sprintf("Currently connected to database: %s\nNo schema information available yet.", src.Database)
+	} else {
+		schemaContext = fmt.Sprintf("Currently connected to database: %s\n\n%s", src.Database, schemaContext)
+	}
+	if settings := src.SessionSettingsContext(); settings != "" {
+		schemaContext = fmt.Sprintf("%s\n\n%s", schemaContext, settings)
+	}
+	return schemaContext, src.GetDatabaseType()
+}
+
+// showReconnectEvent reports automatic database reconnects in the UI
+func showReconnectEvent(event db.ReconnectEvent) {
+	switch {
+	case event.Done:
+		ui.ShowSuccess("Reconnected to database.")
+	case event.Failed:
+		ui.ShowError(fmt.Sprintf("Could not reconnect to database: %v", event.Err))
+	case event.Attempt == 0:
+		ui.ShowWarning("Database connection lost. Reconnecting...")
+	}
+}
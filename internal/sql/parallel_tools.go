@@ -0,0 +1,133 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aiq/aiq/internal/llm"
+	"github.com/aiq/aiq/internal/tool"
+	"github.com/aiq/aiq/internal/tool/builtin"
+	"github.com/aiq/aiq/internal/tool/schema"
+)
+
+// maxParallelToolCalls bounds the tool calls of one response that run at the same time
+const maxParallelToolCalls = 4
+
+// parallelResult is the result of a tool call executed ahead of the sequential loop
+type parallelResult struct {
+	result json.RawMessage
+	err    error
+}
+
+// parallelBatches marks the tool calls of a response that can run concurrently
+// Only runs of at least two consecutive read-only calls are marked: calls that need
+// confirmation or are refused by policy stay sequential and act as barriers, so no call runs
+// before a call issued earlier has been confirmed.
+func (h *ToolHandler) parallelBatches(toolCalls []llm.ToolCall) []bool {
+	eligible := make([]bool, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		eligible[i] = h.canRunInParallel(toolCall)
+	}
+
+	parallel := make([]bool, len(toolCalls))
+	for start := 0; start < len(toolCalls); {
+		end := start
+		for end < len(toolCalls) && eligible[end] {
+			end++
+		}
+		if end-start >= 2 {
+			for i := start; i < end; i++ {
+				parallel[i] = true
+			}
+		}
+		if end == start {
+			end++
+		}
+		start = end
+	}
+	return parallel
+}
+
+// canRunInParallel reports whether code classifies a tool call as read-only and independent of shared state
+// Only read-only SQL, the render tools and custom tools declared low risk qualify. Tools whose low
+// rating would rest on the LLM's own risk_level (file operations, HTTP requests, external MCP tools)
+// stay sequential, so a write cannot run concurrently with a read of the same resource.
+func (h *ToolHandler) canRunInParallel(toolCall llm.ToolCall) bool {
+	name := toolCall.Function.Name
+	args, err := toolCall.ParseArguments()
+	if err != nil {
+		return false
+	}
+
+	switch name {
+	case "execute_sql":
+		// DDL refreshes the cached schema that concurrent queries are validated against
+		if !readOnlySQL(args) {
+			return false
+		}
+	case "render_table", "render_chart":
+	default:
+		if level, ok := builtin.CustomToolRiskLevel(name); !ok || level != "low" {
+			return false
+		}
+	}
+
+	var validationErr *schema.ValidationError
+	if errors.As(h.Tools().Validate(name, args), &validationErr) {
+		return false
+	}
+	if h.policy != PolicyPrompt {
+		return h.policyDenial(name, args) == ""
+	}
+	// SQL the LLM rated high still asks for confirmation, which a batch must not overtake
+	return name != "execute_sql" || tool.GetRiskAssessor(name).AssessRisk(name, args) == tool.RiskLow
+}
+
+// runParallelBatch executes the run of parallel calls starting at start with a bounded worker pool
+// Results are stored by call index, and the loop reports them in the order the calls were issued.
+func (h *ToolHandler) runParallelBatch(ctx context.Context, toolCalls []llm.ToolCall, parallel []bool, start int, results map[int]parallelResult) {
+	end := start
+	for end < len(toolCalls) && parallel[end] {
+		end++
+	}
+
+	stopWaiting := h.out.Loading(fmt.Sprintf("Running %d tool calls in parallel...", end-start))
+	defer stopWaiting()
+
+	var mu sync.Mutex
+	runBounded(end-start, maxParallelToolCalls, func(i int) {
+		result, err := h.ExecuteTool(ctx, toolCalls[start+i])
+		mu.Lock()
+		results[start+i] = parallelResult{result: result, err: err}
+		mu.Unlock()
+	})
+}
+
+// runBounded calls run for 0..n-1 with at most limit calls running at a time and waits for all of them
+func runBounded(n, limit int, run func(i int)) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// readOnlySQL reports whether code classifies every statement as read-only and none changes the schema
+// A risk_level supplied by the LLM is ignored.
+func readOnlySQL(args map[string]interface{}) bool {
+	sqlText, ok := args["sql"].(string)
+	if !ok || tool.IsSchemaChangingSQL(sqlText) {
+		return false
+	}
+	return tool.IsReadOnlySQL(sqlText)
+}
@@ -0,0 +1,141 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aiq/aiq/internal/llm"
+)
+
+// newToolCall builds a tool call with JSON-encoded arguments
+func newToolCall(id, name string, args map[string]interface{}) llm.ToolCall {
+	data, _ := json.Marshal(args)
+	toolCall := llm.ToolCall{ID: id, Type: "function"}
+	toolCall.Function.Name = name
+	toolCall.Function.Arguments = string(data)
+	return toolCall
+}
+
+// TestParallelBatches tests which tool calls of a response are marked to run concurrently
+func TestParallelBatches(t *testing.T) {
+	sqlCall := func(sql string) llm.ToolCall {
+		return newToolCall("call", "execute_sql", map[string]interface{}{"sql": sql})
+	}
+	renderCall := newToolCall("call", "render_table", map[string]interface{}{"columns": []string{"a"}, "rows": [][]interface{}{{1}}})
+	selectCall := sqlCall("SELECT * FROM users")
+
+	tests := []struct {
+		name     string
+		policy   ConfirmPolicy
+		calls    []llm.ToolCall
+		expected []bool
+	}{
+		{"single call", PolicyPrompt, []llm.ToolCall{selectCall}, []bool{false}},
+		{"two reads", PolicyPrompt, []llm.ToolCall{selectCall, sqlCall("SHOW TABLES")}, []bool{true, true}},
+		{"reads and renders", PolicyPrompt, []llm.ToolCall{selectCall, renderCall, renderCall}, []bool{true, true, true}},
+		{"write splits the run", PolicyPrompt,
+			[]llm.ToolCall{selectCall, selectCall, sqlCall("DELETE FROM users"), selectCall, selectCall},
+			[]bool{true, true, false, true, true}},
+		{"DDL stays sequential", PolicyPrompt, []llm.ToolCall{selectCall, sqlCall("CREATE TABLE t (id INT)"), selectCall}, []bool{false, false, false}},
+		{"write after read in one call", PolicyPrompt, []llm.ToolCall{selectCall, sqlCall("SELECT 1; DROP TABLE users")}, []bool{false, false}},
+		{"read rated high by the LLM waits for confirmation", PolicyPrompt,
+			[]llm.ToolCall{selectCall, newToolCall("call", "execute_sql", map[string]interface{}{"sql": "SELECT 1", "risk_level": "high"})},
+			[]bool{false, false}},
+		{"policy decides instead of the LLM's rating", PolicyDeny,
+			[]llm.ToolCall{selectCall, newToolCall("call", "execute_sql", map[string]interface{}{"sql": "SELECT 1", "risk_level": "high"})},
+			[]bool{true, true}},
+		{"file operations stay sequential", PolicyPrompt,
+			[]llm.ToolCall{
+				newToolCall("call", "file_operations", map[string]interface{}{"operation": "read", "file_path": "a.txt", "risk_level": "low"}),
+				newToolCall("call", "file_operations", map[string]interface{}{"operation": "write", "file_path": "a.txt", "content": "x", "risk_level": "low"}),
+			},
+			[]bool{false, false}},
+		{"external tools stay sequential", PolicyPrompt,
+			[]llm.ToolCall{newToolCall("call", "docs__search", map[string]interface{}{"risk_level": "low"}), renderCall},
+			[]bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &ToolHandler{policy: tt.policy}
+			got := h.parallelBatches(tt.calls)
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestRunBounded tests that the worker pool caps concurrency and keeps results by index
+func TestRunBounded(t *testing.T) {
+	for _, n := range []int{1, 4, 10} {
+		t.Run(fmt.Sprintf("%d calls", n), func(t *testing.T) {
+			var running, peak int32
+			results := make([]int, n)
+			runBounded(n, maxParallelToolCalls, func(i int) {
+				now := atomic.AddInt32(&running, 1)
+				for {
+					old := atomic.LoadInt32(&peak)
+					if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+						break
+					}
+				}
+				// Later calls finish first, so completion order is the reverse of the call order
+				time.Sleep(time.Duration(n-i) * 2 * time.Millisecond)
+				results[i] = i
+				atomic.AddInt32(&running, -1)
+			})
+
+			expectedPeak := n
+			if expectedPeak > maxParallelToolCalls {
+				expectedPeak = maxParallelToolCalls
+			}
+			if int(peak) > maxParallelToolCalls {
+				t.Errorf("Expected at most %d concurrent calls, got %d", maxParallelToolCalls, peak)
+			}
+			if n > 1 && int(peak) < 2 {
+				t.Errorf("Expected calls to overlap (peak %d of %d), got %d", expectedPeak, n, peak)
+			}
+			for i, got := range results {
+				if got != i {
+					t.Errorf("Expected result %d at index %d, got %d", i, i, got)
+				}
+			}
+		})
+	}
+}
+
+// TestRunParallelBatch tests that each call's result is stored at its own index
+func TestRunParallelBatch(t *testing.T) {
+	var calls []llm.ToolCall
+	for i := 0; i < 6; i++ {
+		calls = append(calls, newToolCall(fmt.Sprintf("call_%d", i), "render_table",
+			map[string]interface{}{"columns": []string{fmt.Sprintf("col_%d", i)}, "rows": [][]interface{}{{i}}}))
+	}
+	// The first call is sequential; the batch starts at the second
+	parallel := []bool{false, true, true, true, true, true}
+
+	h := &ToolHandler{}
+	results := make(map[int]parallelResult)
+	h.runParallelBatch(context.Background(), calls, parallel, 1, results)
+
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 results, got %d", len(results))
+	}
+	if _, ok := results[0]; ok {
+		t.Error("Expected the sequential call to be left out of the batch")
+	}
+	for i := 1; i < len(calls); i++ {
+		result := results[i]
+		if result.err != nil {
+			t.Fatalf("Call %d failed: %v", i, result.err)
+		}
+		if !strings.Contains(string(result.result), fmt.Sprintf("col_%d", i)) {
+			t.Errorf("Expected result of call %d, got %s", i, result.result)
+		}
+	}
+}
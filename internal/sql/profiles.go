@@ -0,0 +1,137 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/llm"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// llmClients holds the LLM client of each task
+// Tasks using the same profile share one client, so they share its streaming and retry state.
+type llmClients struct {
+	cfg       *config.Config
+	byProfile map[string]*llm.Client
+	profiles  map[string]string // Task -> profile
+}
+
+// newLLMClients creates the clients of the profiles configured for each task
+// chatProfile, if set, overrides the configured profile of the chat task.
+func newLLMClients(cfg *config.Config, chatProfile string) (*llmClients, error) {
+	c := &llmClients{
+		cfg:       cfg,
+		byProfile: make(map[string]*llm.Client),
+		profiles:  make(map[string]string),
+	}
+	for _, task := range config.Tasks {
+		profile := cfg.ProfileFor(task)
+		if task == config.TaskChat && chatProfile != "" {
+			profile = chatProfile
+		}
+		if err := c.use(task, profile); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// use makes task use the named profile, creating its client if needed
+func (c *llmClients) use(task, profile string) error {
+	if profile == "" {
+		profile = config.DefaultProfile
+	}
+	if _, ok := c.byProfile[profile]; !ok {
+		llmCfg, err := c.cfg.Profile(profile)
+		if err != nil {
+			return err
+		}
+		apiKey, err := llmCfg.ResolveAPIKey()
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", profile, err)
+		}
+		client, err := llm.NewProviderClient(llmCfg.Provider, llmCfg.URL, apiKey, llmCfg.Model)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", profile, err)
+		}
+		client.SetStreaming(!llmCfg.DisableStreaming)
+		client.SetMaxRetries(llmCfg.Retries())
+		c.byProfile[profile] = client
+	}
+	c.profiles[task] = profile
+	return nil
+}
+
+// client returns the client of task
+func (c *llmClients) client(task string) *llm.Client {
+	return c.byProfile[c.profiles[task]]
+}
+
+// configure makes a tool handler use the clients of the skill matching and compression tasks
+func (c *llmClients) configure(h *ToolHandler) {
+	h.SetTaskClients(c.client(config.TaskSkills), c.client(config.TaskCompression))
+}
+
+// handleModelCommand handles /model [<profile> [task]]
+// Without arguments it lists the profiles and the profile of each task.
+func handleModelCommand(clients *llmClients, args []string) {
+	fmt.Println()
+	if len(args) == 0 {
+		listProfiles(clients)
+		fmt.Println()
+		return
+	}
+	if len(args) > 2 {
+		ui.ShowWarning("Usage: /model [<profile> [chat|skills|compression|all]]")
+		fmt.Println()
+		return
+	}
+
+	tasks := []string{config.TaskChat}
+	if len(args) == 2 {
+		switch task := strings.ToLower(args[1]); task {
+		case config.TaskChat, config.TaskSkills, config.TaskCompression:
+			tasks = []string{task}
+		case "all":
+			tasks = config.Tasks
+		default:
+			ui.ShowWarning(fmt.Sprintf("Unknown task %q (use chat, skills, compression or all)", args[1]))
+			fmt.Println()
+			return
+		}
+	}
+
+	for _, task := range tasks {
+		if err := clients.use(task, args[0]); err != nil {
+			ui.ShowError(err.Error())
+			fmt.Println()
+			return
+		}
+	}
+	model := clients.client(tasks[0]).Model()
+	ui.ShowSuccess(fmt.Sprintf("Using profile %s (%s) for %s.", args[0], model, strings.Join(tasks, ", ")))
+	fmt.Println()
+}
+
+// listProfiles prints the configured profiles and the tasks using each
+func listProfiles(clients *llmClients) {
+	rows := make([][]string, 0, len(clients.cfg.Profiles)+1)
+	for _, name := range clients.cfg.ProfileNames() {
+		llmCfg, err := clients.cfg.Profile(name)
+		if err != nil {
+			continue
+		}
+		var tasks []string
+		for _, task := range config.Tasks {
+			if clients.profiles[task] == name {
+				tasks = append(tasks, task)
+			}
+		}
+		rows = append(rows, []string{name, llmCfg.ProviderName(), llmCfg.Model, strings.Join(tasks, ", ")})
+	}
+	ui.PrintTable([]string{"Profile", "Provider", "Model", "Used for"}, rows)
+	if len(clients.cfg.Profiles) == 0 {
+		ui.ShowInfo("Add profiles in the config menu (profile), then switch with /model <profile>.")
+	}
+}
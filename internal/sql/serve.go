@@ -0,0 +1,803 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aiq/aiq/internal/db"
+	"github.com/aiq/aiq/internal/session"
+)
+
+// DefaultServeAddress is the address aiq serve listens on unless told otherwise
+const DefaultServeAddress = "127.0.0.1:8787"
+
+// confirmationTimeout is how long a turn waits for a pending confirmation before refusing it
+const confirmationTimeout = 10 * time.Minute
+
+// Every session holds a connection pool and the subprocesses of its MCP servers, so their number and lifetime are bounded
+const (
+	DefaultMaxSessions        = 16               // Sessions open at the same time
+	DefaultSessionIdleTimeout = 30 * time.Minute // Idle time after which a session is closed
+	maxSessionEvents          = 1000             // Most recent events kept per session for event streams
+	maxSessionResults         = 100              // Most recent result sets kept per session
+)
+
+// ServeOptions configures the local HTTP API server
+type ServeOptions struct {
+	Listen      string        // Address to listen on, e.g. 127.0.0.1:8787
+	Token       string        // Bearer token every request must carry
+	Log         io.Writer     // Server log and progress output of turns; nil writes to stderr
+	MaxSessions int           // Sessions open at the same time; 0 means DefaultMaxSessions
+	IdleTimeout time.Duration // Sessions idle this long are closed; 0 means DefaultSessionIdleTimeout
+}
+
+// GenerateServeToken returns a random bearer token for aiq serve
+func GenerateServeToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// RunServe serves sessions over HTTP until ctx is cancelled
+//
+// Endpoints (all require "Authorization: Bearer <token>"):
+//
+//	POST   /v1/sessions                      create a session {"source", "database", "confirm"}
+//	GET    /v1/sessions                      list sessions
+//	GET    /v1/sessions/{id}                 session metadata and history
+//	DELETE /v1/sessions/{id}                 close a session
+//	POST   /v1/sessions/{id}/messages        start a turn {"content"}
+//	GET    /v1/sessions/{id}/events          stream events (SSE), resumable with Last-Event-ID
+//	POST   /v1/sessions/{id}/confirmations   answer a confirmation {"tool_call_id", "approved"}
+//	GET    /v1/sessions/{id}/results[/{n}]   stored result sets
+//
+// At most MaxSessions sessions are open at a time; sessions without requests, turns or event
+// streams for IdleTimeout are closed.
+func RunServe(ctx context.Context, opts ServeOptions) error {
+	if opts.Token == "" {
+		return fmt.Errorf("a bearer token is required")
+	}
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", opts.Listen, err)
+	}
+
+	log := opts.Log
+	if log == nil {
+		log = os.Stderr
+	}
+	server := newAPIServer(opts.Token)
+	server.log = log
+	if opts.MaxSessions > 0 {
+		server.maxSessions = opts.MaxSessions
+	}
+	if opts.IdleTimeout > 0 {
+		server.idleTimeout = opts.IdleTimeout
+	}
+	defer server.closeAll()
+	go server.reapIdleSessions(ctx)
+	httpServer := &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- httpServer.Serve(listener) }()
+	fmt.Fprintf(log, "aiq API listening on http://%s\n", listener.Addr())
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		// Open event streams end with the base context; running turns finish in the background
+		return httpServer.Shutdown(shutdownCtx)
+	}
+}
+
+// apiServer routes API requests to in-memory sessions
+type apiServer struct {
+	token       string
+	log         io.Writer // Progress output of turns, prefixed per session
+	maxSessions int
+	idleTimeout time.Duration
+
+	logMu sync.Mutex // Serializes lines written to log
+
+	mu       sync.Mutex
+	sessions map[string]*apiSession
+}
+
+func newAPIServer(token string) *apiServer {
+	return &apiServer{
+		token:       token,
+		maxSessions: DefaultMaxSessions,
+		idleTimeout: DefaultSessionIdleTimeout,
+		sessions:    make(map[string]*apiSession),
+	}
+}
+
+// apiEvent is a stream event numbered for SSE resumption
+type apiEvent struct {
+	ID    int
+	Event StreamEvent
+}
+
+// storedResult is a result set kept for GET /results
+type storedResult struct {
+	ID   int
+	Turn int
+	Set  db.ResultSet
+}
+
+// apiSession is one conversation bound to a source
+type apiSession struct {
+	id      string
+	created time.Time
+	ask     *askSession
+
+	mu         sync.Mutex
+	busy       bool
+	turn       int
+	lastActive time.Time            // Last request for the session or end of a turn
+	events     []apiEvent           // The most recent maxSessionEvents events
+	lastEvent  int                  // ID of the last event published
+	changed    chan struct{}        // Closed and replaced whenever an event is added
+	results    []storedResult       // The most recent maxSessionResults result sets
+	lastResult int                  // ID of the last result set stored
+	history    []session.Message    // Snapshot of the conversation after the last turn
+	pending    map[string]chan bool // Confirmations waiting for an answer, by tool call ID
+	closed     bool
+}
+
+// ServeHTTP authenticates and dispatches a request
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="aiq"`)
+		writeAPIError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" || parts[1] != "sessions" {
+		writeAPIError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodPost:
+			s.createSession(w, r)
+		case http.MethodGet:
+			s.listSessions(w)
+		default:
+			writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	sess := s.session(parts[2])
+	if sess == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("session %s not found", parts[2]))
+		return
+	}
+
+	route := strings.Join(parts[3:], "/")
+	switch {
+	case route == "" && r.Method == http.MethodGet:
+		s.getSession(w, sess)
+	case route == "" && r.Method == http.MethodDelete:
+		s.deleteSession(w, sess)
+	case route == "messages" && r.Method == http.MethodPost:
+		s.postMessage(w, r, sess)
+	case route == "events" && r.Method == http.MethodGet:
+		s.streamEvents(w, r, sess)
+	case route == "confirmations" && r.Method == http.MethodPost:
+		s.postConfirmation(w, r, sess)
+	case (route == "results" || strings.HasPrefix(route, "results/")) && r.Method == http.MethodGet:
+		s.getResults(w, sess, strings.TrimPrefix(strings.TrimPrefix(route, "results"), "/"))
+	default:
+		writeAPIError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// authorized checks the bearer token in constant time
+func (s *apiServer) authorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(s.token)) == 1
+}
+
+// sessionLog returns the writer for a session's progress output
+func (s *apiServer) sessionLog(id string) io.Writer {
+	if s.log == nil {
+		return io.Discard
+	}
+	return &prefixWriter{mu: &s.logMu, w: s.log, prefix: "[" + id + "] "}
+}
+
+// prefixWriter writes whole lines to w, each starting with prefix
+// Sessions share mu, so lines of concurrent turns are not interleaved. Not being a terminal, the
+// writer also keeps spinners off.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := fmt.Fprintf(p.w, "%s%s", p.prefix, p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+// session returns the session with the given ID and marks it active, or nil
+func (s *apiServer) session(id string) *apiSession {
+	s.mu.Lock()
+	sess := s.sessions[id]
+	s.mu.Unlock()
+	if sess != nil {
+		sess.touch()
+	}
+	return sess
+}
+
+// reapIdleSessions closes idle sessions until ctx is cancelled
+func (s *apiServer) reapIdleSessions(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.reapIdle(now)
+		}
+	}
+}
+
+// reapIdle closes the sessions that have been idle for idleTimeout at now and returns how many it closed
+func (s *apiServer) reapIdle(now time.Time) int {
+	s.mu.Lock()
+	var idle []*apiSession
+	for id, sess := range s.sessions {
+		if sess.idleSince(now) >= s.idleTimeout {
+			idle = append(idle, sess)
+			delete(s.sessions, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, sess := range idle {
+		fmt.Fprintf(s.sessionLog(sess.id), "Closing session idle for more than %s\n", s.idleTimeout)
+		sess.close()
+	}
+	return len(idle)
+}
+
+// createSession connects to the requested source and registers a new session
+func (s *apiServer) createSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Source   string `json:"source"`
+		Database string `json:"database"`
+		Confirm  string `json:"confirm"`
+		Profile  string `json:"profile"`
+	}
+	if err := decodeAPIRequest(r, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	policy := PolicyPrompt
+	if req.Confirm != "" {
+		var err error
+		if policy, err = ParseConfirmPolicy(req.Confirm); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	// Checked before connecting, and again when registering in case other sessions were created meanwhile
+	if err := s.checkSessionLimit(); err != nil {
+		writeAPIError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	id, err := GenerateServeToken()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	id = id[:16]
+
+	ask, err := openAskSession(AskOptions{SourceName: req.Source, Database: req.Database, Policy: policy, Profile: req.Profile, Log: s.sessionLog(id)})
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sess := &apiSession{
+		id:         id,
+		created:    time.Now().UTC(),
+		ask:        ask,
+		lastActive: time.Now(),
+		changed:    make(chan struct{}),
+		pending:    make(map[string]chan bool),
+	}
+	s.mu.Lock()
+	if len(s.sessions) >= s.maxSessions {
+		s.mu.Unlock()
+		ask.Close()
+		writeAPIError(w, http.StatusTooManyRequests, s.sessionLimitMessage())
+		return
+	}
+	s.sessions[id] = sess
+	s.mu.Unlock()
+
+	writeAPIJSON(w, http.StatusCreated, sess.summary())
+}
+
+// checkSessionLimit fails if no more sessions can be created
+func (s *apiServer) checkSessionLimit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sessions) >= s.maxSessions {
+		return errors.New(s.sessionLimitMessage())
+	}
+	return nil
+}
+
+// sessionLimitMessage explains that the session limit is reached
+func (s *apiServer) sessionLimitMessage() string {
+	return fmt.Sprintf("too many open sessions (limit %d); delete a session or wait for idle ones to close after %s", s.maxSessions, s.idleTimeout)
+}
+
+func (s *apiServer) listSessions(w http.ResponseWriter) {
+	s.mu.Lock()
+	list := make([]map[string]interface{}, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		list = append(list, sess.summary())
+	}
+	s.mu.Unlock()
+	writeAPIJSON(w, http.StatusOK, map[string]interface{}{"sessions": list})
+}
+
+func (s *apiServer) getSession(w http.ResponseWriter, sess *apiSession) {
+	doc := sess.summary()
+	sess.mu.Lock()
+	doc["messages"] = sess.history
+	sess.mu.Unlock()
+	writeAPIJSON(w, http.StatusOK, doc)
+}
+
+// deleteSession closes a session, refusing its pending confirmations
+func (s *apiServer) deleteSession(w http.ResponseWriter, sess *apiSession) {
+	s.mu.Lock()
+	delete(s.sessions, sess.id)
+	s.mu.Unlock()
+	sess.close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// closeAll closes every session when the server stops
+func (s *apiServer) closeAll() {
+	s.mu.Lock()
+	sessions := s.sessions
+	s.sessions = make(map[string]*apiSession)
+	s.mu.Unlock()
+	for _, sess := range sessions {
+		sess.close()
+	}
+}
+
+// postMessage starts a turn in the background; its progress is delivered as events
+func (s *apiServer) postMessage(w http.ResponseWriter, r *http.Request, sess *apiSession) {
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := decodeAPIRequest(r, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	question := strings.TrimSpace(req.Content)
+	if question == "" {
+		writeAPIError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	turn, err := sess.startTurn()
+	if err != nil {
+		writeAPIError(w, http.StatusConflict, err.Error())
+		return
+	}
+	go sess.runTurn(turn, question)
+	writeAPIJSON(w, http.StatusAccepted, map[string]interface{}{"session_id": sess.id, "turn": turn})
+}
+
+// streamEvents writes session events as server-sent events until the client disconnects
+// Events already delivered are skipped with the Last-Event-ID header or ?after=<id>
+func (s *apiServer) streamEvents(w http.ResponseWriter, r *http.Request, sess *apiSession) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	after := 0
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("after")
+	}
+	if lastID != "" {
+		n, err := strconv.Atoi(lastID)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid event ID %q", lastID))
+			return
+		}
+		after = n
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		events, changed, closed := sess.eventsAfter(after)
+		for _, event := range events {
+			data, err := json.Marshal(event.Event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Event.Type, data)
+			after = event.ID
+		}
+		flusher.Flush()
+		if closed {
+			return
+		}
+		// A client following the stream keeps the session alive
+		sess.touch()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+	}
+}
+
+// postConfirmation answers a pending confirmation_required event
+func (s *apiServer) postConfirmation(w http.ResponseWriter, r *http.Request, sess *apiSession) {
+	var req StreamInput
+	if err := decodeAPIRequest(r, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := sess.answer(req.ToolCallID, req.Approved); err != nil {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getResults returns all stored result sets, or the one with the given ID
+func (s *apiServer) getResults(w http.ResponseWriter, sess *apiSession, id string) {
+	sess.mu.Lock()
+	results := append([]storedResult(nil), sess.results...)
+	sess.mu.Unlock()
+
+	if id == "" {
+		list := make([]map[string]interface{}, 0, len(results))
+		for _, result := range results {
+			list = append(list, result.toJSON())
+		}
+		writeAPIJSON(w, http.StatusOK, map[string]interface{}{"results": list})
+		return
+	}
+
+	n, err := strconv.Atoi(id)
+	if err == nil {
+		for _, result := range results {
+			if result.ID == n {
+				writeAPIJSON(w, http.StatusOK, result.toJSON())
+				return
+			}
+		}
+	}
+	writeAPIError(w, http.StatusNotFound, fmt.Sprintf("result %s not found", id))
+}
+
+// summary describes the session for API responses
+func (a *apiSession) summary() map[string]interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return map[string]interface{}{
+		"id":            a.id,
+		"source":        a.ask.sess.Metadata.DataSource,
+		"database":      a.ask.databaseName,
+		"database_type": a.ask.sess.Metadata.DatabaseType,
+		"confirm":       policyName(a.ask.opts.Policy),
+		"created_at":    a.created,
+		"busy":          a.busy,
+		"turns":         a.turn,
+		"last_active":   a.lastActive.UTC(),
+	}
+}
+
+// touch marks the session as active now
+func (a *apiSession) touch() {
+	a.mu.Lock()
+	a.lastActive = time.Now()
+	a.mu.Unlock()
+}
+
+// idleSince returns how long the session has been idle at now
+// A running turn or a pending confirmation keeps the session active.
+func (a *apiSession) idleSince(now time.Time) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.busy || len(a.pending) > 0 {
+		return 0
+	}
+	return now.Sub(a.lastActive)
+}
+
+// startTurn reserves the session for a new turn; a session runs one turn at a time
+func (a *apiSession) startTurn() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return 0, fmt.Errorf("session %s is closed", a.id)
+	}
+	if a.busy {
+		return 0, fmt.Errorf("session %s is still processing the previous message", a.id)
+	}
+	a.busy = true
+	a.turn++
+	return a.turn, nil
+}
+
+// runTurn runs one turn, publishing its events and storing its result sets
+func (a *apiSession) runTurn(turn int, question string) {
+	defer func() {
+		a.mu.Lock()
+		a.busy = false
+		a.mu.Unlock()
+	}()
+
+	emit := func(event StreamEvent) {
+		event.Turn = turn
+		a.publish(event)
+	}
+	answer, outcome, err := a.ask.run(question, func(h *ToolHandler) {
+		h.SetEventHandler(emit)
+		if a.ask.opts.Policy == PolicyPrompt {
+			h.SetConfirmer(a.confirm)
+		}
+	})
+	if err == nil {
+		err = outcomeError(outcome, a.ask.opts.Policy)
+	}
+
+	a.mu.Lock()
+	resultIDs := make([]int, 0, len(outcome.ResultSets))
+	for _, set := range outcome.ResultSets {
+		a.lastResult++
+		a.results = append(a.results, storedResult{ID: a.lastResult, Turn: turn, Set: set})
+		resultIDs = append(resultIDs, a.lastResult)
+	}
+	if len(a.results) > maxSessionResults {
+		a.results = append([]storedResult(nil), a.results[len(a.results)-maxSessionResults:]...)
+	}
+	a.history = append([]session.Message(nil), a.ask.sess.GetHistory()...)
+	a.lastActive = time.Now()
+	a.mu.Unlock()
+
+	final := StreamEvent{
+		Type:      EventFinal,
+		Status:    "success",
+		Content:   answer,
+		Results:   resultSetsToJSON(outcome.ResultSets),
+		ResultIDs: resultIDs,
+		Denied:    outcome.Denied,
+		Usage:     turnUsage(outcome),
+	}
+	if err != nil {
+		final.Status = "error"
+		final.Error = err.Error()
+	}
+	emit(final)
+}
+
+// confirm publishes a confirmation_required event and waits for POST /confirmations
+// A closed session or no answer within confirmationTimeout counts as a refusal
+func (a *apiSession) confirm(req ConfirmRequest) (bool, error) {
+	answer := make(chan bool, 1)
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return false, fmt.Errorf("session closed")
+	}
+	a.pending[req.ToolCall.ID] = answer
+	turn := a.turn
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.pending, req.ToolCall.ID)
+		a.mu.Unlock()
+	}()
+
+	a.publish(StreamEvent{
+		Type:       EventConfirmationRequired,
+		Turn:       turn,
+		ToolCallID: req.ToolCall.ID,
+		Name:       req.ToolCall.Function.Name,
+		Arguments:  req.Arguments,
+		RiskLevel:  req.RiskLevel.String(),
+		Prompt:     req.Prompt,
+	})
+
+	timer := time.NewTimer(confirmationTimeout)
+	defer timer.Stop()
+	select {
+	case approved, ok := <-answer:
+		if !ok {
+			return false, fmt.Errorf("session closed")
+		}
+		return approved, nil
+	case <-timer.C:
+		return false, fmt.Errorf("no confirmation within %s", confirmationTimeout)
+	}
+}
+
+// answer delivers the answer to a pending confirmation
+// Without a tool call ID the only pending confirmation is answered
+func (a *apiSession) answer(toolCallID string, approved bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if toolCallID == "" && len(a.pending) == 1 {
+		for id := range a.pending {
+			toolCallID = id
+		}
+	}
+	ch, ok := a.pending[toolCallID]
+	if !ok {
+		if toolCallID == "" {
+			return errors.New("no single pending confirmation; specify tool_call_id")
+		}
+		return fmt.Errorf("no pending confirmation for tool call %s", toolCallID)
+	}
+	delete(a.pending, toolCallID)
+	ch <- approved
+	return nil
+}
+
+// publish appends an event and wakes up event streams
+// Only the most recent maxSessionEvents events are kept; streams resuming from an older ID skip the dropped ones.
+func (a *apiSession) publish(event StreamEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastEvent++
+	a.events = append(a.events, apiEvent{ID: a.lastEvent, Event: event})
+	if len(a.events) > maxSessionEvents {
+		a.events = append([]apiEvent(nil), a.events[len(a.events)-maxSessionEvents:]...)
+	}
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// eventsAfter returns the events newer than id, a channel closed on the next event, and whether the session is closed
+func (a *apiSession) eventsAfter(id int) ([]apiEvent, <-chan struct{}, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var events []apiEvent
+	for i, event := range a.events {
+		if event.ID > id {
+			events = append(events, a.events[i:]...)
+			break
+		}
+	}
+	return events, a.changed, a.closed
+}
+
+// close refuses pending confirmations, ends event streams and closes the connection once no turn is running
+func (a *apiSession) close() {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return
+	}
+	a.closed = true
+	for id, ch := range a.pending {
+		close(ch)
+		delete(a.pending, id)
+	}
+	close(a.changed)
+	a.changed = make(chan struct{})
+	busy := a.busy
+	a.mu.Unlock()
+
+	if !busy {
+		a.ask.Close()
+		return
+	}
+	// Let the running turn finish before closing its connection
+	go func() {
+		for {
+			time.Sleep(100 * time.Millisecond)
+			a.mu.Lock()
+			busy := a.busy
+			a.mu.Unlock()
+			if !busy {
+				a.ask.Close()
+				return
+			}
+		}
+	}()
+}
+
+func (r storedResult) toJSON() map[string]interface{} {
+	doc := resultSetsToJSON([]db.ResultSet{r.Set})[0]
+	doc["id"] = r.ID
+	doc["turn"] = r.Turn
+	return doc
+}
+
+// decodeAPIRequest decodes an optional JSON request body
+func decodeAPIRequest(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,269 @@
+package sql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aiq/aiq/internal/tool"
+)
+
+// TestAPIServer_Authorized tests bearer token checks
+func TestAPIServer_Authorized(t *testing.T) {
+	s := newAPIServer("secret-token")
+
+	tests := []struct {
+		name   string
+		header string
+		ok     bool
+	}{
+		{"valid token", "Bearer secret-token", true},
+		{"case-insensitive scheme", "bearer secret-token", true},
+		{"wrong token", "Bearer other-token", false},
+		{"token prefix", "Bearer secret", false},
+		{"missing token", "Bearer ", false},
+		{"basic auth", "Basic c2VjcmV0LXRva2Vu", false},
+		{"no header", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/sessions", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if got := s.authorized(r); got != tt.ok {
+				t.Errorf("Expected authorized=%v, got %v", tt.ok, got)
+			}
+		})
+	}
+}
+
+// TestAPIServer_Routing tests authentication and routing of requests that need no database
+func TestAPIServer_Routing(t *testing.T) {
+	s := newAPIServer("secret-token")
+
+	request := func(method, path, auth string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", "Bearer "+auth)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request(http.MethodGet, "/v1/sessions", "")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), "Bearer") {
+		t.Errorf("Expected 401 with a Bearer challenge, got %d %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+
+	w = request(http.MethodGet, "/v1/sessions", "secret-token")
+	var list map[string][]interface{}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &list) != nil || len(list["sessions"]) != 0 {
+		t.Errorf("Expected an empty session list, got %d %s", w.Code, w.Body.String())
+	}
+
+	for _, tt := range []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/v2/sessions", http.StatusNotFound},
+		{http.MethodPut, "/v1/sessions", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v1/sessions/unknown/events", http.StatusNotFound},
+		{http.MethodPost, "/v1/sessions/unknown/confirmations", http.StatusNotFound},
+	} {
+		if w := request(tt.method, tt.path, "secret-token"); w.Code != tt.code {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.code, w.Code)
+		}
+	}
+}
+
+// newTestAPISession returns a session without a connection for confirmation tests
+func newTestAPISession() *apiSession {
+	return &apiSession{
+		id:         "test",
+		ask:        &askSession{},
+		lastActive: time.Now(),
+		changed:    make(chan struct{}),
+		pending:    make(map[string]chan bool),
+	}
+}
+
+// confirmAsync asks for confirmation of toolCallID in the background and waits for its event
+func confirmAsync(t *testing.T, sess *apiSession, toolCallID string) <-chan bool {
+	t.Helper()
+	_, changed, _ := sess.eventsAfter(0)
+	result := make(chan bool, 1)
+	go func() {
+		approved, _ := sess.confirm(ConfirmRequest{
+			ToolCall:  newToolCall(toolCallID, "execute_sql", map[string]interface{}{"sql": "DELETE FROM users"}),
+			RiskLevel: tool.RiskHigh,
+			Prompt:    "Execute DELETE?",
+		})
+		result <- approved
+	}()
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a confirmation_required event")
+	}
+	return result
+}
+
+// waitApproval waits for the result of a confirmation
+func waitApproval(t *testing.T, result <-chan bool) bool {
+	t.Helper()
+	select {
+	case approved := <-result:
+		return approved
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the confirmation to be answered")
+		return false
+	}
+}
+
+// TestAPISession_Confirm tests routing of confirmation answers to pending tool calls
+func TestAPISession_Confirm(t *testing.T) {
+	t.Run("answer by tool call ID", func(t *testing.T) {
+		sess := newTestAPISession()
+		result := confirmAsync(t, sess, "call_1")
+
+		events, _, _ := sess.eventsAfter(0)
+		if len(events) != 1 || events[0].Event.Type != EventConfirmationRequired || events[0].Event.ToolCallID != "call_1" {
+			t.Fatalf("Expected a confirmation_required event for call_1, got %+v", events)
+		}
+		if err := sess.answer("call_2", true); err == nil {
+			t.Error("Expected an error for a tool call that is not pending")
+		}
+		if err := sess.answer("call_1", true); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !waitApproval(t, result) {
+			t.Error("Expected the call to be approved")
+		}
+		if err := sess.answer("call_1", true); err == nil {
+			t.Error("Expected an answered confirmation to be no longer pending")
+		}
+	})
+
+	t.Run("single pending confirmation without ID", func(t *testing.T) {
+		sess := newTestAPISession()
+		result := confirmAsync(t, sess, "call_1")
+		if err := sess.answer("", false); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if waitApproval(t, result) {
+			t.Error("Expected the call to be refused")
+		}
+		if err := sess.answer("", true); err == nil {
+			t.Error("Expected an error without a pending confirmation")
+		}
+	})
+
+	t.Run("closing the session refuses pending confirmations", func(t *testing.T) {
+		sess := newTestAPISession()
+		result := confirmAsync(t, sess, "call_1")
+		sess.close()
+		if waitApproval(t, result) {
+			t.Error("Expected the call to be refused")
+		}
+		if _, err := sess.confirm(ConfirmRequest{ToolCall: newToolCall("call_2", "execute_sql", nil)}); err == nil {
+			t.Error("Expected confirmations on a closed session to fail")
+		}
+	})
+}
+
+// TestAPISession_EventsAfter tests resuming an event stream after a given event ID
+func TestAPISession_EventsAfter(t *testing.T) {
+	sess := newTestAPISession()
+	for _, eventType := range []string{EventToolCall, EventToolResult, EventFinal} {
+		sess.publish(StreamEvent{Type: eventType})
+	}
+
+	events, _, closed := sess.eventsAfter(1)
+	if len(events) != 2 || events[0].ID != 2 || events[1].Event.Type != EventFinal {
+		t.Errorf("Expected events 2 and 3, got %+v", events)
+	}
+	if closed {
+		t.Error("Expected the session to be open")
+	}
+	if events, _, _ := sess.eventsAfter(3); len(events) != 0 {
+		t.Errorf("Expected no events after the last one, got %+v", events)
+	}
+}
+
+// TestAPISession_EventCap tests that only the most recent events are kept
+func TestAPISession_EventCap(t *testing.T) {
+	sess := newTestAPISession()
+	for i := 0; i < maxSessionEvents+5; i++ {
+		sess.publish(StreamEvent{Type: EventToolCall})
+	}
+
+	events, _, _ := sess.eventsAfter(0)
+	if len(events) != maxSessionEvents || events[0].ID != 6 || events[len(events)-1].ID != maxSessionEvents+5 {
+		t.Errorf("Expected events 6 to %d, got %d events starting at %d", maxSessionEvents+5, len(events), events[0].ID)
+	}
+	if events, _, _ := sess.eventsAfter(maxSessionEvents + 3); len(events) != 2 || events[0].ID != maxSessionEvents+4 {
+		t.Errorf("Expected the last 2 events, got %+v", events)
+	}
+}
+
+// TestAPIServer_SessionLimits tests the session limit and the closing of idle sessions
+func TestAPIServer_SessionLimits(t *testing.T) {
+	s := newAPIServer("secret-token")
+	s.maxSessions = 2
+	s.idleTimeout = time.Minute
+
+	idle, busy := newTestAPISession(), newTestAPISession()
+	idle.id, busy.id = "idle", "busy"
+	idle.lastActive = time.Now().Add(-2 * time.Minute)
+	busy.lastActive = idle.lastActive
+	busy.busy = true
+	s.sessions[idle.id], s.sessions[busy.id] = idle, busy
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(`{"source":"none"}`))
+	r.Header.Set("Authorization", "Bearer secret-token")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "limit 2") {
+		t.Errorf("Expected 429 at the session limit, got %d %s", w.Code, w.Body.String())
+	}
+
+	if closed := s.reapIdle(time.Now()); closed != 1 {
+		t.Errorf("Expected 1 idle session to be closed, got %d", closed)
+	}
+	if s.session("idle") != nil || s.session("busy") == nil {
+		t.Error("Expected only the idle session to be removed")
+	}
+	if _, _, closed := idle.eventsAfter(0); !closed {
+		t.Error("Expected the idle session to be closed")
+	}
+
+	// A request marks a session active again
+	busy.busy = false
+	s.session("busy")
+	if closed := s.reapIdle(time.Now()); closed != 0 {
+		t.Errorf("Expected the recently used session to stay open, got %d closed", closed)
+	}
+}
+
+// TestPrefixWriter tests that session output reaches the log as whole, prefixed lines
+func TestPrefixWriter(t *testing.T) {
+	var log strings.Builder
+	s := newAPIServer("secret-token")
+	s.log = &log
+
+	a, b := s.sessionLog("a"), s.sessionLog("b")
+	a.Write([]byte("Running "))
+	b.Write([]byte("Tool call:\n"))
+	a.Write([]byte("query\nDone\n"))
+
+	expected := "[b] Tool call:\n[a] Running query\n[a] Done\n"
+	if log.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, log.String())
+	}
+}
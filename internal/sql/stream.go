@@ -0,0 +1,172 @@
+package sql
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/aiq/aiq/internal/llm"
+	"github.com/aiq/aiq/internal/tool"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// Stream event types emitted with --format stream-json
+const (
+	EventAssistant            = "assistant"
+	EventToolCall             = "tool_call"
+	EventToolResult           = "tool_result"
+	EventConfirmationRequired = "confirmation_required"
+	EventFinal                = "final"
+)
+
+// StreamEvent is one newline-delimited JSON event describing progress of a turn
+// Message carries the chat message exactly as it is sent to the LLM (assistant and tool_result events)
+type StreamEvent struct {
+	Type       string                   `json:"type"`
+	Turn       int                      `json:"turn"`
+	Content    string                   `json:"content,omitempty"`
+	Message    map[string]interface{}   `json:"message,omitempty"`
+	ToolCallID string                   `json:"tool_call_id,omitempty"`
+	Name       string                   `json:"name,omitempty"`
+	Arguments  map[string]interface{}   `json:"arguments,omitempty"`
+	RiskLevel  string                   `json:"risk_level,omitempty"`
+	Prompt     string                   `json:"prompt,omitempty"`
+	Status     string                   `json:"status,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+	Results    []map[string]interface{} `json:"results,omitempty"`
+	ResultIDs  []int                    `json:"result_ids,omitempty"` // IDs of stored results (aiq serve)
+	Denied     []string                 `json:"denied,omitempty"`
+	Usage      *llm.UsageStats          `json:"usage,omitempty"` // Tokens used by the turn (final events)
+}
+
+// ConfirmRequest describes a tool call that needs the user's approval
+type ConfirmRequest struct {
+	ToolCall  llm.ToolCall
+	Arguments map[string]interface{}
+	RiskLevel tool.RiskLevel
+	Prompt    string
+}
+
+// SetEventHandler sets a function that receives stream events while the loop runs
+func (h *ToolHandler) SetEventHandler(handler func(StreamEvent)) {
+	h.onEvent = handler
+}
+
+// SetConfirmer replaces the interactive confirmation prompt for high-risk tool calls
+func (h *ToolHandler) SetConfirmer(confirm func(ConfirmRequest) (bool, error)) {
+	h.confirmer = confirm
+}
+
+// emit sends an event to the event handler, if any
+func (h *ToolHandler) emit(event StreamEvent) {
+	if h.onEvent != nil {
+		h.onEvent(event)
+	}
+}
+
+// confirm asks for approval of a high-risk tool call
+func (h *ToolHandler) confirm(req ConfirmRequest) (bool, error) {
+	if h.confirmer != nil {
+		return h.confirmer(req)
+	}
+	return ui.ShowConfirm(req.Prompt)
+}
+
+// toolResultMessage builds the tool message returned to the LLM and reports it as a tool_result event
+func (h *ToolHandler) toolResultMessage(toolCallID string, content string) map[string]interface{} {
+	msg := map[string]interface{}{
+		"role":         "tool",
+		"content":      content,
+		"tool_call_id": toolCallID,
+	}
+	h.emit(StreamEvent{Type: EventToolResult, ToolCallID: toolCallID, Message: msg})
+	return msg
+}
+
+// StreamInput is one JSON line read from stdin in stream-json mode
+// {"type":"user","content":"..."} starts a turn; {"type":"confirmation","tool_call_id":"...","approved":true}
+// answers a confirmation_required event
+type StreamInput struct {
+	Type       string `json:"type"`
+	Content    string `json:"content,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Approved   bool   `json:"approved,omitempty"`
+}
+
+// StreamIO writes stream events as JSON lines and reads inputs from JSON lines
+type StreamIO struct {
+	mu   sync.Mutex
+	out  *json.Encoder
+	in   *bufio.Scanner
+	turn int
+}
+
+// NewStreamIO creates a stream reading inputs from in and writing events to out
+func NewStreamIO(in io.Reader, out io.Writer) *StreamIO {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return &StreamIO{out: json.NewEncoder(out), in: scanner}
+}
+
+// NextTurn starts numbering events of a new turn
+func (s *StreamIO) NextTurn() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turn++
+}
+
+// Emit writes an event as a single JSON line
+func (s *StreamIO) Emit(event StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event.Turn = s.turn
+	// Encoding errors (e.g. a closed pipe) leave nothing useful to report to
+	_ = s.out.Encode(event)
+}
+
+// ReadInput reads the next non-empty JSON line; io.EOF means the input was closed
+func (s *StreamIO) ReadInput() (StreamInput, error) {
+	for s.in.Scan() {
+		line := strings.TrimSpace(s.in.Text())
+		if line == "" {
+			continue
+		}
+		var input StreamInput
+		if err := json.Unmarshal([]byte(line), &input); err != nil {
+			return StreamInput{}, fmt.Errorf("invalid input line: %w", err)
+		}
+		return input, nil
+	}
+	if err := s.in.Err(); err != nil {
+		return StreamInput{}, err
+	}
+	return StreamInput{}, io.EOF
+}
+
+// Confirm emits a confirmation_required event and waits for the matching answer on stdin
+// Closed input or an answer for another tool call counts as a refusal
+func (s *StreamIO) Confirm(req ConfirmRequest) (bool, error) {
+	s.Emit(StreamEvent{
+		Type:       EventConfirmationRequired,
+		ToolCallID: req.ToolCall.ID,
+		Name:       req.ToolCall.Function.Name,
+		Arguments:  req.Arguments,
+		RiskLevel:  req.RiskLevel.String(),
+		Prompt:     req.Prompt,
+	})
+
+	input, err := s.ReadInput()
+	if err != nil {
+		return false, err
+	}
+	if input.Type != "confirmation" {
+		return false, fmt.Errorf("expected a confirmation for tool call %s, got %q", req.ToolCall.ID, input.Type)
+	}
+	if input.ToolCallID != "" && input.ToolCallID != req.ToolCall.ID {
+		return false, fmt.Errorf("confirmation is for tool call %s, expected %s", input.ToolCallID, req.ToolCall.ID)
+	}
+	return input.Approved, nil
+}
@@ -0,0 +1,116 @@
+package sql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aiq/aiq/internal/tool"
+)
+
+// decodeEvents decodes the JSON lines written by a stream
+func decodeEvents(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var events []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Expected one JSON event per line, got %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// TestStreamIO_Emit tests the encoding of stream-json events
+func TestStreamIO_Emit(t *testing.T) {
+	var out bytes.Buffer
+	stream := NewStreamIO(strings.NewReader(""), &out)
+
+	stream.NextTurn()
+	stream.Emit(StreamEvent{Type: EventToolCall, ToolCallID: "call_1", Name: "execute_sql", Arguments: map[string]interface{}{"sql": "SELECT 1"}})
+	stream.NextTurn()
+	stream.Emit(StreamEvent{Type: EventFinal, Turn: 7, Status: "success", Content: "done", Results: []map[string]interface{}{{"row_count": 1}}})
+
+	events := decodeEvents(t, &out)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0]["type"] != EventToolCall || events[0]["turn"] != float64(1) || events[0]["tool_call_id"] != "call_1" {
+		t.Errorf("Unexpected tool_call event: %v", events[0])
+	}
+	if args, ok := events[0]["arguments"].(map[string]interface{}); !ok || args["sql"] != "SELECT 1" {
+		t.Errorf("Expected the arguments to be included, got %v", events[0]["arguments"])
+	}
+	// The stream numbers turns itself, overriding the event's own
+	if events[1]["turn"] != float64(2) || events[1]["status"] != "success" {
+		t.Errorf("Unexpected final event: %v", events[1])
+	}
+
+	// Empty fields are omitted so each event only carries what applies to its type
+	for _, field := range []string{"content", "error", "results", "denied", "usage", "message"} {
+		if _, ok := events[0][field]; ok {
+			t.Errorf("Expected %q to be omitted from the tool_call event", field)
+		}
+	}
+}
+
+// TestStreamIO_ReadInput tests reading JSON lines from stdin
+func TestStreamIO_ReadInput(t *testing.T) {
+	stream := NewStreamIO(strings.NewReader("\n{\"type\":\"user\",\"content\":\"how many users?\"}\n  \nnot json\n"), io.Discard)
+
+	input, err := stream.ReadInput()
+	if err != nil || input.Type != "user" || input.Content != "how many users?" {
+		t.Errorf("Expected the user message, got %+v, %v", input, err)
+	}
+	if _, err := stream.ReadInput(); err == nil || !strings.Contains(err.Error(), "invalid input line") {
+		t.Errorf("Expected an invalid input error, got %v", err)
+	}
+	if _, err := stream.ReadInput(); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end of the input, got %v", err)
+	}
+}
+
+// TestStreamIO_Confirm tests confirmation requests and the answers read for them
+func TestStreamIO_Confirm(t *testing.T) {
+	req := ConfirmRequest{
+		ToolCall:  newToolCall("call_1", "execute_sql", map[string]interface{}{"sql": "DELETE FROM users"}),
+		Arguments: map[string]interface{}{"sql": "DELETE FROM users"},
+		RiskLevel: tool.RiskHigh,
+		Prompt:    "Execute DELETE?",
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		approved bool
+		wantErr  bool
+	}{
+		{"approved", `{"type":"confirmation","tool_call_id":"call_1","approved":true}`, true, false},
+		{"refused", `{"type":"confirmation","tool_call_id":"call_1","approved":false}`, false, false},
+		{"without tool call ID", `{"type":"confirmation","approved":true}`, true, false},
+		{"other tool call", `{"type":"confirmation","tool_call_id":"call_2","approved":true}`, false, true},
+		{"user message instead", `{"type":"user","content":"never mind"}`, false, true},
+		{"closed input", ``, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			stream := NewStreamIO(strings.NewReader(tt.input), &out)
+			approved, err := stream.Confirm(req)
+			if approved != tt.approved || (err != nil) != tt.wantErr {
+				t.Errorf("Expected approved=%v error=%v, got %v, %v", tt.approved, tt.wantErr, approved, err)
+			}
+
+			events := decodeEvents(t, &out)
+			if len(events) != 1 || events[0]["type"] != EventConfirmationRequired {
+				t.Fatalf("Expected one confirmation_required event, got %v", events)
+			}
+			if events[0]["tool_call_id"] != "call_1" || events[0]["name"] != "execute_sql" || events[0]["risk_level"] != "high" || events[0]["prompt"] != "Execute DELETE?" {
+				t.Errorf("Unexpected confirmation_required event: %v", events[0])
+			}
+		})
+	}
+}
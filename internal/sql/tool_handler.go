@@ -3,19 +3,62 @@ package sql
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/aiq/aiq/internal/config"
 	"github.com/aiq/aiq/internal/db"
+	"github.com/aiq/aiq/internal/jobs"
 	"github.com/aiq/aiq/internal/llm"
+	"github.com/aiq/aiq/internal/mcp"
 	"github.com/aiq/aiq/internal/prompt"
 	"github.com/aiq/aiq/internal/skills"
 	"github.com/aiq/aiq/internal/tool"
 	"github.com/aiq/aiq/internal/tool/builtin"
+	"github.com/aiq/aiq/internal/tool/schema"
 	"github.com/aiq/aiq/internal/ui"
 )
 
+// ConfirmPolicy decides tool calls without asking the user, for non-interactive runs
+type ConfirmPolicy string
+
+const (
+	// PolicyPrompt asks the user to confirm high-risk operations (interactive chat)
+	PolicyPrompt ConfirmPolicy = ""
+	// PolicyDeny only runs read-only SQL, rendering and custom tools declared low risk; everything else is refused
+	PolicyDeny ConfirmPolicy = "deny"
+	// PolicyAllowLowRisk also runs CREATE TABLE; other writes and tools rated low only by the LLM are refused
+	PolicyAllowLowRisk ConfirmPolicy = "allow-low-risk"
+)
+
+// ParseConfirmPolicy parses a non-interactive confirmation policy name
+func ParseConfirmPolicy(name string) (ConfirmPolicy, error) {
+	switch policy := ConfirmPolicy(strings.ToLower(name)); policy {
+	case PolicyDeny, PolicyAllowLowRisk:
+		return policy, nil
+	case "prompt":
+		return PolicyPrompt, nil
+	}
+	return PolicyPrompt, fmt.Errorf("unknown confirmation policy %q (use prompt, deny or allow-low-risk)", name)
+}
+
+// TurnOutcome summarizes the tool calls of the last HandleToolCallLoop run
+type TurnOutcome struct {
+	ResultSets []db.ResultSet            // Result sets of successful execute_sql calls, in order
+	Denied     []string                  // Tool calls refused by the confirmation policy
+	SQLError   string                    // Error of the last execute_sql call, if it failed
+	Limits     []LimitHit                // Loop limits reached, with the action taken
+	Streamed   bool                      // The text of the last LLM reply was printed as it arrived
+	Usage      llm.UsageStats            // Tokens used by the LLM calls of the turn, including skill matching and compression
+	ModelUsage map[string]llm.UsageStats // Usage per model, as tasks may use different profiles
+}
+
 // ToolHandler handles tool execution and manages tool calling loop
 type ToolHandler struct {
 	conn          *db.Connection
@@ -24,6 +67,25 @@ type ToolHandler struct {
 	promptBuilder *prompt.Builder
 	compressor    *prompt.Compressor
 	promptLoader  *prompt.Loader
+	schema        *db.Schema // Loaded schema used to validate SQL identifiers before execution
+	databaseName  string     // Database the schema was loaded from (used for refresh after DDL)
+	schemaStale   bool       // A refresh after DDL failed; the schema is kept for context but not used for validation
+	schemaLoaded  time.Time  // When the schema was last loaded; bounds re-fetches for unknown identifiers
+	tempTables    []string   // Temporary tables created by this handler, which INFORMATION_SCHEMA doesn't list
+	schemaMu      sync.Mutex // Serializes validation with schema reloads; read-only queries run in parallel
+	jobs          *jobs.Manager
+	background    bool // Run every execute_sql call of this turn in the background
+	policy        ConfirmPolicy
+	outcome       TurnOutcome
+	renderResults bool // Print execute_sql results as tables while the loop runs
+	streamText    bool // Print assistant text as it streams in
+	onEvent       func(StreamEvent)
+	confirmer     func(ConfirmRequest) (bool, error)
+	external      *mcp.Manager   // Tools of external MCP servers
+	registry      *tool.Registry // aiq's own tools, built once per turn (see Tools)
+	limits        config.LoopLimits
+	taskClients   []*llm.Client // Clients of skill matching and compression, if set apart from the chat client
+	out           *ui.Output    // Progress output (tool calls, spinners, warnings); nil writes to stdout
 }
 
 // NewToolHandler creates a new tool handler
@@ -41,7 +103,7 @@ func NewToolHandler(conn *db.Connection, skillsManager *skills.Manager, llmClien
 	if err != nil {
 		// Log error but continue with default prompts (fallback behavior)
 		// This allows the system to work even if prompt files can't be loaded
-		fmt.Printf("Warning: Failed to load prompts: %v. Using default prompts.\n", err)
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load prompts: %v. Using default prompts.\n", err)
 		promptLoader = nil
 	}
 	return &ToolHandler{
@@ -51,6 +113,189 @@ func NewToolHandler(conn *db.Connection, skillsManager *skills.Manager, llmClien
 		promptBuilder: prompt.NewBuilder(""), // Will be set in HandleToolCallLoop
 		compressor:    compressor,
 		promptLoader:  promptLoader,
+		renderResults: true,
+		streamText:    true,
+	}
+}
+
+// SetOutput sends progress output (tool calls, spinners, warnings) to w instead of stdout
+func (h *ToolHandler) SetOutput(w io.Writer) {
+	h.out = ui.NewOutput(w)
+}
+
+// SetTaskClients sets the LLM clients used for skill matching and compression
+// By default both use the client the handler was created with.
+func (h *ToolHandler) SetTaskClients(skillsClient, compressionClient *llm.Client) {
+	h.matcher.SetLLMClient(skillsClient)
+	h.compressor.SetLLMClient(compressionClient)
+	h.taskClients = []*llm.Client{skillsClient, compressionClient}
+}
+
+// SetSchema sets the schema used to validate identifiers in generated SQL before execution
+// The schema is refreshed in place after successful DDL, so callers holding the same pointer see the changes
+func (h *ToolHandler) SetSchema(schema *db.Schema, databaseName string) {
+	h.schema = schema
+	h.databaseName = databaseName
+	h.schemaLoaded = time.Now()
+}
+
+// SetJobs enables background execution of execute_sql calls through the given job manager
+// If background is true, every query of this turn runs in the background regardless of the LLM's choice
+func (h *ToolHandler) SetJobs(manager *jobs.Manager, background bool) {
+	h.jobs = manager
+	h.background = background
+}
+
+// SetConfirmPolicy makes tool calls that would need confirmation follow policy instead of prompting
+func (h *ToolHandler) SetConfirmPolicy(policy ConfirmPolicy) {
+	h.policy = policy
+}
+
+// SetRenderResults sets whether execute_sql results are printed as tables during the loop
+// Results are always available from Outcome
+func (h *ToolHandler) SetRenderResults(render bool) {
+	h.renderResults = render
+}
+
+// SetStreamText sets whether assistant text is printed as it streams in
+// When disabled, replies are requested without streaming.
+func (h *ToolHandler) SetStreamText(stream bool) {
+	h.streamText = stream
+}
+
+// Outcome returns the summary of the tool calls made by the last HandleToolCallLoop run
+func (h *ToolHandler) Outcome() TurnOutcome {
+	return h.outcome
+}
+
+// policyDenial returns why the confirmation policy refuses a tool call, or "" if it may run
+// Calls are classified by code only: the LLM's risk_level argument is never trusted here, SQL is
+// checked statement by statement, and other tools run only if their author declared them low risk.
+func (h *ToolHandler) policyDenial(toolName string, args map[string]interface{}) string {
+	if h.policy != PolicyDeny && h.policy != PolicyAllowLowRisk {
+		return ""
+	}
+	switch toolName {
+	case "render_table", "render_chart":
+		return ""
+	case "execute_sql":
+		sql, _ := args["sql"].(string)
+		if h.policy == PolicyDeny {
+			if tool.IsReadOnlySQL(sql) {
+				return ""
+			}
+			return "only read-only SQL is allowed by the deny policy"
+		}
+		if tool.IsLowRiskSQL(sql) {
+			return ""
+		}
+		return "SQL other than reads and CREATE TABLE requires confirmation, which the allow-low-risk policy refuses"
+	}
+	// Custom tools carry the risk level declared by their author, not by the LLM
+	if level, ok := builtin.CustomToolRiskLevel(toolName); ok && level == "low" {
+		return ""
+	}
+	return fmt.Sprintf("tool %s is not allowed by the %s policy", toolName, h.policy)
+}
+
+// startBackgroundSQL validates sql and starts it as a background job
+// Schema-changing statements are rejected because the schema refresh after DDL must not race
+// with queries of the current turn
+func (h *ToolHandler) startBackgroundSQL(ctx context.Context, sql string, params []interface{}) (jobs.Job, error) {
+	if err := h.validateSQL(ctx, sql); err != nil {
+		return jobs.Job{}, err
+	}
+	if tool.IsSchemaChangingSQL(sql) {
+		return jobs.Job{}, fmt.Errorf("schema-changing statements cannot run in the background; run it without background")
+	}
+
+	conn := h.conn
+	return h.jobs.Start(ctx, sql, func(jobCtx context.Context) (*db.QueryResult, error) {
+		// Background jobs are stopped with /jobs cancel rather than the default statement timeout
+		return tool.ExecuteSQL(db.WithQueryTimeout(jobCtx, 0), conn, sql, params...)
+	}), nil
+}
+
+// executeSQL validates sql against the loaded schema and executes it
+// Unknown tables/columns are rejected without a round trip to the database
+func (h *ToolHandler) executeSQL(ctx context.Context, sql string, params []interface{}) (*db.QueryResult, error) {
+	if err := h.validateSQL(ctx, sql); err != nil {
+		return nil, err
+	}
+
+	result, err := tool.ExecuteSQL(ctx, h.conn, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if tool.IsSchemaChangingSQL(sql) {
+		h.schemaMu.Lock()
+		h.refreshSchema(ctx, sql)
+		h.schemaMu.Unlock()
+	}
+	return result, nil
+}
+
+// schemaRefetchInterval is the minimum time between schema re-fetches triggered by unknown identifiers
+const schemaRefetchInterval = time.Minute
+
+// loadSchema loads the schema of a database (replaced in tests)
+var loadSchema = (*db.Connection).GetSchema
+
+// validateSQL checks the identifiers in sql against the schema, unless a failed refresh left it outdated
+// Tables and columns created outside aiq since the schema was loaded would be rejected, so before
+// reporting unknown identifiers the schema is re-fetched, at most once per schemaRefetchInterval.
+func (h *ToolHandler) validateSQL(ctx context.Context, sql string) error {
+	h.schemaMu.Lock()
+	defer h.schemaMu.Unlock()
+
+	if h.schemaStale {
+		return nil
+	}
+	err := tool.ValidateSQLIdentifiers(sql, h.schema)
+	var unknownErr *tool.UnknownIdentifierError
+	if !errors.As(err, &unknownErr) || h.conn == nil || time.Since(h.schemaLoaded) < schemaRefetchInterval {
+		return err
+	}
+
+	h.refreshSchema(ctx, "")
+	if h.schemaStale {
+		return nil
+	}
+	return tool.ValidateSQLIdentifiers(sql, h.schema)
+}
+
+// refreshSchema reloads the schema after DDL so new tables and columns pass validation
+// The caller holds schemaMu.
+func (h *ToolHandler) refreshSchema(ctx context.Context, sql string) {
+	if h.schema == nil || h.conn == nil {
+		return
+	}
+
+	h.schemaLoaded = time.Now()
+	fresh, err := loadSchema(h.conn, ctx, h.databaseName)
+	if err != nil {
+		// Validation against the outdated schema would reject the new names; skip it for the rest of
+		// the turn. The schema itself is shared with the system prompt and MCP resources, so it is kept.
+		h.schemaStale = true
+		return
+	}
+	*h.schema = *fresh
+	h.schemaStale = false
+
+	// Temporary tables are not listed in INFORMATION_SCHEMA; register them without column metadata
+	h.tempTables = append(h.tempTables, tool.CreatedTemporaryTables(sql)...)
+	for _, name := range h.tempTables {
+		exists := false
+		for _, t := range h.schema.Tables {
+			if strings.EqualFold(t.Name, name) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			h.schema.Tables = append(h.schema.Tables, db.TableInfo{Name: name})
+		}
 	}
 }
 
@@ -168,6 +413,157 @@ func formatQueryResultSummary(result *db.QueryResult) string {
 	return fmt.Sprintf("Query executed successfully. Returned %d row(s) with columns: %s. Sample data: %s", rowCount, columnsStr, sampleDataStr)
 }
 
+// formatSQLParams formats bound parameter values for display next to the SQL text
+func formatSQLParams(params []interface{}) string {
+	parts := make([]string, len(params))
+	for i, p := range params {
+		switch v := p.(type) {
+		case nil:
+			parts[i] = "NULL"
+		case string:
+			parts[i] = fmt.Sprintf("%q", v)
+		default:
+			parts[i] = fmt.Sprintf("%v", v)
+		}
+	}
+	return fmt.Sprintf("Params: [%s]", strings.Join(parts, ", "))
+}
+
+// resultSetsToJSON converts result sets to the JSON shape returned to the LLM
+func resultSetsToJSON(sets []db.ResultSet) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(sets))
+	for _, set := range sets {
+		setJSON := map[string]interface{}{
+			"columns":   set.Columns,
+			"rows":      set.Rows,
+			"row_count": len(set.Rows),
+		}
+		if set.RowsAffected >= 0 {
+			setJSON["rows_affected"] = set.RowsAffected
+		}
+		out = append(out, setJSON)
+	}
+	return out
+}
+
+// resultSetsFromJSON extracts result sets from an execute_sql tool result
+// Results with a single result set only carry top-level columns/rows
+func resultSetsFromJSON(resultData map[string]interface{}) []db.ResultSet {
+	if setsInterface, ok := resultData["result_sets"].([]interface{}); ok {
+		sets := make([]db.ResultSet, 0, len(setsInterface))
+		for _, setInterface := range setsInterface {
+			if setData, ok := setInterface.(map[string]interface{}); ok {
+				sets = append(sets, resultSetFromJSON(setData))
+			}
+		}
+		return sets
+	}
+
+	if _, ok := resultData["columns"]; !ok {
+		return nil
+	}
+	return []db.ResultSet{resultSetFromJSON(resultData)}
+}
+
+func resultSetFromJSON(data map[string]interface{}) db.ResultSet {
+	set := db.ResultSet{RowsAffected: -1}
+	if columns, ok := data["columns"].([]interface{}); ok {
+		set.Columns = make([]string, len(columns))
+		for i, col := range columns {
+			set.Columns[i] = fmt.Sprintf("%v", col)
+		}
+	}
+	if rows, ok := data["rows"].([]interface{}); ok {
+		set.Rows = make([][]string, len(rows))
+		for i, rowInterface := range rows {
+			if rowArray, ok := rowInterface.([]interface{}); ok {
+				set.Rows[i] = make([]string, len(rowArray))
+				for j, val := range rowArray {
+					set.Rows[i][j] = fmt.Sprintf("%v", val)
+				}
+			}
+		}
+	}
+	if affected, ok := data["rows_affected"].(float64); ok {
+		set.RowsAffected = int64(affected)
+	}
+	return set
+}
+
+// renderResultSet prints one result set in mysql client style to out
+func renderResultSet(out *ui.Output, set db.ResultSet, index, total int) {
+	if total > 1 {
+		out.Println()
+		out.Printf("Result set %d of %d\n", index+1, total)
+	}
+
+	if len(set.Columns) == 0 {
+		if set.RowsAffected >= 0 {
+			out.Println()
+			out.Printf("Query OK, %d row(s) affected\n", set.RowsAffected)
+		}
+		return
+	}
+
+	if len(set.Rows) > 0 {
+		out.Println()
+		tableOutput, tableErr := tool.RenderTableString(set.Columns, set.Rows)
+		if tableErr == nil {
+			out.Println(tableOutput)
+		}
+		out.Printf("%d row(s) in set\n", len(set.Rows))
+	} else if total > 1 {
+		out.Println("Empty set")
+	}
+}
+
+// sqlErrorResult converts an execute_sql error into the structured JSON returned to the LLM
+func sqlErrorResult(err error) json.RawMessage {
+	// Extract structured error information
+	errorInfo := tool.ExtractErrorInfo(err)
+	errorMessage := err.Error()
+
+	// Build structured error JSON with backward compatibility
+	errorJSON := map[string]interface{}{
+		"status": "error",
+		"error":  errorMessage, // Keep original error message for backward compatibility
+	}
+
+	// Add structured error fields if available
+	if errorInfo.ErrorCode != "" {
+		errorJSON["error_code"] = errorInfo.ErrorCode
+	}
+	if errorInfo.SQLState != "" {
+		errorJSON["sql_state"] = errorInfo.SQLState
+	}
+	if errorInfo.ErrorType != "" && errorInfo.ErrorType != "unknown" {
+		errorJSON["error_type"] = errorInfo.ErrorType
+	}
+	if len(errorInfo.AffectedResources) > 0 {
+		errorJSON["affected_resources"] = errorInfo.AffectedResources
+	}
+	if errorInfo.Constraint != "" {
+		errorJSON["constraint"] = errorInfo.Constraint
+	}
+	if len(errorInfo.Dependencies) > 0 {
+		errorJSON["dependencies"] = errorInfo.Dependencies
+	}
+	if len(errorInfo.SuggestedActions) > 0 {
+		errorJSON["suggested_actions"] = errorInfo.SuggestedActions
+	}
+	if len(errorInfo.UnknownIdentifiers) > 0 {
+		errorJSON["unknown_identifiers"] = errorInfo.UnknownIdentifiers
+	}
+
+	jsonData, jsonErr := json.Marshal(errorJSON)
+	if jsonErr != nil {
+		// Fallback if JSON encoding fails
+		errorMsg := fmt.Sprintf(`{"status":"error","error":"%s"}`, strings.ReplaceAll(errorMessage, `"`, `\"`))
+		return json.RawMessage(errorMsg)
+	}
+	return json.RawMessage(jsonData)
+}
+
 // ExecuteTool executes a tool call and returns the result
 func (h *ToolHandler) ExecuteTool(ctx context.Context, toolCall llm.ToolCall) (json.RawMessage, error) {
 	toolName := toolCall.Function.Name
@@ -193,292 +589,208 @@ func (h *ToolHandler) ExecuteTool(ctx context.Context, toolCall llm.ToolCall) (j
 		return nil, err
 	}
 
-	switch toolName {
-	case "execute_sql":
-		sql, ok := args["sql"].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid sql parameter")
-		}
-
-		// Execute SQL - this does NOT print anything, only returns data
-		result, err := tool.ExecuteSQL(ctx, h.conn, sql)
-		if err != nil {
-			// Extract structured error information
-			errorInfo := tool.ExtractErrorInfo(err)
-			errorMessage := err.Error()
-
-			// Build structured error JSON with backward compatibility
-			errorJSON := map[string]interface{}{
-				"status": "error",
-				"error":  errorMessage, // Keep original error message for backward compatibility
-			}
-
-			// Add structured error fields if available
-			if errorInfo.ErrorCode != "" {
-				errorJSON["error_code"] = errorInfo.ErrorCode
-			}
-			if errorInfo.ErrorType != "" && errorInfo.ErrorType != "unknown" {
-				errorJSON["error_type"] = errorInfo.ErrorType
-			}
-			if len(errorInfo.AffectedResources) > 0 {
-				errorJSON["affected_resources"] = errorInfo.AffectedResources
-			}
-			if len(errorInfo.Dependencies) > 0 {
-				errorJSON["dependencies"] = errorInfo.Dependencies
-			}
-			if len(errorInfo.SuggestedActions) > 0 {
-				errorJSON["suggested_actions"] = errorInfo.SuggestedActions
-			}
+	// Tools of external MCP servers are namespaced and never shadow aiq's own tools
+	if h.external.Has(toolName) {
+		return h.external.Call(ctx, toolName, args)
+	}
 
-			jsonData, jsonErr := json.Marshal(errorJSON)
-			if jsonErr != nil {
-				// Fallback if JSON encoding fails
-				errorMsg := fmt.Sprintf(`{"status":"error","error":"%s"}`, strings.ReplaceAll(errorMessage, `"`, `\"`))
-				return json.RawMessage(errorMsg), nil
-			}
-			return json.RawMessage(jsonData), nil
+	result, err := h.Tools().Execute(ctx, toolName, args)
+	if err != nil {
+		var validationErr *schema.ValidationError
+		if errors.As(err, &validationErr) {
+			return invalidArgumentsResult(validationErr), nil
 		}
-
-		// Convert result to JSON and return to LLM
-		// LLM will decide how to display this (via render_table or text description)
-		resultJSON := map[string]interface{}{
-			"status":    "success",
-			"columns":   result.Columns,
-			"rows":      result.Rows,
-			"row_count": len(result.Rows),
-		}
-
-		// For operations with no data returned, add completion message
-		// Let LLM decide whether task is complete based on task type (definitive vs exploratory)
-		if len(result.Rows) == 0 {
-			resultJSON["status"] = "success"
-			// Don't add specific instruction - let LLM decide based on task context
-			// LLM will determine if this is definitive (complete) or exploratory (needs continuation)
+		// Check if it's truly an unknown tool or an execution error
+		if errors.Is(err, tool.ErrUnknownTool) {
+			return nil, fmt.Errorf("unknown tool: %s", toolName)
 		}
+		return sqlErrorResult(err), nil
+	}
+	if raw, ok := result.(json.RawMessage); ok {
+		return raw, nil
+	}
 
-		jsonData, err := json.Marshal(resultJSON)
+	// Convert result to JSON
+	// For execute_command, use truncated output for LLM and add status field
+	if toolName == "execute_command" {
+		// First marshal to JSON, then unmarshal to map to add status and use truncated output
+		jsonBytes, err := json.Marshal(result)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal result: %w", err)
 		}
-		return json.RawMessage(jsonData), nil
 
-	case "render_table":
-		columnsInterface, ok := args["columns"].([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid columns parameter")
-		}
-		rowsInterface, ok := args["rows"].([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid rows parameter")
+		var resultMap map[string]interface{}
+		if err := json.Unmarshal(jsonBytes, &resultMap); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal result: %w", err)
 		}
 
-		// Convert to string slices
-		columns := make([]string, len(columnsInterface))
-		for i, col := range columnsInterface {
-			columns[i] = fmt.Sprintf("%v", col)
-		}
-
-		rows := make([][]string, len(rowsInterface))
-		for i, rowInterface := range rowsInterface {
-			rowArray, ok := rowInterface.([]interface{})
-			if !ok {
-				return nil, fmt.Errorf("invalid row format")
-			}
-			rows[i] = make([]string, len(rowArray))
-			for j, val := range rowArray {
-				rows[i][j] = fmt.Sprintf("%v", val)
-			}
+		// Check exit_code to determine status (simple rule: 0 = success, non-zero = error)
+		exitCode := 0
+		if ec, ok := resultMap["exit_code"].(float64); ok {
+			exitCode = int(ec)
+		} else if ec, ok := resultMap["exit_code"].(int); ok {
+			exitCode = ec
 		}
 
-		// Format the table as a string (do not print)
-		tableOutput, err := tool.RenderTableString(columns, rows)
-		if err != nil {
-			errorMsg := fmt.Sprintf(`{"error": "%s"}`, err.Error())
-			return json.RawMessage(errorMsg), nil
-		}
-
-		// Return formatted table to LLM
-		resultJSON := map[string]interface{}{
-			"status":    "success",
-			"format":    "table",
-			"output":    tableOutput,
-			"row_count": len(rows),
-		}
-		jsonData, err := json.Marshal(resultJSON)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal result: %w", err)
-		}
-		return json.RawMessage(jsonData), nil
+		// Save full output for display (before truncation)
+		fullStdout, _ := resultMap["stdout"].(string)
+		fullStderr, _ := resultMap["stderr"].(string)
 
-	case "render_chart":
-		columnsInterface, ok := args["columns"].([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid columns parameter")
-		}
-		rowsInterface, ok := args["rows"].([]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid rows parameter")
+		// Use truncated output for LLM (if available)
+		if truncatedStdout, ok := resultMap["truncated_stdout"].(string); ok && truncatedStdout != "" {
+			// Keep full output in a separate field for display
+			resultMap["_full_stdout"] = fullStdout
+			resultMap["stdout"] = truncatedStdout
 		}
-		chartTypeStr, ok := args["chart_type"].(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid chart_type parameter")
+		if truncatedStderr, ok := resultMap["truncated_stderr"].(string); ok && truncatedStderr != "" {
+			// Keep full output in a separate field for display
+			resultMap["_full_stderr"] = fullStderr
+			resultMap["stderr"] = truncatedStderr
 		}
 
-		// Convert to string slices
-		columns := make([]string, len(columnsInterface))
-		for i, col := range columnsInterface {
-			columns[i] = fmt.Sprintf("%v", col)
-		}
+		// Remove truncated fields from JSON (they're only for internal use)
+		delete(resultMap, "truncated_stdout")
+		delete(resultMap, "truncated_stderr")
 
-		rows := make([][]string, len(rowsInterface))
-		for i, rowInterface := range rowsInterface {
-			rowArray, ok := rowInterface.([]interface{})
-			if !ok {
-				return nil, fmt.Errorf("invalid row format")
-			}
-			rows[i] = make([]string, len(rowArray))
-			for j, val := range rowArray {
-				rows[i][j] = fmt.Sprintf("%v", val)
-			}
+		// Add explicit status field based on exit_code
+		if exitCode == 0 {
+			resultMap["status"] = "success"
+		} else {
+			resultMap["status"] = "error"
+			resultMap["error"] = fmt.Sprintf("Command exited with code %d", exitCode)
 		}
 
-		// Create QueryResult
-		result := &db.QueryResult{
-			Columns: columns,
-			Rows:    rows,
-		}
+		// Note: Keep _full_stdout and _full_stderr for display in tool_handler
+		// They will be removed before sending to LLM in the tool call loop
 
-		chartOutput, err := tool.RenderChartString(result, chartTypeStr)
-		if err != nil {
-			errorMsg := fmt.Sprintf(`{"error": "%s"}`, err.Error())
-			return json.RawMessage(errorMsg), nil
-		}
-
-		// Return formatted chart to LLM
-		resultJSON := map[string]interface{}{
-			"status":     "success",
-			"format":     "chart",
-			"output":     chartOutput,
-			"chart_type": chartTypeStr,
-			"row_count":  len(result.Rows),
-		}
-		jsonData, err := json.Marshal(resultJSON)
+		// Marshal back to JSON
+		jsonData, err := json.Marshal(resultMap)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal result: %w", err)
 		}
 		return json.RawMessage(jsonData), nil
+	}
 
-	default:
-		// Try built-in tools - use ExecuteBuiltinTool (no callback support yet)
-		result, err := builtin.ExecuteBuiltinTool(ctx, toolName, args, h.conn)
-		if err != nil {
-			// Check if it's truly an unknown tool or an execution error
-			if strings.Contains(err.Error(), "unknown built-in tool") {
-				return nil, fmt.Errorf("unknown tool: %s", toolName)
-			}
-			// For execution errors, extract structured error information
-			errorInfo := tool.ExtractErrorInfo(err)
-			errorMessage := err.Error()
-
-			// Build structured error JSON with backward compatibility
-			errorJSON := map[string]interface{}{
-				"status": "error",
-				"error":  errorMessage, // Keep original error message for backward compatibility
-			}
+	// For other tools, convert result to JSON as-is
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return json.RawMessage(resultJSON), nil
+}
 
-			// Add structured error fields if available
-			if errorInfo.ErrorCode != "" {
-				errorJSON["error_code"] = errorInfo.ErrorCode
-			}
-			if errorInfo.ErrorType != "" && errorInfo.ErrorType != "unknown" {
-				errorJSON["error_type"] = errorInfo.ErrorType
-			}
-			if len(errorInfo.AffectedResources) > 0 {
-				errorJSON["affected_resources"] = errorInfo.AffectedResources
-			}
-			if len(errorInfo.Dependencies) > 0 {
-				errorJSON["dependencies"] = errorInfo.Dependencies
-			}
-			if len(errorInfo.SuggestedActions) > 0 {
-				errorJSON["suggested_actions"] = errorInfo.SuggestedActions
-			}
+// Tools returns the registry of aiq's own tools for this handler
+// HandleToolCallLoop rebuilds it at the start of every turn so custom tools added to ~/.aiq/tools are picked up;
+// outside a turn it is built on first use.
+func (h *ToolHandler) Tools() *tool.Registry {
+	if h.registry == nil {
+		h.registry = tool.NewDefaultRegistry(h.conn, h.runSQL)
+	}
+	return h.registry
+}
 
-			jsonData, jsonErr := json.Marshal(errorJSON)
-			if jsonErr != nil {
-				// Fallback if JSON encoding fails
-				errorMsg := fmt.Sprintf(`{"status":"error","error":"%s"}`, strings.ReplaceAll(errorMessage, `"`, `\"`))
-				return json.RawMessage(errorMsg), nil
+// invalidArgumentsResult reports arguments that do not match a tool's schema back to the LLM
+func invalidArgumentsResult(err *schema.ValidationError) json.RawMessage {
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"status":         "error",
+		"error_type":     "invalid_arguments",
+		"error":          err.Error(),
+		"invalid_fields": err.Errors,
+		"instruction":    "Fix the listed arguments to match the tool's parameter schema and call the tool again.",
+	})
+	return json.RawMessage(jsonData)
+}
+
+// runSQL executes the execute_sql tool
+func (h *ToolHandler) runSQL(ctx context.Context, p tool.SQLParams) (interface{}, error) {
+	sql := p.SQL
+	params, err := p.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	// Long-running queries can run in the background; the result is attached to a later turn
+	background := p.Background
+	if h.jobs != nil && (background || h.background) {
+		job, err := h.startBackgroundSQL(ctx, sql, params)
+		if err == nil {
+			resultJSON := map[string]interface{}{
+				"status":      "success",
+				"background":  true,
+				"job_id":      job.ID,
+				"instruction": "The query is running in the background. Its result will be added to the conversation when it completes. Tell the user briefly that it was started; do not wait for it, poll or re-run it.",
 			}
-			return json.RawMessage(jsonData), nil
-		}
-		// Convert result to JSON
-		// For execute_command, use truncated output for LLM and add status field
-		if toolName == "execute_command" {
-			// First marshal to JSON, then unmarshal to map to add status and use truncated output
-			jsonBytes, err := json.Marshal(result)
+			jsonData, err := json.Marshal(resultJSON)
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal result: %w", err)
 			}
+			return json.RawMessage(jsonData), nil
+		}
+		return sqlErrorResult(err), nil
+	}
 
-			var resultMap map[string]interface{}
-			if err := json.Unmarshal(jsonBytes, &resultMap); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal result: %w", err)
-			}
-
-			// Check exit_code to determine status (simple rule: 0 = success, non-zero = error)
-			exitCode := 0
-			if ec, ok := resultMap["exit_code"].(float64); ok {
-				exitCode = int(ec)
-			} else if ec, ok := resultMap["exit_code"].(int); ok {
-				exitCode = ec
-			}
-
-			// Save full output for display (before truncation)
-			fullStdout, _ := resultMap["stdout"].(string)
-			fullStderr, _ := resultMap["stderr"].(string)
-
-			// Use truncated output for LLM (if available)
-			if truncatedStdout, ok := resultMap["truncated_stdout"].(string); ok && truncatedStdout != "" {
-				// Keep full output in a separate field for display
-				resultMap["_full_stdout"] = fullStdout
-				resultMap["stdout"] = truncatedStdout
-			}
-			if truncatedStderr, ok := resultMap["truncated_stderr"].(string); ok && truncatedStderr != "" {
-				// Keep full output in a separate field for display
-				resultMap["_full_stderr"] = fullStderr
-				resultMap["stderr"] = truncatedStderr
-			}
+	// Validate and execute SQL - this does NOT print anything, only returns data
+	result, err := h.executeSQL(ctx, sql, params)
+	if err != nil {
+		return sqlErrorResult(err), nil
+	}
 
-			// Remove truncated fields from JSON (they're only for internal use)
-			delete(resultMap, "truncated_stdout")
-			delete(resultMap, "truncated_stderr")
+	// Convert result to JSON and return to LLM
+	// LLM will decide how to display this (via render_table or text description)
+	resultJSON := map[string]interface{}{
+		"status":    "success",
+		"columns":   result.Columns,
+		"rows":      result.Rows,
+		"row_count": len(result.Rows),
+	}
+	if result.RowsAffected >= 0 {
+		resultJSON["rows_affected"] = result.RowsAffected
+	}
+	// Stored procedures may return several result sets; include all of them in order
+	if len(result.ResultSets) > 1 {
+		resultJSON["result_sets"] = resultSetsToJSON(result.ResultSets)
+	}
 
-			// Add explicit status field based on exit_code
-			if exitCode == 0 {
-				resultMap["status"] = "success"
-			} else {
-				resultMap["status"] = "error"
-				resultMap["error"] = fmt.Sprintf("Command exited with code %d", exitCode)
-			}
+	// For operations with no data returned, add completion message
+	// Let LLM decide whether task is complete based on task type (definitive vs exploratory)
+	if len(result.Rows) == 0 {
+		resultJSON["status"] = "success"
+		// Don't add specific instruction - let LLM decide based on task context
+		// LLM will determine if this is definitive (complete) or exploratory (needs continuation)
+	}
 
-			// Note: Keep _full_stdout and _full_stderr for display in tool_handler
-			// They will be removed before sending to LLM in the tool call loop
+	jsonData, err := json.Marshal(resultJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return json.RawMessage(jsonData), nil
+}
 
-			// Marshal back to JSON
-			jsonData, err := json.Marshal(resultMap)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal result: %w", err)
-			}
-			return json.RawMessage(jsonData), nil
-		}
+// chat sends one request of the tool-calling loop
+// With text streaming enabled, assistant text is printed as it arrives; stopThinking removes the
+// spinner before the first piece is printed. Waits before retries are shown through setStatus.
+func (h *ToolHandler) chat(ctx context.Context, llmClient *llm.Client, messages []interface{}, tools []llm.Function, setStatus func(string), stopThinking func()) (*llm.ChatResponse, error) {
+	h.outcome.Streamed = false
+	ctx = llm.WithRetryNotify(ctx, func(retry llm.Retry) {
+		seconds := int(math.Ceil(retry.Wait.Seconds()))
+		setStatus(fmt.Sprintf("%s, retrying in %ds (%d/%d)...", retry.Reason(), seconds, retry.Attempt, retry.MaxRetries))
+	})
+	if !h.streamText {
+		return llmClient.ChatWithTools(ctx, messages, tools)
+	}
 
-		// For other tools, convert result to JSON as-is
-		resultJSON, err := json.Marshal(result)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal result: %w", err)
+	response, err := llmClient.ChatWithToolsStream(ctx, messages, tools, func(text string) {
+		if !h.outcome.Streamed {
+			stopThinking()
+			h.out.Println()
+			h.outcome.Streamed = true
 		}
-		return json.RawMessage(resultJSON), nil
+		h.out.Print(text)
+	})
+	if h.outcome.Streamed {
+		h.out.Print("\n\n")
 	}
+	return response, err
 }
 
 // HandleToolCallLoop handles the complete tool calling loop
@@ -487,6 +799,12 @@ func (h *ToolHandler) ExecuteTool(ctx context.Context, toolCall llm.ToolCall) (j
 // If rawMessages is provided, it will be used directly (includes tool calls and results from previous sessions)
 // Otherwise, conversationHistory will be converted to messages
 func (h *ToolHandler) HandleToolCallLoop(ctx context.Context, llmClient *llm.Client, userInput string, schemaContext string, databaseType string, conversationHistory []llm.ChatMessage, tools []llm.Function, rawMessages []interface{}) (string, *db.QueryResult, []interface{}, error) {
+	tracker := trackUsage(append([]*llm.Client{llmClient}, h.taskClients...)...)
+	defer func() {
+		h.outcome.Usage, h.outcome.ModelUsage = tracker.usage()
+	}()
+	h.registry = tool.NewDefaultRegistry(h.conn, h.runSQL)
+
 	// Determine mode: free mode or database mode
 	isFreeMode := schemaContext == "" || h.conn == nil
 
@@ -582,7 +900,7 @@ You are a helpful AI assistant for database queries and related tasks.
 			// Evict Skills not matched in recent queries before loading new ones
 			evicted := h.skillsManager.EvictUnusedSkills(skills.DefaultEvictionQueries)
 			if len(evicted) > 0 {
-				ui.ShowInfo(fmt.Sprintf("Evicted %d unused skill(s): %v", len(evicted), evicted))
+				h.out.Info(fmt.Sprintf("Evicted %d unused skill(s): %v", len(evicted), evicted))
 			}
 
 			if len(matchedMetadata) > 0 {
@@ -607,9 +925,9 @@ You are a helpful AI assistant for database queries and related tasks.
 					}
 					// Show which Skills were loaded with descriptions
 					if len(loadedSkills) > 0 {
-						fmt.Print(ui.InfoText("Loaded "))
-						fmt.Print(ui.HighlightText(fmt.Sprintf("%d skill(s)", len(loadedSkills))))
-						fmt.Print(ui.InfoText(": "))
+						h.out.Print(ui.InfoText("Loaded "))
+						h.out.Print(ui.HighlightText(fmt.Sprintf("%d skill(s)", len(loadedSkills))))
+						h.out.Print(ui.InfoText(": "))
 						skillDisplays := make([]string, 0, len(loadedSkills))
 						for _, skill := range loadedSkills {
 							if md, exists := skillMetadataMap[skill.Name]; exists && md.Description != "" {
@@ -618,11 +936,11 @@ You are a helpful AI assistant for database queries and related tasks.
 								skillDisplays = append(skillDisplays, ui.HighlightText(skill.Name))
 							}
 						}
-						fmt.Print(strings.Join(skillDisplays, ", "))
-						fmt.Println()
+						h.out.Print(strings.Join(skillDisplays, ", "))
+						h.out.Println()
 					}
 				} else {
-					ui.ShowWarning(fmt.Sprintf("Failed to load some skills: %v", err))
+					h.out.Warning(fmt.Sprintf("Failed to load some skills: %v", err))
 				}
 			}
 		}
@@ -710,21 +1028,54 @@ You are a helpful AI assistant for database queries and related tasks.
 		"content": userInput, // Ensure content is string
 	})
 
+	h.outcome = TurnOutcome{}
 	var lastQueryResult *db.QueryResult
 	var hasSuccessfulToolExecution bool // Track if any tool executed successfully in this request
-	maxIterations := 10                 // Prevent infinite loops
+	budget := newLoopBudget(h.limits)   // Prevent runaway loops
 
-	for i := 0; i < maxIterations; i++ {
+	for i := 0; ; i++ {
 		// Messages array already contains full conversation history including tool calls and results
 		// If messages are too long, compression logic will handle it
 
+		// Before another LLM call, check the iteration, time, token and error budgets
+		if reason := budget.exceeded(); reason != "" {
+			action := h.limitAction(reason)
+			h.outcome.Limits = append(h.outcome.Limits, LimitHit{Reason: reason, Action: action})
+			h.emit(StreamEvent{Type: EventLimitReached, Content: reason, Status: action})
+			switch action {
+			case LimitContinue:
+				budget.extend()
+			case LimitSummarize:
+				answer, summarized, err := h.summarizeProgress(ctx, llmClient, messages, reason)
+				if err != nil {
+					return "", lastQueryResult, messages, err
+				}
+				return answer, lastQueryResult, summarized, nil
+			default:
+				h.out.Warning(fmt.Sprintf("Stopped: the request %s.", reason))
+				return "", lastQueryResult, messages, nil
+			}
+		}
+
 		// Call LLM - show "Thinking..." while LLM is processing
-		stopThinking := ui.ShowLoading("Thinking...")
-		response, err := llmClient.ChatWithTools(ctx, messages, tools)
+		setStatus, stopThinking := h.out.LoadingStatus("Thinking...")
+		response, err := h.chat(ctx, llmClient, messages, tools, setStatus, stopThinking)
 		stopThinking()
 		if err != nil {
+			if ctx.Err() != nil {
+				// Keep the text streamed before the request was cancelled
+				partial := ""
+				if response != nil && len(response.Choices) > 0 {
+					partial = response.Choices[0].Message.Content
+				}
+				if partial != "" {
+					messages = append(messages, map[string]interface{}{"role": "assistant", "content": partial})
+				}
+				return partial, lastQueryResult, messages, fmt.Errorf("request cancelled: %w", ctx.Err())
+			}
 			return "", nil, nil, fmt.Errorf("LLM call failed: %w", err)
 		}
+		budget.addCall(messages, response)
 
 		if len(response.Choices) == 0 {
 			return "", nil, nil, fmt.Errorf("no choices in response")
@@ -751,6 +1102,9 @@ You are a helpful AI assistant for database queries and related tasks.
 			assistantMsg["tool_calls"] = message.ToolCalls
 		}
 		messages = append(messages, assistantMsg)
+		if message.Content != "" {
+			h.emit(StreamEvent{Type: EventAssistant, Content: message.Content, Message: assistantMsg})
+		}
 
 		// If no tool calls, this is LLM's final response
 		if len(message.ToolCalls) == 0 {
@@ -835,19 +1189,27 @@ You are a helpful AI assistant for database queries and related tasks.
 		}
 
 		// Execute tool calls
-		for _, toolCall := range message.ToolCalls {
+		// Runs of read-only calls are executed concurrently when the first of them is reached;
+		// results are still displayed and returned to the LLM one by one, in the order the calls were issued
+		parallel := h.parallelBatches(message.ToolCalls)
+		parallelResults := make(map[int]parallelResult)
+		firstResult := len(messages)
+		for callIndex, toolCall := range message.ToolCalls {
 			// Parse arguments for risk assessment
 			args, parseErr := toolCall.ParseArguments()
 			if parseErr != nil {
-				ui.ShowError(fmt.Sprintf("Tool [%s] failed: %v", toolCall.Function.Name, parseErr))
+				h.out.Error(fmt.Sprintf("Tool [%s] failed: %v", toolCall.Function.Name, parseErr))
 				errorMsg := fmt.Sprintf(`{"error": "%s"}`, parseErr.Error())
 				toolResult := json.RawMessage(errorMsg)
-				toolMsg := map[string]interface{}{
-					"role":         "tool",
-					"content":      string(toolResult),
-					"tool_call_id": toolCall.ID,
-				}
-				messages = append(messages, toolMsg)
+				messages = append(messages, h.toolResultMessage(toolCall.ID, string(toolResult)))
+				continue
+			}
+
+			// Reject arguments that do not match the tool's schema before assessing risk or asking for confirmation
+			var validationErr *schema.ValidationError
+			if !h.external.Has(toolCall.Function.Name) && errors.As(h.Tools().Validate(toolCall.Function.Name, args), &validationErr) {
+				h.out.Warning(fmt.Sprintf("Tool [%s] called with invalid arguments: %s", toolCall.Function.Name, validationErr.Error()))
+				messages = append(messages, h.toolResultMessage(toolCall.ID, string(invalidArgumentsResult(validationErr))))
 				continue
 			}
 
@@ -856,92 +1218,93 @@ You are a helpful AI assistant for database queries and related tasks.
 			riskLevel := riskAssessor.AssessRisk(toolCall.Function.Name, args)
 			// Log risk assessment result (written to ~/.aiq/logs/risk_assessment.log)
 			tool.LogRiskAssessment("Tool: %s, RiskLevel: %v", toolCall.Function.Name, riskLevel)
+			h.emit(StreamEvent{
+				Type:       EventToolCall,
+				ToolCallID: toolCall.ID,
+				Name:       toolCall.Function.Name,
+				Arguments:  args,
+				RiskLevel:  riskLevel.String(),
+			})
+
+			// Non-interactive runs decide by policy instead of prompting
+			if h.policy != PolicyPrompt {
+				if reason := h.policyDenial(toolCall.Function.Name, args); reason != "" {
+					h.out.Warning(fmt.Sprintf("Tool [%s] denied: %s", toolCall.Function.Name, reason))
+					h.outcome.Denied = append(h.outcome.Denied, strings.TrimPrefix(h.formatToolCall(toolCall), "Calling tool "))
+					resultJSON, _ := json.Marshal(map[string]interface{}{
+						"status":  "denied",
+						"message": fmt.Sprintf("Not executed: %s. Do not retry it; tell the user it must be run interactively.", reason),
+					})
+					messages = append(messages, h.toolResultMessage(toolCall.ID, string(resultJSON)))
+					continue
+				}
+				// The policy approved the call, so it runs without a prompt whatever risk_level the LLM claimed
+				riskLevel = tool.RiskLow
+			}
 
 			// For execute_sql, handle confirmation based on risk level
 			if toolCall.Function.Name == "execute_sql" {
 				sql, ok := args["sql"].(string)
 				if !ok {
 					err := fmt.Errorf("invalid sql parameter")
-					ui.ShowError(fmt.Sprintf("Tool [%s] failed: %v", toolCall.Function.Name, err))
+					h.out.Error(fmt.Sprintf("Tool [%s] failed: %v", toolCall.Function.Name, err))
 					errorMsg := fmt.Sprintf(`{"error": "%s"}`, err.Error())
 					toolResult := json.RawMessage(errorMsg)
-					toolMsg := map[string]interface{}{
-						"role":         "tool",
-						"content":      string(toolResult),
-						"tool_call_id": toolCall.ID,
-					}
-					messages = append(messages, toolMsg)
+					messages = append(messages, h.toolResultMessage(toolCall.ID, string(toolResult)))
 					continue
 				}
 
 				// Only show SQL and ask for confirmation if high-risk
 				if riskLevel == tool.RiskHigh {
-					fmt.Println()
-					ui.ShowInfo("Generated SQL:")
-					fmt.Println(ui.HighlightSQL(sql))
-					fmt.Println()
+					h.out.Println()
+					h.out.Info("Generated SQL:")
+					h.out.Println(ui.HighlightSQL(sql))
+					if params, err := tool.ParseSQLParams(args); err == nil && len(params) > 0 {
+						h.out.Println(formatSQLParams(params))
+					}
+					h.out.Println()
 
-					confirm, err := ui.ShowConfirm("Execute this query?")
+					confirm, err := h.confirm(ConfirmRequest{ToolCall: toolCall, Arguments: args, RiskLevel: riskLevel, Prompt: "Execute this query?"})
 					if err != nil {
-						fmt.Println()
+						h.out.Println()
 						// Treat as cancelled
-						ui.ShowWarning("Query execution cancelled.")
+						h.out.Warning("Query execution cancelled.")
 						toolResult := json.RawMessage(`{"status":"cancelled","message":"query execution cancelled by user"}`)
-						toolMsg := map[string]interface{}{
-							"role":         "tool",
-							"content":      string(toolResult),
-							"tool_call_id": toolCall.ID,
-						}
-						messages = append(messages, toolMsg)
+						messages = append(messages, h.toolResultMessage(toolCall.ID, string(toolResult)))
 						continue
 					}
 					if !confirm {
-						ui.ShowWarning("Query execution cancelled.")
+						h.out.Warning("Query execution cancelled.")
 						toolResult := json.RawMessage(`{"status":"cancelled","message":"query execution cancelled by user"}`)
-						toolMsg := map[string]interface{}{
-							"role":         "tool",
-							"content":      string(toolResult),
-							"tool_call_id": toolCall.ID,
-						}
-						messages = append(messages, toolMsg)
+						messages = append(messages, h.toolResultMessage(toolCall.ID, string(toolResult)))
 						continue
 					}
 				}
 				// For low-risk SQL, execute automatically without confirmation
 			}
 
-			// For other tools (execute_command, file_operations, http_request), handle confirmation based on risk level
-			if toolCall.Function.Name == "execute_command" || toolCall.Function.Name == "file_operations" || toolCall.Function.Name == "http_request" {
+			// For other tools (execute_command, file_operations, http_request, custom and external MCP tools), handle confirmation based on risk level
+			if toolCall.Function.Name == "execute_command" || toolCall.Function.Name == "file_operations" || toolCall.Function.Name == "http_request" || builtin.IsCustomTool(toolCall.Function.Name) || h.external.Has(toolCall.Function.Name) {
 				if riskLevel == tool.RiskHigh {
 					// Show tool call details and ask for confirmation
 					toolCallDisplay := h.formatToolCall(toolCall)
-					fmt.Println()
-					ui.ShowInfo("Tool call:")
-					fmt.Println(toolCallDisplay)
-					fmt.Println()
+					h.out.Println()
+					h.out.Info("Tool call:")
+					h.out.Println(toolCallDisplay)
+					h.out.Println()
 
-					confirm, err := ui.ShowConfirm("Execute this operation?")
+					confirm, err := h.confirm(ConfirmRequest{ToolCall: toolCall, Arguments: args, RiskLevel: riskLevel, Prompt: "Execute this operation?"})
 					if err != nil {
-						fmt.Println()
-						ui.ShowWarning("Operation cancelled.")
+						h.out.Println()
+						h.out.Warning("Operation cancelled.")
 						toolResult := json.RawMessage(`{"status":"cancelled","message":"operation cancelled by user"}`)
-						toolMsg := map[string]interface{}{
-							"role":         "tool",
-							"content":      string(toolResult),
-							"tool_call_id": toolCall.ID,
-						}
-						messages = append(messages, toolMsg)
+						messages = append(messages, h.toolResultMessage(toolCall.ID, string(toolResult)))
 						continue
 					}
 					if !confirm {
-						ui.ShowWarning("Operation cancelled.")
+						h.out.Warning("Operation cancelled.")
 						toolResult := json.RawMessage(`{"status":"cancelled","message":"operation cancelled by user"}`)
-						toolMsg := map[string]interface{}{
-							"role":         "tool",
-							"content":      string(toolResult),
-							"tool_call_id": toolCall.ID,
-						}
-						messages = append(messages, toolMsg)
+						messages = append(messages, h.toolResultMessage(toolCall.ID, string(toolResult)))
 						continue
 					}
 				}
@@ -978,7 +1341,7 @@ You are a helpful AI assistant for database queries and related tasks.
 			// Special handling for execute_command: track time and output based on output_mode
 			if toolCall.Function.Name == "execute_command" {
 				// Display tool call with loading icon (normal color for main command)
-				fmt.Println("⏳ " + toolCallDisplay)
+				h.out.Println("⏳ " + toolCallDisplay)
 				startTime = time.Now()
 
 				// Use already parsed args (from risk assessment above)
@@ -989,10 +1352,10 @@ You are a helpful AI assistant for database queries and related tasks.
 				} else {
 					if outputMode == "full" {
 						// Full output mode: display all output without truncation
-						result, execErr := builtin.ExecuteBuiltinToolWithCallback(ctx, "execute_command", args, h.conn, func(line string) {
+						result, execErr := h.Tools().Execute(builtin.WithOutputCallback(ctx, func(line string) {
 							// Print each line immediately (full output)
-							fmt.Println(line)
-						})
+							h.out.Println(line)
+						}), "execute_command", args)
 
 						if execErr != nil {
 							err = execErr
@@ -1033,13 +1396,13 @@ You are a helpful AI assistant for database queries and related tasks.
 						}
 					} else {
 						// Streaming output mode: rolling window display
-						rollingOutput := ui.NewRollingOutput(3)
+						rollingOutput := h.out.RollingOutput(3)
 
 						// Execute with callback for streaming output - rolling window display
-						result, execErr := builtin.ExecuteBuiltinToolWithCallback(ctx, "execute_command", args, h.conn, func(line string) {
+						result, execErr := h.Tools().Execute(builtin.WithOutputCallback(ctx, func(line string) {
 							// AddLine handles the rolling display (clears old lines, prints new ones)
 							rollingOutput.AddLine(line)
-						})
+						}), "execute_command", args)
 
 						// Show summary after command completes
 						rollingOutput.Finish()
@@ -1097,29 +1460,40 @@ You are a helpful AI assistant for database queries and related tasks.
 					}
 				}
 			} else {
-				// For other tools, use normal display
-				ui.ShowInfo(toolCallDisplay)
-
-				waitingMsg := "Waiting..."
-				if toolCall.Function.Name == "execute_sql" {
-					waitingMsg = "Executing SQL..."
-				} else if toolCall.Function.Name == "http_request" {
-					waitingMsg = "Waiting for HTTP response..."
+				if parallel[callIndex] {
+					if _, done := parallelResults[callIndex]; !done {
+						h.runParallelBatch(ctx, message.ToolCalls, parallel, callIndex, parallelResults)
+					}
+					h.out.Info(toolCallDisplay)
+					toolResult, err = parallelResults[callIndex].result, parallelResults[callIndex].err
+				} else {
+					// For other tools, use normal display
+					h.out.Info(toolCallDisplay)
+
+					waitingMsg := "Waiting..."
+					if toolCall.Function.Name == "execute_sql" {
+						waitingMsg = "Executing SQL..."
+					} else if toolCall.Function.Name == "http_request" {
+						waitingMsg = "Waiting for HTTP response..."
+					}
+					stopWaiting := h.out.Loading(waitingMsg)
+					toolResult, err = h.ExecuteTool(ctx, toolCall)
+					stopWaiting()
 				}
-				stopWaiting := ui.ShowLoading(waitingMsg)
-				toolResult, err = h.ExecuteTool(ctx, toolCall)
-				stopWaiting()
 			}
 			if err != nil {
+				if toolCall.Function.Name == "execute_sql" {
+					h.outcome.SQLError = err.Error()
+				}
 				// Format error message for LLM
 				errorMsg := fmt.Sprintf(`{"error": "%s"}`, strings.ReplaceAll(err.Error(), `"`, `\"`))
 				toolResult = json.RawMessage(errorMsg)
 				// Show user-friendly error message with duration for execute_command
 				if toolCall.Function.Name == "execute_command" {
 					duration := time.Since(startTime)
-					ui.ShowError(fmt.Sprintf("Tool [execute_command] failed: %s (%.1fs)", err.Error(), duration.Seconds()))
+					h.out.Error(fmt.Sprintf("Tool [execute_command] failed: %s (%.1fs)", err.Error(), duration.Seconds()))
 				} else {
-					ui.ShowError(fmt.Sprintf("Tool [%s] failed: %s", toolCall.Function.Name, err.Error()))
+					h.out.Error(fmt.Sprintf("Tool [%s] failed: %s", toolCall.Function.Name, err.Error()))
 				}
 			} else {
 				// Check if tool result contains an error (even if ExecuteTool returned nil error)
@@ -1139,11 +1513,11 @@ You are a helpful AI assistant for database queries and related tasks.
 
 						// Display status with icon and duration
 						if exitCode == 0 {
-							ui.ShowSuccess(fmt.Sprintf("Tool [execute_command] completed (%.1fs)", duration.Seconds()))
+							h.out.Success(fmt.Sprintf("Tool [execute_command] completed (%.1fs)", duration.Seconds()))
 						} else {
-							ui.ShowError(fmt.Sprintf("Tool [execute_command] failed with exit code %d (%.1fs)", exitCode, duration.Seconds()))
+							h.out.Error(fmt.Sprintf("Tool [execute_command] failed with exit code %d (%.1fs)", exitCode, duration.Seconds()))
 						}
-						fmt.Println()
+						h.out.Println()
 
 						// Remove internal fields before sending to LLM
 						delete(resultData, "_full_stdout")
@@ -1154,13 +1528,18 @@ You are a helpful AI assistant for database queries and related tasks.
 					} else {
 						// For other tools, use existing display logic
 						if errorMsg, hasError := resultData["error"].(string); hasError && errorMsg != "" {
-							ui.ShowError(fmt.Sprintf("Tool [%s] failed: %s", toolCall.Function.Name, errorMsg))
+							h.out.Error(fmt.Sprintf("Tool [%s] failed: %s", toolCall.Function.Name, errorMsg))
+							if toolCall.Function.Name == "execute_sql" {
+								h.outcome.SQLError = errorMsg
+							}
+						} else if jobID, ok := resultData["job_id"].(float64); ok {
+							h.out.Info(fmt.Sprintf("Query started in the background as job #%d. Use /jobs to check on it.", int(jobID)))
 						} else {
-							ui.ShowSuccess(fmt.Sprintf("Tool [%s] executed successfully", toolCall.Function.Name))
+							h.out.Success(fmt.Sprintf("Tool [%s] executed successfully", toolCall.Function.Name))
 						}
 					}
 				} else {
-					ui.ShowSuccess(fmt.Sprintf("Tool [%s] executed successfully", toolCall.Function.Name))
+					h.out.Success(fmt.Sprintf("Tool [%s] executed successfully", toolCall.Function.Name))
 				}
 			}
 
@@ -1177,7 +1556,7 @@ You are a helpful AI assistant for database queries and related tasks.
 							rowCount = rc
 						}
 						title := fmt.Sprintf("Chart (%d rows)", rowCount)
-						ui.DisplayChart(output, chartType, title)
+						h.out.Chart(output, chartType, title)
 
 						// Simplify result for LLM - chart already displayed
 						simplifiedResult := map[string]interface{}{
@@ -1198,50 +1577,53 @@ You are a helpful AI assistant for database queries and related tasks.
 			if toolCall.Function.Name == "execute_sql" && err == nil {
 				var resultData map[string]interface{}
 				if err := json.Unmarshal(toolResult, &resultData); err == nil {
-					if columns, ok := resultData["columns"].([]interface{}); ok {
-						if rows, ok := resultData["rows"].([]interface{}); ok {
-							// Convert to QueryResult
-							cols := make([]string, len(columns))
-							for i, col := range columns {
-								cols[i] = fmt.Sprintf("%v", col)
+					if sets := resultSetsFromJSON(resultData); sets != nil {
+						h.outcome.ResultSets = append(h.outcome.ResultSets, sets...)
+						h.outcome.SQLError = ""
+						totalRows := 0
+						queryResult := &db.QueryResult{}
+						for i, set := range sets {
+							// Like db.QueryResult, the call's result mirrors its first set with columns
+							if len(set.Columns) > 0 && queryResult.Columns == nil {
+								queryResult.Columns = set.Columns
+								queryResult.Rows = set.Rows
 							}
-							rowsData := make([][]string, len(rows))
-							for i, rowInterface := range rows {
-								rowArray, ok := rowInterface.([]interface{})
-								if ok {
-									rowsData[i] = make([]string, len(rowArray))
-									for j, val := range rowArray {
-										rowsData[i][j] = fmt.Sprintf("%v", val)
-									}
-								}
-							}
-							queryResult := &db.QueryResult{
-								Columns: cols,
-								Rows:    rowsData,
+							totalRows += len(set.Rows)
+							if h.renderResults {
+								renderResultSet(h.out, set, i, len(sets))
 							}
+						}
+
+						if queryResult.Columns != nil {
 							lastQueryResult = queryResult
+						}
 
-							// Directly render table output (mysql client style)
-							if len(rowsData) > 0 {
-								fmt.Println()
-								tableOutput, tableErr := tool.RenderTableString(cols, rowsData)
-								if tableErr == nil {
-									fmt.Println(tableOutput)
+						// Simplify result for LLM - results are already displayed to user
+						// Tell LLM to return minimal response (no content) since results are already shown
+						simplifiedResult := map[string]interface{}{
+							"status":      "success",
+							"row_count":   totalRows,
+							"displayed":   true,
+							"instruction": "CRITICAL: Results are already displayed to the user in table format. Do NOT repeat the results in your response. Return finish_reason='stop' with empty content (no text output). The user can see the results above.",
+						}
+						if affected, ok := resultData["rows_affected"]; ok {
+							simplifiedResult["rows_affected"] = affected
+						}
+						if len(sets) > 1 {
+							simplifiedResult["result_set_count"] = len(sets)
+							// Each statement of a batch reports its own counts
+							summaries := make([]map[string]interface{}, 0, len(sets))
+							for _, set := range sets {
+								summary := map[string]interface{}{"row_count": len(set.Rows)}
+								if set.RowsAffected >= 0 {
+									summary["rows_affected"] = set.RowsAffected
 								}
-								fmt.Printf("%d row(s) in set\n", len(rowsData))
-							}
-
-							// Simplify result for LLM - results are already displayed to user
-							// Tell LLM to return minimal response (no content) since results are already shown
-							simplifiedResult := map[string]interface{}{
-								"status":      "success",
-								"row_count":   len(rowsData),
-								"displayed":   true,
-								"instruction": "CRITICAL: Results are already displayed to the user in table format. Do NOT repeat the results in your response. Return finish_reason='stop' with empty content (no text output). The user can see the results above.",
+								summaries = append(summaries, summary)
 							}
-							simplifiedJSON, _ := json.Marshal(simplifiedResult)
-							toolResult = json.RawMessage(simplifiedJSON)
+							simplifiedResult["result_sets"] = summaries
 						}
+						simplifiedJSON, _ := json.Marshal(simplifiedResult)
+						toolResult = json.RawMessage(simplifiedJSON)
 					}
 				}
 			}
@@ -1261,14 +1643,11 @@ You are a helpful AI assistant for database queries and related tasks.
 			// - task_type="definitive" + all succeeded → return finish_reason="stop" with minimal output
 			// - task_type="definitive" + any failed → analyze errors and retry/alternative
 			// - task_type="exploratory" + any result → plan next steps
-			toolMsg := map[string]interface{}{
-				"role":         "tool",
-				"content":      string(toolResult),
-				"tool_call_id": toolCall.ID,
-			}
-			messages = append(messages, toolMsg)
+			messages = append(messages, h.toolResultMessage(toolCall.ID, string(toolResult)))
 		}
 
+		budget.addToolResults(messages[firstResult:])
+
 		// After processing all tool calls, continue loop to let LLM process results
 		// LLM will decide next action based on task_type and execution status:
 		// - task_type="definitive" + all succeeded → return finish_reason="stop" with minimal output
@@ -1276,6 +1655,4 @@ You are a helpful AI assistant for database queries and related tasks.
 		// - task_type="exploratory" + any result → plan next steps
 		// Note: We always continue the loop here - LLM will decide whether to continue or finish
 	}
-
-	return "", nil, nil, fmt.Errorf("max iterations reached")
 }
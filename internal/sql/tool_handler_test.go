@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aiq/aiq/internal/db"
+	"github.com/aiq/aiq/internal/tool"
+)
+
+// TestPolicyDenial tests that confirmation policies classify tool calls by code, not by the LLM's risk_level
+func TestPolicyDenial(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ConfirmPolicy
+		tool    string
+		args    map[string]interface{}
+		allowed bool
+	}{
+		{"deny select", PolicyDeny, "execute_sql", map[string]interface{}{"sql": "SELECT * FROM users"}, true},
+		{"deny select then drop", PolicyDeny, "execute_sql", map[string]interface{}{"sql": "SELECT 1; DROP TABLE users"}, false},
+		{"deny create table", PolicyDeny, "execute_sql", map[string]interface{}{"sql": "CREATE TABLE t (id INT)"}, false},
+		{"deny render", PolicyDeny, "render_table", map[string]interface{}{}, true},
+		{"deny file read", PolicyDeny, "file_operations", map[string]interface{}{"operation": "read", "path": "a"}, false},
+		{"allow-low-risk create table", PolicyAllowLowRisk, "execute_sql", map[string]interface{}{"sql": "CREATE TABLE t (id INT)"}, true},
+		{"allow-low-risk delete labelled low", PolicyAllowLowRisk, "execute_sql", map[string]interface{}{"sql": "DELETE FROM users", "risk_level": "low"}, false},
+		{"allow-low-risk select then drop", PolicyAllowLowRisk, "execute_sql", map[string]interface{}{"sql": "SELECT 1; DROP TABLE users"}, false},
+		{"allow-low-risk file write labelled low", PolicyAllowLowRisk, "file_operations", map[string]interface{}{"operation": "write", "path": "a", "risk_level": "low"}, false},
+		{"allow-low-risk external tool labelled low", PolicyAllowLowRisk, "search_docs", map[string]interface{}{"risk_level": "low"}, false},
+		{"prompt leaves the decision to the user", PolicyPrompt, "execute_sql", map[string]interface{}{"sql": "DROP TABLE users"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &ToolHandler{policy: tt.policy}
+			reason := h.policyDenial(tt.tool, tt.args)
+			if allowed := reason == ""; allowed != tt.allowed {
+				t.Errorf("Expected allowed=%v, got denial %q", tt.allowed, reason)
+			}
+		})
+	}
+}
+
+// TestValidateSQL_Refetch tests that unknown identifiers trigger a rate-limited schema re-fetch
+func TestValidateSQL_Refetch(t *testing.T) {
+	fetches := 0
+	remote := &db.Schema{Tables: []db.TableInfo{{Name: "users", Columns: []db.ColumnInfo{{Name: "id"}}}}}
+	oldLoad := loadSchema
+	loadSchema = func(conn *db.Connection, ctx context.Context, databaseName string) (*db.Schema, error) {
+		fetches++
+		copied := *remote
+		return &copied, nil
+	}
+	defer func() { loadSchema = oldLoad }()
+
+	h := &ToolHandler{conn: &db.Connection{}}
+	h.SetSchema(&db.Schema{Tables: []db.TableInfo{{Name: "users", Columns: []db.ColumnInfo{{Name: "id"}}}}}, "shop")
+	h.schemaLoaded = time.Now().Add(-2 * schemaRefetchInterval)
+
+	// Another client creates a table after the schema was loaded
+	remote.Tables = append(remote.Tables, db.TableInfo{Name: "invoices", Columns: []db.ColumnInfo{{Name: "id"}}})
+	if err := h.validateSQL(context.Background(), "SELECT id FROM invoices"); err != nil {
+		t.Errorf("Expected the re-fetched schema to know invoices, got %v", err)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected 1 schema fetch, got %d", fetches)
+	}
+
+	// Within the interval unknown identifiers are reported without another fetch
+	var unknownErr *tool.UnknownIdentifierError
+	if err := h.validateSQL(context.Background(), "SELECT id FROM refunds"); !errors.As(err, &unknownErr) {
+		t.Errorf("Expected *tool.UnknownIdentifierError, got %v", err)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected no fetch within the interval, got %d", fetches)
+	}
+
+	// After the interval a name that is still missing is rejected once the schema was re-fetched
+	h.schemaLoaded = time.Now().Add(-2 * schemaRefetchInterval)
+	if err := h.validateSQL(context.Background(), "SELECT id FROM refunds"); !errors.As(err, &unknownErr) {
+		t.Errorf("Expected *tool.UnknownIdentifierError, got %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("Expected 2 schema fetches, got %d", fetches)
+	}
+}
@@ -0,0 +1,182 @@
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/llm"
+	"github.com/aiq/aiq/internal/session"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// recordUsage adds the token usage of a turn to the session
+func recordUsage(sess *session.Session, outcome TurnOutcome) {
+	byModel := make(map[string]session.TokenUsage, len(outcome.ModelUsage))
+	for model, usage := range outcome.ModelUsage {
+		byModel[model] = session.TokenUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			Calls:            usage.Calls,
+			UnreportedCalls:  usage.UnreportedCalls,
+		}
+	}
+	sess.RecordUsage(byModel)
+}
+
+// usageTracker measures the token usage of a turn across the LLM clients its tasks use
+type usageTracker struct {
+	clients []*llm.Client
+	before  []llm.UsageStats
+}
+
+// trackUsage starts measuring the usage of clients; nil and repeated clients are skipped
+func trackUsage(clients ...*llm.Client) *usageTracker {
+	t := &usageTracker{}
+	for _, client := range clients {
+		if client == nil || containsClient(t.clients, client) {
+			continue
+		}
+		t.clients = append(t.clients, client)
+		t.before = append(t.before, client.Usage())
+	}
+	return t
+}
+
+// containsClient reports whether client is in clients
+func containsClient(clients []*llm.Client, client *llm.Client) bool {
+	for _, c := range clients {
+		if c == client {
+			return true
+		}
+	}
+	return false
+}
+
+// usage returns the usage since trackUsage, in total and per model
+func (t *usageTracker) usage() (llm.UsageStats, map[string]llm.UsageStats) {
+	var total llm.UsageStats
+	byModel := make(map[string]llm.UsageStats)
+	for i, client := range t.clients {
+		usage := client.Usage().Sub(t.before[i])
+		if usage.Calls == 0 {
+			continue
+		}
+		total = total.Add(usage)
+		byModel[client.Model()] = byModel[client.Model()].Add(usage)
+	}
+	return total, byModel
+}
+
+// turnUsage returns the token usage of a turn for its final event, or nil without LLM calls
+func turnUsage(outcome TurnOutcome) *llm.UsageStats {
+	if outcome.Usage.Calls == 0 {
+		return nil
+	}
+	usage := outcome.Usage
+	return &usage
+}
+
+// showUsage prints the token usage of the last request and of the session
+// Costs are estimated for models with a price in the config.
+func showUsage(sess *session.Session, cfg *config.Config) {
+	fmt.Println()
+	if sess.UsageTurns() == 0 {
+		ui.ShowInfo("No LLM calls in this session yet.")
+		fmt.Println()
+		return
+	}
+
+	headers := []string{"Scope", "Model", "Calls", "Prompt", "Completion", "Total", "Est. cost"}
+	var rows [][]string
+	for _, last := range sess.LastTurnUsage() {
+		rows = append(rows, usageRow("Last request", last.Model, last.TokenUsage, cfg))
+	}
+
+	byModel := sess.UsageByModel()
+	models := make([]string, 0, len(byModel))
+	for model := range byModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	sessionScope := fmt.Sprintf("Session (%d requests)", sess.UsageTurns())
+	if sess.UsageTurns() == 1 {
+		sessionScope = "Session (1 request)"
+	}
+
+	var unpriced []string
+	for _, model := range models {
+		if _, ok := cfg.PriceFor(model); !ok {
+			unpriced = append(unpriced, model)
+		}
+	}
+	if len(models) == 1 {
+		rows = append(rows, usageRow(sessionScope, models[0], sess.Metadata.Usage, cfg))
+	} else {
+		for _, model := range models {
+			rows = append(rows, usageRow("Session", model, byModel[model], cfg))
+		}
+		total := usageRow(sessionScope, "all", sess.Metadata.Usage, cfg)
+		total[6] = "-"
+		if len(unpriced) < len(models) {
+			cost := 0.0
+			for _, model := range models {
+				if price, ok := cfg.PriceFor(model); ok {
+					cost += price.Cost(byModel[model].PromptTokens, byModel[model].CompletionTokens)
+				}
+			}
+			total[6] = formatCost(cost)
+			if len(unpriced) > 0 {
+				total[6] += " (partial)"
+			}
+		}
+		rows = append(rows, total)
+	}
+	ui.PrintTable(headers, rows)
+	fmt.Println()
+
+	if n := sess.Metadata.Usage.UnreportedCalls; n > 0 {
+		ui.ShowWarning(fmt.Sprintf("%d call(s) did not report token usage and are not counted.", n))
+	}
+	if len(unpriced) > 0 {
+		ui.ShowInfo(fmt.Sprintf("No price configured for %s. Add input and output prices per million tokens under pricing in the config file for cost estimates.", strings.Join(unpriced, ", ")))
+	}
+	fmt.Println()
+}
+
+// usageRow formats one row of the /usage table
+func usageRow(scope, model string, usage session.TokenUsage, cfg *config.Config) []string {
+	cost := "-"
+	if price, ok := cfg.PriceFor(model); ok {
+		cost = formatCost(price.Cost(usage.PromptTokens, usage.CompletionTokens))
+	}
+	return []string{
+		scope,
+		model,
+		strconv.Itoa(usage.Calls),
+		formatTokenCount(usage.PromptTokens),
+		formatTokenCount(usage.CompletionTokens),
+		formatTokenCount(usage.TotalTokens()),
+		cost,
+	}
+}
+
+// formatTokenCount formats n with thousands separators, e.g. 12,345
+func formatTokenCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatCost formats a cost in US dollars; small amounts keep more digits
+func formatCost(cost float64) string {
+	if cost < 1 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
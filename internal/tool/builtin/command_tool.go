@@ -3,7 +3,6 @@ package builtin
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -70,10 +69,12 @@ func (t *CommandTool) SetBlockedCommands(commands []string) {
 
 // CommandParams represents parameters for command execution
 type CommandParams struct {
-	Command    string   `json:"command"`
-	Args       []string `json:"args,omitempty"`
-	WorkingDir string   `json:"working_dir,omitempty"`
-	Timeout    int      `json:"timeout,omitempty"` // Timeout in seconds, default 60
+	Command    string   `json:"command" description:"Command to execute (e.g., 'ls -la')"`
+	Args       []string `json:"args,omitempty" description:"Command arguments"`
+	WorkingDir string   `json:"working_dir,omitempty" description:"Working directory for command execution"`
+	Timeout    int      `json:"timeout,omitempty" description:"Timeout in seconds (default: 60)"`
+	RiskLevel  string   `json:"risk_level,omitempty" enum:"low,medium,high" description:"Optional: Risk level assessment for this operation. 'low' = safe to execute automatically (e.g., ls, cat, pwd), 'medium'/'high' = requires user confirmation (e.g., rm, sudo). If not provided, system will assess risk conservatively."`
+	TaskHints
 }
 
 // OutputCallback is called when command produces output (for real-time display)
@@ -126,20 +127,16 @@ func truncateOutputBySize(output string, maxSize int) string {
 
 // Execute executes a shell command with streaming output and truncation
 func (t *CommandTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	return t.ExecuteWithCallback(ctx, params, nil)
-}
-
-// ExecuteWithCallback executes a shell command with optional output callback for real-time display
-func (t *CommandTool) ExecuteWithCallback(ctx context.Context, params map[string]interface{}, callback OutputCallback) (interface{}, error) {
-	// Parse parameters
 	var cmdParams CommandParams
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %w", err)
-	}
-	if err := json.Unmarshal(paramsJSON, &cmdParams); err != nil {
-		return nil, fmt.Errorf("failed to parse params: %w", err)
+	if err := decodeParams(params, &cmdParams); err != nil {
+		return nil, err
 	}
+	return t.Run(ctx, cmdParams)
+}
+
+// Run executes a shell command, passing its output lines to the context's OutputCallback (see WithOutputCallback)
+func (t *CommandTool) Run(ctx context.Context, cmdParams CommandParams) (interface{}, error) {
+	callback := outputCallback(ctx)
 
 	// Validate command
 	if cmdParams.Command == "" {
@@ -373,52 +370,3 @@ func (t *CommandTool) getBlockedCommandList() []string {
 	}
 	return list
 }
-
-// GetDefinition returns the tool definition for LLM
-func (t *CommandTool) GetDefinition() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "function",
-		"function": map[string]interface{}{
-			"name":        "execute_command",
-			"description": "Execute shell commands for system operations (installation, setup, configuration). Use for system operations, NOT for database queries. Most commands are allowed, but dangerous commands (like rm, sudo, dd) are blocked for security.",
-			"parameters": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"command": map[string]interface{}{
-						"type":        "string",
-						"description": "Command to execute (e.g., 'ls -la')",
-					},
-					"args": map[string]interface{}{
-						"type":        "array",
-						"items":       map[string]interface{}{"type": "string"},
-						"description": "Command arguments",
-					},
-					"working_dir": map[string]interface{}{
-						"type":        "string",
-						"description": "Working directory for command execution",
-					},
-					"timeout": map[string]interface{}{
-						"type":        "integer",
-						"description": "Timeout in seconds (default: 60)",
-					},
-					"risk_level": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"low", "medium", "high"},
-						"description": "Optional: Risk level assessment for this operation. 'low' = safe to execute automatically (e.g., ls, cat, pwd), 'medium'/'high' = requires user confirmation (e.g., rm, sudo). If not provided, system will assess risk conservatively.",
-					},
-					"task_type": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"definitive", "exploratory"},
-						"description": "Optional: Task type classification. 'definitive' = task is clear and complete, 'exploratory' = task requires information gathering or multi-step process. If not provided, system will infer from context.",
-					},
-					"output_mode": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"full", "streaming"},
-						"description": "Optional: Output display mode. 'full' = display all results to user (for definitive tasks), 'streaming' = real-time streaming with truncation (for exploratory tasks). If not provided, inferred from task_type.",
-					},
-				},
-				"required": []string{"command"},
-			},
-		},
-	}
-}
@@ -0,0 +1,441 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aiq/aiq/internal/config"
+	"github.com/aiq/aiq/internal/ui"
+)
+
+// maxCustomHTTPBody bounds the response body returned to the LLM by HTTP custom tools
+const maxCustomHTTPBody = 64 * 1024
+
+// customToolName matches valid custom tool names (also valid LLM function names)
+var customToolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// reservedToolNames cannot be used by custom tools
+var reservedToolNames = map[string]bool{
+	"execute_sql":     true,
+	"render_table":    true,
+	"render_chart":    true,
+	"http_request":    true,
+	"execute_command": true,
+	"file_operations": true,
+}
+
+// CustomTool is a tool declared in a YAML file in ~/.aiq/tools
+//
+//	name: pod_logs
+//	description: Show recent logs of a Kubernetes pod
+//	risk_level: low
+//	parameters:
+//	  type: object
+//	  properties:
+//	    pod: {type: string}
+//	  required: [pod]
+//	command: kubectl logs {{.pod}} --tail 200
+//
+// Parameter values are shell-quoted in command templates and query-escaped in URL templates.
+// HTTP url, headers and body may reference environment variables as ${VAR}.
+type CustomTool struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	RiskLevel   string                 `yaml:"risk_level"` // low or high (default)
+	Parameters  map[string]interface{} `yaml:"parameters"`
+	Command     string                 `yaml:"command,omitempty"`
+	HTTP        *CustomHTTPRequest     `yaml:"http,omitempty"`
+	Timeout     int                    `yaml:"timeout,omitempty"` // Seconds
+
+	path      string
+	command   *template.Template
+	url       *template.Template
+	body      *template.Template
+	headers   map[string]*template.Template
+	paramKeys []string
+}
+
+// CustomHTTPRequest is the HTTP request template of a custom tool
+type CustomHTTPRequest struct {
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+}
+
+// templateFuncs are available in custom tool templates
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	},
+}
+
+// LoadCustomTools loads all *.yaml and *.yml tool files in dir
+// Invalid files are skipped and reported in the returned errors.
+func LoadCustomTools(dir string) ([]*CustomTool, []error) {
+	var paths []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var tools []*CustomTool
+	var errs []error
+	seen := make(map[string]string)
+	for _, path := range paths {
+		t, err := loadCustomTool(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		if other, ok := seen[t.Name]; ok {
+			errs = append(errs, fmt.Errorf("%s: tool %q is already defined in %s", filepath.Base(path), t.Name, other))
+			continue
+		}
+		seen[t.Name] = filepath.Base(path)
+		tools = append(tools, t)
+	}
+	return tools, errs
+}
+
+// loadCustomTool parses and validates one tool file
+func loadCustomTool(path string) (*CustomTool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t CustomTool
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	t.path = path
+	if err := t.compile(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// compile validates the tool and parses its templates
+func (t *CustomTool) compile() error {
+	if !customToolName.MatchString(t.Name) {
+		return fmt.Errorf("invalid name %q (use letters, digits, _ and -)", t.Name)
+	}
+	if reservedToolNames[t.Name] {
+		return fmt.Errorf("name %q is reserved for a built-in tool", t.Name)
+	}
+	if strings.TrimSpace(t.Description) == "" {
+		return fmt.Errorf("description is required")
+	}
+	switch strings.ToLower(t.RiskLevel) {
+	case "":
+		t.RiskLevel = "high"
+	case "low", "high":
+		t.RiskLevel = strings.ToLower(t.RiskLevel)
+	default:
+		return fmt.Errorf("invalid risk_level %q (use low or high)", t.RiskLevel)
+	}
+	if (t.Command == "") == (t.HTTP == nil) {
+		return fmt.Errorf("exactly one of command or http is required")
+	}
+
+	if t.Parameters == nil {
+		t.Parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	if props, ok := t.Parameters["properties"].(map[string]interface{}); ok {
+		for key := range props {
+			t.paramKeys = append(t.paramKeys, key)
+		}
+		sort.Strings(t.paramKeys)
+	}
+
+	parse := func(name, text string) (*template.Template, error) {
+		tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", name, err)
+		}
+		// Catch references to undeclared parameters at load time
+		if err := tmpl.Execute(&bytes.Buffer{}, t.templateData(nil, nil)); err != nil {
+			return nil, fmt.Errorf("invalid %s template: %w", name, err)
+		}
+		return tmpl, nil
+	}
+
+	var err error
+	if t.Command != "" {
+		if t.command, err = parse("command", t.Command); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	method := strings.ToUpper(t.HTTP.Method)
+	if method == "" {
+		method = "GET"
+	}
+	switch method {
+	case "GET", "POST", "PUT", "DELETE":
+		t.HTTP.Method = method
+	default:
+		return fmt.Errorf("unsupported HTTP method: %s", t.HTTP.Method)
+	}
+	if t.HTTP.URL == "" {
+		return fmt.Errorf("http.url is required")
+	}
+	if t.url, err = parse("url", os.ExpandEnv(t.HTTP.URL)); err != nil {
+		return err
+	}
+	if t.body, err = parse("body", os.ExpandEnv(t.HTTP.Body)); err != nil {
+		return err
+	}
+	t.headers = make(map[string]*template.Template, len(t.HTTP.Headers))
+	for key, value := range t.HTTP.Headers {
+		if t.headers[key], err = parse("header "+key, os.ExpandEnv(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateData returns the declared parameters with values from params, passed through escape
+// Parameters not provided render as empty strings.
+func (t *CustomTool) templateData(params map[string]interface{}, escape func(string) string) map[string]interface{} {
+	data := make(map[string]interface{}, len(t.paramKeys))
+	for _, key := range t.paramKeys {
+		value, ok := params[key]
+		if !ok || value == nil {
+			data[key] = ""
+			continue
+		}
+		if escape == nil {
+			data[key] = value
+			continue
+		}
+		data[key] = escape(paramString(value))
+	}
+	return data
+}
+
+// paramString formats a parameter value for substitution
+func paramString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, bool, int, int64:
+		return fmt.Sprintf("%v", v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// render executes tmpl with data
+func render(tmpl *template.Template, data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Execute renders the tool's template and runs it with CommandTool or HTTPTool
+// Command output is streamed to the context's OutputCallback (see WithOutputCallback).
+func (t *CustomTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if missing := t.missingParams(params); len(missing) > 0 {
+		return nil, fmt.Errorf("missing required parameter(s): %s", strings.Join(missing, ", "))
+	}
+
+	if t.command != nil {
+		command, err := render(t.command, t.templateData(params, shellQuote))
+		if err != nil {
+			return nil, err
+		}
+		result, err := NewCommandTool().Run(ctx, CommandParams{Command: command, Timeout: t.Timeout})
+		if err != nil {
+			return nil, err
+		}
+		cmdResult, ok := result.(CommandResult)
+		if !ok {
+			return result, nil
+		}
+		status := "success"
+		if cmdResult.ExitCode != 0 {
+			status = "error"
+		}
+		return map[string]interface{}{
+			"status":    status,
+			"exit_code": cmdResult.ExitCode,
+			"stdout":    cmdResult.TruncatedStdout,
+			"stderr":    cmdResult.TruncatedStderr,
+		}, nil
+	}
+
+	rawData := t.templateData(params, nil)
+	requestURL, err := render(t.url, t.templateData(params, url.QueryEscape))
+	if err != nil {
+		return nil, err
+	}
+	body, err := render(t.body, rawData)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(t.headers))
+	for key, tmpl := range t.headers {
+		if headers[key], err = render(tmpl, rawData); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := NewHTTPTool().Run(ctx, HTTPRequestParams{
+		Method:  t.HTTP.Method,
+		URL:     requestURL,
+		Headers: headers,
+		Body:    body,
+		Timeout: t.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := result.(HTTPResponse)
+	if !ok {
+		return result, nil
+	}
+	status := "success"
+	if resp.Status >= 400 {
+		status = "error"
+	}
+	respBody := resp.Body
+	if len(respBody) > maxCustomHTTPBody {
+		respBody = respBody[:maxCustomHTTPBody] + "\n... (truncated)"
+	}
+	return map[string]interface{}{
+		"status":      status,
+		"http_status": resp.Status,
+		"body":        respBody,
+	}, nil
+}
+
+// missingParams returns required parameters absent from params
+func (t *CustomTool) missingParams(params map[string]interface{}) []string {
+	var missing []string
+	switch required := t.Parameters["required"].(type) {
+	case []interface{}:
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if v, present := params[name]; !present || v == nil {
+					missing = append(missing, name)
+				}
+			}
+		}
+	}
+	return missing
+}
+
+// customToolRegistry caches the tools of ~/.aiq/tools, reloading when the files change
+type customToolRegistry struct {
+	mu        sync.Mutex
+	signature string
+	tools     map[string]*CustomTool
+	ordered   []*CustomTool
+}
+
+var customTools = &customToolRegistry{}
+
+// customToolsDir returns the directory custom tools are loaded from (replaced in tests)
+var customToolsDir = config.GetToolsDir
+
+// load returns the current custom tools, reloading them if the directory changed
+func (r *customToolRegistry) load() []*CustomTool {
+	dir, err := customToolsDir()
+	if err != nil {
+		return nil
+	}
+	signature := dirSignature(dir)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if signature == r.signature && r.tools != nil {
+		return r.ordered
+	}
+
+	tools, errs := LoadCustomTools(dir)
+	for _, err := range errs {
+		// Stderr, so the output of ask and mcp stays clean
+		ui.NewOutput(os.Stderr).Warning(fmt.Sprintf("Skipping custom tool %v", err))
+	}
+	r.signature = signature
+	r.ordered = tools
+	r.tools = make(map[string]*CustomTool, len(tools))
+	for _, t := range tools {
+		r.tools[t.Name] = t
+	}
+	return r.ordered
+}
+
+// get returns the custom tool with the given name, or nil
+func (r *customToolRegistry) get(name string) *CustomTool {
+	for _, t := range r.load() {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// dirSignature summarizes the tool files of dir so changes trigger a reload
+func dirSignature(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// CustomToolRiskLevel returns the declared risk level ("low" or "high") of a custom tool
+func CustomToolRiskLevel(name string) (string, bool) {
+	if reservedToolNames[name] {
+		return "", false
+	}
+	t := customTools.get(name)
+	if t == nil {
+		return "", false
+	}
+	return t.RiskLevel, true
+}
+
+// IsCustomTool reports whether name is a tool loaded from ~/.aiq/tools
+func IsCustomTool(name string) bool {
+	_, ok := CustomToolRiskLevel(name)
+	return ok
+}
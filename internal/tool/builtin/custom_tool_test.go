@@ -0,0 +1,236 @@
+package builtin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeToolFile writes a tool file into dir
+func writeToolFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+// loadOne loads a single tool from YAML content
+func loadOne(t *testing.T, content string) (*CustomTool, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tool.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write tool file: %v", err)
+	}
+	return loadCustomTool(path)
+}
+
+// TestLoadCustomTools tests loading and validating tool files
+func TestLoadCustomTools(t *testing.T) {
+	t.Run("loads valid tools", func(t *testing.T) {
+		dir := t.TempDir()
+		writeToolFile(t, dir, "greet.yaml", `
+name: greet
+description: Greet someone
+risk_level: low
+parameters:
+  type: object
+  properties:
+    who: {type: string}
+command: echo hello {{.who}}
+`)
+		writeToolFile(t, dir, "notes.txt", "not a tool")
+
+		tools, errs := LoadCustomTools(dir)
+		if len(errs) != 0 {
+			t.Fatalf("Expected no errors, got %v", errs)
+		}
+		if len(tools) != 1 || tools[0].Name != "greet" || tools[0].RiskLevel != "low" {
+			t.Fatalf("Expected tool greet with low risk, got %+v", tools)
+		}
+		if tools[0].Description != "Greet someone" || tools[0].Parameters["type"] != "object" {
+			t.Errorf("Expected definition of greet, got %+v", tools[0])
+		}
+	})
+
+	t.Run("defaults to high risk", func(t *testing.T) {
+		tool, err := loadOne(t, "name: t\ndescription: d\ncommand: date\n")
+		if err != nil {
+			t.Fatalf("Expected tool to load, got %v", err)
+		}
+		if tool.RiskLevel != "high" {
+			t.Errorf("Expected risk level high, got %s", tool.RiskLevel)
+		}
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		dir := t.TempDir()
+		writeToolFile(t, dir, "a.yaml", "name: t\ndescription: d\ncommand: date\n")
+		writeToolFile(t, dir, "b.yml", "name: t\ndescription: d\ncommand: date\n")
+		tools, errs := LoadCustomTools(dir)
+		if len(tools) != 1 || len(errs) != 1 {
+			t.Errorf("Expected 1 tool and 1 error, got %d and %v", len(tools), errs)
+		}
+	})
+
+	invalid := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"invalid name", "name: a b\ndescription: d\ncommand: date\n", "invalid name"},
+		{"reserved name", "name: execute_sql\ndescription: d\ncommand: date\n", "reserved"},
+		{"missing description", "name: t\ncommand: date\n", "description is required"},
+		{"invalid risk level", "name: t\ndescription: d\nrisk_level: medium\ncommand: date\n", "invalid risk_level"},
+		{"no implementation", "name: t\ndescription: d\n", "exactly one of command or http"},
+		{"both implementations", "name: t\ndescription: d\ncommand: date\nhttp: {url: 'http://x'}\n", "exactly one of command or http"},
+		{"undeclared parameter", "name: t\ndescription: d\ncommand: echo {{.missing}}\n", "invalid command template"},
+		{"unsupported method", "name: t\ndescription: d\nhttp: {method: PATCH, url: 'http://x'}\n", "unsupported HTTP method"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadOne(t, tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+// TestCustomTool_Command tests command tools and shell quoting of parameters
+func TestCustomTool_Command(t *testing.T) {
+	tool, err := loadOne(t, `
+name: greet
+description: Greet someone
+parameters:
+  type: object
+  properties:
+    who: {type: string}
+    times: {type: integer}
+  required: [who]
+command: echo hello {{.who}} {{.times}}
+`)
+	if err != nil {
+		t.Fatalf("Failed to load tool: %v", err)
+	}
+
+	t.Run("quotes parameters", func(t *testing.T) {
+		result, err := tool.Execute(context.Background(), map[string]interface{}{"who": "it's; echo injected", "times": float64(2)})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		output := result.(map[string]interface{})
+		if output["status"] != "success" {
+			t.Errorf("Expected success, got %v", output)
+		}
+		if stdout := strings.TrimSpace(output["stdout"].(string)); stdout != "hello it's; echo injected 2" {
+			t.Errorf("Expected parameter passed as one word, got %q", stdout)
+		}
+	})
+
+	t.Run("missing required parameter", func(t *testing.T) {
+		if _, err := tool.Execute(context.Background(), map[string]interface{}{}); err == nil {
+			t.Error("Expected error for missing required parameter")
+		}
+	})
+}
+
+// TestCustomTool_HTTP tests HTTP tools and URL escaping of parameters
+func TestCustomTool_HTTP(t *testing.T) {
+	var gotQuery, gotHeader, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("q")
+		gotHeader = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Write([]byte(`{"value":42}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("AIQ_TEST_METRICS_TOKEN", "secret")
+	tool, err := loadOne(t, `
+name: metric
+description: Read a metric
+risk_level: low
+parameters:
+  type: object
+  properties:
+    query: {type: string}
+http:
+  method: post
+  url: `+server.URL+`/metrics?q={{.query}}
+  headers:
+    Authorization: Bearer ${AIQ_TEST_METRICS_TOKEN}
+  body: '{"query": {{json .query}}}'
+`)
+	if err != nil {
+		t.Fatalf("Failed to load tool: %v", err)
+	}
+
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"query": "a&b=c"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	output := result.(map[string]interface{})
+	if output["status"] != "success" || output["body"] != `{"value":42}` {
+		t.Errorf("Expected success with body, got %v", output)
+	}
+	if gotQuery != "a&b=c" {
+		t.Errorf("Expected escaped query parameter a&b=c, got %q", gotQuery)
+	}
+	if gotHeader != "Bearer secret" {
+		t.Errorf("Expected header from environment, got %q", gotHeader)
+	}
+	if gotBody != `{"query": "a&b=c"}` {
+		t.Errorf("Expected JSON body, got %q", gotBody)
+	}
+}
+
+// TestCustomToolRegistry tests dynamic definitions and execution through the built-in registry
+func TestCustomToolRegistry(t *testing.T) {
+	dir := t.TempDir()
+	oldDir, oldRegistry := customToolsDir, customTools
+	customToolsDir = func() (string, error) { return dir, nil }
+	customTools = &customToolRegistry{}
+	defer func() { customToolsDir, customTools = oldDir, oldRegistry }()
+
+	if IsCustomTool("greet") {
+		t.Fatal("Expected no custom tools in empty directory")
+	}
+
+	writeToolFile(t, dir, "greet.yaml", "name: greet\ndescription: d\nrisk_level: low\ncommand: echo hi\n")
+
+	t.Run("lists custom tools", func(t *testing.T) {
+		if tools := CustomTools(); len(tools) != 1 || tools[0].Name != "greet" {
+			t.Errorf("Expected greet in custom tools, got %+v", tools)
+		}
+		if level, ok := CustomToolRiskLevel("greet"); !ok || level != "low" {
+			t.Errorf("Expected risk level low, got %q", level)
+		}
+	})
+
+	t.Run("executes custom tools", func(t *testing.T) {
+		var lines []string
+		ctx := WithOutputCallback(context.Background(), func(line string) { lines = append(lines, line) })
+		result, err := CustomTools()[0].Execute(ctx, map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if stdout := result.(map[string]interface{})["stdout"]; strings.TrimSpace(stdout.(string)) != "hi" {
+			t.Errorf("Expected output hi, got %v", stdout)
+		}
+		if len(lines) != 1 || lines[0] != "hi" {
+			t.Errorf("Expected the output to be streamed to the callback, got %q", lines)
+		}
+	})
+
+	t.Run("built-in names are not custom", func(t *testing.T) {
+		if IsCustomTool("execute_command") {
+			t.Error("Expected execute_command not to be a custom tool")
+		}
+	})
+}
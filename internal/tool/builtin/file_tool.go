@@ -2,7 +2,6 @@ package builtin
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -35,6 +34,15 @@ func NewFileTool() (*FileTool, error) {
 	}, nil
 }
 
+// FileOperationParams represents the parameters of the file_operations tool
+type FileOperationParams struct {
+	Operation string `json:"operation" enum:"read,write,list,exists" description:"Operation to perform"`
+	Path      string `json:"path" description:"File or directory path"`
+	Content   string `json:"content,omitempty" description:"Content to write (for write operation)"`
+	RiskLevel string `json:"risk_level,omitempty" enum:"low,medium,high" description:"Optional: Risk level assessment for this operation. 'low' = safe to execute automatically (e.g., read, list, exists), 'medium'/'high' = requires user confirmation (e.g., write). If not provided, system will assess risk conservatively."`
+	TaskHints
+}
+
 // FileReadParams represents parameters for file read
 type FileReadParams struct {
 	Path string `json:"path"`
@@ -93,16 +101,7 @@ func (t *FileTool) validatePath(path string) error {
 }
 
 // ReadFile reads a file
-func (t *FileTool) ReadFile(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	var fileParams FileReadParams
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %w", err)
-	}
-	if err := json.Unmarshal(paramsJSON, &fileParams); err != nil {
-		return nil, fmt.Errorf("failed to parse params: %w", err)
-	}
-
+func (t *FileTool) ReadFile(ctx context.Context, fileParams FileReadParams) (interface{}, error) {
 	if fileParams.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
@@ -126,16 +125,7 @@ func (t *FileTool) ReadFile(ctx context.Context, params map[string]interface{})
 }
 
 // WriteFile writes to a file
-func (t *FileTool) WriteFile(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	var fileParams FileWriteParams
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %w", err)
-	}
-	if err := json.Unmarshal(paramsJSON, &fileParams); err != nil {
-		return nil, fmt.Errorf("failed to parse params: %w", err)
-	}
-
+func (t *FileTool) WriteFile(ctx context.Context, fileParams FileWriteParams) (interface{}, error) {
 	if fileParams.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
@@ -167,16 +157,7 @@ func (t *FileTool) WriteFile(ctx context.Context, params map[string]interface{})
 }
 
 // ListDirectory lists files in a directory
-func (t *FileTool) ListDirectory(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	var fileParams FileListParams
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %w", err)
-	}
-	if err := json.Unmarshal(paramsJSON, &fileParams); err != nil {
-		return nil, fmt.Errorf("failed to parse params: %w", err)
-	}
-
+func (t *FileTool) ListDirectory(ctx context.Context, fileParams FileListParams) (interface{}, error) {
 	path := fileParams.Path
 	if path == "" {
 		// Default to current working directory
@@ -211,16 +192,7 @@ func (t *FileTool) ListDirectory(ctx context.Context, params map[string]interfac
 }
 
 // FileExists checks if a file exists
-func (t *FileTool) FileExists(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	var fileParams FileExistsParams
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %w", err)
-	}
-	if err := json.Unmarshal(paramsJSON, &fileParams); err != nil {
-		return nil, fmt.Errorf("failed to parse params: %w", err)
-	}
-
+func (t *FileTool) FileExists(ctx context.Context, fileParams FileExistsParams) (interface{}, error) {
 	if fileParams.Path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
@@ -229,7 +201,7 @@ func (t *FileTool) FileExists(ctx context.Context, params map[string]interface{}
 		return nil, err
 	}
 
-	_, err = os.Stat(fileParams.Path)
+	_, err := os.Stat(fileParams.Path)
 	exists := err == nil
 
 	return FileResult{
@@ -240,66 +212,27 @@ func (t *FileTool) FileExists(ctx context.Context, params map[string]interface{}
 
 // Execute executes a file operation based on operation type
 func (t *FileTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	operation, ok := params["operation"].(string)
-	if !ok {
-		return nil, fmt.Errorf("operation is required (read, write, list, exists)")
+	var fileParams FileOperationParams
+	if err := decodeParams(params, &fileParams); err != nil {
+		return nil, err
 	}
+	return t.Run(ctx, fileParams)
+}
 
-	switch operation {
+// Run executes the file operation named by params.Operation
+func (t *FileTool) Run(ctx context.Context, params FileOperationParams) (interface{}, error) {
+	switch params.Operation {
+	case "":
+		return nil, fmt.Errorf("operation is required (read, write, list, exists)")
 	case "read":
-		return t.ReadFile(ctx, params)
+		return t.ReadFile(ctx, FileReadParams{Path: params.Path})
 	case "write":
-		return t.WriteFile(ctx, params)
+		return t.WriteFile(ctx, FileWriteParams{Path: params.Path, Content: params.Content})
 	case "list":
-		return t.ListDirectory(ctx, params)
+		return t.ListDirectory(ctx, FileListParams{Path: params.Path})
 	case "exists":
-		return t.FileExists(ctx, params)
+		return t.FileExists(ctx, FileExistsParams{Path: params.Path})
 	default:
-		return nil, fmt.Errorf("unknown operation: %s", operation)
-	}
-}
-
-// GetDefinition returns the tool definition for LLM
-func (t *FileTool) GetDefinition() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "function",
-		"function": map[string]interface{}{
-			"name":        "file_operations",
-			"description": "Perform file operations (read, write, list, check existence). Restricted to user config directory and current working directory.",
-			"parameters": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"operation": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"read", "write", "list", "exists"},
-						"description": "Operation to perform",
-					},
-					"path": map[string]interface{}{
-						"type":        "string",
-						"description": "File or directory path",
-					},
-					"content": map[string]interface{}{
-						"type":        "string",
-						"description": "Content to write (for write operation)",
-					},
-					"risk_level": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"low", "medium", "high"},
-						"description": "Optional: Risk level assessment for this operation. 'low' = safe to execute automatically (e.g., read, list, exists), 'medium'/'high' = requires user confirmation (e.g., write). If not provided, system will assess risk conservatively.",
-					},
-					"task_type": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"definitive", "exploratory"},
-						"description": "Optional: Task type classification. 'definitive' = task is clear and complete, 'exploratory' = task requires information gathering or multi-step process. If not provided, system will infer from context.",
-					},
-					"output_mode": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"full", "streaming"},
-						"description": "Optional: Output display mode. 'full' = display all results to user (for definitive tasks), 'streaming' = real-time streaming with truncation (for exploratory tasks). If not provided, inferred from task_type.",
-					},
-				},
-				"required": []string{"operation", "path"},
-			},
-		},
+		return nil, fmt.Errorf("unknown operation: %s", params.Operation)
 	}
 }
@@ -3,7 +3,6 @@ package builtin
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -21,11 +20,13 @@ func NewHTTPTool() *HTTPTool {
 
 // HTTPRequestParams represents parameters for HTTP request
 type HTTPRequestParams struct {
-	Method  string            `json:"method"` // GET, POST, PUT, DELETE
-	URL     string            `json:"url"`
-	Headers map[string]string `json:"headers,omitempty"`
-	Body    string            `json:"body,omitempty"`
-	Timeout int               `json:"timeout,omitempty"` // Timeout in seconds, default 30
+	Method    string            `json:"method,omitempty" enum:"GET,POST,PUT,DELETE" default:"GET" description:"HTTP method"`
+	URL       string            `json:"url" description:"URL to request"`
+	Headers   map[string]string `json:"headers,omitempty" description:"HTTP headers as key-value pairs"`
+	Body      string            `json:"body,omitempty" description:"Request body (for POST/PUT)"`
+	Timeout   int               `json:"timeout,omitempty" description:"Timeout in seconds (default: 30)"`
+	RiskLevel string            `json:"risk_level,omitempty" enum:"low,medium,high" description:"Optional: Risk level assessment for this operation. 'low' = safe to execute automatically (e.g., GET, HEAD), 'medium'/'high' = requires user confirmation (e.g., POST, DELETE). If not provided, system will assess risk conservatively."`
+	TaskHints
 }
 
 // HTTPResponse represents HTTP response
@@ -38,16 +39,15 @@ type HTTPResponse struct {
 
 // Execute executes an HTTP request
 func (t *HTTPTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	// Parse parameters
 	var httpParams HTTPRequestParams
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %w", err)
-	}
-	if err := json.Unmarshal(paramsJSON, &httpParams); err != nil {
-		return nil, fmt.Errorf("failed to parse params: %w", err)
+	if err := decodeParams(params, &httpParams); err != nil {
+		return nil, err
 	}
+	return t.Run(ctx, httpParams)
+}
 
+// Run executes an HTTP request
+func (t *HTTPTool) Run(ctx context.Context, httpParams HTTPRequestParams) (interface{}, error) {
 	// Validate method
 	method := httpParams.Method
 	if method == "" {
@@ -64,8 +64,7 @@ func (t *HTTPTool) Execute(ctx context.Context, params map[string]interface{}) (
 	if httpParams.URL == "" {
 		return nil, fmt.Errorf("URL is required")
 	}
-	_, err = url.Parse(httpParams.URL)
-	if err != nil {
+	if _, err := url.Parse(httpParams.URL); err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
@@ -130,57 +129,3 @@ func (t *HTTPTool) Execute(ctx context.Context, params map[string]interface{}) (
 
 	return response, nil
 }
-
-// GetDefinition returns the tool definition for LLM
-func (t *HTTPTool) GetDefinition() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "function",
-		"function": map[string]interface{}{
-			"name":        "http_request",
-			"description": "Make HTTP requests (GET, POST, PUT, DELETE). Use this to fetch data from APIs or send data to endpoints.",
-			"parameters": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"method": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"GET", "POST", "PUT", "DELETE"},
-						"description": "HTTP method",
-						"default":     "GET",
-					},
-					"url": map[string]interface{}{
-						"type":        "string",
-						"description": "URL to request",
-					},
-					"headers": map[string]interface{}{
-						"type":        "object",
-						"description": "HTTP headers as key-value pairs",
-					},
-					"body": map[string]interface{}{
-						"type":        "string",
-						"description": "Request body (for POST/PUT)",
-					},
-					"timeout": map[string]interface{}{
-						"type":        "integer",
-						"description": "Timeout in seconds (default: 30)",
-					},
-					"risk_level": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"low", "medium", "high"},
-						"description": "Optional: Risk level assessment for this operation. 'low' = safe to execute automatically (e.g., GET, HEAD), 'medium'/'high' = requires user confirmation (e.g., POST, DELETE). If not provided, system will assess risk conservatively.",
-					},
-					"task_type": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"definitive", "exploratory"},
-						"description": "Optional: Task type classification. 'definitive' = task is clear and complete, 'exploratory' = task requires information gathering or multi-step process. If not provided, system will infer from context.",
-					},
-					"output_mode": map[string]interface{}{
-						"type":        "string",
-						"enum":        []string{"full", "streaming"},
-						"description": "Optional: Output display mode. 'full' = display all results to user (for definitive tasks), 'streaming' = real-time streaming with truncation (for exploratory tasks). If not provided, inferred from task_type.",
-					},
-				},
-				"required": []string{"url"},
-			},
-		},
-	}
-}
@@ -2,57 +2,46 @@ package builtin
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-
-	"github.com/aiq/aiq/internal/db"
 )
 
-// GetBuiltinToolDefinitions returns LLM function definitions for all built-in tools
-func GetBuiltinToolDefinitions(dbConn *db.Connection) []map[string]interface{} {
-	definitions := []map[string]interface{}{}
-
-	// HTTP tool
-	httpTool := NewHTTPTool()
-	definitions = append(definitions, httpTool.GetDefinition())
+// TaskHints are optional hints the LLM can give with any tool call
+type TaskHints struct {
+	TaskType   string `json:"task_type,omitempty" enum:"definitive,exploratory" description:"Optional: Task type classification. 'definitive' = task is clear and complete, 'exploratory' = task requires information gathering or multi-step process. If not provided, system will infer from context."`
+	OutputMode string `json:"output_mode,omitempty" enum:"full,streaming" description:"Optional: Output display mode. 'full' = display all results to user (for definitive tasks), 'streaming' = real-time streaming with truncation (for exploratory tasks). If not provided, inferred from task_type."`
+}
 
-	// Command tool
-	commandTool := NewCommandTool()
-	definitions = append(definitions, commandTool.GetDefinition())
+// The built-in tools are registered with the tool registry (see tool.NewDefaultRegistry),
+// which generates their parameter schemas and decodes arguments into the params structs passed to Run.
 
-	// File tool
-	fileTool, err := NewFileTool()
-	if err == nil {
-		definitions = append(definitions, fileTool.GetDefinition())
-	}
+// outputCallbackKey is the context key of the OutputCallback
+type outputCallbackKey struct{}
 
-	// Note: Database query tool is already available as "execute_sql" in the main tool set
-	// No need to add a duplicate "query_database" tool definition
+// WithOutputCallback returns a context that streams the output of commands run by tools to callback
+func WithOutputCallback(ctx context.Context, callback OutputCallback) context.Context {
+	return context.WithValue(ctx, outputCallbackKey{}, callback)
+}
 
-	return definitions
+// outputCallback returns the OutputCallback of ctx, or nil
+func outputCallback(ctx context.Context) OutputCallback {
+	callback, _ := ctx.Value(outputCallbackKey{}).(OutputCallback)
+	return callback
 }
 
-// ExecuteBuiltinTool executes a built-in tool by name
-func ExecuteBuiltinTool(ctx context.Context, name string, params map[string]interface{}, dbConn *db.Connection) (interface{}, error) {
-	return ExecuteBuiltinToolWithCallback(ctx, name, params, dbConn, nil)
+// CustomTools returns the custom tools declared in ~/.aiq/tools
+func CustomTools() []*CustomTool {
+	return customTools.load()
 }
 
-// ExecuteBuiltinToolWithCallback executes a built-in tool with optional output callback
-func ExecuteBuiltinToolWithCallback(ctx context.Context, name string, params map[string]interface{}, dbConn *db.Connection, callback OutputCallback) (interface{}, error) {
-	switch name {
-	case "http_request":
-		httpTool := NewHTTPTool()
-		return httpTool.Execute(ctx, params)
-	case "execute_command":
-		commandTool := NewCommandTool()
-		return commandTool.ExecuteWithCallback(ctx, params, callback)
-	case "file_operations":
-		fileTool, err := NewFileTool()
-		if err != nil {
-			return nil, err
-		}
-		return fileTool.Execute(ctx, params)
-	// Note: Database queries use "execute_sql" tool, handled separately
-	default:
-		return nil, fmt.Errorf("unknown built-in tool: %s", name)
+// decodeParams decodes map arguments into a params struct
+func decodeParams(params map[string]interface{}, v interface{}) error {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal params: %w", err)
+	}
+	if err := json.Unmarshal(paramsJSON, v); err != nil {
+		return fmt.Errorf("failed to parse params: %w", err)
 	}
+	return nil
 }
@@ -1,86 +1,154 @@
 package tool
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+
+	"github.com/aiq/aiq/internal/db"
+	"github.com/aiq/aiq/internal/tool/builtin"
 )
 
-// GetToolDefinitions returns the list of available tools as LLM Function definitions
-func GetToolDefinitions() []map[string]interface{} {
-	return []map[string]interface{}{
-		{
-			"type": "function",
-			"function": map[string]interface{}{
-				"name":        "execute_sql",
-				"description": "Execute a SQL query against the database and return the results. Use this when the user wants to query the database.",
-				"parameters": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"sql": map[string]interface{}{
-							"type":        "string",
-							"description": "The SQL query to execute",
-						},
-					},
-					"required": []string{"sql"},
-				},
-			},
-		},
-		{
-			"type": "function",
-			"function": map[string]interface{}{
-				"name":        "render_table",
-				"description": "Display query results as a formatted table. Use this to show data in a tabular format.",
-				"parameters": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"columns": map[string]interface{}{
-							"type":        "array",
-							"items":       map[string]interface{}{"type": "string"},
-							"description": "Column names",
-						},
-						"rows": map[string]interface{}{
-							"type":        "array",
-							"items":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
-							"description": "Row data, each row is an array of string values",
-						},
-					},
-					"required": []string{"columns", "rows"},
-				},
-			},
-		},
-		{
-			"type": "function",
-			"function": map[string]interface{}{
-				"name":        "render_chart",
-				"description": "Display query results as a chart (bar, line, pie, or scatter). Use this when the user wants to visualize data.",
-				"parameters": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"columns": map[string]interface{}{
-							"type":        "array",
-							"items":       map[string]interface{}{"type": "string"},
-							"description": "Column names",
-						},
-						"rows": map[string]interface{}{
-							"type":        "array",
-							"items":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
-							"description": "Row data, each row is an array of string values",
-						},
-						"chart_type": map[string]interface{}{
-							"type":        "string",
-							"enum":        []string{"bar", "line", "pie", "scatter"},
-							"description": "Type of chart to render",
-						},
-					},
-					"required": []string{"columns", "rows", "chart_type"},
-				},
-			},
-		},
+// SQLParams are the parameters of the execute_sql tool
+type SQLParams struct {
+	SQL        string        `json:"sql" description:"The SQL query to execute"`
+	Params     []interface{} `json:"params,omitempty" type:"string,number,boolean,null" description:"Optional: Values bound to the placeholders in sql, in order. Always pass values taken from the user's message (names, emails, search terms, dates) here rather than quoting them into the SQL text."`
+	Background bool          `json:"background,omitempty" description:"Optional: Run the query in the background so the chat stays usable. Use for long-running queries (large scans, warehouse aggregations) or when the user asks for it. The result is added to the conversation when the query completes; do not wait for it or re-run it."`
+	RiskLevel  string        `json:"risk_level,omitempty" enum:"low,medium,high" description:"Optional: Risk level assessment for this operation. 'low' = safe to execute automatically (e.g., SELECT, SHOW), 'medium'/'high' = requires user confirmation (e.g., DROP, TRUNCATE). If not provided, system will assess risk conservatively."`
+	builtin.TaskHints
+}
+
+// Values returns the bind values of the query (see ParseSQLParams)
+func (p SQLParams) Values() ([]interface{}, error) {
+	return normalizeSQLParams(p.Params)
+}
+
+// TableParams are the parameters of the render_table tool
+type TableParams struct {
+	Columns []string        `json:"columns" description:"Column names"`
+	Rows    [][]interface{} `json:"rows" type:"string,number,boolean,null" description:"Row data, each row is an array of string values"`
+}
+
+// ChartParams are the parameters of the render_chart tool
+type ChartParams struct {
+	Columns   []string        `json:"columns" description:"Column names from query results (e.g., [\"category\", \"total_revenue\"])"`
+	Rows      [][]interface{} `json:"rows" type:"string,number,boolean,null" description:"Row data from query results, each row is an array of string values (e.g., [[\"Appliances\", \"159.98\"], [\"Electronics\", \"2699.95\"]])"`
+	ChartType string          `json:"chart_type" enum:"bar,line,pie,scatter" description:"Type of chart: 'pie' for pie charts, 'bar' for bar charts, 'line' for line charts, 'scatter' for scatter plots"`
+}
+
+// SQLRunner executes the execute_sql tool for a session
+type SQLRunner func(ctx context.Context, params SQLParams) (interface{}, error)
+
+// NewDefaultRegistry returns the tools offered to the LLM, in the order they are offered:
+// execute_sql (only with a database connection), render_table, render_chart and the built-in tools,
+// including custom tools from ~/.aiq/tools
+// runSQL executes execute_sql; it may be nil when the registry is only used for definitions.
+func NewDefaultRegistry(dbConn *db.Connection, runSQL SQLRunner) *Registry {
+	r := NewRegistry()
+
+	if dbConn != nil {
+		sqlTool := NewTool("execute_sql",
+			"**MANDATORY TOOL CALL**: Execute a SQL query against the database and return the results. Available ONLY in database mode when a database source is selected. **CRITICAL**: When the user requests database operations (SELECT, INSERT, UPDATE, DELETE, CREATE, DROP, ALTER, SHOW, etc.), you MUST call this tool. Do NOT describe what you will do in text - actually call the tool. Do NOT say 'I will execute' or 'Stand by while I execute' - just call the tool directly.",
+			func(ctx context.Context, params SQLParams) (interface{}, error) {
+				if runSQL == nil {
+					return nil, fmt.Errorf("SQL execution is not available")
+				}
+				return runSQL(ctx, params)
+			})
+		// The placeholder syntax depends on the driver
+		setPropertyDescription(sqlTool, "sql", fmt.Sprintf("The SQL query to execute. Use placeholders (%s, %s, ...) for values and pass them in params instead of writing literals into the SQL text", dbConn.Placeholder(1), dbConn.Placeholder(2)))
+		r.Register(sqlTool)
 	}
+
+	r.Register(NewTool("render_table",
+		"Format query results as a table string. Use this when you want to show data in a tabular format. **IMPORTANT**: If recent query results are available in conversation history, use that data directly. Only generate new SQL queries if the user explicitly requests different data.",
+		renderTable))
+
+	r.Register(NewTool("render_chart",
+		"**MANDATORY TOOL CALL**: When the user requests chart visualization (pie chart, bar chart, line chart, etc.), you MUST call this tool. Do NOT return text descriptions or JSON data. The chart will be automatically displayed in the terminal. **CRITICAL**: Check conversation history for recent query results first. Extract columns and rows from the result_summary or previous execute_sql results. Only generate new SQL queries if the user explicitly requests different data or no recent results are available.",
+		renderChart))
+
+	r.Register(NewTool("http_request",
+		"Make HTTP requests (GET, POST, PUT, DELETE). Use this to fetch data from APIs or send data to endpoints.",
+		builtin.NewHTTPTool().Run))
+
+	// Command output is streamed to the callback set with builtin.WithOutputCallback
+	r.Register(NewTool("execute_command",
+		"Execute shell commands for system operations (installation, setup, configuration). Use for system operations, NOT for database queries. Most commands are allowed, but dangerous commands (like rm, sudo, dd) are blocked for security.",
+		builtin.NewCommandTool().Run))
+
+	if fileTool, err := builtin.NewFileTool(); err == nil {
+		r.Register(NewTool("file_operations",
+			"Perform file operations (read, write, list, check existence). Restricted to user config directory and current working directory.",
+			fileTool.Run))
+	}
+
+	// Custom tools declare their parameters as JSON Schema in their own files
+	for _, custom := range builtin.CustomTools() {
+		r.Register(&Tool{
+			Name:        custom.Name,
+			Description: custom.Description,
+			Parameters:  custom.Parameters,
+			Execute:     custom.Execute,
+		})
+	}
+
+	return r
 }
 
-// ConvertToLLMFunctions converts tool definitions to LLM Function format
-func ConvertToLLMFunctions() ([]map[string]interface{}, error) {
-	return GetToolDefinitions(), nil
+// setPropertyDescription replaces the description of a generated parameter
+func setPropertyDescription(t *Tool, property, description string) {
+	if properties, ok := t.Parameters["properties"].(map[string]interface{}); ok {
+		if prop, ok := properties[property].(map[string]interface{}); ok {
+			prop["description"] = description
+		}
+	}
+}
+
+// renderTable formats the rows as a table for the LLM
+func renderTable(ctx context.Context, params TableParams) (interface{}, error) {
+	rows := stringRows(params.Rows)
+	tableOutput, err := RenderTableString(params.Columns, rows)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}, nil
+	}
+	return map[string]interface{}{
+		"status":    "success",
+		"format":    "table",
+		"output":    tableOutput,
+		"row_count": len(rows),
+	}, nil
+}
+
+// renderChart renders the rows as a chart for the LLM
+func renderChart(ctx context.Context, params ChartParams) (interface{}, error) {
+	result := &db.QueryResult{
+		Columns: params.Columns,
+		Rows:    stringRows(params.Rows),
+	}
+	chartOutput, err := RenderChartString(result, params.ChartType)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}, nil
+	}
+	return map[string]interface{}{
+		"status":     "success",
+		"format":     "chart",
+		"output":     chartOutput,
+		"chart_type": params.ChartType,
+		"row_count":  len(result.Rows),
+	}, nil
+}
+
+// stringRows converts row values to their display strings
+func stringRows(rows [][]interface{}) [][]string {
+	out := make([][]string, len(rows))
+	for i, row := range rows {
+		out[i] = make([]string, len(row))
+		for j, val := range row {
+			out[i][j] = fmt.Sprintf("%v", val)
+		}
+	}
+	return out
 }
 
 // ToolCallResult represents the result of a tool execution for LLM
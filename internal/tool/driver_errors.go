@@ -0,0 +1,289 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// sqliteError matches SQLite driver errors that expose their (extended) result code,
+// such as modernc.org/sqlite's *Error
+type sqliteError interface {
+	error
+	Code() int
+}
+
+// mysqlErrorTypes maps MySQL server error numbers to error types
+var mysqlErrorTypes = map[uint16]string{
+	// Syntax
+	1064: "syntax_error", // ER_PARSE_ERROR
+	1149: "syntax_error", // ER_SYNTAX_ERROR
+
+	// Missing objects
+	1046: "resource_not_found", // ER_NO_DB_ERROR
+	1049: "resource_not_found", // ER_BAD_DB_ERROR
+	1051: "resource_not_found", // ER_BAD_TABLE_ERROR
+	1054: "resource_not_found", // ER_BAD_FIELD_ERROR
+	1091: "resource_not_found", // ER_CANT_DROP_FIELD_OR_KEY
+	1146: "resource_not_found", // ER_NO_SUCH_TABLE
+	1176: "resource_not_found", // ER_KEY_DOES_NOT_EXITS
+	1305: "resource_not_found", // ER_SP_DOES_NOT_EXIST
+	1360: "resource_not_found", // ER_TRG_DOES_NOT_EXIST
+
+	// Existing objects
+	1007: "resource_exists", // ER_DB_CREATE_EXISTS
+	1050: "resource_exists", // ER_TABLE_EXISTS_ERROR
+	1060: "resource_exists", // ER_DUP_FIELDNAME
+	1061: "resource_exists", // ER_DUP_KEYNAME
+	1304: "resource_exists", // ER_SP_ALREADY_EXISTS
+	1359: "resource_exists", // ER_TRG_ALREADY_EXISTS
+
+	// Integrity
+	1062: "duplicate_key",          // ER_DUP_ENTRY
+	1586: "duplicate_key",          // ER_DUP_ENTRY_WITH_KEY_NAME
+	1216: "foreign_key_constraint", // ER_NO_REFERENCED_ROW
+	1217: "foreign_key_constraint", // ER_ROW_IS_REFERENCED
+	1451: "foreign_key_constraint", // ER_ROW_IS_REFERENCED_2
+	1452: "foreign_key_constraint", // ER_NO_REFERENCED_ROW_2
+	3730: "foreign_key_constraint", // ER_FK_CANNOT_DROP_PARENT
+	1048: "constraint_violation",   // ER_BAD_NULL_ERROR
+	1364: "constraint_violation",   // ER_NO_DEFAULT_FOR_FIELD
+	3819: "constraint_violation",   // ER_CHECK_CONSTRAINT_VIOLATED
+
+	// Data
+	1264: "data_error", // ER_WARN_DATA_OUT_OF_RANGE
+	1265: "data_error", // WARN_DATA_TRUNCATED
+	1292: "data_error", // ER_TRUNCATED_WRONG_VALUE
+	1366: "data_error", // ER_TRUNCATED_WRONG_VALUE_FOR_FIELD
+	1406: "data_error", // ER_DATA_TOO_LONG
+	1365: "data_error", // ER_DIVISION_BY_ZERO
+
+	// Permissions
+	1044: "permission_denied", // ER_DBACCESS_DENIED_ERROR
+	1045: "permission_denied", // ER_ACCESS_DENIED_ERROR
+	1142: "permission_denied", // ER_TABLEACCESS_DENIED_ERROR
+	1143: "permission_denied", // ER_COLUMNACCESS_DENIED_ERROR
+	1227: "permission_denied", // ER_SPECIFIC_ACCESS_DENIED_ERROR
+	1370: "permission_denied", // ER_PROCACCESS_DENIED_ERROR
+	1290: "permission_denied", // ER_OPTION_PREVENTS_STATEMENT (e.g. --read-only)
+
+	// Concurrency and limits
+	1213: "transaction_conflict", // ER_LOCK_DEADLOCK
+	1205: "lock_timeout",         // ER_LOCK_WAIT_TIMEOUT
+	3024: "timeout",              // ER_QUERY_TIMEOUT
+	1317: "timeout",              // ER_QUERY_INTERRUPTED
+	1040: "connection_error",     // ER_CON_COUNT_ERROR
+	1053: "connection_error",     // ER_SERVER_SHUTDOWN
+}
+
+// postgresErrorTypes maps PostgreSQL SQLSTATE codes to error types
+var postgresErrorTypes = map[pq.ErrorCode]string{
+	"42601": "syntax_error",           // syntax_error
+	"42P01": "resource_not_found",     // undefined_table
+	"42703": "resource_not_found",     // undefined_column
+	"42883": "resource_not_found",     // undefined_function
+	"42704": "resource_not_found",     // undefined_object
+	"3D000": "resource_not_found",     // invalid_catalog_name
+	"3F000": "resource_not_found",     // invalid_schema_name
+	"42P07": "resource_exists",        // duplicate_table
+	"42701": "resource_exists",        // duplicate_column
+	"42P04": "resource_exists",        // duplicate_database
+	"42P06": "resource_exists",        // duplicate_schema
+	"42710": "resource_exists",        // duplicate_object
+	"42723": "resource_exists",        // duplicate_function
+	"42501": "permission_denied",      // insufficient_privilege
+	"28000": "permission_denied",      // invalid_authorization_specification
+	"28P01": "permission_denied",      // invalid_password
+	"25006": "permission_denied",      // read_only_sql_transaction
+	"23505": "duplicate_key",          // unique_violation
+	"23503": "foreign_key_constraint", // foreign_key_violation
+	"2BP01": "foreign_key_constraint", // dependent_objects_still_exist
+	"23502": "constraint_violation",   // not_null_violation
+	"23514": "constraint_violation",   // check_violation
+	"23P01": "constraint_violation",   // exclusion_violation
+	"40P01": "transaction_conflict",   // deadlock_detected
+	"40001": "transaction_conflict",   // serialization_failure
+	"55P03": "lock_timeout",           // lock_not_available
+	"57014": "timeout",                // query_canceled
+	"57P01": "connection_error",       // admin_shutdown
+	"53300": "connection_error",       // too_many_connections
+}
+
+// postgresClassTypes maps PostgreSQL SQLSTATE classes to error types when the exact code is not listed
+var postgresClassTypes = map[pq.ErrorClass]string{
+	"08": "connection_error",     // connection_exception
+	"22": "data_error",           // data_exception
+	"23": "constraint_violation", // integrity_constraint_violation
+	"40": "transaction_conflict", // transaction_rollback
+	"53": "connection_error",     // insufficient_resources
+}
+
+// pgReferencedByRe matches the detail of a foreign key violation on the referenced side
+// e.g. `Key (id)=(1) is still referenced from table "orders".`
+var pgReferencedByRe = regexp.MustCompile(`table "([^"]+)"`)
+
+// decodeDriverError fills info from a native driver error type.
+// Returns false if err does not wrap a recognized driver error.
+func decodeDriverError(err error, info *ErrorInfo) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		decodeMySQLError(mysqlErr, info)
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		decodePostgresError(pqErr, info)
+		return true
+	}
+
+	var liteErr sqliteError
+	if errors.As(err, &liteErr) {
+		decodeSQLiteError(liteErr, info)
+		return true
+	}
+
+	return false
+}
+
+// decodeMySQLError categorizes a MySQL error by its server error number, falling back to SQLSTATE.
+// MySQL does not report table/column names separately, so resources are still taken from the message.
+func decodeMySQLError(mysqlErr *mysql.MySQLError, info *ErrorInfo) {
+	info.ErrorCode = strconv.Itoa(int(mysqlErr.Number))
+	if mysqlErr.SQLState != [5]byte{} {
+		info.SQLState = string(mysqlErr.SQLState[:])
+	}
+
+	if errorType, ok := mysqlErrorTypes[mysqlErr.Number]; ok {
+		info.ErrorType = errorType
+		return
+	}
+
+	info.ErrorType = errorTypeFromSQLState(info.SQLState)
+}
+
+// decodePostgresError categorizes a PostgreSQL error by SQLSTATE and uses its structured fields
+func decodePostgresError(pqErr *pq.Error, info *ErrorInfo) {
+	info.ErrorCode = string(pqErr.Code)
+	info.SQLState = string(pqErr.Code)
+	info.Constraint = pqErr.Constraint
+
+	if errorType, ok := postgresErrorTypes[pqErr.Code]; ok {
+		info.ErrorType = errorType
+	} else if errorType, ok := postgresClassTypes[pqErr.Code.Class()]; ok {
+		info.ErrorType = errorType
+	} else {
+		info.ErrorType = "unknown"
+	}
+
+	table := pqErr.Table
+	if table != "" && pqErr.Schema != "" && pqErr.Schema != "public" {
+		table = pqErr.Schema + "." + table
+	}
+	switch {
+	case table != "" && pqErr.Column != "":
+		info.AffectedResources = append(info.AffectedResources, table+"."+pqErr.Column)
+	case table != "":
+		info.AffectedResources = append(info.AffectedResources, table)
+	case pqErr.Column != "":
+		info.AffectedResources = append(info.AffectedResources, pqErr.Column)
+	case pqErr.DataTypeName != "":
+		info.AffectedResources = append(info.AffectedResources, pqErr.DataTypeName)
+	}
+
+	if pqErr.Code == "23503" {
+		// Table is always the referencing table; the detail names the other side
+		if m := pgReferencedByRe.FindStringSubmatch(pqErr.Detail); len(m) > 1 {
+			if strings.Contains(pqErr.Detail, "still referenced") {
+				// The referenced (parent) table is only named in the message
+				info.Dependencies = append(info.Dependencies, table)
+				if affected, _, _, found := extractForeignKeyConstraint(pqErr.Message); found {
+					info.AffectedResources = []string{affected}
+				}
+				info.SuggestedActions = append(info.SuggestedActions,
+					fmt.Sprintf("Delete or update the referencing rows in '%s' first, or drop the foreign key constraint '%s'", table, pqErr.Constraint))
+			} else {
+				info.Dependencies = append(info.Dependencies, m[1])
+				info.SuggestedActions = append(info.SuggestedActions,
+					fmt.Sprintf("Insert the referenced row into '%s' first (constraint '%s')", m[1], pqErr.Constraint))
+			}
+		}
+	}
+	if pqErr.Code == "2BP01" {
+		info.SuggestedActions = append(info.SuggestedActions,
+			"Drop the dependent objects first, or use DROP ... CASCADE if they should be removed too")
+	}
+
+	if pqErr.Detail != "" && len(info.SuggestedActions) == 0 && info.ErrorType != "syntax_error" {
+		info.SuggestedActions = append(info.SuggestedActions, pqErr.Detail)
+	}
+	if pqErr.Hint != "" {
+		info.SuggestedActions = append(info.SuggestedActions, pqErr.Hint)
+	}
+	if pqErr.Position != "" && info.ErrorType == "syntax_error" {
+		info.SuggestedActions = append(info.SuggestedActions,
+			fmt.Sprintf("Check SQL syntax near character position %s", pqErr.Position))
+	}
+}
+
+// decodeSQLiteError categorizes a SQLite error by its extended result code
+func decodeSQLiteError(liteErr sqliteError, info *ErrorInfo) {
+	code := liteErr.Code()
+	info.ErrorCode = strconv.Itoa(code)
+
+	switch code {
+	case 787: // SQLITE_CONSTRAINT_FOREIGNKEY
+		info.ErrorType = "foreign_key_constraint"
+		return
+	case 1555, 2067: // SQLITE_CONSTRAINT_PRIMARYKEY, SQLITE_CONSTRAINT_UNIQUE
+		info.ErrorType = "duplicate_key"
+		return
+	}
+
+	switch code & 0xff {
+	case 19: // SQLITE_CONSTRAINT
+		info.ErrorType = "constraint_violation"
+	case 3, 8, 23: // SQLITE_PERM, SQLITE_READONLY, SQLITE_AUTH
+		info.ErrorType = "permission_denied"
+	case 5, 6: // SQLITE_BUSY, SQLITE_LOCKED
+		info.ErrorType = "lock_timeout"
+	case 9: // SQLITE_INTERRUPT
+		info.ErrorType = "timeout"
+	case 14: // SQLITE_CANTOPEN
+		info.ErrorType = "connection_error"
+	case 18, 20, 25: // SQLITE_TOOBIG, SQLITE_MISMATCH, SQLITE_RANGE
+		info.ErrorType = "data_error"
+	default:
+		// SQLITE_ERROR covers syntax errors and missing objects alike; the message decides
+		info.ErrorType = "unknown"
+	}
+}
+
+// errorTypeFromSQLState categorizes an error by its standard SQLSTATE class
+func errorTypeFromSQLState(sqlState string) string {
+	if len(sqlState) < 2 {
+		return "unknown"
+	}
+
+	switch sqlState[:2] {
+	case "08":
+		return "connection_error"
+	case "22":
+		return "data_error"
+	case "23":
+		return "constraint_violation"
+	case "28":
+		return "permission_denied"
+	case "40":
+		return "transaction_conflict"
+	case "3D", "3F":
+		return "resource_not_found"
+	}
+
+	return "unknown"
+}
@@ -1,18 +1,26 @@
 package tool
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/aiq/aiq/internal/db"
 )
 
 // ErrorInfo contains structured error information extracted from error messages
 type ErrorInfo struct {
 	ErrorCode         string   // Database error code if available
+	SQLState          string   // SQLSTATE reported by the driver if available
+	Constraint        string   // Violated constraint name if available
 	ErrorType         string   // Categorized error type
 	AffectedResources []string // List of affected resources (tables, columns, etc.)
 	Dependencies      []string // List of dependencies (resources that must be resolved first)
 	SuggestedActions  []string // Suggested actions to resolve the error
+
+	// UnknownIdentifiers lists tables/columns rejected by pre-execution schema validation
+	UnknownIdentifiers []UnknownIdentifier
 }
 
 // ExtractErrorInfo extracts structured error information from an error
@@ -21,52 +29,161 @@ func ExtractErrorInfo(err error) ErrorInfo {
 		return ErrorInfo{}
 	}
 
+	// Identifiers rejected before execution carry their own structured details
+	var identErr *UnknownIdentifierError
+	if errors.As(err, &identErr) {
+		return extractUnknownIdentifiers(identErr)
+	}
+
+	// A dropped connection is not a problem with the SQL; say so explicitly so it isn't rewritten
+	var lostErr *db.ConnectionLostError
+	if errors.As(err, &lostErr) {
+		return extractConnectionLost(lostErr)
+	}
+
 	errorMsg := err.Error()
 	info := ErrorInfo{
-		ErrorType:         categorizeErrorType(errorMsg),
 		AffectedResources: []string{},
 		Dependencies:      []string{},
 		SuggestedActions:  []string{},
 	}
 
-	// Extract error code if present (e.g., "Error 3730 (HY000)")
-	if code := extractErrorCode(errorMsg); code != "" {
-		info.ErrorCode = code
+	// Native driver errors carry reliable codes and fields regardless of server language;
+	// message patterns are only used when the driver error type is not recognized
+	if !decodeDriverError(err, &info) {
+		info.ErrorType = categorizeErrorType(errorMsg)
+
+		// Extract error code if present (e.g., "Error 3730 (HY000)")
+		if code := extractErrorCode(errorMsg); code != "" {
+			info.ErrorCode = code
+		}
+	} else if info.ErrorType == "unknown" {
+		info.ErrorType = categorizeErrorType(errorMsg)
 	}
 
-	// Extract information based on error type
+	applyErrorDetails(&info, errorMsg)
+
+	return info
+}
+
+// applyErrorDetails fills affected resources and suggested actions for the error type.
+// Resources already decoded from the driver error take precedence over message patterns.
+func applyErrorDetails(info *ErrorInfo, errorMsg string) {
+	resolved := len(info.AffectedResources) > 0
+	var actions []string
+
 	switch info.ErrorType {
 	case "foreign_key_constraint":
-		if affected, dep, constraint, found := extractForeignKeyConstraint(errorMsg); found {
-			info.AffectedResources = append(info.AffectedResources, affected)
-			info.Dependencies = append(info.Dependencies, dep)
-			info.SuggestedActions = append(info.SuggestedActions,
-				fmt.Sprintf("Drop the dependent table '%s' first, or drop the foreign key constraint '%s'", dep, constraint))
+		if !resolved {
+			if affected, dep, constraint, found := extractForeignKeyConstraint(errorMsg); found {
+				info.AffectedResources = append(info.AffectedResources, affected)
+				info.Dependencies = append(info.Dependencies, dep)
+				info.Constraint = constraint
+			}
+		}
+		if len(info.Dependencies) > 0 && info.Constraint != "" {
+			actions = append(actions,
+				fmt.Sprintf("Drop the dependent table '%s' first, or drop the foreign key constraint '%s'", info.Dependencies[0], info.Constraint))
+		} else {
+			actions = append(actions, "Resolve the rows or objects referenced by the foreign key constraint first")
 		}
 	case "syntax_error":
-		if affected, found := extractSyntaxError(errorMsg); found {
-			info.AffectedResources = append(info.AffectedResources, affected)
-			info.SuggestedActions = append(info.SuggestedActions, "Check SQL syntax and correct the error")
+		if !resolved {
+			if affected, found := extractSyntaxError(errorMsg); found {
+				info.AffectedResources = append(info.AffectedResources, affected)
+			}
 		}
+		actions = append(actions, "Check SQL syntax and correct the error")
 	case "permission_denied":
-		if affected, found := extractPermissionError(errorMsg); found {
-			info.AffectedResources = append(info.AffectedResources, affected)
-			info.SuggestedActions = append(info.SuggestedActions, "Check user permissions and grant necessary privileges")
+		if !resolved {
+			if affected, found := extractPermissionError(errorMsg); found {
+				info.AffectedResources = append(info.AffectedResources, affected)
+			}
 		}
+		actions = append(actions, "Check user permissions and grant necessary privileges")
 	case "resource_not_found":
-		if affected, found := extractResourceNotFound(errorMsg); found {
-			info.AffectedResources = append(info.AffectedResources, affected)
-			info.SuggestedActions = append(info.SuggestedActions, "Verify the resource exists or create it first")
+		if !resolved {
+			if affected, found := extractResourceNotFound(errorMsg); found {
+				info.AffectedResources = append(info.AffectedResources, affected)
+			}
 		}
+		actions = append(actions, "Verify the resource exists or create it first")
 	case "resource_exists":
-		if affected, found := extractResourceExists(errorMsg); found {
-			info.AffectedResources = append(info.AffectedResources, affected)
-			info.SuggestedActions = append(info.SuggestedActions, "Use IF NOT EXISTS clause or drop existing resource first")
+		if !resolved {
+			if affected, found := extractResourceExists(errorMsg); found {
+				info.AffectedResources = append(info.AffectedResources, affected)
+			}
 		}
+		actions = append(actions, "Use IF NOT EXISTS clause or drop existing resource first")
+	case "duplicate_key":
+		actions = append(actions, "A row with the same unique key already exists; update it instead or use a different key value")
+	case "constraint_violation":
+		actions = append(actions, "Provide values that satisfy the NOT NULL or CHECK constraint")
+	case "data_error":
+		actions = append(actions, "Check value types, ranges and lengths against the column definitions")
+	case "transaction_conflict":
+		actions = append(actions, "The transaction was rolled back due to a conflict; retry it")
+	case "lock_timeout":
+		actions = append(actions, "Another session holds a conflicting lock; retry later or shorten the transaction")
 	case "connection_error":
-		info.SuggestedActions = append(info.SuggestedActions, "Check database connection and network connectivity")
+		actions = append(actions, "Check database connection and network connectivity")
 	case "timeout":
-		info.SuggestedActions = append(info.SuggestedActions, "Operation timed out, consider increasing timeout or optimizing query")
+		actions = append(actions, "Operation timed out, consider increasing timeout or optimizing query")
+	}
+
+	// Driver-provided hints come first; generic actions are only added when nothing specific is known
+	if len(info.SuggestedActions) == 0 {
+		info.SuggestedActions = append(info.SuggestedActions, actions...)
+	}
+}
+
+// extractConnectionLost converts a lost-connection error into ErrorInfo
+func extractConnectionLost(lostErr *db.ConnectionLostError) ErrorInfo {
+	info := ErrorInfo{
+		ErrorType:         "connection_error",
+		AffectedResources: []string{},
+		Dependencies:      []string{},
+	}
+	if lostErr.Reconnected {
+		info.ErrorType = "connection_lost"
+		info.SuggestedActions = []string{
+			"The SQL is not at fault: the connection dropped and has been re-established",
+			"The statement was not retried because it may modify data; check whether it took effect before running it again unchanged",
+			"Session state such as temporary tables and variables was reset by the reconnect",
+		}
+	} else {
+		info.SuggestedActions = []string{
+			"The SQL is not at fault: the database server is unreachable",
+			"Do not modify the SQL; tell the user the database is unavailable",
+		}
+	}
+	return info
+}
+
+// extractUnknownIdentifiers converts a schema validation error into ErrorInfo with "did you mean" actions
+func extractUnknownIdentifiers(identErr *UnknownIdentifierError) ErrorInfo {
+	info := ErrorInfo{
+		ErrorType:          "resource_not_found",
+		AffectedResources:  []string{},
+		Dependencies:       []string{},
+		SuggestedActions:   []string{},
+		UnknownIdentifiers: identErr.Identifiers,
+	}
+
+	for _, id := range identErr.Identifiers {
+		resource := id.Name
+		if id.Table != "" {
+			resource = id.Table + "." + id.Name
+		}
+		info.AffectedResources = append(info.AffectedResources, resource)
+
+		if len(id.Suggestions) > 0 {
+			info.SuggestedActions = append(info.SuggestedActions,
+				fmt.Sprintf("Replace %s '%s' with one of: %s", id.Kind, id.Name, strings.Join(id.Suggestions, ", ")))
+		} else {
+			info.SuggestedActions = append(info.SuggestedActions,
+				fmt.Sprintf("No %s named '%s' exists in the schema; check the schema context for the correct name", id.Kind, id.Name))
+		}
 	}
 
 	return info
@@ -195,6 +312,13 @@ func extractResourceNotFound(errorMsg string) (affectedResource string, found bo
 		return matches[1], true
 	}
 
+	// SQLite pattern: "no such table: X"
+	re = regexp.MustCompile(`(?i)no such (?:table|column|function|index):\s*([^\s,;]+)`)
+	matches = re.FindStringSubmatch(errorMsg)
+	if len(matches) > 1 {
+		return matches[1], true
+	}
+
 	// Generic pattern
 	if strings.Contains(strings.ToLower(errorMsg), "doesn't exist") ||
 		strings.Contains(strings.ToLower(errorMsg), "does not exist") {
@@ -269,6 +393,8 @@ func categorizeErrorType(errorMsg string) string {
 	// Resource not found
 	if strings.Contains(errorLower, "doesn't exist") ||
 		strings.Contains(errorLower, "does not exist") ||
+		strings.Contains(errorLower, "no such table") ||
+		strings.Contains(errorLower, "no such column") ||
 		strings.Contains(errorLower, "not found") {
 		return "resource_not_found"
 	}
@@ -2,8 +2,14 @@ package tool
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+
+	"github.com/aiq/aiq/internal/db"
 )
 
 // TestErrorExtractor_StructuredExtraction tests structured error extraction
@@ -319,9 +325,179 @@ func TestErrorExtractor_SuggestedActions(t *testing.T) {
 	})
 }
 
+// fakeSQLiteError mimics SQLite driver errors exposing an extended result code
+type fakeSQLiteError struct {
+	code int
+	msg  string
+}
+
+func (e *fakeSQLiteError) Error() string { return e.msg }
+func (e *fakeSQLiteError) Code() int     { return e.code }
+
+// TestErrorExtractor_DriverErrors tests decoding of native driver error types
+func TestErrorExtractor_DriverErrors(t *testing.T) {
+	t.Run("decodes MySQL error number and SQLSTATE regardless of message language", func(t *testing.T) {
+		mysqlErr := &mysql.MySQLError{Number: 1146, SQLState: [5]byte{'4', '2', 'S', '0', '2'}, Message: "La tabla 'shop.usuarios' no existe"}
+		info := ExtractErrorInfo(fmt.Errorf("query execution failed: %w", mysqlErr))
+
+		if info.ErrorCode != "1146" {
+			t.Errorf("Expected error code '1146', got %q", info.ErrorCode)
+		}
+		if info.SQLState != "42S02" {
+			t.Errorf("Expected SQLSTATE '42S02', got %q", info.SQLState)
+		}
+		if info.ErrorType != "resource_not_found" {
+			t.Errorf("Expected error type 'resource_not_found', got %q", info.ErrorType)
+		}
+		if len(info.SuggestedActions) == 0 {
+			t.Error("Expected suggested actions to be generated")
+		}
+	})
+
+	t.Run("decodes MySQL duplicate entry", func(t *testing.T) {
+		mysqlErr := &mysql.MySQLError{Number: 1062, SQLState: [5]byte{'2', '3', '0', '0', '0'}, Message: "Duplicate entry '1' for key 'PRIMARY'"}
+		info := ExtractErrorInfo(mysqlErr)
+
+		if info.ErrorType != "duplicate_key" {
+			t.Errorf("Expected error type 'duplicate_key', got %q", info.ErrorType)
+		}
+	})
+
+	t.Run("falls back to SQLSTATE class for unmapped MySQL numbers", func(t *testing.T) {
+		mysqlErr := &mysql.MySQLError{Number: 9999, SQLState: [5]byte{'2', '2', '0', '0', '3'}, Message: "something"}
+		info := ExtractErrorInfo(mysqlErr)
+
+		if info.ErrorType != "data_error" {
+			t.Errorf("Expected error type 'data_error', got %q", info.ErrorType)
+		}
+	})
+
+	t.Run("keeps message extraction for MySQL foreign key errors", func(t *testing.T) {
+		mysqlErr := &mysql.MySQLError{Number: 3730, SQLState: [5]byte{'H', 'Y', '0', '0', '0'},
+			Message: "Cannot drop table 'users' referenced by foreign key constraint 'fk_sales_user' on table 'sales'"}
+		info := ExtractErrorInfo(mysqlErr)
+
+		if info.ErrorType != "foreign_key_constraint" {
+			t.Errorf("Expected error type 'foreign_key_constraint', got %q", info.ErrorType)
+		}
+		if len(info.Dependencies) != 1 || info.Dependencies[0] != "sales" {
+			t.Errorf("Expected dependency 'sales', got %v", info.Dependencies)
+		}
+		if info.Constraint != "fk_sales_user" {
+			t.Errorf("Expected constraint 'fk_sales_user', got %q", info.Constraint)
+		}
+	})
+
+	t.Run("decodes PostgreSQL structured fields", func(t *testing.T) {
+		pqErr := &pq.Error{Code: "42703", Message: "column \"emial\" does not exist", Table: "users", Column: "emial"}
+		info := ExtractErrorInfo(fmt.Errorf("query execution failed: %w", pqErr))
+
+		if info.ErrorCode != "42703" || info.SQLState != "42703" {
+			t.Errorf("Expected code and SQLSTATE '42703', got %q / %q", info.ErrorCode, info.SQLState)
+		}
+		if info.ErrorType != "resource_not_found" {
+			t.Errorf("Expected error type 'resource_not_found', got %q", info.ErrorType)
+		}
+		if len(info.AffectedResources) != 1 || info.AffectedResources[0] != "users.emial" {
+			t.Errorf("Expected affected resource 'users.emial', got %v", info.AffectedResources)
+		}
+	})
+
+	t.Run("decodes PostgreSQL foreign key violation on delete", func(t *testing.T) {
+		pqErr := &pq.Error{
+			Code:       "23503",
+			Message:    "update or delete on table \"users\" violates foreign key constraint \"fk_sales_user\" on table \"sales\"",
+			Detail:     "Key (id)=(1) is still referenced from table \"sales\".",
+			Table:      "sales",
+			Constraint: "fk_sales_user",
+		}
+		info := ExtractErrorInfo(pqErr)
+
+		if info.ErrorType != "foreign_key_constraint" {
+			t.Errorf("Expected error type 'foreign_key_constraint', got %q", info.ErrorType)
+		}
+		if len(info.AffectedResources) != 1 || info.AffectedResources[0] != "users" {
+			t.Errorf("Expected affected resource 'users', got %v", info.AffectedResources)
+		}
+		if len(info.Dependencies) != 1 || info.Dependencies[0] != "sales" {
+			t.Errorf("Expected dependency 'sales', got %v", info.Dependencies)
+		}
+		if info.Constraint != "fk_sales_user" {
+			t.Errorf("Expected constraint 'fk_sales_user', got %q", info.Constraint)
+		}
+	})
+
+	t.Run("includes PostgreSQL hint in suggested actions", func(t *testing.T) {
+		pqErr := &pq.Error{Code: "42883", Message: "function foo(integer) does not exist", Hint: "No function matches the given name and argument types."}
+		info := ExtractErrorInfo(pqErr)
+
+		found := false
+		for _, action := range info.SuggestedActions {
+			if action == pqErr.Hint {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected hint in suggested actions, got %v", info.SuggestedActions)
+		}
+	})
+
+	t.Run("decodes SQLite extended result codes", func(t *testing.T) {
+		info := ExtractErrorInfo(&fakeSQLiteError{code: 2067, msg: "UNIQUE constraint failed: users.email"})
+		if info.ErrorType != "duplicate_key" {
+			t.Errorf("Expected error type 'duplicate_key', got %q", info.ErrorType)
+		}
+		if info.ErrorCode != "2067" {
+			t.Errorf("Expected error code '2067', got %q", info.ErrorCode)
+		}
+
+		info = ExtractErrorInfo(&fakeSQLiteError{code: 5, msg: "database is locked"})
+		if info.ErrorType != "lock_timeout" {
+			t.Errorf("Expected error type 'lock_timeout', got %q", info.ErrorType)
+		}
+	})
+
+	t.Run("uses message for generic SQLite errors", func(t *testing.T) {
+		info := ExtractErrorInfo(&fakeSQLiteError{code: 1, msg: "no such table: orders"})
+		if info.ErrorType != "resource_not_found" {
+			t.Errorf("Expected error type 'resource_not_found', got %q", info.ErrorType)
+		}
+		if len(info.AffectedResources) != 1 || info.AffectedResources[0] != "orders" {
+			t.Errorf("Expected affected resource 'orders', got %v", info.AffectedResources)
+		}
+	})
+}
+
 // Helper function to check if string contains substring (case-insensitive)
 func contains(s, substr string) bool {
 	sLower := strings.ToLower(s)
 	substrLower := strings.ToLower(substr)
 	return strings.Contains(sLower, substrLower)
 }
+
+// TestErrorExtractor_ConnectionLost tests that lost connections are not reported as SQL errors
+func TestErrorExtractor_ConnectionLost(t *testing.T) {
+	t.Run("reconnected write was not retried", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", &db.ConnectionLostError{Err: errors.New("broken pipe"), Reconnected: true})
+
+		info := ExtractErrorInfo(err)
+
+		if info.ErrorType != "connection_lost" {
+			t.Errorf("Expected error type connection_lost, got %s", info.ErrorType)
+		}
+		if len(info.SuggestedActions) == 0 || !strings.Contains(info.SuggestedActions[0], "not at fault") {
+			t.Errorf("Expected actions saying the SQL is not at fault, got %v", info.SuggestedActions)
+		}
+	})
+
+	t.Run("server unreachable", func(t *testing.T) {
+		info := ExtractErrorInfo(&db.ConnectionLostError{Err: errors.New("connection refused")})
+
+		if info.ErrorType != "connection_error" {
+			t.Errorf("Expected error type connection_error, got %s", info.ErrorType)
+		}
+		if len(info.SuggestedActions) == 0 {
+			t.Error("Expected suggested actions to be generated")
+		}
+	})
+}
@@ -3,6 +3,8 @@ package tool
 import (
 	"regexp"
 	"strings"
+
+	"github.com/aiq/aiq/internal/tool/builtin"
 )
 
 // RiskLevel represents the risk level of a tool operation
@@ -15,6 +17,14 @@ const (
 	RiskHigh
 )
 
+// String returns the risk level name used in logs and machine-readable output
+func (r RiskLevel) String() string {
+	if r == RiskLow {
+		return "low"
+	}
+	return "high"
+}
+
 // RiskAssessor interface for assessing risk of tool operations
 // Each tool type can implement its own risk assessor
 type RiskAssessor interface {
@@ -320,6 +330,9 @@ func GetRiskAssessor(toolName string) RiskAssessor {
 	case "http_request":
 		return NewHTTPRequestRiskAssessor()
 	default:
+		if _, ok := builtin.CustomToolRiskLevel(toolName); ok {
+			return NewCustomToolRiskAssessor()
+		}
 		// Default: conservative assessor that always requires confirmation
 		return &DefaultRiskAssessor{}
 	}
@@ -339,3 +352,23 @@ func (r *DefaultRiskAssessor) AssessRisk(toolName string, args map[string]interf
 	LogRiskAssessment("UnknownTool: Default to high risk (requires confirmation), tool: %s", toolName)
 	return RiskHigh
 }
+
+// CustomToolRiskAssessor assesses risk for custom tools declared in ~/.aiq/tools
+type CustomToolRiskAssessor struct{}
+
+// NewCustomToolRiskAssessor creates a new custom tool risk assessor
+func NewCustomToolRiskAssessor() *CustomToolRiskAssessor {
+	return &CustomToolRiskAssessor{}
+}
+
+// AssessRisk returns the risk level declared in the tool file
+// LLM-provided risk_level is ignored: the tool's author decides whether it needs confirmation.
+func (r *CustomToolRiskAssessor) AssessRisk(toolName string, args map[string]interface{}) RiskLevel {
+	riskLevelStr, ok := builtin.CustomToolRiskLevel(toolName)
+	if !ok {
+		LogRiskAssessment("CustomTool: Tool no longer defined, default to high risk, tool: %s", toolName)
+		return RiskHigh
+	}
+	LogRiskAssessment("CustomTool: Declared risk_level=%s, tool: %s", riskLevelStr, toolName)
+	return assessRiskFromLLM(riskLevelStr)
+}
@@ -414,3 +414,24 @@ func TestRiskAssessor_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// TestSQLRiskAssessor_Params tests that bound params do not change statement classification
+func TestSQLRiskAssessor_Params(t *testing.T) {
+	assessor := NewSQLRiskAssessor()
+
+	selectArgs := map[string]interface{}{
+		"sql":    "SELECT * FROM users WHERE name = ?",
+		"params": []interface{}{"x'; DROP TABLE users; --"},
+	}
+	if level := assessor.AssessRisk("execute_sql", selectArgs); level != RiskLow {
+		t.Errorf("Expected SELECT with params to be low risk, got %v", level)
+	}
+
+	deleteArgs := map[string]interface{}{
+		"sql":    "DELETE FROM users WHERE name = ?",
+		"params": []interface{}{"alice"},
+	}
+	if level := assessor.AssessRisk("execute_sql", deleteArgs); level != RiskHigh {
+		t.Errorf("Expected DELETE with params to require confirmation, got %v", level)
+	}
+}
@@ -0,0 +1,151 @@
+// Package schema generates JSON Schemas for tool parameters from Go structs
+// and validates tool call arguments against them.
+//
+// Struct fields are described with tags:
+//
+//	type Params struct {
+//		Query  string   `json:"query" description:"Search terms"`
+//		Limit  int      `json:"limit,omitempty" description:"Maximum results"`
+//		Format string   `json:"format,omitempty" enum:"json,csv" default:"json"`
+//		Values []any    `json:"values,omitempty" type:"string,number,boolean,null"`
+//	}
+//
+// Fields without omitempty are required. The type tag overrides the generated type
+// (of the items, for slices); several comma-separated types allow any of them.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Generate returns the JSON Schema of the struct v (or a pointer to it)
+// It panics if v is not a struct, since parameter types are fixed at compile time.
+func Generate(v interface{}) map[string]interface{} {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("schema: parameters must be a struct, got %v", t))
+	}
+	return objectSchema(t)
+}
+
+// objectSchema returns the schema of a struct type
+func objectSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	addFields(t, properties, &required)
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// addFields adds the exported fields of t, flattening embedded structs
+func addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, omitempty := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		prop := fieldSchema(field.Type, field.Tag.Get("type"))
+		if description := field.Tag.Get("description"); description != "" {
+			prop["description"] = description
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			prop["enum"] = strings.Split(enum, ",")
+		}
+		if def := field.Tag.Get("default"); def != "" {
+			prop["default"] = def
+		}
+		properties[name] = prop
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// jsonName returns the JSON name of a field and whether it is optional
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name, false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
+
+// fieldSchema returns the schema of a field type; override replaces the type of scalars and slice items
+func fieldSchema(t reflect.Type, override string) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": fieldSchema(t.Elem(), override),
+		}
+	}
+	if override != "" {
+		types := strings.Split(override, ",")
+		if len(types) == 1 {
+			return map[string]interface{}{"type": types[0]}
+		}
+		return map[string]interface{}{"type": types}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object"}
+	case reflect.Struct:
+		return objectSchema(t)
+	default:
+		// interface{} without a type tag accepts any value
+		return map[string]interface{}{}
+	}
+}
+
+// Decode converts validated arguments into the parameter struct out
+func Decode(args map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("failed to marshal arguments: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode arguments: %w", err)
+	}
+	return nil
+}
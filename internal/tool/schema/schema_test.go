@@ -0,0 +1,153 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testHints struct {
+	Mode string `json:"mode,omitempty" enum:"fast,slow"`
+}
+
+type testParams struct {
+	Query   string            `json:"query" description:"Search terms"`
+	Limit   int               `json:"limit,omitempty"`
+	Ratio   float64           `json:"ratio,omitempty"`
+	Verbose bool              `json:"verbose,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+	Values  []interface{}     `json:"values,omitempty" type:"string,null"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Method  string            `json:"method,omitempty" default:"GET"`
+	ignored string
+	Skipped string `json:"-"`
+	testHints
+}
+
+// TestGenerate tests schema generation from struct tags
+func TestGenerate(t *testing.T) {
+	s := Generate(testParams{})
+	props := s["properties"].(map[string]interface{})
+
+	t.Run("required fields", func(t *testing.T) {
+		if !reflect.DeepEqual(s["required"], []string{"query"}) {
+			t.Errorf("Expected only query to be required, got %v", s["required"])
+		}
+	})
+
+	t.Run("types", func(t *testing.T) {
+		expected := map[string]interface{}{
+			"limit":   "integer",
+			"ratio":   "number",
+			"verbose": "boolean",
+			"tags":    "array",
+			"headers": "object",
+			"mode":    "string",
+		}
+		for name, typ := range expected {
+			prop, ok := props[name].(map[string]interface{})
+			if !ok {
+				t.Fatalf("Expected property %s", name)
+			}
+			if prop["type"] != typ {
+				t.Errorf("Expected %s to have type %s, got %v", name, typ, prop["type"])
+			}
+		}
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		query := props["query"].(map[string]interface{})
+		if query["description"] != "Search terms" {
+			t.Errorf("Expected description, got %v", query["description"])
+		}
+		mode := props["mode"].(map[string]interface{})
+		if !reflect.DeepEqual(mode["enum"], []string{"fast", "slow"}) {
+			t.Errorf("Expected enum of embedded field, got %v", mode["enum"])
+		}
+		if props["method"].(map[string]interface{})["default"] != "GET" {
+			t.Error("Expected default GET")
+		}
+		items := props["values"].(map[string]interface{})["items"].(map[string]interface{})
+		if !reflect.DeepEqual(items["type"], []string{"string", "null"}) {
+			t.Errorf("Expected item type union, got %v", items["type"])
+		}
+	})
+
+	t.Run("skipped fields", func(t *testing.T) {
+		for _, name := range []string{"ignored", "Skipped", "-"} {
+			if _, ok := props[name]; ok {
+				t.Errorf("Expected %s not to be in the schema", name)
+			}
+		}
+	})
+}
+
+// TestValidate tests validating decoded JSON arguments
+func TestValidate(t *testing.T) {
+	s := Generate(testParams{})
+	parse := func(t *testing.T, args string) map[string]interface{} {
+		t.Helper()
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(args), &m); err != nil {
+			t.Fatalf("Invalid test arguments: %v", err)
+		}
+		return m
+	}
+
+	t.Run("valid arguments", func(t *testing.T) {
+		args := parse(t, `{"query":"x","limit":3,"tags":["a"],"values":["a",null],"mode":"fast","extra":1}`)
+		if err := Validate("search", s, args); err != nil {
+			t.Errorf("Expected valid arguments, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		args  string
+		field string
+	}{
+		{"missing required", `{}`, "query"},
+		{"null required", `{"query":null}`, "query"},
+		{"wrong type", `{"query":1}`, "query"},
+		{"fractional integer", `{"query":"x","limit":1.5}`, "limit"},
+		{"enum", `{"query":"x","mode":"medium"}`, "mode"},
+		{"array items", `{"query":"x","tags":["a",2]}`, "tags[1]"},
+		{"item type union", `{"query":"x","values":[true]}`, "values[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate("search", s, parse(t, tt.args))
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected ValidationError, got %v", err)
+			}
+			if len(validationErr.Errors) != 1 || validationErr.Errors[0].Field != tt.field {
+				t.Errorf("Expected one error for %s, got %+v", tt.field, validationErr.Errors)
+			}
+		})
+	}
+
+	t.Run("hand-written schemas", func(t *testing.T) {
+		// Schemas decoded from YAML or JSON use []interface{} for required and enum
+		var custom map[string]interface{}
+		json.Unmarshal([]byte(`{"type":"object","properties":{"pod":{"type":"string","enum":["a","b"]}},"required":["pod"]}`), &custom)
+		if err := Validate("logs", custom, parse(t, `{"pod":"a"}`)); err != nil {
+			t.Errorf("Expected valid arguments, got %v", err)
+		}
+		if err := Validate("logs", custom, parse(t, `{"pod":"c"}`)); err == nil {
+			t.Error("Expected enum violation")
+		}
+	})
+}
+
+// TestDecode tests decoding arguments into parameter structs
+func TestDecode(t *testing.T) {
+	var p testParams
+	if err := Decode(map[string]interface{}{"query": "x", "limit": float64(3), "mode": "slow"}, &p); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if p.Query != "x" || p.Limit != 3 || p.Mode != "slow" {
+		t.Errorf("Expected decoded fields, got %+v", p)
+	}
+}
@@ -0,0 +1,200 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// FieldError describes one invalid argument
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the arguments of a tool call that do not match the tool's schema
+type ValidationError struct {
+	Tool   string
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fmt.Sprintf("%s: %s", fe.Field, fe.Message)
+	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(parts, "; "))
+}
+
+// Validate checks args against the object schema of tool
+// It supports the subset of JSON Schema used by tool definitions: type (including unions),
+// properties, required, items and enum. Properties not declared in the schema are allowed.
+func Validate(tool string, schema map[string]interface{}, args map[string]interface{}) error {
+	v := &validator{}
+	v.object("", schema, args)
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Tool: tool, Errors: v.errors}
+}
+
+type validator struct {
+	errors []FieldError
+}
+
+func (v *validator) fail(field, format string, args ...interface{}) {
+	if field == "" {
+		field = "(arguments)"
+	}
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// object validates the properties of an object value
+func (v *validator) object(path string, schema map[string]interface{}, value map[string]interface{}) {
+	for _, name := range stringList(schema["required"]) {
+		if val, ok := value[name]; !ok || val == nil {
+			v.fail(join(path, name), "is required")
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		val, ok := value[name]
+		if !ok || val == nil {
+			continue
+		}
+		if prop, ok := properties[name].(map[string]interface{}); ok {
+			v.value(join(path, name), prop, val)
+		}
+	}
+}
+
+// value validates one value against its schema
+func (v *validator) value(path string, schema map[string]interface{}, value interface{}) {
+	types := stringList(schema["type"])
+	if len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "expected %s, got %s", strings.Join(types, " or "), typeName(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		allowed := stringList(enum)
+		if len(allowed) > 0 && !contains(allowed, fmt.Sprintf("%v", value)) {
+			v.fail(path, "must be one of %s, got %v", strings.Join(allowed, ", "), value)
+			return
+		}
+	}
+
+	switch val := value.(type) {
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				v.value(fmt.Sprintf("%s[%d]", path, i), items, item)
+			}
+		}
+	case map[string]interface{}:
+		if _, ok := schema["properties"]; ok {
+			v.object(path, schema, val)
+		}
+	}
+}
+
+// hasType reports whether a decoded JSON value has the JSON Schema type t
+func hasType(value interface{}, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		switch value.(type) {
+		case float64, float32, int, int64:
+			return true
+		}
+	case "integer":
+		switch n := value.(type) {
+		case int, int64:
+			return true
+		case float64:
+			return n == math.Trunc(n) && !math.IsInf(n, 0)
+		}
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+// typeName returns the JSON type name of a decoded value
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// stringList converts a string, []string or []interface{} schema keyword to a list
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case string:
+		return []string{list}
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			out = append(out, fmt.Sprintf("%v", item))
+		}
+		return out
+	}
+	return nil
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,18 +2,67 @@ package tool
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 
 	"github.com/aiq/aiq/internal/db"
 )
 
 // ExecuteSQL executes a SQL query and returns results
+// params are bound to the query's placeholders by the driver instead of being interpolated into the SQL text
 // This function does NOT print anything - it only returns data
 // The LLM will decide how to display the results (via render_table or text description)
-func ExecuteSQL(ctx context.Context, conn *db.Connection, sql string) (*db.QueryResult, error) {
-	result, err := conn.ExecuteQuery(ctx, sql)
+func ExecuteSQL(ctx context.Context, conn *db.Connection, sql string, params ...interface{}) (*db.QueryResult, error) {
+	result, err := conn.ExecuteQuery(ctx, sql, params...)
 	if err != nil {
 		return nil, fmt.Errorf("query execution failed: %w", err)
 	}
 	return result, nil
 }
+
+// ParseSQLParams extracts the optional "params" array from execute_sql arguments
+// JSON numbers without a fractional part are bound as integers; only scalar values are accepted
+func ParseSQLParams(args map[string]interface{}) ([]interface{}, error) {
+	raw, ok := args["params"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid params parameter: expected an array of values, got %T", raw)
+	}
+	return normalizeSQLParams(list)
+}
+
+// normalizeSQLParams converts decoded JSON values to bind values
+func normalizeSQLParams(list []interface{}) ([]interface{}, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	params := make([]interface{}, len(list))
+	for i, v := range list {
+		switch val := v.(type) {
+		case nil, string, bool:
+			params[i] = val
+		case float64:
+			if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+				params[i] = int64(val)
+			} else {
+				params[i] = val
+			}
+		case json.Number:
+			if n, err := val.Int64(); err == nil {
+				params[i] = n
+			} else if f, err := val.Float64(); err == nil {
+				params[i] = f
+			} else {
+				params[i] = val.String()
+			}
+		default:
+			return nil, fmt.Errorf("invalid params[%d]: expected a string, number, boolean or null, got %T", i, v)
+		}
+	}
+	return params, nil
+}
@@ -161,3 +161,52 @@ func TestSQLTool_ResultFormatting(t *testing.T) {
 		}
 	})
 }
+
+// TestSQLTool_ParseParams tests parsing of the optional execute_sql params array
+func TestSQLTool_ParseParams(t *testing.T) {
+	t.Run("missing params", func(t *testing.T) {
+		params, err := ParseSQLParams(map[string]interface{}{"sql": "SELECT 1"})
+		if err != nil || params != nil {
+			t.Errorf("Expected nil params and no error, got %v, %v", params, err)
+		}
+	})
+
+	t.Run("scalar values", func(t *testing.T) {
+		args := map[string]interface{}{
+			"sql":    "SELECT * FROM users WHERE email = ? AND id > ? AND score < ? AND active = ? AND deleted_at IS ?",
+			"params": []interface{}{"o'brien@example.com", float64(10), 4.5, true, nil},
+		}
+		params, err := ParseSQLParams(args)
+		if err != nil {
+			t.Fatalf("ParseSQLParams failed: %v", err)
+		}
+		if len(params) != 5 {
+			t.Fatalf("Expected 5 params, got %d", len(params))
+		}
+		if params[0] != "o'brien@example.com" {
+			t.Errorf("Expected string to pass through unchanged, got %v", params[0])
+		}
+		if v, ok := params[1].(int64); !ok || v != 10 {
+			t.Errorf("Expected whole number to be bound as int64 10, got %T %v", params[1], params[1])
+		}
+		if v, ok := params[2].(float64); !ok || v != 4.5 {
+			t.Errorf("Expected float64 4.5, got %T %v", params[2], params[2])
+		}
+		if params[3] != true || params[4] != nil {
+			t.Errorf("Expected bool and nil to pass through, got %v, %v", params[3], params[4])
+		}
+	})
+
+	t.Run("rejects non-array params", func(t *testing.T) {
+		if _, err := ParseSQLParams(map[string]interface{}{"params": "abc"}); err == nil {
+			t.Error("Expected error for non-array params")
+		}
+	})
+
+	t.Run("rejects nested values", func(t *testing.T) {
+		args := map[string]interface{}{"params": []interface{}{map[string]interface{}{"a": 1}}}
+		if _, err := ParseSQLParams(args); err == nil {
+			t.Error("Expected error for object param")
+		}
+	})
+}
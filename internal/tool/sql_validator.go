@@ -0,0 +1,893 @@
+package tool
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aiq/aiq/internal/db"
+)
+
+// maxIdentifierSuggestions is the maximum number of "did you mean" candidates per unknown identifier
+const maxIdentifierSuggestions = 3
+
+// UnknownIdentifier describes a table or column referenced in SQL that does not exist in the schema
+type UnknownIdentifier struct {
+	Kind        string   `json:"kind"`            // "table" or "column"
+	Name        string   `json:"name"`            // Identifier as written in the SQL
+	Table       string   `json:"table,omitempty"` // Table the column was resolved against (qualified references only)
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// UnknownIdentifierError is returned when SQL references tables or columns missing from the loaded schema
+// It is produced before the statement is sent to the database
+type UnknownIdentifierError struct {
+	Identifiers []UnknownIdentifier
+}
+
+// Error implements the error interface
+func (e *UnknownIdentifierError) Error() string {
+	parts := make([]string, 0, len(e.Identifiers))
+	for _, id := range e.Identifiers {
+		var msg string
+		if id.Kind == "table" {
+			msg = fmt.Sprintf("Unknown table '%s'", id.Name)
+		} else if id.Table != "" {
+			msg = fmt.Sprintf("Unknown column '%s' in table '%s'", id.Name, id.Table)
+		} else {
+			msg = fmt.Sprintf("Unknown column '%s'", id.Name)
+		}
+		if len(id.Suggestions) > 0 {
+			msg += fmt.Sprintf(" (did you mean: %s?)", strings.Join(id.Suggestions, ", "))
+		}
+		parts = append(parts, msg)
+	}
+	return "schema validation failed: " + strings.Join(parts, "; ")
+}
+
+// ValidateSQLIdentifiers checks tables and columns referenced by sql against schema
+// Returns *UnknownIdentifierError if any reference cannot be resolved, nil otherwise
+// Validation is conservative: statements or clauses that cannot be analyzed reliably
+// (DDL, subqueries, CTEs, schema-qualified names) are passed through to the database
+func ValidateSQLIdentifiers(sql string, schema *db.Schema) error {
+	if schema == nil || len(schema.Tables) == 0 {
+		return nil
+	}
+
+	idx := newSchemaIndex(schema)
+	var unknown []UnknownIdentifier
+	for _, stmt := range splitStatements(tokenizeSQL(sql)) {
+		unknown = append(unknown, validateStatement(stmt, idx)...)
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+	return &UnknownIdentifierError{Identifiers: unknown}
+}
+
+// schemaIndex provides case-insensitive lookups over a schema
+type schemaIndex struct {
+	tables map[string]*db.TableInfo
+	names  []string
+}
+
+func newSchemaIndex(schema *db.Schema) *schemaIndex {
+	idx := &schemaIndex{tables: make(map[string]*db.TableInfo, len(schema.Tables))}
+	for i := range schema.Tables {
+		t := &schema.Tables[i]
+		idx.tables[strings.ToLower(t.Name)] = t
+		idx.names = append(idx.names, t.Name)
+	}
+	return idx
+}
+
+func (s *schemaIndex) table(name string) *db.TableInfo {
+	return s.tables[strings.ToLower(name)]
+}
+
+func tableHasColumn(t *db.TableInfo, column string) bool {
+	for _, col := range t.Columns {
+		if strings.EqualFold(col.Name, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenKind classifies lexical tokens produced by tokenizeSQL
+type tokenKind int
+
+const (
+	tokWord   tokenKind = iota // Bare word (keyword or identifier)
+	tokQuoted                  // Backtick-quoted identifier
+	tokString                  // String literal (single or double quoted)
+	tokNumber                  // Numeric literal
+	tokParam                   // Placeholder or variable (?, $1, :name, @var)
+	tokCast                    // PostgreSQL ::type cast
+	tokPunct                   // Single punctuation or operator character
+)
+
+type sqlToken struct {
+	kind tokenKind
+	text string
+}
+
+func (t sqlToken) isIdent() bool {
+	if t.kind == tokQuoted {
+		return true
+	}
+	return t.kind == tokWord && !isSQLKeyword(t.text)
+}
+
+func (t sqlToken) isKeyword(kw string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, kw)
+}
+
+func (t sqlToken) isPunct(p string) bool {
+	return t.kind == tokPunct && t.text == p
+}
+
+// tokenizeSQL splits SQL into tokens, dropping whitespace and comments
+func tokenizeSQL(sql string) []sqlToken {
+	runes := []rune(sql)
+	n := len(runes)
+	tokens := make([]sqlToken, 0, n/4)
+
+	isWordRune := func(r rune) bool {
+		return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+
+	for i := 0; i < n; {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < n && runes[i+1] == '-', r == '#':
+			for i < n && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < n && runes[i+1] == '*':
+			i += 2
+			for i+1 < n && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '\'' || r == '"' || r == '`':
+			quote := r
+			start := i + 1
+			i++
+			for i < n {
+				if runes[i] == '\\' && quote != '`' {
+					i += 2
+					continue
+				}
+				if runes[i] == quote {
+					if i+1 < n && runes[i+1] == quote {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			end := i
+			if end > n {
+				end = n
+			}
+			kind := tokString
+			if quote == '`' {
+				kind = tokQuoted
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: string(runes[start:end])})
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < n && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokNumber, text: string(runes[start:i])})
+		case r == '?':
+			tokens = append(tokens, sqlToken{kind: tokParam, text: "?"})
+			i++
+		case (r == '$' || r == '@') && i+1 < n && (isWordRune(runes[i+1]) || runes[i+1] == '@'):
+			start := i
+			i++
+			for i < n && (isWordRune(runes[i]) || runes[i] == '@' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokParam, text: string(runes[start:i])})
+		case r == ':' && i+1 < n && runes[i+1] == ':':
+			i += 2
+			for i < n && unicode.IsSpace(runes[i]) {
+				i++
+			}
+			start := i
+			for i < n && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokCast, text: string(runes[start:i])})
+		case r == ':' && i+1 < n && (unicode.IsLetter(runes[i+1]) || runes[i+1] == '_'):
+			start := i
+			i++
+			for i < n && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokParam, text: string(runes[start:i])})
+		case isWordRune(r):
+			start := i
+			for i < n && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokWord, text: string(runes[start:i])})
+		default:
+			tokens = append(tokens, sqlToken{kind: tokPunct, text: string(r)})
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// splitStatements splits a token stream on top-level semicolons
+func splitStatements(tokens []sqlToken) [][]sqlToken {
+	var stmts [][]sqlToken
+	start := 0
+	for i, tok := range tokens {
+		if tok.isPunct(";") {
+			if i > start {
+				stmts = append(stmts, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		stmts = append(stmts, tokens[start:])
+	}
+	return stmts
+}
+
+// tableRef is a table referenced in FROM/JOIN/UPDATE/INTO/USING
+type tableRef struct {
+	name  string
+	alias string
+	info  *db.TableInfo // nil if the table cannot be validated (qualified or CTE)
+}
+
+// parenKind classifies the innermost parenthesized context of a token
+type parenKind int
+
+const (
+	parenNone     parenKind = iota // Top level
+	parenGroup                     // Grouping or value list
+	parenCall                      // Function call arguments
+	parenSubquery                  // Subquery
+	parenOpaque                    // Index hints, partition lists: never validated
+)
+
+// classifyParens returns the innermost paren context of every token and whether any subquery was found
+func classifyParens(tokens []sqlToken) ([]parenKind, bool) {
+	kinds := make([]parenKind, len(tokens))
+	stack := []parenKind{}
+	hasSubquery := false
+	for i, tok := range tokens {
+		current := parenNone
+		if len(stack) > 0 {
+			current = stack[len(stack)-1]
+		}
+		kinds[i] = current
+
+		if tok.isPunct("(") {
+			kind := parenGroup
+			if i > 0 && (tokens[i-1].kind == tokWord || tokens[i-1].kind == tokQuoted) {
+				prev := strings.ToUpper(tokens[i-1].text)
+				switch {
+				case prev == "INDEX" || prev == "KEY" || prev == "PARTITION":
+					kind = parenOpaque
+				case !isClauseKeyword(prev):
+					kind = parenCall
+				}
+			}
+			if i+1 < len(tokens) && (tokens[i+1].isKeyword("SELECT") || tokens[i+1].isKeyword("WITH")) {
+				kind = parenSubquery
+				hasSubquery = true
+			}
+			if current == parenOpaque {
+				kind = parenOpaque
+			}
+			stack = append(stack, kind)
+		} else if tok.isPunct(")") && len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return kinds, hasSubquery
+}
+
+// validateStatement validates a single statement and returns the unknown identifiers it references
+func validateStatement(tokens []sqlToken, idx *schemaIndex) []UnknownIdentifier {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	// EXPLAIN <statement> is validated as the wrapped statement
+	if tokens[0].isKeyword("EXPLAIN") {
+		tokens = tokens[1:]
+		for len(tokens) > 0 && (tokens[0].isKeyword("ANALYZE") || tokens[0].isKeyword("EXTENDED")) {
+			tokens = tokens[1:]
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+	}
+
+	switch strings.ToUpper(tokens[0].text) {
+	case "SELECT", "WITH", "INSERT", "REPLACE", "UPDATE", "DELETE":
+	default:
+		// DDL and administrative statements are not validated (their targets may not exist yet)
+		return nil
+	}
+
+	parens, complexQuery := classifyParens(tokens)
+	cteNames := collectCTENames(tokens)
+	if len(cteNames) > 0 {
+		complexQuery = true
+	}
+	for _, tok := range tokens {
+		if tok.kind == tokWord && isOpaqueConstruct(tok.text) {
+			complexQuery = true
+		}
+	}
+
+	consumed := make(map[int]bool)
+	aliases := make(map[string]bool)
+	var refs []tableRef
+	var unknown []UnknownIdentifier
+	reported := make(map[string]bool)
+
+	report := func(id UnknownIdentifier) {
+		key := id.Kind + ":" + strings.ToLower(id.Table) + ":" + strings.ToLower(id.Name)
+		if !reported[key] {
+			reported[key] = true
+			unknown = append(unknown, id)
+		}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		// FROM inside function calls (EXTRACT(YEAR FROM x), TRIM(... FROM x)) does not name a table
+		if tok.kind != tokWord || parens[i] == parenCall || parens[i] == parenOpaque {
+			continue
+		}
+
+		keyword := strings.ToUpper(tok.text)
+		switch keyword {
+		case "FROM", "JOIN", "INTO", "USING":
+		case "UPDATE":
+			// UPDATE only names a table at statement start (not ON DUPLICATE KEY UPDATE)
+			if i != 0 {
+				continue
+			}
+		default:
+			continue
+		}
+
+		j := i + 1
+		for j < len(tokens) {
+			if tokens[j].isPunct("(") {
+				// Derived table (or USING (col) list, which is checked like any column)
+				if keyword != "USING" {
+					complexQuery = true
+				}
+				break
+			}
+			if !tokens[j].isIdent() {
+				break
+			}
+
+			// Read possibly-qualified name: part[.part[.part]]
+			parts := []string{tokens[j].text}
+			consumed[j] = true
+			j++
+			for j+1 < len(tokens) && tokens[j].isPunct(".") && tokens[j+1].isIdent() {
+				parts = append(parts, tokens[j+1].text)
+				consumed[j+1] = true
+				j += 2
+			}
+			name := parts[len(parts)-1]
+			ref := tableRef{name: name}
+
+			if keyword != "INTO" && j < len(tokens) && tokens[j].isPunct("(") {
+				// Table-valued function such as generate_series(...)
+				complexQuery = true
+				break
+			}
+
+			if len(parts) == 1 && !cteNames[strings.ToLower(name)] {
+				if info := idx.table(name); info != nil {
+					ref.info = info
+				} else if !isSystemTable(name) {
+					report(UnknownIdentifier{
+						Kind:        "table",
+						Name:        name,
+						Suggestions: closestNames(name, idx.names),
+					})
+				}
+			}
+
+			// Optional alias: [AS] alias
+			if j < len(tokens) && tokens[j].isKeyword("AS") {
+				j++
+			}
+			if j < len(tokens) && tokens[j].isIdent() {
+				ref.alias = tokens[j].text
+				consumed[j] = true
+				aliases[strings.ToLower(ref.alias)] = true
+				j++
+			}
+			refs = append(refs, ref)
+
+			if j < len(tokens) && tokens[j].isPunct(",") && (keyword == "FROM" || keyword == "USING") {
+				j++
+				continue
+			}
+			break
+		}
+		i = j - 1
+	}
+
+	// Resolve qualifier -> table info (aliases and bare table names)
+	qualifiers := make(map[string]*tableRef)
+	allResolved := len(refs) > 0
+	for k := range refs {
+		ref := &refs[k]
+		if ref.info == nil || len(ref.info.Columns) == 0 {
+			// Unknown, qualified, CTE, or temporary table without column metadata
+			allResolved = false
+		}
+		qualifiers[strings.ToLower(ref.name)] = ref
+		if ref.alias != "" {
+			qualifiers[strings.ToLower(ref.alias)] = ref
+		}
+	}
+
+	// Collect output aliases so later references (ORDER BY total) are not flagged
+	for i, tok := range tokens {
+		if !tok.isIdent() || i == 0 {
+			continue
+		}
+		if introducesAlias(tokens[i-1]) || endsExpression(tokens[i-1]) {
+			aliases[strings.ToLower(tok.text)] = true
+		}
+	}
+
+	for i, tok := range tokens {
+		if !tok.isIdent() || consumed[i] || parens[i] == parenOpaque {
+			continue
+		}
+		if i+1 < len(tokens) {
+			next := tokens[i+1]
+			// Function call, or a unit keyword inside one (EXTRACT(EPOCH FROM ts))
+			if next.isPunct("(") || (parens[i] == parenCall && next.isKeyword("FROM")) {
+				continue
+			}
+		}
+
+		// Qualified reference: qualifier.column
+		if i+2 < len(tokens) && tokens[i+1].isPunct(".") {
+			if i > 0 && tokens[i-1].isPunct(".") {
+				continue
+			}
+			colTok := tokens[i+2]
+			if colTok.kind != tokWord && colTok.kind != tokQuoted {
+				continue
+			}
+			if i+3 < len(tokens) && tokens[i+3].isPunct(".") {
+				// schema.table.column - not validated
+				continue
+			}
+			ref, ok := qualifiers[strings.ToLower(tok.text)]
+			if !ok || ref.info == nil || len(ref.info.Columns) == 0 {
+				continue
+			}
+			if !tableHasColumn(ref.info, colTok.text) {
+				report(UnknownIdentifier{
+					Kind:        "column",
+					Name:        colTok.text,
+					Table:       ref.info.Name,
+					Suggestions: closestNames(colTok.text, columnNames(ref.info)),
+				})
+			}
+			continue
+		}
+		if i > 0 && tokens[i-1].isPunct(".") {
+			// Column part of a qualified reference, handled above
+			continue
+		}
+
+		// Unqualified reference - only when every table in scope is known
+		if complexQuery || !allResolved {
+			continue
+		}
+		name := strings.ToLower(tok.text)
+		if aliases[name] || qualifiers[name] != nil {
+			continue
+		}
+		found := false
+		for k := range refs {
+			if tableHasColumn(refs[k].info, tok.text) {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		var candidates []string
+		for k := range refs {
+			candidates = append(candidates, columnNames(refs[k].info)...)
+		}
+		id := UnknownIdentifier{
+			Kind:        "column",
+			Name:        tok.text,
+			Suggestions: closestNames(tok.text, candidates),
+		}
+		if len(refs) == 1 {
+			id.Table = refs[0].info.Name
+		}
+		report(id)
+	}
+
+	return unknown
+}
+
+// introducesAlias reports whether tok is a keyword whose following identifier is a name, not a column
+func introducesAlias(tok sqlToken) bool {
+	if tok.kind != tokWord {
+		return false
+	}
+	switch strings.ToUpper(tok.text) {
+	case "AS", "OVER", "WINDOW", "USING", "COLLATE":
+		return true
+	}
+	return false
+}
+
+// isOpaqueConstruct reports whether word introduces syntax whose names cannot be resolved from the schema
+func isOpaqueConstruct(word string) bool {
+	switch strings.ToUpper(word) {
+	case "JSON_TABLE", "XMLTABLE", "UNNEST", "LATERAL", "ONLY", "TABLESAMPLE":
+		return true
+	}
+	return false
+}
+
+// collectCTENames returns the names defined in a leading WITH clause
+func collectCTENames(tokens []sqlToken) map[string]bool {
+	names := make(map[string]bool)
+	if len(tokens) == 0 || !tokens[0].isKeyword("WITH") {
+		return names
+	}
+	depth := 0
+	expectName := true
+	for i := 1; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.isPunct("("):
+			depth++
+		case tok.isPunct(")"):
+			depth--
+		case depth == 0 && tok.isPunct(","):
+			expectName = true
+		case depth == 0 && tok.isKeyword("RECURSIVE"):
+		case depth == 0 && expectName && tok.isIdent():
+			names[strings.ToLower(tok.text)] = true
+			expectName = false
+		case depth == 0 && (tok.isKeyword("SELECT") || tok.isKeyword("INSERT") || tok.isKeyword("UPDATE") || tok.isKeyword("DELETE")):
+			return names
+		}
+	}
+	return names
+}
+
+// endsExpression reports whether tok can end an expression, making a following identifier an implicit alias
+func endsExpression(tok sqlToken) bool {
+	switch tok.kind {
+	case tokQuoted, tokString, tokNumber, tokParam, tokCast:
+		return true
+	case tokPunct:
+		return tok.text == ")" || tok.text == "*"
+	case tokWord:
+		return !isSQLKeyword(tok.text) || isValueKeyword(tok.text)
+	}
+	return false
+}
+
+func columnNames(t *db.TableInfo) []string {
+	names := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// isSystemTable reports whether name refers to a catalog table reachable without qualification
+func isSystemTable(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "dual" || strings.HasPrefix(lower, "pg_")
+}
+
+// closestNames returns up to maxIdentifierSuggestions candidates ranked by similarity to name
+// Similarity combines edit distance with shared underscore-separated tokens
+func closestNames(name string, candidates []string) []string {
+	type scored struct {
+		name  string
+		score float64
+	}
+	target := strings.ToLower(name)
+	targetTokens := splitIdentifierTokens(target)
+
+	seen := make(map[string]bool)
+	var results []scored
+	for _, cand := range candidates {
+		lower := strings.ToLower(cand)
+		if seen[lower] {
+			continue
+		}
+		seen[lower] = true
+
+		dist := levenshtein(target, lower)
+		maxLen := len(target)
+		if len(lower) > maxLen {
+			maxLen = len(lower)
+		}
+		similarity := 1 - float64(dist)/float64(maxLen)
+
+		shared := 0
+		candTokens := splitIdentifierTokens(lower)
+		for _, tt := range targetTokens {
+			for _, ct := range candTokens {
+				if tt == ct || (len(tt) >= 3 && len(ct) >= 3 && (strings.HasPrefix(ct, tt) || strings.HasPrefix(tt, ct))) {
+					shared++
+					break
+				}
+			}
+		}
+		tokenScore := 0.0
+		if len(targetTokens) > 0 {
+			tokenScore = float64(shared) / float64(len(targetTokens))
+		}
+
+		// Require either a close spelling or at least one shared token
+		if dist > 2 && similarity < 0.5 && shared == 0 {
+			continue
+		}
+		results = append(results, scored{name: cand, score: similarity + tokenScore})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	if len(results) > maxIdentifierSuggestions {
+		results = results[:maxIdentifierSuggestions]
+	}
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.name
+	}
+	return names
+}
+
+func splitIdentifierTokens(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+}
+
+// levenshtein computes the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// isClauseKeyword reports whether a keyword preceding "(" introduces a subquery or list rather than a function call
+func isClauseKeyword(word string) bool {
+	switch strings.ToUpper(word) {
+	case "IN", "EXISTS", "FROM", "JOIN", "AS", "ON", "WHERE", "AND", "OR", "NOT", "SELECT",
+		"VALUES", "ANY", "ALL", "SOME", "WITH", "UNION", "BY", "HAVING", "THEN", "ELSE",
+		"WHEN", "USING", "SET", "LATERAL", "INTERSECT", "EXCEPT", "RETURNING", "CASE", "DISTINCT":
+		return true
+	}
+	return false
+}
+
+// isValueKeyword reports whether a keyword is a value that can end an expression
+func isValueKeyword(word string) bool {
+	switch strings.ToUpper(word) {
+	case "NULL", "TRUE", "FALSE", "END", "CURRENT_DATE", "CURRENT_TIME", "CURRENT_TIMESTAMP",
+		"LOCALTIME", "LOCALTIMESTAMP", "UTC_DATE", "UTC_TIME", "UTC_TIMESTAMP", "CURRENT_USER":
+		return true
+	}
+	return false
+}
+
+// sqlKeywords contains reserved words and common non-reserved words that are never validated as identifiers
+var sqlKeywords = map[string]bool{
+	"ADD": true, "ALL": true, "ALTER": true, "ANALYZE": true, "AND": true, "ANY": true, "AS": true,
+	"ASC": true, "BETWEEN": true, "BINARY": true, "BOTH": true, "BY": true, "CASCADE": true,
+	"CASE": true, "CAST": true, "CHAR": true, "CHARACTER": true, "COLLATE": true, "COLUMN": true,
+	"CONSTRAINT": true, "CREATE": true, "CROSS": true, "CUBE": true, "CURRENT": true,
+	"CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true, "CURRENT_USER": true,
+	"DATE": true, "DATETIME": true, "DAY": true, "DECIMAL": true, "DEFAULT": true, "DELETE": true,
+	"DESC": true, "DESCRIBE": true, "DISTINCT": true, "DIV": true, "DROP": true, "DUPLICATE": true,
+	"ELSE": true, "END": true, "ESCAPE": true, "EXCEPT": true, "EXISTS": true, "EXPLAIN": true,
+	"EXTENDED": true, "FALSE": true, "FETCH": true, "FIRST": true, "FOLLOWING": true, "FOR": true,
+	"FORCE": true, "FOREIGN": true, "FROM": true, "FULL": true, "GROUP": true, "HAVING": true,
+	"HOUR": true, "IF": true, "IGNORE": true, "ILIKE": true, "IN": true, "INDEX": true, "INNER": true,
+	"INSERT": true, "INT": true, "INTEGER": true, "INTERSECT": true, "INTERVAL": true, "INTO": true,
+	"IS": true, "JOIN": true, "KEY": true, "LAST": true, "LATERAL": true, "LEADING": true, "LEFT": true,
+	"LIKE": true, "LIMIT": true, "LOCALTIME": true, "LOCALTIMESTAMP": true, "LOCK": true, "MINUTE": true,
+	"MOD": true, "MODE": true, "MONTH": true, "NATURAL": true, "NEXT": true, "NO": true, "NOT": true,
+	"NULL": true, "NULLS": true, "OF": true, "OFFSET": true, "ON": true, "ONLY": true, "OR": true,
+	"ORDER": true, "OUTER": true, "OVER": true, "PARTITION": true, "PRECEDING": true, "PRIMARY": true,
+	"QUARTER": true, "RANGE": true, "RECURSIVE": true, "REGEXP": true, "REPLACE": true,
+	"RETURNING": true, "RIGHT": true, "RLIKE": true, "ROLLUP": true, "ROW": true, "ROWS": true,
+	"SECOND": true, "SELECT": true, "SEPARATOR": true, "SET": true, "SHARE": true, "SHOW": true,
+	"SIGNED": true, "SIMILAR": true, "SOME": true, "STRAIGHT_JOIN": true, "TABLE": true, "THEN": true,
+	"TIES": true, "TIME": true, "TIMESTAMP": true, "TO": true, "TRAILING": true, "TRUE": true,
+	"UNBOUNDED": true, "UNION": true, "UNIQUE": true, "UNKNOWN": true, "UNSIGNED": true, "UPDATE": true,
+	"USE": true, "USING": true, "UTC_DATE": true, "UTC_TIME": true, "UTC_TIMESTAMP": true,
+	"VALUE": true, "VALUES": true, "VARCHAR": true, "WEEK": true, "WHEN": true, "WHERE": true,
+	"WINDOW": true, "WITH": true, "WITHIN": true, "XOR": true, "YEAR": true, "ZONE": true,
+	"HIGH_PRIORITY": true, "LOW_PRIORITY": true, "DELAYED": true, "SQL_CALC_FOUND_ROWS": true,
+	"SQL_NO_CACHE": true, "SQL_CACHE": true, "OUTFILE": true, "DUMPFILE": true,
+	// Date/time units and full-text modifiers used as bare words inside function calls
+	"MICROSECOND": true, "MILLISECOND": true, "EPOCH": true, "DOW": true, "DOY": true, "ISODOW": true,
+	"ISOYEAR": true, "DECADE": true, "CENTURY": true, "MILLENNIUM": true, "DAY_HOUR": true,
+	"DAY_MINUTE": true, "DAY_SECOND": true, "DAY_MICROSECOND": true, "HOUR_MINUTE": true,
+	"HOUR_SECOND": true, "HOUR_MICROSECOND": true, "MINUTE_SECOND": true, "MINUTE_MICROSECOND": true,
+	"SECOND_MICROSECOND": true, "YEAR_MONTH": true, "BOOLEAN": true, "LANGUAGE": true,
+	"EXPANSION": true, "QUERY": true, "AGAINST": true,
+}
+
+func isSQLKeyword(word string) bool {
+	return sqlKeywords[strings.ToUpper(word)]
+}
+
+// IsSchemaChangingSQL reports whether sql contains DDL that can change the tables or columns in the schema
+func IsSchemaChangingSQL(sql string) bool {
+	for _, stmt := range splitStatements(tokenizeSQL(sql)) {
+		if len(stmt) == 0 || stmt[0].kind != tokWord {
+			continue
+		}
+		switch strings.ToUpper(stmt[0].text) {
+		case "CREATE", "ALTER", "DROP", "RENAME":
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyStatements are the statement keywords IsReadOnlySQL accepts
+var readOnlyStatements = map[string]bool{"SELECT": true, "SHOW": true, "DESCRIBE": true, "DESC": true, "EXPLAIN": true}
+
+// intoPattern matches INTO anywhere in the text, including in spots the tokenizer treats as literals
+var intoPattern = regexp.MustCompile(`(?i)\bINTO\b`)
+
+// CountSQLStatements returns the number of statements in sql
+func CountSQLStatements(sql string) int {
+	return len(splitStatements(tokenizeSQL(sql)))
+}
+
+// IsReadOnlySQL reports whether every statement in sql only reads data: SELECT, SHOW, DESCRIBE or
+// EXPLAIN without ANALYZE, and no SELECT ... INTO. Unlike the risk assessor's whitelist it checks
+// each statement, and it errs towards false: a semicolon the tokenizer places inside a string or
+// comment makes the statement boundaries ambiguous, since the database may quote or comment differently.
+func IsReadOnlySQL(sql string) bool {
+	return everyStatement(sql, isReadOnlyStatement)
+}
+
+// IsLowRiskSQL reports whether every statement in sql is read-only or a CREATE TABLE, the statements
+// the risk assessor runs without confirmation. It is as conservative as IsReadOnlySQL.
+func IsLowRiskSQL(sql string) bool {
+	return everyStatement(sql, func(stmt []sqlToken) bool {
+		return isReadOnlyStatement(stmt) || (len(stmt) > 1 && stmt[0].isKeyword("CREATE") && stmt[1].isKeyword("TABLE"))
+	})
+}
+
+// everyStatement reports whether sql has unambiguous statement boundaries, contains no INTO and
+// every statement satisfies ok
+func everyStatement(sql string, ok func(stmt []sqlToken) bool) bool {
+	tokens := tokenizeSQL(sql)
+	separators := 0
+	for _, tok := range tokens {
+		if tok.isPunct(";") {
+			separators++
+		}
+	}
+	if separators != strings.Count(sql, ";") || intoPattern.MatchString(sql) {
+		return false
+	}
+
+	stmts := splitStatements(tokens)
+	if len(stmts) == 0 {
+		return false
+	}
+	for _, stmt := range stmts {
+		if !ok(stmt) {
+			return false
+		}
+	}
+	return true
+}
+
+// isReadOnlyStatement reports whether a single statement only reads data
+func isReadOnlyStatement(stmt []sqlToken) bool {
+	if stmt[0].kind != tokWord || !readOnlyStatements[strings.ToUpper(stmt[0].text)] {
+		return false
+	}
+	if stmt[0].isKeyword("EXPLAIN") {
+		// EXPLAIN ANALYZE runs the explained statement, which may be a write
+		for _, tok := range stmt[1:] {
+			if tok.isKeyword("ANALYZE") || tok.isKeyword("ANALYSE") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CreatedTemporaryTables returns the names of temporary tables created by sql
+// Temporary tables are not listed in INFORMATION_SCHEMA, so callers register them explicitly
+func CreatedTemporaryTables(sql string) []string {
+	var names []string
+	for _, stmt := range splitStatements(tokenizeSQL(sql)) {
+		if len(stmt) < 4 || !stmt[0].isKeyword("CREATE") {
+			continue
+		}
+		i := 1
+		if stmt[i].isKeyword("GLOBAL") || stmt[i].isKeyword("LOCAL") {
+			i++
+		}
+		if i >= len(stmt) || !(stmt[i].isKeyword("TEMPORARY") || stmt[i].isKeyword("TEMP")) {
+			continue
+		}
+		i++
+		if i >= len(stmt) || !stmt[i].isKeyword("TABLE") {
+			continue
+		}
+		i++
+		if i+2 < len(stmt) && stmt[i].isKeyword("IF") && stmt[i+1].isKeyword("NOT") && stmt[i+2].isKeyword("EXISTS") {
+			i += 3
+		}
+		if i < len(stmt) && stmt[i].isIdent() {
+			names = append(names, stmt[i].text)
+		}
+	}
+	return names
+}
@@ -0,0 +1,290 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aiq/aiq/internal/db"
+)
+
+// testSchema returns a small schema used by the validator tests
+func testSchema() *db.Schema {
+	return &db.Schema{
+		Tables: []db.TableInfo{
+			{
+				Name: "users",
+				Columns: []db.ColumnInfo{
+					{Name: "id"}, {Name: "email"}, {Name: "full_name"}, {Name: "created_at"},
+				},
+			},
+			{
+				Name: "orders",
+				Columns: []db.ColumnInfo{
+					{Name: "id"}, {Name: "user_id"}, {Name: "total_amount"}, {Name: "status"},
+				},
+			},
+			{
+				Name: "order_items",
+				Columns: []db.ColumnInfo{
+					{Name: "order_id"}, {Name: "sku"}, {Name: "quantity"},
+				},
+			},
+		},
+	}
+}
+
+func validationError(t *testing.T, sql string) *UnknownIdentifierError {
+	t.Helper()
+	err := ValidateSQLIdentifiers(sql, testSchema())
+	if err == nil {
+		return nil
+	}
+	var identErr *UnknownIdentifierError
+	if !errors.As(err, &identErr) {
+		t.Fatalf("Expected *UnknownIdentifierError, got %T: %v", err, err)
+	}
+	return identErr
+}
+
+// TestSQLValidator_ValidQueries tests that valid SQL passes validation
+func TestSQLValidator_ValidQueries(t *testing.T) {
+	queries := []string{
+		"SELECT id, email FROM users",
+		"SELECT u.email, o.total_amount FROM users u JOIN orders o ON o.user_id = u.id WHERE o.status = 'paid'",
+		"SELECT COUNT(*) AS cnt, status FROM orders GROUP BY status ORDER BY cnt DESC",
+		"SELECT full_name name FROM users ORDER BY name",
+		"SELECT EXTRACT(YEAR FROM created_at) FROM users",
+		"SELECT * FROM users WHERE email = 'Unknown column' -- trailing comment",
+		"SELECT `email` FROM `users`",
+		"INSERT INTO orders (user_id, total_amount) VALUES (1, 9.99)",
+		"UPDATE users SET email = 'a@b.c' WHERE id = 1",
+		"DELETE FROM orders WHERE status = 'cancelled'",
+		"SELECT * FROM users, orders WHERE users.id = orders.user_id",
+		"SELECT id FROM users WHERE created_at > NOW() - INTERVAL 7 DAY LIMIT 10",
+		"SELECT 1",
+		"SELECT * FROM DUAL",
+		"EXPLAIN SELECT email FROM users",
+		"SELECT email FROM users WHERE id = ?",
+		"SELECT created_at::date FROM users",
+	}
+
+	for _, sql := range queries {
+		t.Run(sql, func(t *testing.T) {
+			if identErr := validationError(t, sql); identErr != nil {
+				t.Errorf("Expected no validation error, got: %v", identErr)
+			}
+		})
+	}
+}
+
+// TestSQLValidator_UnknownColumn tests unknown column detection with suggestions
+func TestSQLValidator_UnknownColumn(t *testing.T) {
+	t.Run("unqualified column suggests closest match", func(t *testing.T) {
+		identErr := validationError(t, "SELECT emial FROM users")
+		if identErr == nil {
+			t.Fatal("Expected validation error for unknown column")
+		}
+		if len(identErr.Identifiers) != 1 {
+			t.Fatalf("Expected 1 unknown identifier, got %d", len(identErr.Identifiers))
+		}
+		id := identErr.Identifiers[0]
+		if id.Kind != "column" || id.Name != "emial" || id.Table != "users" {
+			t.Errorf("Unexpected identifier: %+v", id)
+		}
+		if len(id.Suggestions) == 0 || id.Suggestions[0] != "email" {
+			t.Errorf("Expected first suggestion 'email', got %v", id.Suggestions)
+		}
+	})
+
+	t.Run("qualified column is scoped to its table", func(t *testing.T) {
+		identErr := validationError(t, "SELECT o.amount FROM users u JOIN orders o ON o.user_id = u.id")
+		if identErr == nil {
+			t.Fatal("Expected validation error for unknown column")
+		}
+		id := identErr.Identifiers[0]
+		if id.Table != "orders" {
+			t.Errorf("Expected column resolved against 'orders', got %q", id.Table)
+		}
+		if len(id.Suggestions) == 0 || id.Suggestions[0] != "total_amount" {
+			t.Errorf("Expected suggestion 'total_amount' (shared token), got %v", id.Suggestions)
+		}
+		for _, s := range id.Suggestions {
+			if s == "email" || s == "full_name" {
+				t.Errorf("Suggestions should only come from 'orders', got %v", id.Suggestions)
+			}
+		}
+	})
+
+	t.Run("duplicate references are reported once", func(t *testing.T) {
+		identErr := validationError(t, "SELECT emial FROM users WHERE emial IS NOT NULL")
+		if identErr == nil || len(identErr.Identifiers) != 1 {
+			t.Fatalf("Expected exactly 1 unknown identifier, got %v", identErr)
+		}
+	})
+}
+
+// TestSQLValidator_UnknownTable tests unknown table detection with suggestions
+func TestSQLValidator_UnknownTable(t *testing.T) {
+	identErr := validationError(t, "SELECT * FROM order_item")
+	if identErr == nil {
+		t.Fatal("Expected validation error for unknown table")
+	}
+	id := identErr.Identifiers[0]
+	if id.Kind != "table" || id.Name != "order_item" {
+		t.Errorf("Unexpected identifier: %+v", id)
+	}
+	if len(id.Suggestions) == 0 || id.Suggestions[0] != "order_items" {
+		t.Errorf("Expected first suggestion 'order_items', got %v", id.Suggestions)
+	}
+}
+
+// TestSQLValidator_Conservative tests that constructs which cannot be resolved are not rejected
+func TestSQLValidator_Conservative(t *testing.T) {
+	t.Run("nil or empty schema skips validation", func(t *testing.T) {
+		if err := ValidateSQLIdentifiers("SELECT nope FROM nothing", nil); err != nil {
+			t.Errorf("Expected nil error for nil schema, got %v", err)
+		}
+		if err := ValidateSQLIdentifiers("SELECT nope FROM nothing", &db.Schema{}); err != nil {
+			t.Errorf("Expected nil error for empty schema, got %v", err)
+		}
+	})
+
+	queries := []string{
+		"CREATE TABLE new_table (id INT)",
+		"DROP TABLE legacy",
+		"SHOW TABLES",
+		"SELECT * FROM other_db.some_table",
+		"WITH recent AS (SELECT id FROM orders) SELECT id FROM recent",
+		"SELECT x FROM (SELECT id AS x FROM users) t",
+		"SELECT email FROM users WHERE id IN (SELECT user_id FROM orders)",
+		"SELECT * FROM generate_series(1, 10) g",
+		"SELECT id FROM users USE INDEX (idx_email)",
+		"SELECT tablename FROM pg_tables",
+	}
+	for _, sql := range queries {
+		t.Run(sql, func(t *testing.T) {
+			if identErr := validationError(t, sql); identErr != nil {
+				t.Errorf("Expected no validation error, got: %v", identErr)
+			}
+		})
+	}
+
+	t.Run("subquery tables are still validated", func(t *testing.T) {
+		identErr := validationError(t, "SELECT email FROM users WHERE id IN (SELECT user_id FROM ordrs)")
+		if identErr == nil || identErr.Identifiers[0].Kind != "table" {
+			t.Fatalf("Expected unknown table inside subquery, got %v", identErr)
+		}
+	})
+
+	t.Run("temporary table without columns skips column checks", func(t *testing.T) {
+		schema := testSchema()
+		schema.Tables = append(schema.Tables, db.TableInfo{Name: "tmp_report"})
+		if err := ValidateSQLIdentifiers("SELECT anything FROM tmp_report", schema); err != nil {
+			t.Errorf("Expected no validation error, got %v", err)
+		}
+	})
+}
+
+// TestSQLValidator_SchemaChanges tests DDL detection helpers
+func TestSQLValidator_SchemaChanges(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected bool
+	}{
+		{"CREATE TABLE t (id INT)", true},
+		{"alter table users add column age int", true},
+		{"SELECT 1; DROP TABLE t", true},
+		{"SELECT * FROM users", false},
+		{"INSERT INTO users (email) VALUES ('create table')", false},
+	}
+	for _, tt := range tests {
+		if got := IsSchemaChangingSQL(tt.sql); got != tt.expected {
+			t.Errorf("IsSchemaChangingSQL(%q) = %v, expected %v", tt.sql, got, tt.expected)
+		}
+	}
+
+	names := CreatedTemporaryTables("CREATE TEMPORARY TABLE IF NOT EXISTS tmp_sales AS SELECT * FROM orders")
+	if len(names) != 1 || names[0] != "tmp_sales" {
+		t.Errorf("Expected [tmp_sales], got %v", names)
+	}
+	if names := CreatedTemporaryTables("CREATE TABLE sales (id INT)"); len(names) != 0 {
+		t.Errorf("Expected no temporary tables, got %v", names)
+	}
+}
+
+// TestSQLValidator_ReadOnly tests classifying SQL as read-only statement by statement
+func TestSQLValidator_ReadOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected bool
+	}{
+		{"select", "SELECT * FROM users", true},
+		{"trailing semicolon and comment", "SELECT 1; -- done", true},
+		{"several reads", "SHOW TABLES; DESCRIBE users; explain select 1", true},
+		{"leading comment", "/* report */ SELECT 1", true},
+		{"semicolon in literal", "SELECT * FROM users WHERE email LIKE '%;%'", false},
+		{"write after read", "SELECT 1; DROP TABLE t", false},
+		{"write hidden by backslash escape", `SELECT 'a\'; DELETE FROM t; --'`, false},
+		{"write hidden by hash comment", "SELECT 1 # x; DELETE FROM t", false},
+		{"create table", "CREATE TABLE t (id INT)", false},
+		{"select into", "SELECT * INTO backup FROM users", false},
+		{"explain analyze", "EXPLAIN ANALYZE DELETE FROM users", false},
+		{"explain options", "EXPLAIN (ANALYZE, BUFFERS) DELETE FROM users", false},
+		{"commented write", "/* SELECT */ DELETE FROM users", false},
+		{"empty", " ; ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsReadOnlySQL(tt.sql); got != tt.expected {
+				t.Errorf("Expected IsReadOnlySQL(%q) = %v, got %v", tt.sql, tt.expected, got)
+			}
+		})
+	}
+
+	lowRisk := []struct {
+		sql      string
+		expected bool
+	}{
+		{"CREATE TABLE t (id INT)", true},
+		{"SELECT 1; CREATE TABLE t (id INT)", true},
+		{"CREATE TABLE t (id INT); DROP TABLE users", false},
+		{"CREATE INDEX idx ON users (email)", false},
+		{"INSERT INTO t VALUES (1)", false},
+	}
+	for _, tt := range lowRisk {
+		if got := IsLowRiskSQL(tt.sql); got != tt.expected {
+			t.Errorf("Expected IsLowRiskSQL(%q) = %v, got %v", tt.sql, tt.expected, got)
+		}
+	}
+
+	if n := CountSQLStatements("SELECT 1; SELECT 2;"); n != 2 {
+		t.Errorf("Expected 2 statements, got %d", n)
+	}
+	if n := CountSQLStatements("SELECT ';' -- ;"); n != 1 {
+		t.Errorf("Expected 1 statement, got %d", n)
+	}
+}
+
+// TestSQLValidator_ErrorInfo tests that validation errors convert to structured error info
+func TestSQLValidator_ErrorInfo(t *testing.T) {
+	err := ValidateSQLIdentifiers("SELECT emial FROM users", testSchema())
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	info := ExtractErrorInfo(fmt.Errorf("wrapped: %w", err))
+	if info.ErrorType != "resource_not_found" {
+		t.Errorf("Expected error type 'resource_not_found', got %q", info.ErrorType)
+	}
+	if len(info.AffectedResources) != 1 || info.AffectedResources[0] != "users.emial" {
+		t.Errorf("Expected affected resource 'users.emial', got %v", info.AffectedResources)
+	}
+	if len(info.UnknownIdentifiers) != 1 {
+		t.Errorf("Expected 1 unknown identifier, got %d", len(info.UnknownIdentifiers))
+	}
+	if len(info.SuggestedActions) == 0 {
+		t.Error("Expected suggested actions")
+	}
+}
@@ -2,9 +2,16 @@ package tool
 
 import (
 	"context"
+	"errors"
 	"fmt"
+
+	"github.com/aiq/aiq/internal/llm"
+	"github.com/aiq/aiq/internal/tool/schema"
 )
 
+// ErrUnknownTool is returned for calls of tools that are not registered
+var ErrUnknownTool = errors.New("unknown tool")
+
 // Tool represents a callable tool/function
 type Tool struct {
 	Name        string
@@ -13,6 +20,36 @@ type Tool struct {
 	Execute     func(ctx context.Context, params map[string]interface{}) (interface{}, error)
 }
 
+// NewTool creates a tool whose parameters are described by the struct type P
+// The JSON Schema offered to the LLM is generated from P's tags (see package schema);
+// arguments are validated against it by the registry and decoded into P before run is called.
+func NewTool[P any](name, description string, run func(ctx context.Context, params P) (interface{}, error)) *Tool {
+	var zero P
+	return &Tool{
+		Name:        name,
+		Description: description,
+		Parameters:  schema.Generate(zero),
+		Execute: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			var params P
+			if err := schema.Decode(args, &params); err != nil {
+				return nil, err
+			}
+			return run(ctx, params)
+		},
+	}
+}
+
+// Validate checks arguments against the tool's parameter schema
+// Invalid arguments are reported as a *schema.ValidationError.
+func (t *Tool) Validate(args map[string]interface{}) error {
+	return schema.Validate(t.Name, t.Parameters, args)
+}
+
+// Function returns the tool as an LLM function definition
+func (t *Tool) Function() llm.Function {
+	return llm.Function{Name: t.Name, Description: t.Description, Parameters: t.Parameters}
+}
+
 // ToolCall represents a request to call a tool
 type ToolCall struct {
 	Name      string                 `json:"name"`
@@ -29,6 +66,7 @@ type ToolResult struct {
 // Registry manages available tools
 type Registry struct {
 	tools map[string]*Tool
+	order []string // Registration order, which is the order tools are offered to the LLM
 }
 
 // NewRegistry creates a new tool registry
@@ -40,6 +78,9 @@ func NewRegistry() *Registry {
 
 // Register registers a tool in the registry
 func (r *Registry) Register(tool *Tool) {
+	if _, exists := r.tools[tool.Name]; !exists {
+		r.order = append(r.order, tool.Name)
+	}
 	r.tools[tool.Name] = tool
 }
 
@@ -47,16 +88,52 @@ func (r *Registry) Register(tool *Tool) {
 func (r *Registry) GetTool(name string) (*Tool, error) {
 	tool, exists := r.tools[name]
 	if !exists {
-		return nil, fmt.Errorf("tool '%s' not found", name)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTool, name)
 	}
 	return tool, nil
 }
 
+// Has reports whether a tool is registered
+func (r *Registry) Has(name string) bool {
+	_, exists := r.tools[name]
+	return exists
+}
+
+// Validate checks the arguments of a call of the named tool
+func (r *Registry) Validate(name string, args map[string]interface{}) error {
+	tool, err := r.GetTool(name)
+	if err != nil {
+		return err
+	}
+	return tool.Validate(args)
+}
+
+// Execute validates the arguments and runs the named tool
+func (r *Registry) Execute(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+	tool, err := r.GetTool(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := tool.Validate(args); err != nil {
+		return nil, err
+	}
+	return tool.Execute(ctx, args)
+}
+
+// Functions returns all registered tools as LLM functions, in registration order
+func (r *Registry) Functions() []llm.Function {
+	functions := make([]llm.Function, 0, len(r.order))
+	for _, name := range r.order {
+		functions = append(functions, r.tools[name].Function())
+	}
+	return functions
+}
+
 // ListTools returns all registered tools
 func (r *Registry) ListTools() []*Tool {
-	tools := make([]*Tool, 0, len(r.tools))
-	for _, tool := range r.tools {
-		tools = append(tools, tool)
+	tools := make([]*Tool, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, r.tools[name])
 	}
 	return tools
 }
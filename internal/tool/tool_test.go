@@ -0,0 +1,123 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aiq/aiq/internal/tool/builtin"
+	"github.com/aiq/aiq/internal/tool/schema"
+)
+
+type greetParams struct {
+	Name  string `json:"name" description:"Who to greet"`
+	Times int    `json:"times,omitempty"`
+}
+
+// TestRegistry_TypedTools tests typed tools, argument validation and registration order
+func TestRegistry_TypedTools(t *testing.T) {
+	var got greetParams
+	r := NewRegistry()
+	r.Register(NewTool("greet", "Greet someone", func(ctx context.Context, p greetParams) (interface{}, error) {
+		got = p
+		return "hello " + p.Name, nil
+	}))
+	r.Register(NewTool("noop", "Do nothing", func(ctx context.Context, p struct{}) (interface{}, error) {
+		return nil, nil
+	}))
+
+	t.Run("functions in registration order", func(t *testing.T) {
+		functions := r.Functions()
+		if len(functions) != 2 || functions[0].Name != "greet" || functions[1].Name != "noop" {
+			t.Fatalf("Expected greet and noop, got %+v", functions)
+		}
+		props := functions[0].Parameters["properties"].(map[string]interface{})
+		if props["name"].(map[string]interface{})["description"] != "Who to greet" {
+			t.Errorf("Expected generated description, got %v", props["name"])
+		}
+	})
+
+	t.Run("decodes valid arguments", func(t *testing.T) {
+		result, err := r.Execute(context.Background(), "greet", map[string]interface{}{"name": "ann", "times": float64(2)})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if result != "hello ann" || got.Times != 2 {
+			t.Errorf("Expected decoded parameters, got %v and %+v", result, got)
+		}
+	})
+
+	t.Run("rejects invalid arguments", func(t *testing.T) {
+		got = greetParams{}
+		_, err := r.Execute(context.Background(), "greet", map[string]interface{}{"times": "two"})
+		var validationErr *schema.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationError, got %v", err)
+		}
+		if len(validationErr.Errors) != 2 {
+			t.Errorf("Expected errors for name and times, got %+v", validationErr.Errors)
+		}
+		if got.Name != "" {
+			t.Error("Expected tool not to run")
+		}
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		if _, err := r.Execute(context.Background(), "missing", nil); !errors.Is(err, ErrUnknownTool) {
+			t.Errorf("Expected ErrUnknownTool, got %v", err)
+		}
+	})
+}
+
+// TestNewDefaultRegistry tests the tools offered in free mode
+func TestNewDefaultRegistry(t *testing.T) {
+	r := NewDefaultRegistry(nil, nil)
+	if r.Has("execute_sql") {
+		t.Error("Expected no execute_sql without a database connection")
+	}
+	for _, name := range []string{"render_table", "render_chart", "http_request", "execute_command", "file_operations"} {
+		if !r.Has(name) {
+			t.Errorf("Expected %s to be registered", name)
+		}
+	}
+
+	t.Run("render_table accepts scalar cells", func(t *testing.T) {
+		result, err := r.Execute(context.Background(), "render_table", map[string]interface{}{
+			"columns": []interface{}{"name", "total"},
+			"rows":    []interface{}{[]interface{}{"a", float64(3)}},
+		})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if result.(map[string]interface{})["status"] != "success" {
+			t.Errorf("Expected success, got %v", result)
+		}
+	})
+
+	t.Run("render_chart validates chart_type", func(t *testing.T) {
+		_, err := r.Execute(context.Background(), "render_chart", map[string]interface{}{
+			"columns":    []interface{}{"a"},
+			"rows":       []interface{}{},
+			"chart_type": "donut",
+		})
+		var validationErr *schema.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Errors[0].Field != "chart_type" {
+			t.Errorf("Expected chart_type error, got %v", err)
+		}
+	})
+
+	t.Run("execute_command streams output to the context callback", func(t *testing.T) {
+		var lines []string
+		ctx := builtin.WithOutputCallback(context.Background(), func(line string) { lines = append(lines, line) })
+		result, err := r.Execute(ctx, "execute_command", map[string]interface{}{"command": "echo streamed"})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if cmdResult, ok := result.(builtin.CommandResult); !ok || cmdResult.ExitCode != 0 {
+			t.Errorf("Expected a successful command result, got %v", result)
+		}
+		if len(lines) != 1 || lines[0] != "streamed" {
+			t.Errorf("Expected the output line to be streamed, got %q", lines)
+		}
+	})
+}
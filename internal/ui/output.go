@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
@@ -14,6 +15,7 @@ type RollingOutput struct {
 	lines        []string // Buffer of all output lines
 	printedLines int      // Number of lines currently displayed
 	enabled      bool     // Whether ANSI sequences are supported
+	out          io.Writer
 	mu           sync.Mutex
 }
 
@@ -31,6 +33,7 @@ func NewRollingOutput(windowSize int) *RollingOutput {
 		lines:        make([]string, 0),
 		printedLines: 0,
 		enabled:      isANSISupported(),
+		out:          os.Stdout,
 	}
 }
 
@@ -63,7 +66,7 @@ func (r *RollingOutput) updateDisplay() {
 	if !r.enabled {
 		// Fallback: just print the latest line
 		if len(r.lines) > 0 {
-			fmt.Printf("  %s\n", HintText(r.lines[len(r.lines)-1]))
+			fmt.Fprintf(r.out, "  %s\n", HintText(r.lines[len(r.lines)-1]))
 		}
 		return
 	}
@@ -79,13 +82,13 @@ func (r *RollingOutput) updateDisplay() {
 	if r.printedLines > 0 {
 		for i := 0; i < r.printedLines; i++ {
 			// Move up one line and clear it
-			fmt.Print("\033[A\033[K")
+			fmt.Fprint(r.out, "\033[A\033[K")
 		}
 	}
 
 	// Print the new lines
 	for _, line := range displayLines {
-		fmt.Printf("  %s\n", HintText(line))
+		fmt.Fprintf(r.out, "  %s\n", HintText(line))
 	}
 
 	// Update count of printed lines
@@ -104,7 +107,7 @@ func (r *RollingOutput) Finish() {
 
 	// If there are more lines than displayed, show a hint
 	if len(r.lines) > r.windowSize {
-		fmt.Printf("  %s\n", HintText(fmt.Sprintf("... (%d more lines above)", len(r.lines)-r.windowSize)))
+		fmt.Fprintf(r.out, "  %s\n", HintText(fmt.Sprintf("... (%d more lines above)", len(r.lines)-r.windowSize)))
 	}
 }
 
@@ -2,6 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"sync"
 	"time"
 )
 
@@ -11,6 +14,10 @@ type Spinner struct {
 	index  int
 	active bool
 	done   chan bool
+	out    io.Writer
+
+	mu      sync.Mutex
+	message string
 }
 
 // NewSpinner creates a new spinner with default frames
@@ -20,6 +27,7 @@ func NewSpinner() *Spinner {
 		index:  0,
 		active: false,
 		done:   make(chan bool),
+		out:    os.Stdout,
 	}
 }
 
@@ -29,6 +37,7 @@ func (s *Spinner) Start(message string) {
 		return
 	}
 	s.active = true
+	s.SetMessage(message)
 	
 	go func() {
 		ticker := time.NewTicker(100 * time.Millisecond)
@@ -37,7 +46,9 @@ func (s *Spinner) Start(message string) {
 		for {
 			select {
 			case <-ticker.C:
-				fmt.Printf("\r%s %s", s.frames[s.index], message)
+				s.mu.Lock()
+				fmt.Fprintf(s.out, "\r%s %s\033[K", s.frames[s.index], s.message)
+				s.mu.Unlock()
 				s.index = (s.index + 1) % len(s.frames)
 			case <-s.done:
 				return
@@ -46,6 +57,13 @@ func (s *Spinner) Start(message string) {
 	}()
 }
 
+// SetMessage replaces the message shown next to the spinner
+func (s *Spinner) SetMessage(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.message = message
+}
+
 // Stop stops the spinner animation
 func (s *Spinner) Stop() {
 	if !s.active {
@@ -53,12 +71,17 @@ func (s *Spinner) Stop() {
 	}
 	s.active = false
 	s.done <- true
-	fmt.Print("\r\033[K") // Clear the line
+	fmt.Fprint(s.out, "\r\033[K") // Clear the line
 }
 
 // ShowLoading displays a loading message with spinner
+// Without a terminal (cron, CI, pipes) the animation is skipped so logs stay clean
 func ShowLoading(message string) func() {
-	spinner := NewSpinner()
-	spinner.Start(message)
-	return spinner.Stop
+	return NewOutput(os.Stdout).Loading(message)
+}
+
+// ShowLoadingStatus displays a loading message with spinner like ShowLoading and also returns a
+// function that replaces the message, e.g. to report a wait
+func ShowLoadingStatus(message string) (setMessage func(string), stop func()) {
+	return NewOutput(os.Stdout).LoadingStatus(message)
 }
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output writes messages, tables and spinners to a writer instead of the process's stdout
+// Non-interactive modes pass stderr (or a log) so their stdout only carries data.
+// A nil Output writes to os.Stdout.
+type Output struct {
+	w io.Writer
+}
+
+// NewOutput returns an Output writing to w
+func NewOutput(w io.Writer) *Output {
+	return &Output{w: w}
+}
+
+// Writer returns the writer the output goes to
+func (o *Output) Writer() io.Writer {
+	if o == nil || o.w == nil {
+		return os.Stdout
+	}
+	return o.w
+}
+
+// isTerminal reports whether the output goes to a terminal, where ANSI sequences and spinners work
+func (o *Output) isTerminal() bool {
+	f, ok := o.Writer().(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Print writes its operands like fmt.Print
+func (o *Output) Print(a ...interface{}) {
+	fmt.Fprint(o.Writer(), a...)
+}
+
+// Println writes its operands like fmt.Println
+func (o *Output) Println(a ...interface{}) {
+	fmt.Fprintln(o.Writer(), a...)
+}
+
+// Printf writes a formatted string like fmt.Printf
+func (o *Output) Printf(format string, a ...interface{}) {
+	fmt.Fprintf(o.Writer(), format, a...)
+}
+
+// Success displays a success message
+func (o *Output) Success(message string) {
+	o.Println(SuccessText("✓ " + message))
+}
+
+// Error displays an error message
+func (o *Output) Error(message string) {
+	o.Println(ErrorText("✗ " + message))
+}
+
+// Info displays an info message
+func (o *Output) Info(message string) {
+	o.Println(InfoText("ℹ " + message))
+}
+
+// Warning displays a warning message
+func (o *Output) Warning(message string) {
+	o.Println(WarningText("⚠ " + message))
+}
+
+// Table prints a table
+func (o *Output) Table(headers []string, rows [][]string) {
+	table := NewTable(headers)
+	for _, row := range rows {
+		table.AddRow(row)
+	}
+	o.Println(table.Render())
+}
+
+// Chart displays a chart with its type and title
+func (o *Output) Chart(chartOutput string, chartType string, title string) {
+	o.Println()
+	if title != "" {
+		o.Println(InfoText(fmt.Sprintf("Chart Type: %s | Title: %s", chartType, title)))
+	} else {
+		o.Println(InfoText(fmt.Sprintf("Chart Type: %s", chartType)))
+	}
+	o.Println()
+	o.Println(chartOutput)
+	o.Println()
+}
+
+// Loading displays a spinner and returns a function that stops it
+// Without a terminal the animation is skipped so logs stay clean
+func (o *Output) Loading(message string) func() {
+	_, stop := o.LoadingStatus(message)
+	return stop
+}
+
+// LoadingStatus displays a spinner like Loading and also returns a function that replaces its message
+func (o *Output) LoadingStatus(message string) (setMessage func(string), stop func()) {
+	if !o.isTerminal() {
+		return func(string) {}, func() {}
+	}
+	spinner := NewSpinner()
+	spinner.out = o.Writer()
+	spinner.Start(message)
+	return spinner.SetMessage, spinner.Stop
+}
+
+// RollingOutput returns a rolling window display writing to the output
+func (o *Output) RollingOutput(windowSize int) *RollingOutput {
+	r := NewRollingOutput(windowSize)
+	r.out = o.Writer()
+	r.enabled = o.isTerminal()
+	return r
+}